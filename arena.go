@@ -0,0 +1,84 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+// defaultArenaBatchSize is the default number of nodes allocated at a time by
+// a NodeArena.
+const defaultArenaBatchSize = 1024
+
+// NodeArena allocates nodes in batches to reduce the number of allocations
+// and the pressure on the garbage collector when building many small parse
+// trees, e.g. in batch pipelines. All nodes allocated by the arena are freed
+// at once by Reset and the memory is reused for later allocations.
+//
+// A NodeArena is not safe for concurrent use.
+type NodeArena[V comparable] struct {
+	batchSize int
+
+	// slabs are the batches of nodes allocated by the arena.
+	slabs [][]Node[V]
+
+	// slab is the index of the current slab in slabs.
+	slab int
+
+	// next is the index of the next free node in the current slab.
+	next int
+}
+
+// NewNodeArena returns a new NodeArena that allocates batchSize nodes at a
+// time. If batchSize is zero or less a default size is used.
+func NewNodeArena[V comparable](batchSize int) *NodeArena[V] {
+	if batchSize <= 0 {
+		batchSize = defaultArenaBatchSize
+	}
+	return &NodeArena[V]{
+		batchSize: batchSize,
+	}
+}
+
+// New returns a new zero valued node allocated from the arena.
+func (a *NodeArena[V]) New() *Node[V] {
+	if a.slab < len(a.slabs) && a.next == len(a.slabs[a.slab]) {
+		a.slab++
+		a.next = 0
+	}
+	if a.slab == len(a.slabs) {
+		a.slabs = append(a.slabs, make([]Node[V], a.batchSize))
+	}
+
+	n := &a.slabs[a.slab][a.next]
+	a.next++
+	return n
+}
+
+// Len returns the number of nodes allocated since the arena was created or
+// last reset.
+func (a *NodeArena[V]) Len() int {
+	return a.slab*a.batchSize + a.next
+}
+
+// Reset frees all nodes allocated by the arena so that the memory can be
+// reused. Nodes allocated before Reset, and trees containing them, must not be
+// used after calling Reset.
+func (a *NodeArena[V]) Reset() {
+	for i := 0; i <= a.slab && i < len(a.slabs); i++ {
+		var zero Node[V]
+		for j := range a.slabs[i] {
+			a.slabs[i][j] = zero
+		}
+	}
+	a.slab = 0
+	a.next = 0
+}
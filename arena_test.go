@@ -0,0 +1,89 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNodeArena(t *testing.T) {
+	t.Parallel()
+
+	a := NewNodeArena[string](2)
+
+	var nodes []*Node[string]
+	for i := 0; i < 5; i++ {
+		n := a.New()
+		if diff := cmp.Diff(&Node[string]{}, n); diff != "" {
+			t.Errorf("New: (-want, +got): \n%s", diff)
+		}
+		n.Value = "x"
+		nodes = append(nodes, n)
+	}
+	if got, want := a.Len(), 5; got != want {
+		t.Errorf("Len: want: %v, got: %v", want, got)
+	}
+	if got, want := len(a.slabs), 3; got != want {
+		t.Errorf("slabs: want: %v, got: %v", want, got)
+	}
+
+	a.Reset()
+	if got, want := a.Len(), 0; got != want {
+		t.Errorf("Len: want: %v, got: %v", want, got)
+	}
+
+	// Memory is reused after Reset.
+	for i := 0; i < 5; i++ {
+		n := a.New()
+		if n != nodes[i] {
+			t.Errorf("New: node %d was not reused", i)
+		}
+		if diff := cmp.Diff(&Node[string]{}, n); diff != "" {
+			t.Errorf("New: (-want, +got): \n%s", diff)
+		}
+	}
+	if got, want := len(a.slabs), 3; got != want {
+		t.Errorf("slabs: want: %v, got: %v", want, got)
+	}
+}
+
+func TestParser_SetArena(t *testing.T) {
+	t.Parallel()
+
+	a := NewNodeArena[string](0)
+	p := NewParser[string](nil)
+	p.SetArena(a)
+
+	_ = p.Push("A")
+	_ = p.Node("B")
+	_ = p.Climb()
+	_ = p.Node("C")
+
+	want := newTree(
+		&Node[string]{
+			Value:    "A",
+			Children: []*Node[string]{{Value: "B"}},
+		},
+		&Node[string]{Value: "C"},
+	)
+	if diff := cmp.Diff(want, p.Root()); diff != "" {
+		t.Errorf("Root: (-want, +got): \n%s", diff)
+	}
+	if got, want := a.Len(), 3; got != want {
+		t.Errorf("Len: want: %v, got: %v", want, got)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrPrefixSize indicates that an unsupported length prefix size was used.
+var ErrPrefixSize = errors.New("unsupported length prefix size")
+
+// ByteState is a state of a ByteLexer. It returns the next state or nil when
+// lexing is done. Returning io.EOF stops lexing without an error.
+type ByteState func(ctx context.Context, l *ByteLexer) (ByteState, error)
+
+// ByteLexer lexes binary input such as wire formats. It provides primitives
+// for reading fixed-length fields, length-prefixed fields, and
+// delimiter-terminated fields. The Pos of each emitted Lexeme is the byte
+// offset of the field in the input and its Value holds the field's bytes.
+// Line and Column are always zero.
+type ByteLexer struct {
+	r     *bufio.Reader
+	state ByteState
+
+	// pos is the byte offset of the next byte of input.
+	pos int
+
+	lexemes chan *Lexeme
+	done    chan struct{}
+
+	// stop is closed when lexing should stop.
+	stop chan struct{}
+
+	mu      sync.Mutex
+	err     error
+	started bool
+}
+
+// NewByteLexer returns a new ByteLexer that reads from r starting with the
+// given state.
+func NewByteLexer(r io.Reader, startingState ByteState) *ByteLexer {
+	return &ByteLexer{
+		r:       bufio.NewReader(r),
+		state:   startingState,
+		lexemes: make(chan *Lexeme),
+		done:    make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Lex starts a new goroutine to lex the input and returns a channel of
+// lexemes. The channel is closed when lexing is done, an error occurs, or ctx
+// is cancelled.
+func (l *ByteLexer) Lex(ctx context.Context) <-chan *Lexeme {
+	l.mu.Lock()
+	l.started = true
+	l.mu.Unlock()
+
+	// Close the stop channel when ctx is done so that Emit doesn't block.
+	go func() {
+		select {
+		case <-ctx.Done():
+			close(l.stop)
+		case <-l.done:
+		}
+	}()
+
+	go func() {
+		defer close(l.done)
+		defer close(l.lexemes)
+		for l.state != nil {
+			select {
+			case <-ctx.Done():
+				l.setErr(ctx.Err())
+				return
+			default:
+			}
+
+			var err error
+			l.state, err = l.state(ctx, l)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					l.setErr(err)
+				}
+				return
+			}
+		}
+	}()
+	return l.lexemes
+}
+
+// Pos returns the byte offset of the next byte of input.
+func (l *ByteLexer) Pos() int {
+	return l.pos
+}
+
+// Peek returns the next n bytes without advancing the reader.
+func (l *ByteLexer) Peek(n int) ([]byte, error) {
+	b, err := l.r.Peek(n)
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return b, err
+}
+
+// Discard skips the next n bytes and returns the number of bytes discarded.
+func (l *ByteLexer) Discard(n int) (int, error) {
+	d, err := l.r.Discard(n)
+	l.pos += d
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return d, err
+}
+
+// read reads exactly n bytes. The buffer grows as input is read so that a
+// large n from untrusted input doesn't cause a large allocation. io.EOF is
+// returned if no bytes are read and io.ErrUnexpectedEOF if fewer than n
+// bytes are read.
+func (l *ByteLexer) read(n uint64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(l.r, int64(n)))
+	l.pos += len(b)
+	if err != nil {
+		return b, fmt.Errorf("reading input: %w", err)
+	}
+	if uint64(len(b)) < n {
+		if len(b) == 0 {
+			return b, io.EOF
+		}
+		return b, io.ErrUnexpectedEOF
+	}
+	return b, nil
+}
+
+// Fixed reads a field of n bytes and returns a lexeme of the given type for
+// it. The lexeme is not emitted. An error wrapping bufio.ErrNegativeCount is
+// returned if n is negative.
+func (l *ByteLexer) Fixed(typ LexemeType, n int) (*Lexeme, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("%w: %d", bufio.ErrNegativeCount, n)
+	}
+	pos := l.pos
+	b, err := l.read(uint64(n))
+	if err != nil {
+		return nil, err
+	}
+	return &Lexeme{Type: typ, Value: string(b), Pos: pos}, nil
+}
+
+// LengthPrefixed reads a field preceded by its length in bytes and returns a
+// lexeme of the given type for it. The length is an unsigned integer of
+// prefixSize bytes, which must be 1, 2, 4, or 8, encoded using order. The
+// lexeme's Pos is the offset of the prefix and its Value is the field without
+// the prefix. The lexeme is not emitted.
+func (l *ByteLexer) LengthPrefixed(typ LexemeType, prefixSize int, order binary.ByteOrder) (*Lexeme, error) {
+	switch prefixSize {
+	case 1, 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrPrefixSize, prefixSize)
+	}
+
+	pos := l.pos
+	prefix, err := l.read(uint64(prefixSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var n uint64
+	switch prefixSize {
+	case 1:
+		n = uint64(prefix[0])
+	case 2:
+		n = uint64(order.Uint16(prefix))
+	case 4:
+		n = uint64(order.Uint32(prefix))
+	case 8:
+		n = order.Uint64(prefix)
+	}
+
+	b, err := l.read(n)
+	if errors.Is(err, io.EOF) && n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Lexeme{Type: typ, Value: string(b), Pos: pos}, nil
+}
+
+// Delimited reads a field terminated by delim and returns a lexeme of the
+// given type for it. The delimiter is consumed but not included in the
+// lexeme's Value. The lexeme is not emitted.
+func (l *ByteLexer) Delimited(typ LexemeType, delim byte) (*Lexeme, error) {
+	pos := l.pos
+	b, err := l.r.ReadBytes(delim)
+	l.pos += len(b)
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(b) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	return &Lexeme{Type: typ, Value: string(b[:len(b)-1]), Pos: pos}, nil
+}
+
+// Emit emits the lexeme. It blocks until the lexeme is received or lexing
+// is cancelled. If Lex has not been called, this is a no-op.
+func (l *ByteLexer) Emit(lexeme *Lexeme) {
+	l.mu.Lock()
+	started := l.started
+	l.mu.Unlock()
+	if !started {
+		return
+	}
+
+	select {
+	case l.lexemes <- lexeme:
+	case <-l.stop:
+	}
+}
+
+// setErr sets the lexer's error value.
+func (l *ByteLexer) setErr(err error) {
+	l.mu.Lock()
+	if l.err == nil {
+		l.err = err
+	}
+	l.mu.Unlock()
+}
+
+// Err returns the first error encountered.
+func (l *ByteLexer) Err() error {
+	l.mu.Lock()
+	err := l.err
+	l.mu.Unlock()
+	return err
+}
+
+// Done returns a channel that is closed when the lexer is finished running.
+func (l *ByteLexer) Done() <-chan struct{} {
+	return l.done
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	magicType LexemeType = iota + 300
+	blobType
+	nameType
+)
+
+// recordState lexes records consisting of a 2 byte magic number, a
+// length-prefixed blob, and a NUL-terminated name.
+func recordState(_ context.Context, l *ByteLexer) (ByteState, error) {
+	magic, err := l.Fixed(magicType, 2)
+	if err != nil {
+		return nil, err
+	}
+	l.Emit(magic)
+
+	blob, err := l.LengthPrefixed(blobType, 2, binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	l.Emit(blob)
+
+	name, err := l.Delimited(nameType, 0)
+	if err != nil {
+		return nil, err
+	}
+	l.Emit(name)
+
+	return recordState, nil
+}
+
+func TestByteLexer(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input []byte
+		want  []*Lexeme
+		err   error
+	}{
+		"records": {
+			input: []byte("MZ\x00\x03abcfoo\x00MZ\x00\x00\x00"),
+			want: []*Lexeme{
+				{Type: magicType, Value: "MZ", Pos: 0},
+				{Type: blobType, Value: "abc", Pos: 2},
+				{Type: nameType, Value: "foo", Pos: 7},
+				{Type: magicType, Value: "MZ", Pos: 11},
+				{Type: blobType, Value: "", Pos: 13},
+				{Type: nameType, Value: "", Pos: 15},
+			},
+		},
+		"short blob": {
+			input: []byte("MZ\xff\xffabc"),
+			want: []*Lexeme{
+				{Type: magicType, Value: "MZ", Pos: 0},
+			},
+			err: io.ErrUnexpectedEOF,
+		},
+		"unterminated name": {
+			input: []byte("MZ\x00\x00foo"),
+			want: []*Lexeme{
+				{Type: magicType, Value: "MZ", Pos: 0},
+				{Type: blobType, Value: "", Pos: 2},
+			},
+			err: io.ErrUnexpectedEOF,
+		},
+		"short magic": {
+			input: []byte("M"),
+			err:   io.ErrUnexpectedEOF,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewByteLexer(bytes.NewReader(tc.input), recordState)
+			var got []*Lexeme
+			for lexeme := range l.Lex(context.Background()) {
+				got = append(got, lexeme)
+			}
+			<-l.Done()
+
+			if err := l.Err(); !errors.Is(err, tc.err) {
+				t.Errorf("Err: want: %v, got: %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Lex: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestByteLexer_LengthPrefixed(t *testing.T) {
+	t.Parallel()
+
+	l := NewByteLexer(bytes.NewReader([]byte("\x03\x00\x00\x00abcd")), nil)
+	if _, err := l.LengthPrefixed(blobType, 3, binary.LittleEndian); !errors.Is(err, ErrPrefixSize) {
+		t.Errorf("LengthPrefixed: want: %v, got: %v", ErrPrefixSize, err)
+	}
+
+	got, err := l.LengthPrefixed(blobType, 4, binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("LengthPrefixed: %v", err)
+	}
+	if diff := cmp.Diff(&Lexeme{Type: blobType, Value: "abc"}, got); diff != "" {
+		t.Errorf("LengthPrefixed: (-want, +got): \n%s", diff)
+	}
+	if got, want := l.Pos(), 7; got != want {
+		t.Errorf("Pos: want: %v, got: %v", want, got)
+	}
+}
+
+func TestByteLexer_Fixed_negative(t *testing.T) {
+	t.Parallel()
+
+	l := NewByteLexer(bytes.NewReader([]byte("abc")), nil)
+	if _, err := l.Fixed(blobType, -1); !errors.Is(err, bufio.ErrNegativeCount) {
+		t.Errorf("Fixed: want: %v, got: %v", bufio.ErrNegativeCount, err)
+	}
+	if got, want := l.Pos(), 0; got != want {
+		t.Errorf("Pos: want: %d, got: %d", want, got)
+	}
+}
+
+func TestByteLexer_Emit(t *testing.T) {
+	t.Parallel()
+
+	// Emit before Lex returns rather than blocking.
+	l := NewByteLexer(bytes.NewReader(nil), nil)
+	l.Emit(&Lexeme{Type: blobType})
+
+	// Emit returns when ctx is cancelled even if the lexeme isn't received.
+	ctx, cancel := context.WithCancel(context.Background())
+	l = NewByteLexer(bytes.NewReader(nil), func(_ context.Context, l *ByteLexer) (ByteState, error) {
+		l.Emit(&Lexeme{Type: blobType})
+		return nil, nil
+	})
+	_ = l.Lex(ctx)
+	cancel()
+	<-l.Done()
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+// Checkpoint is a snapshot of the state of a Parser created by
+// Parser.Checkpoint.
+type Checkpoint[V comparable] struct {
+	// released is true if the checkpoint has been restored or released.
+	released bool
+
+	// tapePos is the position in the parser's lexeme tape.
+	tapePos int
+
+	lexeme   *Lexeme
+	next     *Lexeme
+	history  [historySize]*Lexeme
+	consumed int
+	nodes    int
+	warnings int
+
+	// node is the current node.
+	node *Node[V]
+
+	// boundaries is a copy of the parser's boundaries.
+	boundaries []*Node[V]
+
+	// path holds the number of children of the current node and each of its
+	// ancestors.
+	path []int
+}
+
+// Checkpoint returns a snapshot of the parser's lexeme lookahead and current
+// position in the tree. Lexemes consumed after the checkpoint is created are
+// buffered so that they can be read again after calling Restore. This allows
+// speculative parsing; a ParseFn can try one rule and if it fails, restore the
+// checkpoint and try another.
+//
+// Each checkpoint must be passed to either Restore or Release so that the
+// parser can stop buffering lexemes.
+func (p *Parser[V]) Checkpoint() *Checkpoint[V] {
+	cp := &Checkpoint[V]{
+		tapePos:  p.tapePos,
+		lexeme:   p.lexeme,
+		next:     p.next,
+		history:  p.history,
+		consumed: p.consumed,
+		nodes:    p.nodes,
+		warnings: len(p.warnings),
+		node:     p.node,
+
+		boundaries: append([]*Node[V](nil), p.boundaries...),
+	}
+	for n := p.node; n != nil; n = n.Parent {
+		cp.path = append(cp.path, len(n.Children))
+	}
+	p.checkpoints++
+	return cp
+}
+
+// Restore rewinds the parser to the state saved in cp and releases cp. Nodes
+// added to the current node at the time of the checkpoint, or to any of its
+// ancestors, are removed from the tree. Changes made to existing nodes by
+// Replace, RotateLeft, and RotateRight are not undone, and hooks are not
+// called for the removed nodes.
+func (p *Parser[V]) Restore(cp *Checkpoint[V]) {
+	if cp.released {
+		return
+	}
+
+	p.tapePos = cp.tapePos
+	p.lexeme = cp.lexeme
+	p.next = cp.next
+	p.history = cp.history
+	p.consumed = cp.consumed
+	p.nodes = cp.nodes
+	if cp.warnings < len(p.warnings) {
+		p.warnings = p.warnings[:cp.warnings]
+	}
+	p.node = cp.node
+	p.boundaries = cp.boundaries
+
+	i := 0
+	for n := cp.node; n != nil && i < len(cp.path); n = n.Parent {
+		if cp.path[i] > len(n.Children) {
+			// Children were removed by other means.
+			i++
+			continue
+		}
+		for _, c := range n.Children[cp.path[i]:] {
+			if c != nil && c.Parent == n {
+				c.Parent = nil
+			}
+		}
+		if cp.path[i] == 0 {
+			n.Children = nil
+		} else {
+			n.Children = n.Children[:cp.path[i]]
+		}
+		i++
+	}
+
+	p.record(EventRestore, p.node)
+	p.Release(cp)
+}
+
+// Release discards cp without restoring it. Lexemes consumed after the
+// checkpoint remain consumed.
+func (p *Parser[V]) Release(cp *Checkpoint[V]) {
+	if cp.released {
+		return
+	}
+	cp.released = true
+	p.checkpoints--
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParser_Restore(t *testing.T) {
+	t.Parallel()
+
+	lexemes, cancel := testLexer(t, "A B C D")
+	defer cancel()
+
+	p := NewParser[string](lexemes)
+	_ = p.Node(p.Next().Value)
+
+	cp := p.Checkpoint()
+	_ = p.Push(p.Next().Value)
+	_ = p.Node(p.Next().Value)
+	_ = p.Climb()
+	_ = p.Climb()
+	p.Restore(cp)
+
+	if got, want := p.Pos(), p.Root(); got != want {
+		t.Errorf("Pos: want: %v, got: %v", want, got)
+	}
+
+	// The lexemes consumed after the checkpoint are read again.
+	var got []string
+	for l := p.Next(); l != nil; l = p.Next() {
+		got = append(got, l.Value)
+		_ = p.Node(l.Value)
+	}
+	if diff := cmp.Diff([]string{"B", "C", "D"}, got); diff != "" {
+		t.Errorf("Next: (-want, +got): \n%s", diff)
+	}
+
+	want := newTree(
+		&Node[string]{Value: "A"},
+		&Node[string]{Value: "B", Pos: 2, Column: 2},
+		&Node[string]{Value: "C", Pos: 4, Column: 4},
+		&Node[string]{Value: "D", Pos: 6, Column: 6},
+	)
+	if diff := cmp.Diff(want, p.Root()); diff != "" {
+		t.Errorf("Root: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestParser_Release(t *testing.T) {
+	t.Parallel()
+
+	lexemes, cancel := testLexer(t, "A B C")
+	defer cancel()
+
+	p := NewParser[string](lexemes)
+
+	outer := p.Checkpoint()
+	_ = p.Next()
+	inner := p.Checkpoint()
+	_ = p.Next()
+	p.Release(inner)
+
+	if got, want := p.Peek().Value, "C"; got != want {
+		t.Errorf("Peek: want: %v, got: %v", want, got)
+	}
+
+	p.Restore(outer)
+	// Restoring a released checkpoint is a no-op.
+	p.Restore(inner)
+
+	var got []string
+	for l := p.Next(); l != nil; l = p.Next() {
+		got = append(got, l.Value)
+	}
+	if diff := cmp.Diff([]string{"A", "B", "C"}, got); diff != "" {
+		t.Errorf("Next: (-want, +got): \n%s", diff)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ianlewis/runeio"
+)
+
+// ChunkSplitFunc returns the length in bytes of the next chunk at the start
+// of data. Chunks include any separators so that together they cover the
+// whole input. Returning zero requests more data. If atEOF is true and zero is
+// returned, the remaining data is used as the final chunk. A non-nil error
+// stops parsing.
+type ChunkSplitFunc func(data []byte, atEOF bool) (int, error)
+
+// SplitLines is a ChunkSplitFunc that returns each line, including its
+// newline, as a chunk. It can be used for newline-delimited records.
+func SplitLines(data []byte, _ bool) (int, error) {
+	return bytes.IndexByte(data, '\n') + 1, nil
+}
+
+// SplitBlankLines is a ChunkSplitFunc that returns chunks separated by one or
+// more blank lines. The blank lines are included at the end of the chunk.
+func SplitBlankLines(data []byte, atEOF bool) (int, error) {
+	i := bytes.Index(data, []byte("\n\n"))
+	if i < 0 {
+		return 0, nil
+	}
+	// Include all following blank lines.
+	n := i + 2
+	for n < len(data) && data[n] == '\n' {
+		n++
+	}
+	if n == len(data) && !atEOF {
+		// More blank lines may follow.
+		return 0, nil
+	}
+	return n, nil
+}
+
+// ChunkParser lexes and parses independent chunks of input concurrently. The
+// input is split into chunks which are each lexed and parsed as if by
+// LexParse. The resulting trees are merged, in input order, under a single
+// root node. Positions of nodes are relative to the start of the input.
+type ChunkParser[V comparable] struct {
+	// Split splits the input into chunks.
+	Split ChunkSplitFunc
+
+	// State is the initial lexer state for each chunk. It must be safe to
+	// use from multiple goroutines.
+	State State
+
+	// ParseFn is the initial parse function for each chunk. It must be safe
+	// to use from multiple goroutines.
+	ParseFn ParseFn[V]
+
+	// Workers is the number of chunks parsed concurrently. If zero or less
+	// runtime.GOMAXPROCS(0) is used.
+	Workers int
+
+	// MaxChunkSize is the maximum size of a chunk in bytes. If zero or less
+	// bufio.MaxScanTokenSize is used.
+	MaxChunkSize int
+}
+
+// chunk is a piece of input to be parsed.
+type chunk struct {
+	// index is the index of the chunk in the input.
+	index int
+
+	// data is the chunk input.
+	data []byte
+
+	// pos, line, and column are the position of the start of the chunk.
+	pos, line, column int
+}
+
+// Parse splits r into chunks and parses them. The children of each chunk's
+// root node are added as children of the returned root node. The first
+// error encountered is returned.
+func (c *ChunkParser[V]) Parse(ctx context.Context, r io.Reader) (*Node[V], error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := c.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		mu      sync.Mutex
+		results []*Node[V]
+		err     error
+	)
+	setErr := func(e error) {
+		mu.Lock()
+		if err == nil {
+			err = e
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	chunks := make(chan *chunk)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ch := range chunks {
+				root, pErr := c.parseChunk(ctx, ch)
+				if pErr != nil {
+					setErr(pErr)
+					continue
+				}
+				mu.Lock()
+				results[ch.index] = root
+				mu.Unlock()
+			}
+		}()
+	}
+
+	sErr := c.scan(ctx, r, func(ch *chunk) {
+		mu.Lock()
+		results = append(results, nil)
+		mu.Unlock()
+		select {
+		case chunks <- ch:
+		case <-ctx.Done():
+		}
+	})
+	close(chunks)
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+	if sErr != nil {
+		return nil, sErr
+	}
+	// NOTE: Chunks are not parsed if the context is canceled while they are
+	//       being sent to the workers.
+	if cErr := ctx.Err(); cErr != nil {
+		//nolint:wrapcheck // We don't need to wrap the context Error.
+		return nil, cErr
+	}
+
+	root := &Node[V]{}
+	for _, n := range results {
+		if n == nil {
+			continue
+		}
+		for _, child := range n.Children {
+			child.Parent = root
+			root.Children = append(root.Children, child)
+		}
+	}
+	return root, nil
+}
+
+// scan splits r into chunks and calls f for each chunk.
+func (c *ChunkParser[V]) scan(ctx context.Context, r io.Reader, f func(*chunk)) error {
+	maxSize := c.MaxChunkSize
+	if maxSize <= 0 {
+		maxSize = bufio.MaxScanTokenSize
+	}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxSize)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		n, err := c.Split(data, atEOF)
+		if err != nil {
+			return 0, nil, err
+		}
+		if n == 0 && atEOF {
+			n = len(data)
+		}
+		if n == 0 {
+			return 0, nil, nil
+		}
+		return n, data[:n], nil
+	})
+
+	var index, pos, line, column int
+	for s.Scan() {
+		select {
+		case <-ctx.Done():
+			//nolint:wrapcheck // We don't need to wrap the context Error.
+			return ctx.Err()
+		default:
+		}
+
+		// NOTE: The scanner's buffer is reused so the chunk must be copied.
+		data := append([]byte(nil), s.Bytes()...)
+		f(&chunk{
+			index:  index,
+			data:   data,
+			pos:    pos,
+			line:   line,
+			column: column,
+		})
+
+		index++
+		pos += utf8.RuneCount(data)
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			line += bytes.Count(data, []byte{'\n'})
+			column = utf8.RuneCount(data[i+1:])
+		} else {
+			column += utf8.RuneCount(data)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("splitting input: %w", err)
+	}
+	return nil
+}
+
+// parseChunk parses a single chunk and adjusts the positions of the nodes in
+// the resulting tree.
+func (c *ChunkParser[V]) parseChunk(ctx context.Context, ch *chunk) (*Node[V], error) {
+	root, err := LexParse(ctx, runeio.NewReader(bytes.NewReader(ch.data)), c.State, c.ParseFn)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range root.Children {
+		offsetPos(n, ch)
+	}
+	return root, nil
+}
+
+// offsetPos offsets the position of the nodes in the tree rooted at n by the
+// position of the chunk.
+func offsetPos[V comparable](n *Node[V], ch *chunk) {
+	if n == nil {
+		return
+	}
+	if n.Line == 0 {
+		n.Column += ch.column
+	}
+	n.Line += ch.line
+	n.Pos += ch.pos
+	for _, c := range n.Children {
+		offsetPos(c, ch)
+	}
+}
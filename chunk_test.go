@@ -0,0 +1,154 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// wordsParseFn adds a node for each word that is not blank.
+func wordsParseFn(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+	for l := p.Next(); l != nil; l = p.Next() {
+		if strings.TrimSpace(l.Value) != "" {
+			_ = p.Node(l.Value)
+		}
+	}
+	return nil, nil
+}
+
+func TestChunkParser(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		split ChunkSplitFunc
+		input string
+		want  *Node[string]
+	}{
+		"lines": {
+			split: SplitLines,
+			input: "A B\nC\nD E",
+			want: newTree(
+				&Node[string]{Value: "A", Pos: 0, Line: 0, Column: 0},
+				&Node[string]{Value: "B", Pos: 2, Line: 0, Column: 2},
+				&Node[string]{Value: "C", Pos: 4, Line: 1, Column: 0},
+				&Node[string]{Value: "D", Pos: 6, Line: 2, Column: 0},
+				&Node[string]{Value: "E", Pos: 8, Line: 2, Column: 2},
+			),
+		},
+		"blank lines": {
+			split: SplitBlankLines,
+			input: "A B\nC\n\n\nD\n",
+			want: newTree(
+				&Node[string]{Value: "A", Pos: 0, Line: 0, Column: 0},
+				&Node[string]{Value: "B", Pos: 2, Line: 0, Column: 2},
+				&Node[string]{Value: "C", Pos: 4, Line: 1, Column: 0},
+				&Node[string]{Value: "D", Pos: 8, Line: 4, Column: 0},
+			),
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &ChunkParser[string]{
+				Split:   tc.split,
+				State:   &wordState{},
+				ParseFn: wordsParseFn,
+				Workers: 2,
+			}
+			got, err := c.Parse(context.Background(), strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Parse: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestChunkParser_error(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test error")
+	c := &ChunkParser[string]{
+		Split: SplitLines,
+		State: &wordState{},
+		ParseFn: func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+			for l := p.Next(); l != nil; l = p.Next() {
+				if l.Value == "bad" {
+					return nil, errTest
+				}
+			}
+			return nil, nil
+		},
+	}
+
+	_, err := c.Parse(context.Background(), strings.NewReader("A\nbad\nC\n"))
+	if !errors.Is(err, errTest) {
+		t.Errorf("Parse: want: %v, got: %v", errTest, err)
+	}
+}
+
+func TestChunkParser_cancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lastChunk := make(chan struct{})
+	var once sync.Once
+	c := &ChunkParser[string]{
+		Split: func(data []byte, atEOF bool) (int, error) {
+			if atEOF {
+				once.Do(func() { close(lastChunk) })
+			}
+			return SplitLines(data, atEOF)
+		},
+		State: &wordState{},
+		ParseFn: func(ctx context.Context, p *Parser[string]) (ParseFn[string], error) {
+			first := false
+			for l := p.Next(); l != nil; l = p.Next() {
+				first = first || l.Value == "A"
+			}
+			if first {
+				// Cancel while the last chunk is waiting to be sent to the
+				// only worker.
+				<-lastChunk
+				time.Sleep(10 * time.Millisecond)
+				cancel()
+			}
+			return wordsParseFn(ctx, p)
+		},
+		Workers: 1,
+	}
+
+	got, err := c.Parse(ctx, strings.NewReader("A\nB"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Parse: want: %v, got: %v", context.Canceled, err)
+	}
+	if got != nil {
+		t.Errorf("Parse: want: %v, got: %v", nil, got)
+	}
+}
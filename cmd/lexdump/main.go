@@ -0,0 +1,156 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// lexdump prints the lexemes in a file along with their positions and types.
+// It is useful when developing grammars.
+//
+// Usage:
+//
+//	lexdump [-lexer name] [file]
+//
+// If no file is given the standard input is read.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/scanner"
+	"unicode"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var errUnknownLexer = errors.New("unknown lexer")
+
+// lexer is implemented by both lexparse.Lexer and lexparse.ScanningLexer.
+type lexer interface {
+	Lex(ctx context.Context) <-chan *lexparse.Lexeme
+	Err() error
+	Done() <-chan struct{}
+}
+
+// lexerDef is a lexer registered with lexdump.
+type lexerDef struct {
+	// newLexer returns a new lexer that reads from r.
+	newLexer func(r io.Reader) lexer
+
+	// typeName returns the name of a lexeme type.
+	typeName func(typ lexparse.LexemeType) string
+}
+
+// lexers are the registered lexers.
+var lexers = map[string]lexerDef{
+	"go": {
+		newLexer: func(r io.Reader) lexer {
+			return lexparse.NewScanningLexer(r, scanner.GoTokens)
+		},
+		typeName: func(typ lexparse.LexemeType) string {
+			return scanner.TokenString(rune(typ))
+		},
+	},
+	"words": {
+		newLexer: func(r io.Reader) lexer {
+			return lexparse.NewLexer(runeio.NewReader(bufio.NewReader(r)), lexparse.StateFn(lexWord))
+		},
+		typeName: func(lexparse.LexemeType) string {
+			return "Word"
+		},
+	},
+}
+
+// lexWord lexes words separated by whitespace.
+func lexWord(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	for {
+		rn, err := l.Peek(1)
+		if err != nil || unicode.IsSpace(rn[0]) {
+			if lexeme := l.Lexeme(0); lexeme.Value != "" {
+				l.Emit(lexeme)
+			}
+			if err != nil {
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return nil, err
+			}
+			if _, err := l.Discard(1); err != nil {
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return nil, err
+			}
+			continue
+		}
+		if _, err := l.Advance(1); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+	}
+}
+
+// dump writes the lexemes read from r to w using the named lexer.
+func dump(ctx context.Context, w io.Writer, r io.Reader, name string) error {
+	def, ok := lexers[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", errUnknownLexer, name)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	l := def.newLexer(r)
+	for lexeme := range l.Lex(ctx) {
+		if _, err := fmt.Fprintf(w, "%d:%d\t%d\t%s\t%q\n",
+			lexeme.Line+1, lexeme.Column+1, lexeme.Pos, def.typeName(lexeme.Type), lexeme.Value); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+	<-l.Done()
+
+	if err := l.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("lexing input: %w", err)
+	}
+	return nil
+}
+
+func main() {
+	names := make([]string, 0, len(lexers))
+	for name := range lexers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	name := flag.String("lexer", "go", "lexer to use ("+strings.Join(names, ", ")+")")
+	flag.Parse()
+
+	var r io.Reader = os.Stdin
+	if flag.NArg() > 0 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lexdump: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if err := dump(context.Background(), os.Stdout, r, *name); err != nil {
+		fmt.Fprintf(os.Stderr, "lexdump: %v\n", err)
+		os.Exit(1) //nolint:gocritic // The file is closed on exit.
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDump(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		lexer string
+		input string
+		want  string
+		err   error
+	}{
+		"go": {
+			lexer: "go",
+			input: "x = 1",
+			want:  "1:1\t0\tIdent\t\"x\"\n1:3\t2\t\"=\"\t\"=\"\n1:5\t4\tInt\t\"1\"\n",
+		},
+		"words": {
+			lexer: "words",
+			input: "foo  bar\nbaz",
+			want:  "1:1\t0\tWord\t\"foo\"\n1:6\t5\tWord\t\"bar\"\n2:1\t9\tWord\t\"baz\"\n",
+		},
+		"unknown": {
+			lexer: "unknown",
+			err:   errUnknownLexer,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var b strings.Builder
+			err := dump(context.Background(), &b, strings.NewReader(tc.input), tc.lexer)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("dump: want: %v, got: %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.want, b.String()); diff != "" {
+				t.Errorf("dump: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
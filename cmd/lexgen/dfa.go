@@ -0,0 +1,428 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// errUnsupported indicates a regular expression feature that can't be
+	// compiled to a DFA, such as anchors.
+	errUnsupported = errors.New("unsupported regular expression")
+
+	// errEmptyMatch indicates a rule that matches the empty string.
+	errEmptyMatch = errors.New("rule matches the empty string")
+)
+
+// nfaState is a state of a nondeterministic finite automaton. A state either
+// has epsilon transitions or a transition on a set of rune ranges.
+type nfaState struct {
+	// eps are the states reachable without consuming input.
+	eps []int
+
+	// ranges are pairs of the inclusive bounds of the runes that lead to
+	// next.
+	ranges []rune
+	next   int
+
+	// rule is the index of the rule accepted in this state or -1.
+	rule int
+}
+
+// nfa is a nondeterministic finite automaton built from regular expressions
+// using Thompson's construction.
+type nfa struct {
+	states []nfaState
+}
+
+// newState adds a new state and returns its index.
+func (n *nfa) newState() int {
+	n.states = append(n.states, nfaState{next: -1, rule: -1})
+	return len(n.states) - 1
+}
+
+// addRule adds the regular expression re for the rule with the given index
+// as an alternative starting at start.
+func (n *nfa) addRule(start int, re *syntax.Regexp, rule int) error {
+	s, e, err := n.build(re)
+	if err != nil {
+		return err
+	}
+	n.states[start].eps = append(n.states[start].eps, s)
+	n.states[e].rule = rule
+	return nil
+}
+
+// build returns the start and end states of a fragment matching re.
+func (n *nfa) build(re *syntax.Regexp) (int, int, error) {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return n.newState(), n.newState(), nil
+	case syntax.OpEmptyMatch:
+		s := n.newState()
+		return s, s, nil
+	case syntax.OpLiteral:
+		start := n.newState()
+		end := start
+		for _, rn := range re.Rune {
+			ranges := []rune{rn, rn}
+			if re.Flags&syntax.FoldCase != 0 {
+				for f := unicode.SimpleFold(rn); f != rn; f = unicode.SimpleFold(f) {
+					ranges = append(ranges, f, f)
+				}
+			}
+			next := n.newState()
+			n.states[end].ranges = ranges
+			n.states[end].next = next
+			end = next
+		}
+		return start, end, nil
+	case syntax.OpCharClass:
+		return n.rangeFrag(re.Rune)
+	case syntax.OpAnyCharNotNL:
+		return n.rangeFrag([]rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune})
+	case syntax.OpAnyChar:
+		return n.rangeFrag([]rune{0, unicode.MaxRune})
+	case syntax.OpCapture:
+		return n.build(re.Sub[0])
+	case syntax.OpConcat:
+		start := n.newState()
+		end := start
+		for _, sub := range re.Sub {
+			s, e, err := n.build(sub)
+			if err != nil {
+				return 0, 0, err
+			}
+			n.states[end].eps = append(n.states[end].eps, s)
+			end = e
+		}
+		return start, end, nil
+	case syntax.OpAlternate:
+		start, end := n.newState(), n.newState()
+		for _, sub := range re.Sub {
+			s, e, err := n.build(sub)
+			if err != nil {
+				return 0, 0, err
+			}
+			n.states[start].eps = append(n.states[start].eps, s)
+			n.states[e].eps = append(n.states[e].eps, end)
+		}
+		return start, end, nil
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		s, e, err := n.build(re.Sub[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		start, end := n.newState(), n.newState()
+		n.states[start].eps = append(n.states[start].eps, s)
+		n.states[e].eps = append(n.states[e].eps, end)
+		if re.Op != syntax.OpPlus {
+			n.states[start].eps = append(n.states[start].eps, end)
+		}
+		if re.Op != syntax.OpQuest {
+			n.states[e].eps = append(n.states[e].eps, s)
+		}
+		return start, end, nil
+	default:
+		// Anchors and word boundaries depend on context that a lexer
+		// doesn't track. Repeats are removed by Simplify.
+		return 0, 0, fmt.Errorf("%w: %s", errUnsupported, re)
+	}
+}
+
+// rangeFrag returns a fragment matching one rune in ranges.
+func (n *nfa) rangeFrag(ranges []rune) (int, int, error) {
+	s, e := n.newState(), n.newState()
+	n.states[s].ranges = ranges
+	n.states[s].next = e
+	return s, e, nil
+}
+
+// closure returns the sorted set of states reachable from states using
+// epsilon transitions.
+func (n *nfa) closure(states []int) []int {
+	seen := map[int]bool{}
+	stack := append([]int(nil), states...)
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		stack = append(stack, n.states[s].eps...)
+	}
+	set := make([]int, 0, len(seen))
+	for s := range seen {
+		set = append(set, s)
+	}
+	sort.Ints(set)
+	return set
+}
+
+// transition is a transition of a DFA on the runes from lo to hi inclusive.
+type transition struct {
+	lo, hi rune
+	next   int
+}
+
+// dfa is a deterministic finite automaton. State zero is the start state.
+type dfa struct {
+	// trans are the transitions of each state sorted by rune. Runes without
+	// a transition stop the automaton.
+	trans [][]transition
+
+	// rules is the index of the rule accepted in each state or -1.
+	rules []int
+}
+
+// compile returns the minimal DFA that matches the regular expressions in
+// Go syntax in patterns. The rule accepted in a state is the index of the
+// first pattern that matches the input read so far.
+func compile(patterns []*syntax.Regexp) (*dfa, error) {
+	n := &nfa{}
+	start := n.newState()
+	for i, re := range patterns {
+		if err := n.addRule(start, re.Simplify(), i); err != nil {
+			return nil, err
+		}
+	}
+	d := determinize(n, start)
+	if d.rules[0] >= 0 {
+		return nil, fmt.Errorf("%w: %s", errEmptyMatch, patterns[d.rules[0]])
+	}
+	return minimize(d), nil
+}
+
+// determinize builds a DFA from n using the subset construction.
+func determinize(n *nfa, start int) *dfa {
+	d := &dfa{}
+	ids := map[string]int{}
+	var sets [][]int
+
+	add := func(set []int) int {
+		key := setKey(set)
+		if id, ok := ids[key]; ok {
+			return id
+		}
+		id := len(sets)
+		ids[key] = id
+		sets = append(sets, set)
+		rule := -1
+		for _, s := range set {
+			if r := n.states[s].rule; r >= 0 && (rule < 0 || r < rule) {
+				rule = r
+			}
+		}
+		d.rules = append(d.rules, rule)
+		d.trans = append(d.trans, nil)
+		return id
+	}
+
+	add(n.closure([]int{start}))
+	for id := 0; id < len(sets); id++ {
+		// Split the runes into intervals on which every state in the set
+		// either has or doesn't have a transition.
+		var bounds []rune
+		for _, s := range sets[id] {
+			r := n.states[s].ranges
+			for i := 0; i < len(r); i += 2 {
+				bounds = append(bounds, r[i], r[i+1]+1)
+			}
+		}
+		bounds = uniqueRunes(bounds)
+
+		var trans []transition
+		for i := 0; i+1 < len(bounds); i++ {
+			lo, hi := bounds[i], bounds[i+1]-1
+			var next []int
+			for _, s := range sets[id] {
+				if inRanges(n.states[s].ranges, lo) {
+					next = append(next, n.states[s].next)
+				}
+			}
+			if len(next) == 0 {
+				continue
+			}
+			target := add(n.closure(next))
+			if l := len(trans); l > 0 && trans[l-1].next == target && trans[l-1].hi+1 == lo {
+				trans[l-1].hi = hi
+				continue
+			}
+			trans = append(trans, transition{lo: lo, hi: hi, next: target})
+		}
+		d.trans[id] = trans
+	}
+	return d
+}
+
+// minimize returns the minimal DFA equivalent to d. States are partitioned
+// by the rule they accept and the partitions are refined until the states
+// in each partition have transitions to the same partitions on the same
+// runes (Moore's algorithm). The states of the result are numbered in
+// breadth-first order from the start state.
+func minimize(d *dfa) *dfa {
+	class := make([]int, len(d.rules))
+	for s, r := range d.rules {
+		class[s] = r + 1
+	}
+
+	for {
+		ids := map[string]int{}
+		next := make([]int, len(class))
+		for s := range class {
+			var b strings.Builder
+			fmt.Fprintf(&b, "%d", class[s])
+			for _, t := range mergeTrans(d.trans[s], class) {
+				fmt.Fprintf(&b, " %d-%d:%d", t.lo, t.hi, t.next)
+			}
+			key := b.String()
+			id, ok := ids[key]
+			if !ok {
+				id = len(ids)
+				ids[key] = id
+			}
+			next[s] = id
+		}
+		changed := countClasses(next) != countClasses(class)
+		class = next
+		if !changed {
+			break
+		}
+	}
+
+	// Number the partitions in breadth-first order from the start state.
+	order := map[int]int{class[0]: 0}
+	reps := []int{0}
+	m := &dfa{}
+	for i := 0; i < len(reps); i++ {
+		s := reps[i]
+		var trans []transition
+		for _, t := range mergeTrans(d.trans[s], class) {
+			target, ok := order[t.next]
+			if !ok {
+				target = len(reps)
+				order[t.next] = target
+				reps = append(reps, firstInClass(class, t.next))
+			}
+			trans = append(trans, transition{lo: t.lo, hi: t.hi, next: target})
+		}
+		m.trans = append(m.trans, trans)
+		m.rules = append(m.rules, d.rules[s])
+	}
+	return m
+}
+
+// mergeTrans returns trans with the targets replaced by their classes and
+// adjacent transitions to the same class merged.
+func mergeTrans(trans []transition, class []int) []transition {
+	var merged []transition
+	for _, t := range trans {
+		c := class[t.next]
+		if l := len(merged); l > 0 && merged[l-1].next == c && merged[l-1].hi+1 == t.lo {
+			merged[l-1].hi = t.hi
+			continue
+		}
+		merged = append(merged, transition{lo: t.lo, hi: t.hi, next: c})
+	}
+	return merged
+}
+
+// next returns the state reached from state on rn or -1 if there is no
+// transition.
+func (d *dfa) next(state int, rn rune) int {
+	trans := d.trans[state]
+	i := sort.Search(len(trans), func(i int) bool { return trans[i].hi >= rn })
+	if i < len(trans) && trans[i].lo <= rn {
+		return trans[i].next
+	}
+	return -1
+}
+
+// match returns the index of the rule with the longest match at the start of
+// input and the length of the match in runes. The rule is -1 if no rule
+// matches.
+func (d *dfa) match(input []rune) (int, int) {
+	rule, size := -1, 0
+	state := 0
+	for i, rn := range input {
+		state = d.next(state, rn)
+		if state < 0 {
+			break
+		}
+		if r := d.rules[state]; r >= 0 {
+			rule, size = r, i+1
+		}
+	}
+	return rule, size
+}
+
+// setKey returns a map key for a set of states.
+func setKey(set []int) string {
+	var b strings.Builder
+	for _, s := range set {
+		b.WriteString(strconv.Itoa(s))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// uniqueRunes sorts rns and removes duplicates.
+func uniqueRunes(rns []rune) []rune {
+	sort.Slice(rns, func(i, j int) bool { return rns[i] < rns[j] })
+	var u []rune
+	for i, rn := range rns {
+		if i == 0 || rn != rns[i-1] {
+			u = append(u, rn)
+		}
+	}
+	return u
+}
+
+// inRanges returns true if rn is in one of the pairs of bounds in ranges.
+func inRanges(ranges []rune, rn rune) bool {
+	for i := 0; i < len(ranges); i += 2 {
+		if ranges[i] <= rn && rn <= ranges[i+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// countClasses returns the number of distinct values in class.
+func countClasses(class []int) int {
+	seen := map[int]bool{}
+	for _, c := range class {
+		seen[c] = true
+	}
+	return len(seen)
+}
+
+// firstInClass returns the first state in the class c.
+func firstInClass(class []int, c int) int {
+	for s, sc := range class {
+		if sc == c {
+			return s
+		}
+	}
+	return -1
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"regexp/syntax"
+	"testing"
+)
+
+// mustCompile compiles the patterns in Go syntax to a DFA.
+func mustCompile(t *testing.T, patterns ...string) *dfa {
+	t.Helper()
+
+	res := make([]*syntax.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := syntax.Parse(p, syntax.Perl)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", p, err)
+		}
+		res = append(res, re)
+	}
+	d, err := compile(res)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return d
+}
+
+func TestCompile_match(t *testing.T) {
+	t.Parallel()
+
+	patterns := []string{
+		`[0-9]+(\.[0-9]+)?`,
+		`if`,
+		`[a-z]+`,
+		`(?i)select`,
+		`\+\+?`,
+		`.`,
+	}
+	d := mustCompile(t, patterns...)
+
+	testCases := map[string]struct {
+		input string
+		rule  int
+		size  int
+	}{
+		"integer": {
+			input: "123+",
+			rule:  0,
+			size:  3,
+		},
+		"decimal": {
+			input: "1.5)",
+			rule:  0,
+			size:  3,
+		},
+		"incomplete decimal": {
+			input: "1.)",
+			rule:  0,
+			size:  1,
+		},
+		"keyword": {
+			input: "if ",
+			rule:  1,
+			size:  2,
+		},
+		"longest match": {
+			input: "iffy",
+			rule:  2,
+			size:  4,
+		},
+		"fold case": {
+			input: "SeLeCt",
+			rule:  3,
+			size:  6,
+		},
+		"fold case prefix": {
+			input: "sel",
+			rule:  2,
+			size:  3,
+		},
+		"increment": {
+			input: "++1",
+			rule:  4,
+			size:  2,
+		},
+		"any": {
+			input: "日本",
+			rule:  5,
+			size:  1,
+		},
+		"no match": {
+			input: "\n",
+			rule:  -1,
+			size:  0,
+		},
+		"empty": {
+			input: "",
+			rule:  -1,
+			size:  0,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rule, size := d.match([]rune(tc.input))
+			if rule != tc.rule || size != tc.size {
+				t.Errorf("match(%q): want: %d, %d, got: %d, %d", tc.input, tc.rule, tc.size, rule, size)
+			}
+		})
+	}
+}
+
+func TestCompile_minimize(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		patterns []string
+		states   int
+	}{
+		"classic": {
+			// The minimal DFA for (a|b)*abb has four states.
+			patterns: []string{`(a|b)*abb`},
+			states:   4,
+		},
+		"alternatives": {
+			patterns: []string{`ab|cb|db`},
+			states:   3,
+		},
+		"distinct rules": {
+			patterns: []string{`a`, `b`},
+			states:   3,
+		},
+		"same rule": {
+			patterns: []string{`a|b`},
+			states:   2,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			d := mustCompile(t, tc.patterns...)
+			if got := len(d.rules); got != tc.states {
+				t.Errorf("compile(%q): want: %d states, got: %d", tc.patterns, tc.states, got)
+			}
+		})
+	}
+}
+
+func TestCompile_err(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		pattern string
+		err     error
+	}{
+		"empty match": {
+			pattern: `a*`,
+			err:     errEmptyMatch,
+		},
+		"begin anchor": {
+			pattern: `^a`,
+			err:     errUnsupported,
+		},
+		"word boundary": {
+			pattern: `a\b`,
+			err:     errUnsupported,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			re, err := syntax.Parse(tc.pattern, syntax.Perl)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tc.pattern, err)
+			}
+			if _, err := compile([]*syntax.Regexp{re}); !errors.Is(err, tc.err) {
+				t.Errorf("compile(%q): want: %v, got: %v", tc.pattern, tc.err, err)
+			}
+		})
+	}
+}
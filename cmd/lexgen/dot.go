@@ -0,0 +1,77 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxDOTRanges is the maximum number of rune ranges in an edge label.
+const maxDOTRanges = 8
+
+// writeDOT writes d to w as a Graphviz DOT graph. States accepting a rule are
+// drawn with a double circle and labelled with the rule's name. Transitions
+// between two states are drawn as a single edge labelled with their runes.
+// Labels with more than maxDOTRanges ranges, such as those for Unicode
+// classes, are truncated.
+func writeDOT(w io.Writer, d *dfa, rules []*rule) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph dfa {\n")
+	fmt.Fprintf(bw, "\trankdir=LR;\n")
+	fmt.Fprintf(bw, "\tnode [shape=circle];\n")
+	for s, r := range d.rules {
+		if r < 0 {
+			fmt.Fprintf(bw, "\t%d;\n", s)
+			continue
+		}
+		label := fmt.Sprintf("%d\n%s", s, rules[r].name)
+		fmt.Fprintf(bw, "\t%d [shape=doublecircle, label=%s];\n", s, strconv.Quote(label))
+	}
+	for s, trans := range d.trans {
+		// Group the transitions by target keeping the order in which the
+		// targets are first reached.
+		var targets []int
+		labels := map[int][]string{}
+		for _, t := range trans {
+			if _, ok := labels[t.next]; !ok {
+				targets = append(targets, t.next)
+			}
+			labels[t.next] = append(labels[t.next], rangeLabel(t.lo, t.hi))
+		}
+		for _, next := range targets {
+			ranges := labels[next]
+			if len(ranges) > maxDOTRanges {
+				ranges = append(ranges[:maxDOTRanges:maxDOTRanges], "...")
+			}
+			label := strings.Join(ranges, " ")
+			fmt.Fprintf(bw, "\t%d -> %d [label=%s];\n", s, next, strconv.Quote(label))
+		}
+	}
+	fmt.Fprintf(bw, "}\n")
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return bw.Flush()
+}
+
+// rangeLabel returns the label for the runes from lo to hi inclusive.
+func rangeLabel(lo, hi rune) string {
+	if lo == hi {
+		return strconv.QuoteRune(lo)
+	}
+	return strconv.QuoteRune(lo) + "-" + strconv.QuoteRune(hi)
+}
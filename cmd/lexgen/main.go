@@ -0,0 +1,272 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// lexgen generates a Go source file defining a lexparse.State from a file of
+// token rules so that lexers for simple token sets don't have to be written
+// by hand. The rules are compiled to a minimal DFA and the generated State
+// runs it over the lexer's buffered input without allocating beyond the
+// emitted lexemes.
+//
+// Usage:
+//
+//	lexgen [-o file] [-package name] [-name name] [-dot] file
+//
+// It is intended to be run by go generate:
+//
+//	//go:generate go run github.com/ianlewis/lexparse/cmd/lexgen -o lexer.go calc.lex
+//
+// Each line of the rule file holds the name of a LexemeType constant in the
+// generated package followed by a pattern. A pattern is either a Go string
+// literal, which is matched literally, or a regular expression in Go syntax
+// that extends to the end of the line. Anchors and word boundaries are not
+// supported. Input matched by a rule named "-" is discarded. Lines starting
+// with '#' are comments.
+//
+//	# calc.lex
+//	numType    [0-9]+(\.[0-9]+)?
+//	identType  [\pL_][\pL\pN_]*
+//	plusType   "+"
+//	minusType  "-"
+//	-          [ \t\r\n]+
+//
+// The State emits a lexeme for the longest match at the current position.
+// If rules match the same input the first rule wins. At the end of the input
+// the State returns a nil State. If no rule matches it returns an error
+// wrapping lexparse.ErrUnexpectedRune. Tokens must fit in the lexer's read
+// buffer.
+//
+// With -dot, lexgen writes the minimized DFA as a Graphviz DOT graph instead
+// of Go source so that the automaton can be inspected:
+//
+//	lexgen -dot calc.lex | dot -Tsvg -o calc.svg
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp/syntax"
+	"strconv"
+	"unicode/utf8"
+)
+
+// transPerLine is the number of transitions written on each line.
+const transPerLine = 4
+
+// config configures the generated code.
+type config struct {
+	// pkg is the name of the generated package.
+	pkg string
+
+	// name is the name of the generated State type.
+	name string
+
+	// src is the name of the rule file.
+	src string
+
+	// dot writes the DFA as a DOT graph instead of Go source.
+	dot bool
+}
+
+// generate writes the Go source for the lexer defined by the rules read from
+// r to w.
+func generate(w io.Writer, r io.Reader, cfg *config) error {
+	rules, err := parseSpec(r)
+	if err != nil {
+		return fmt.Errorf("parsing rules: %w", err)
+	}
+	res := make([]*syntax.Regexp, 0, len(rules))
+	for _, rl := range rules {
+		res = append(res, rl.re)
+	}
+	d, err := compile(res)
+	if err != nil {
+		return fmt.Errorf("compiling rules: %w", err)
+	}
+	if cfg.dot {
+		if err := writeDOT(w, d, rules); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		return nil
+	}
+
+	name := cfg.name
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by lexgen from %s; DO NOT EDIT.\n\n", cfg.src)
+	fmt.Fprintf(&b, "package %s\n\n", cfg.pkg)
+	fmt.Fprintf(&b, "import (\n\"context\"\n\"errors\"\n\"fmt\"\n\"io\"\n\n\"github.com/ianlewis/lexparse\"\n)\n\n")
+
+	fmt.Fprintf(&b, "// %s is a lexparse.State that lexes the tokens defined in %s.\n", name, cfg.src)
+	fmt.Fprintf(&b, "type %s struct{}\n\n", name)
+
+	fmt.Fprintf(&b, "// Name returns the name of the State.\n")
+	fmt.Fprintf(&b, "func (%s) Name() string {\nreturn %q\n}\n\n", name, name)
+
+	fmt.Fprintf(&b, "// Run emits the lexeme for the longest match at the current position.\n")
+	fmt.Fprintf(&b, "func (s %s) Run(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {\n", name)
+	fmt.Fprintf(&b, "for {\n")
+	fmt.Fprintf(&b, "state, n := 0, 0\n")
+	fmt.Fprintf(&b, "rule, size := -1, 0\n")
+	fmt.Fprintf(&b, "var rns []rune\n")
+	fmt.Fprintf(&b, "var err error\n")
+	fmt.Fprintf(&b, "for {\n")
+	fmt.Fprintf(&b, "if n == len(rns) {\n")
+	fmt.Fprintf(&b, "want := l.Buffered()\n")
+	fmt.Fprintf(&b, "if want <= n {\nwant = n + 1\n}\n")
+	fmt.Fprintf(&b, "if rns, err = l.Peek(want); n >= len(rns) {\nbreak\n}\n")
+	fmt.Fprintf(&b, "}\n")
+	fmt.Fprintf(&b, "if state = %sNext(state, rns[n]); state < 0 {\nbreak\n}\n", name)
+	fmt.Fprintf(&b, "n++\n")
+	fmt.Fprintf(&b, "if r := %sAccept[state]; r >= 0 {\nrule, size = r, n\n}\n", name)
+	fmt.Fprintf(&b, "}\n")
+	fmt.Fprintf(&b, "if err != nil && !errors.Is(err, io.EOF) && n >= len(rns) {\nreturn nil, err\n}\n")
+	fmt.Fprintf(&b, "if rule < 0 {\n")
+	fmt.Fprintf(&b, "if len(rns) == 0 {\nreturn nil, nil\n}\n")
+	fmt.Fprintf(&b, "return nil, fmt.Errorf(\"%%w: %%q: line %%d, column %%d\", ")
+	fmt.Fprintf(&b, "lexparse.ErrUnexpectedRune, rns[0], l.Line()+1, l.Column()+1)\n")
+	fmt.Fprintf(&b, "}\n")
+	fmt.Fprintf(&b, "if _, err := l.Advance(size); err != nil {\nreturn nil, err\n}\n")
+	fmt.Fprintf(&b, "if %sRules[rule].skip {\nl.Ignore()\ncontinue\n}\n", name)
+	fmt.Fprintf(&b, "l.Emit(l.Lexeme(%sRules[rule].typ))\n", name)
+	fmt.Fprintf(&b, "return s, nil\n")
+	fmt.Fprintf(&b, "}\n}\n\n")
+
+	fmt.Fprintf(&b, "// %sRules are the rules in %s.\n", name, cfg.src)
+	fmt.Fprintf(&b, "var %sRules = [...]struct {\ntyp lexparse.LexemeType\nskip bool\n}{\n", name)
+	for _, rl := range rules {
+		if rl.skip() {
+			fmt.Fprintf(&b, "{skip: true}, // %s\n", rl.pattern)
+		} else {
+			fmt.Fprintf(&b, "{typ: %s}, // %s\n", rl.name, rl.pattern)
+		}
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "// %sAccept is the index of the rule accepted in each state or -1.\n", name)
+	fmt.Fprintf(&b, "var %sAccept = [...]int{", name)
+	for s, r := range d.rules {
+		if s > 0 {
+			fmt.Fprintf(&b, ", ")
+		}
+		fmt.Fprintf(&b, "%d", r)
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "// %sRange is a transition on the runes from lo to hi inclusive.\n", name)
+	fmt.Fprintf(&b, "type %sRange struct {\nlo, hi rune\nnext int\n}\n\n", name)
+
+	fmt.Fprintf(&b, "// %sTrans are the transitions of each state sorted by rune.\n", name)
+	fmt.Fprintf(&b, "var %sTrans = [...][]%sRange{\n", name, name)
+	for _, trans := range d.trans {
+		fmt.Fprintf(&b, "{")
+		for i, t := range trans {
+			// Wrap long lists of transitions such as those for Unicode
+			// classes.
+			if len(trans) > transPerLine && i%transPerLine == 0 {
+				fmt.Fprintf(&b, "\n")
+			}
+			fmt.Fprintf(&b, "{%s, %s, %d},", runeLit(t.lo), runeLit(t.hi), t.next)
+		}
+		if len(trans) > transPerLine {
+			fmt.Fprintf(&b, "\n")
+		}
+		fmt.Fprintf(&b, "},\n")
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "// %sNext returns the state reached from state on rn or -1.\n", name)
+	fmt.Fprintf(&b, "func %sNext(state int, rn rune) int {\n", name)
+	fmt.Fprintf(&b, "trans := %sTrans[state]\n", name)
+	fmt.Fprintf(&b, "i, j := 0, len(trans)\n")
+	fmt.Fprintf(&b, "for i < j {\n")
+	fmt.Fprintf(&b, "h := int(uint(i+j) >> 1)\n")
+	fmt.Fprintf(&b, "if trans[h].hi < rn {\ni = h + 1\n} else {\nj = h\n}\n")
+	fmt.Fprintf(&b, "}\n")
+	fmt.Fprintf(&b, "if i < len(trans) && trans[i].lo <= rn {\nreturn trans[i].next\n}\n")
+	fmt.Fprintf(&b, "return -1\n")
+	fmt.Fprintf(&b, "}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting output: %w", err)
+	}
+	if _, err := w.Write(src); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// runeLit returns a Go literal for rn.
+func runeLit(rn rune) string {
+	if rn >= ' ' && rn < utf8.RuneSelf {
+		return strconv.QuoteRune(rn)
+	}
+	return fmt.Sprintf("0x%x", rn)
+}
+
+func main() {
+	out := flag.String("o", "", "output file (default standard output)")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package name (default $GOPACKAGE or main)")
+	name := flag.String("name", "lexToken", "name of the State type")
+	dot := flag.Bool("dot", false, "write the DFA as a Graphviz DOT graph")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "usage: lexgen [flags] file\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if *pkg == "" {
+		*pkg = "main"
+	}
+
+	if err := run(flag.Arg(0), *out, &config{
+		pkg:  *pkg,
+		name: *name,
+		src:  filepath.Base(flag.Arg(0)),
+		dot:  *dot,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "lexgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the code for the rule file at path and writes it to the file
+// out or the standard output if out is empty.
+func run(path, out string, cfg *config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	defer f.Close()
+
+	var b bytes.Buffer
+	if err := generate(&b, f, cfg); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(b.Bytes())
+	} else {
+		//nolint:gosec // Generated code is not sensitive.
+		err = os.WriteFile(out, b.Bytes(), 0o644)
+	}
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return err
+}
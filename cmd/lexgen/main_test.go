@@ -0,0 +1,236 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"io"
+	"regexp/syntax"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const calcLex = `
+# A calculator.
+numType    [0-9]+
+identType  [a-z]+
+plusType   "+"
+-          [ \t\n]+
+`
+
+func TestParseSpec(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		want  [][2]string
+	}{
+		"regexp": {
+			input: "numType [0-9]+",
+			want:  [][2]string{{"numType", "[0-9]+"}},
+		},
+		"string": {
+			input: `plusType "+"`,
+			want:  [][2]string{{"plusType", `"+"`}},
+		},
+		"raw string": {
+			input: "starType `*`",
+			want:  [][2]string{{"starType", "`*`"}},
+		},
+		"skip": {
+			input: "-\t[ \\t]+ ",
+			want:  [][2]string{{"-", `[ \t]+`}},
+		},
+		"comments": {
+			input: calcLex,
+			want: [][2]string{
+				{"numType", "[0-9]+"},
+				{"identType", "[a-z]+"},
+				{"plusType", `"+"`},
+				{"-", `[ \t\n]+`},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rules, err := parseSpec(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("parseSpec: %v", err)
+			}
+			got := make([][2]string, 0, len(rules))
+			for _, r := range rules {
+				got = append(got, [2]string{r.name, r.pattern})
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseSpec: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSpec_literal(t *testing.T) {
+	t.Parallel()
+
+	rules, err := parseSpec(strings.NewReader(`dotType "."`))
+	if err != nil {
+		t.Fatalf("parseSpec: %v", err)
+	}
+	d, err := compile([]*syntax.Regexp{rules[0].re})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if rule, _ := d.match([]rune("a")); rule != -1 {
+		t.Errorf("match(%q): want: %d, got: %d", "a", -1, rule)
+	}
+	if rule, size := d.match([]rune(".")); rule != 0 || size != 1 {
+		t.Errorf("match(%q): want: %d, %d, got: %d, %d", ".", 0, 1, rule, size)
+	}
+}
+
+func TestParseSpec_err(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		err   error
+		msg   string
+	}{
+		"empty": {
+			input: "# Nothing.\n",
+			err:   errEmpty,
+		},
+		"missing pattern": {
+			input: "\nnumType\n",
+			err:   errSyntax,
+			msg:   "syntax error: line 2: missing pattern",
+		},
+		"invalid name": {
+			input: "num-type [0-9]+",
+			err:   errSyntax,
+			msg:   `syntax error: line 1: invalid rule name "num-type"`,
+		},
+		"invalid string": {
+			input: `plusType "+`,
+			err:   errSyntax,
+			msg:   `syntax error: line 1: invalid string "+`,
+		},
+		"invalid regexp": {
+			input: "numType [0-9",
+			err:   errSyntax,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseSpec(strings.NewReader(tc.input))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("parseSpec: want: %v, got: %v", tc.err, err)
+			}
+			if tc.msg != "" && err.Error() != tc.msg {
+				t.Errorf("parseSpec: want: %q, got: %q", tc.msg, err.Error())
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	err := generate(&b, strings.NewReader(calcLex), &config{
+		pkg:  "calc",
+		name: "lexCalc",
+		src:  "calc.lex",
+	})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	got := b.String()
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "calc.go", got, 0); err != nil {
+		t.Fatalf("ParseFile: %v\n%s", err, got)
+	}
+	for _, want := range []string{
+		"// Code generated by lexgen from calc.lex; DO NOT EDIT.\n",
+		"package calc\n",
+		"type lexCalc struct{}\n",
+		"func (s lexCalc) Run(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {\n",
+		"\t{typ: numType},   // [0-9]+\n",
+		"\t{skip: true},     // [ \\t\\n]+\n",
+		"var lexCalcAccept = [...]int{-1, 3, 2, 0, 1}\n",
+		"\t\t{0x9, 0xa, 1}, {' ', ' ', 1}, {'+', '+', 2}, {'0', '9', 3},\n\t\t{'a', 'z', 4},\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("generate: want: output containing %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestGenerate_err(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{"numType [0-9]*", "startType ^a"} {
+		err := generate(io.Discard, strings.NewReader(input), &config{
+			pkg:  "p",
+			name: "l",
+			src:  "l.lex",
+		})
+		if !errors.Is(err, errEmptyMatch) && !errors.Is(err, errUnsupported) {
+			t.Errorf("generate(%q): want: %v or %v, got: %v", input, errEmptyMatch, errUnsupported, err)
+		}
+	}
+}
+
+func TestGenerate_dot(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	err := generate(&b, strings.NewReader(calcLex+"identType [\\pL]\n"), &config{
+		pkg:  "calc",
+		name: "lexCalc",
+		src:  "calc.lex",
+		dot:  true,
+	})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	got := b.String()
+
+	for _, want := range []string{
+		"digraph dfa {\n",
+		"\t0;\n",
+		"\t1 [shape=doublecircle, label=\"1\\n-\"];\n",
+		"\t3 [shape=doublecircle, label=\"3\\nnumType\"];\n",
+		"\t0 -> 1 [label=\"'\\\\t'-'\\\\n' ' '\"];\n",
+		"\t3 -> 3 [label=\"'0'-'9'\"];\n",
+		" ...\"];\n",
+		"}\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("generate: want: output containing %q, got:\n%s", want, got)
+		}
+	}
+}
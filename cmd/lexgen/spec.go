@@ -0,0 +1,106 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// errSyntax indicates a syntax error in a rule file.
+	errSyntax = errors.New("syntax error")
+
+	// errEmpty indicates a rule file with no rules.
+	errEmpty = errors.New("no rules")
+)
+
+// skipName is the rule name for input that is matched and discarded.
+const skipName = "-"
+
+// rule is a rule of a rule file.
+type rule struct {
+	// name is the name of the LexemeType constant emitted for the rule or
+	// skipName if the input is discarded.
+	name string
+
+	// pattern is the pattern as written in the rule file.
+	pattern string
+
+	// re is the parsed regular expression.
+	re *syntax.Regexp
+}
+
+// skip returns true if the input matched by the rule is discarded.
+func (r *rule) skip() bool {
+	return r.name == skipName
+}
+
+// parseSpec parses the rules read from r. Each non-empty line that isn't a
+// comment starting with '#' holds a rule name followed by a pattern. The
+// pattern is either a Go string literal matched literally or a regular
+// expression in Go syntax that extends to the end of the line.
+func parseSpec(r io.Reader) ([]*rule, error) {
+	var rules []*rule
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, pattern := text, ""
+		if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+			name, pattern = text[:i], strings.TrimSpace(text[i:])
+		}
+		if name != skipName && !token.IsIdentifier(name) {
+			return nil, fmt.Errorf("%w: line %d: invalid rule name %q", errSyntax, line, name)
+		}
+		if pattern == "" {
+			return nil, fmt.Errorf("%w: line %d: missing pattern", errSyntax, line)
+		}
+
+		expr := pattern
+		flags := syntax.Perl
+		if pattern[0] == '"' || pattern[0] == '`' {
+			lit, err := strconv.Unquote(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: invalid string %s", errSyntax, line, pattern)
+			}
+			expr = lit
+			flags = syntax.Literal
+		}
+		re, err := syntax.Parse(expr, flags)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", errSyntax, line, err)
+		}
+		rules = append(rules, &rule{name: name, pattern: pattern, re: re})
+	}
+	if err := s.Err(); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, errEmpty
+	}
+	return rules, nil
+}
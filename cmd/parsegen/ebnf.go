@@ -0,0 +1,266 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/scanner"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// errSyntax indicates a syntax error in a grammar file.
+	errSyntax = errors.New("syntax error")
+
+	// errRedefined indicates a nonterminal defined more than once.
+	errRedefined = errors.New("production redefined")
+
+	// errEmpty indicates a grammar file with no productions.
+	errEmpty = errors.New("no productions")
+)
+
+const (
+	identType  = lexparse.LexemeType(scanner.Ident)
+	eqType     = lexparse.LexemeType('=')
+	periodType = lexparse.LexemeType('.')
+	barType    = lexparse.LexemeType('|')
+	lparenType = lexparse.LexemeType('(')
+	rparenType = lexparse.LexemeType(')')
+	lbrackType = lexparse.LexemeType('[')
+	rbrackType = lexparse.LexemeType(']')
+	lbraceType = lexparse.LexemeType('{')
+	rbraceType = lexparse.LexemeType('}')
+)
+
+// closing are the types of the lexemes that end an expression.
+var closing = map[lexparse.LexemeType]bool{
+	periodType: true,
+	rparenType: true,
+	rbrackType: true,
+	rbraceType: true,
+}
+
+// production is a BNF production of the expanded grammar.
+type production struct {
+	// name is the nonterminal defined by the production.
+	name string
+
+	// symbols are the names of the production's symbols.
+	symbols []string
+}
+
+// grammar is a grammar read from an EBNF file with its groups, options, and
+// repetitions expanded into BNF productions of helper nonterminals.
+type grammar struct {
+	// start is the name of the start symbol.
+	start string
+
+	// names are the nonterminals defined in the file in order.
+	names []string
+
+	// prods are the expanded productions. The productions of helper
+	// nonterminals follow those of the nonterminals defined in the file.
+	prods []production
+
+	// nonterms are the names of all nonterminals including helpers.
+	nonterms map[string]bool
+}
+
+// isTerm returns true if the symbol name is a terminal. Names that are not
+// defined by a production are terminals.
+func (g *grammar) isTerm(name string) bool {
+	return !g.nonterms[name]
+}
+
+// ebnfParser parses an EBNF grammar file.
+type ebnfParser struct {
+	g *grammar
+
+	// helpers are the productions of helper nonterminals.
+	helpers []production
+
+	// name is the name of the production being parsed.
+	name string
+
+	// nHelpers is the number of helper nonterminals created for the
+	// production being parsed.
+	nHelpers int
+}
+
+// parseEBNF parses a grammar in the following EBNF notation. Names that are
+// not defined by a production are terminals. Comments are written as in Go.
+//
+//	Production  = name "=" [ Expression ] "." .
+//	Expression  = Alternative { "|" Alternative } .
+//	Alternative = Term { Term } .
+//	Term        = name | "(" Expression ")" | "[" Expression "]" | "{" Expression "}" .
+func parseEBNF(ctx context.Context, r io.Reader) (*grammar, error) {
+	l := lexparse.NewScanningLexer(r, scanner.ScanIdents|scanner.ScanComments|scanner.SkipComments)
+	lexemes := l.Lex(ctx)
+	p := lexparse.NewParser[string](lexemes)
+	p.SetLexerErr(l.Err)
+
+	e := &ebnfParser{
+		g: &grammar{nonterms: map[string]bool{}},
+	}
+	_, err := p.Parse(ctx, func(_ context.Context, p *lexparse.Parser[string]) (lexparse.ParseFn[string], error) {
+		for !p.AtEOF() {
+			if err := e.production(p); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	// Let the lexer finish if parsing stopped early.
+	lexparse.Drain(lexemes)
+	<-l.Done()
+	if err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	if len(e.g.names) == 0 {
+		return nil, errEmpty
+	}
+
+	e.g.start = e.g.names[0]
+	e.g.prods = append(e.g.prods, e.helpers...)
+	return e.g, nil
+}
+
+// production parses a production.
+func (e *ebnfParser) production(p *lexparse.Parser[string]) error {
+	name, err := expect(p, identType, "name")
+	if err != nil {
+		return err
+	}
+	if e.g.nonterms[name.Value] {
+		return fmt.Errorf("%w: %q: line %d, column %d", errRedefined, name.Value, name.Line+1, name.Column+1)
+	}
+	if _, err := expect(p, eqType, `"="`); err != nil {
+		return err
+	}
+
+	e.name = name.Value
+	e.nHelpers = 0
+	e.g.names = append(e.g.names, name.Value)
+	e.g.nonterms[name.Value] = true
+
+	alts, err := e.expression(p)
+	if err != nil {
+		return err
+	}
+	if _, err := expect(p, periodType, `"."`); err != nil {
+		return err
+	}
+	for _, alt := range alts {
+		e.g.prods = append(e.g.prods, production{name: name.Value, symbols: alt})
+	}
+	return nil
+}
+
+// expression parses alternatives up to a closing lexeme and returns the
+// symbols of each alternative.
+func (e *ebnfParser) expression(p *lexparse.Parser[string]) ([][]string, error) {
+	var alts [][]string
+	for {
+		var alt []string
+		for {
+			l := p.Peek()
+			if l == nil || closing[l.Type] || l.Type == barType {
+				break
+			}
+			syms, err := e.term(p)
+			if err != nil {
+				return nil, err
+			}
+			alt = append(alt, syms...)
+		}
+		alts = append(alts, alt)
+
+		if l := p.Peek(); l == nil || l.Type != barType {
+			return alts, nil
+		}
+		p.Next()
+	}
+}
+
+// term parses a term and returns its symbols. A group with a single
+// alternative is replaced by its symbols. Other groups, options, and
+// repetitions are replaced by a helper nonterminal.
+func (e *ebnfParser) term(p *lexparse.Parser[string]) ([]string, error) {
+	l := p.Next()
+	switch l.Type {
+	case identType:
+		return []string{l.Value}, nil
+	case lparenType, lbrackType, lbraceType:
+		end := map[lexparse.LexemeType]lexparse.LexemeType{
+			lparenType: rparenType,
+			lbrackType: rbrackType,
+			lbraceType: rbraceType,
+		}[l.Type]
+		alts, err := e.expression(p)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := expect(p, end, fmt.Sprintf("%q", rune(end))); err != nil {
+			return nil, err
+		}
+
+		if l.Type == lparenType && len(alts) == 1 {
+			return alts[0], nil
+		}
+
+		e.nHelpers++
+		helper := fmt.Sprintf("%s#%d", e.name, e.nHelpers)
+		e.g.nonterms[helper] = true
+		for _, alt := range alts {
+			if l.Type == lbraceType {
+				// Repetitions are left recursive so that they can be
+				// parsed by an LALR(1) parser without a stack that grows
+				// with the number of repetitions.
+				alt = append([]string{helper}, alt...)
+			}
+			e.helpers = append(e.helpers, production{name: helper, symbols: alt})
+		}
+		if l.Type != lparenType {
+			e.helpers = append(e.helpers, production{name: helper})
+		}
+		return []string{helper}, nil
+	default:
+		return nil, unexpected(l, "term")
+	}
+}
+
+// expect returns the next lexeme if it has type typ and an error otherwise.
+// want describes the expected lexeme.
+func expect(p *lexparse.Parser[string], typ lexparse.LexemeType, want string) (*lexparse.Lexeme, error) {
+	l := p.Next()
+	if l == nil {
+		return nil, fmt.Errorf("%w: want %s: %w", errSyntax, want, io.ErrUnexpectedEOF)
+	}
+	if l.Type != typ {
+		return nil, unexpected(l, want)
+	}
+	return l, nil
+}
+
+// unexpected returns a syntax error for the lexeme l.
+func unexpected(l *lexparse.Lexeme, want string) error {
+	return fmt.Errorf("%w: want %s, got %q: line %d, column %d", errSyntax, want, l.Value, l.Line+1, l.Column+1)
+}
@@ -0,0 +1,225 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// parsegen generates a Go source file defining a lexparse.Grammar from a
+// grammar written in EBNF so that large grammars don't have to be written by
+// hand. The generated grammar is parsed at runtime by lexparse.TableParser or
+// lexparse.EarleyParser.
+//
+// Usage:
+//
+//	parsegen [-o file] [-package name] [-name name] [-start name] file
+//
+// It is intended to be run by go generate:
+//
+//	//go:generate go run github.com/ianlewis/lexparse/cmd/parsegen -o grammar.go calc.ebnf
+//
+// Grammars are written in the EBNF notation used by the Go specification.
+// Names that are not defined by a production are terminals and must be the
+// names of LexemeType constants in the generated package. The first
+// production defines the start symbol unless -start is given.
+//
+//	// calc.ebnf
+//	Expr = Term { ( plusType | minusType ) Term } .
+//	Term = numType | lparenType Expr rparenType .
+//
+// Groups with more than one alternative, options, and repetitions are
+// replaced by helper nonterminals named after the production, e.g.
+// "Expr#1". Along with the grammar a struct type is generated with a field
+// for each nonterminal defined in the file that computes the value of its
+// nodes. Its Funcs method returns the lexparse.TableFuncs for the parser.
+//
+//	p, err := lexparse.NewTableParser(grammar, (&grammarNodes[int]{
+//		Shift: func(l *lexparse.Lexeme) (int, error) { ... },
+//		Expr:  func(p *lexparse.Production, children []*lexparse.Node[int]) (int, error) { ... },
+//	}).Funcs())
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// errFieldName indicates a nonterminal whose field name in the generated
+// struct conflicts with another field or method.
+var errFieldName = errors.New("conflicting field name")
+
+// config configures the generated code.
+type config struct {
+	// pkg is the name of the generated package.
+	pkg string
+
+	// name is the name of the grammar variable.
+	name string
+
+	// start overrides the start symbol if not empty.
+	start string
+
+	// src is the name of the grammar file.
+	src string
+}
+
+// generate writes the Go source for the grammar read from r to w.
+func generate(ctx context.Context, w io.Writer, r io.Reader, cfg *config) error {
+	g, err := parseEBNF(ctx, r)
+	if err != nil {
+		return fmt.Errorf("parsing grammar: %w", err)
+	}
+	if cfg.start != "" {
+		g.start = cfg.start
+	}
+
+	nodes := cfg.name + "Nodes"
+
+	// Check that each nonterminal has a unique field name that doesn't
+	// conflict with the Shift field or Funcs method.
+	fields := map[string]bool{"Shift": true, "Funcs": true}
+	for _, name := range g.names {
+		field := fieldName(name)
+		if fields[field] {
+			return fmt.Errorf("%w: %s for %q", errFieldName, field, name)
+		}
+		fields[field] = true
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by parsegen from %s; DO NOT EDIT.\n\n", cfg.src)
+	fmt.Fprintf(&b, "package %s\n\n", cfg.pkg)
+	fmt.Fprintf(&b, "import \"github.com/ianlewis/lexparse\"\n\n")
+
+	fmt.Fprintf(&b, "// %s is the grammar defined in %s.\n", cfg.name, cfg.src)
+	fmt.Fprintf(&b, "var %s = &lexparse.Grammar{\n", cfg.name)
+	fmt.Fprintf(&b, "Start: %q,\n", g.start)
+	fmt.Fprintf(&b, "Productions: []lexparse.Production{\n")
+	for _, p := range g.prods {
+		fmt.Fprintf(&b, "{Name: %q", p.name)
+		if len(p.symbols) > 0 {
+			syms := make([]string, 0, len(p.symbols))
+			for _, s := range p.symbols {
+				if g.isTerm(s) {
+					syms = append(syms, fmt.Sprintf("lexparse.Term(%s)", s))
+				} else {
+					syms = append(syms, fmt.Sprintf("lexparse.NonTerm(%q)", s))
+				}
+			}
+			fmt.Fprintf(&b, ", Symbols: []lexparse.Symbol{%s}", strings.Join(syms, ", "))
+		}
+		fmt.Fprintf(&b, "},\n")
+	}
+	fmt.Fprintf(&b, "},\n}\n\n")
+
+	fmt.Fprintf(&b, "// %s holds the functions that compute the values of the nodes of\n", nodes)
+	fmt.Fprintf(&b, "// %s's parse trees. Nil functions and helper nonterminals produce the\n", cfg.name)
+	fmt.Fprintf(&b, "// zero value.\n")
+	fmt.Fprintf(&b, "type %s[V comparable] struct {\n", nodes)
+	fmt.Fprintf(&b, "// Shift returns the value of the leaf node for a lexeme.\n")
+	fmt.Fprintf(&b, "Shift func(l *lexparse.Lexeme) (V, error)\n")
+	for _, name := range g.names {
+		fmt.Fprintf(&b, "\n// %s returns the value of the nodes for %s.\n", fieldName(name), name)
+		fmt.Fprintf(&b, "%s func(p *lexparse.Production, children []*lexparse.Node[V]) (V, error)\n", fieldName(name))
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "// Funcs returns the TableFuncs that call n's functions.\n")
+	fmt.Fprintf(&b, "func (n *%s[V]) Funcs() lexparse.TableFuncs[V] {\n", nodes)
+	fmt.Fprintf(&b, "return lexparse.TableFuncs[V]{\n")
+	fmt.Fprintf(&b, "Shift: n.Shift,\n")
+	fmt.Fprintf(&b, "Reduce: func(p *lexparse.Production, children []*lexparse.Node[V]) (V, error) {\n")
+	fmt.Fprintf(&b, "var fn func(p *lexparse.Production, children []*lexparse.Node[V]) (V, error)\n")
+	fmt.Fprintf(&b, "switch p.Name {\n")
+	for _, name := range g.names {
+		fmt.Fprintf(&b, "case %q:\nfn = n.%s\n", name, fieldName(name))
+	}
+	fmt.Fprintf(&b, "}\n")
+	fmt.Fprintf(&b, "if fn == nil {\nvar zero V\nreturn zero, nil\n}\n")
+	fmt.Fprintf(&b, "return fn(p, children)\n")
+	fmt.Fprintf(&b, "},\n}\n}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting output: %w", err)
+	}
+	if _, err := w.Write(src); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// fieldName returns the name of the struct field for the nonterminal name.
+func fieldName(name string) string {
+	rn, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(rn)) + name[size:]
+}
+
+func main() {
+	out := flag.String("o", "", "output file (default standard output)")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package name (default $GOPACKAGE or main)")
+	name := flag.String("name", "grammar", "name of the grammar variable")
+	start := flag.String("start", "", "start symbol (default the first production)")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "usage: parsegen [flags] file\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if *pkg == "" {
+		*pkg = "main"
+	}
+
+	if err := run(context.Background(), flag.Arg(0), *out, &config{
+		pkg:   *pkg,
+		name:  *name,
+		start: *start,
+		src:   filepath.Base(flag.Arg(0)),
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "parsegen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the code for the grammar file at path and writes it to the
+// file out or the standard output if out is empty.
+func run(ctx context.Context, path, out string, cfg *config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	defer f.Close()
+
+	var b bytes.Buffer
+	if err := generate(ctx, &b, f, cfg); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(b.Bytes())
+	} else {
+		//nolint:gosec // Generated code is not sensitive.
+		err = os.WriteFile(out, b.Bytes(), 0o644)
+	}
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return err
+}
@@ -0,0 +1,315 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"go/parser"
+	"go/token"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/lexparse"
+)
+
+const calcEBNF = `
+// A calculator.
+Expr = [ minus ] Term { ( plus | minus ) Term } .
+Term = factor | lparen Expr rparen .
+factor = num .
+`
+
+func TestParseEBNF(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		want  *grammar
+	}{
+		"sequence": {
+			input: "S = a b .",
+			want: &grammar{
+				start:    "S",
+				names:    []string{"S"},
+				prods:    []production{{name: "S", symbols: []string{"a", "b"}}},
+				nonterms: map[string]bool{"S": true},
+			},
+		},
+		"empty": {
+			input: "S = .",
+			want: &grammar{
+				start:    "S",
+				names:    []string{"S"},
+				prods:    []production{{name: "S"}},
+				nonterms: map[string]bool{"S": true},
+			},
+		},
+		"alternatives": {
+			input: "S = a | T . T = b | .",
+			want: &grammar{
+				start: "S",
+				names: []string{"S", "T"},
+				prods: []production{
+					{name: "S", symbols: []string{"a"}},
+					{name: "S", symbols: []string{"T"}},
+					{name: "T", symbols: []string{"b"}},
+					{name: "T"},
+				},
+				nonterms: map[string]bool{"S": true, "T": true},
+			},
+		},
+		"group": {
+			input: "S = ( a b ) c .",
+			want: &grammar{
+				start:    "S",
+				names:    []string{"S"},
+				prods:    []production{{name: "S", symbols: []string{"a", "b", "c"}}},
+				nonterms: map[string]bool{"S": true},
+			},
+		},
+		"group alternatives": {
+			input: "S = ( a | b ) c .",
+			want: &grammar{
+				start: "S",
+				names: []string{"S"},
+				prods: []production{
+					{name: "S", symbols: []string{"S#1", "c"}},
+					{name: "S#1", symbols: []string{"a"}},
+					{name: "S#1", symbols: []string{"b"}},
+				},
+				nonterms: map[string]bool{"S": true, "S#1": true},
+			},
+		},
+		"option": {
+			input: "S = [ a ] b .",
+			want: &grammar{
+				start: "S",
+				names: []string{"S"},
+				prods: []production{
+					{name: "S", symbols: []string{"S#1", "b"}},
+					{name: "S#1", symbols: []string{"a"}},
+					{name: "S#1"},
+				},
+				nonterms: map[string]bool{"S": true, "S#1": true},
+			},
+		},
+		"repetition": {
+			input: "S = { a b } .",
+			want: &grammar{
+				start: "S",
+				names: []string{"S"},
+				prods: []production{
+					{name: "S", symbols: []string{"S#1"}},
+					{name: "S#1", symbols: []string{"S#1", "a", "b"}},
+					{name: "S#1"},
+				},
+				nonterms: map[string]bool{"S": true, "S#1": true},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseEBNF(context.Background(), strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("parseEBNF: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(grammar{}, production{})); diff != "" {
+				t.Errorf("parseEBNF: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseEBNF_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		err   error
+		msg   string
+	}{
+		"empty": {
+			input: "// Nothing.\n",
+			err:   errEmpty,
+		},
+		"missing equals": {
+			input: "S a .",
+			err:   errSyntax,
+			msg:   `syntax error: want "=", got "a": line 1, column 3`,
+		},
+		"missing period": {
+			input: "S = a",
+			err:   io.ErrUnexpectedEOF,
+		},
+		"unclosed group": {
+			input: "S = ( a .",
+			err:   errSyntax,
+			msg:   `syntax error: want ')', got ".": line 1, column 9`,
+		},
+		"redefined": {
+			input: "S = a .\nS = b .",
+			err:   errRedefined,
+			msg:   `production redefined: "S": line 2, column 1`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseEBNF(context.Background(), strings.NewReader(tc.input))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("parseEBNF: want: %v, got: %v", tc.err, err)
+			}
+			if tc.msg != "" && err.Error() != tc.msg {
+				t.Errorf("parseEBNF: want: %q, got: %q", tc.msg, err.Error())
+			}
+		})
+	}
+}
+
+// lexparseGrammar returns the lexparse.Grammar for g with terminal names
+// mapped to lexeme types by types.
+func lexparseGrammar(g *grammar, types map[string]lexparse.LexemeType) *lexparse.Grammar {
+	lg := &lexparse.Grammar{Start: g.start}
+	for _, p := range g.prods {
+		lp := lexparse.Production{Name: p.name}
+		for _, s := range p.symbols {
+			if g.isTerm(s) {
+				lp.Symbols = append(lp.Symbols, lexparse.Term(types[s]))
+			} else {
+				lp.Symbols = append(lp.Symbols, lexparse.NonTerm(s))
+			}
+		}
+		lg.Productions = append(lg.Productions, lp)
+	}
+	return lg
+}
+
+func TestParseEBNF_tableParser(t *testing.T) {
+	t.Parallel()
+
+	g, err := parseEBNF(context.Background(), strings.NewReader(calcEBNF))
+	if err != nil {
+		t.Fatalf("parseEBNF: %v", err)
+	}
+	types := map[string]lexparse.LexemeType{
+		"num":    lexparse.FirstUserType,
+		"plus":   lexparse.FirstUserType + 1,
+		"minus":  lexparse.FirstUserType + 2,
+		"lparen": lexparse.FirstUserType + 3,
+		"rparen": lexparse.FirstUserType + 4,
+	}
+	tp, err := lexparse.NewTableParser(lexparseGrammar(g, types), lexparse.TableFuncs[string]{})
+	if err != nil {
+		t.Fatalf("NewTableParser: %v", err)
+	}
+
+	testCases := map[string]struct {
+		input []string
+		err   error
+	}{
+		"number": {
+			input: []string{"num"},
+		},
+		"sum": {
+			input: []string{"num", "plus", "lparen", "minus", "num", "rparen", "minus", "num"},
+		},
+		"trailing operator": {
+			input: []string{"num", "plus"},
+			err:   io.ErrUnexpectedEOF,
+		},
+		"double operator": {
+			input: []string{"num", "plus", "plus", "num"},
+			err:   lexparse.ErrUnexpectedLexeme,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lexemes := make([]*lexparse.Lexeme, 0, len(tc.input))
+			for i, s := range tc.input {
+				lexemes = append(lexemes, &lexparse.Lexeme{Type: types[s], Value: s, Pos: i, Column: i})
+			}
+			_, err := tp.Parse(context.Background(), lexparse.FromSlice(lexemes).Lex(context.Background()))
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	err := generate(context.Background(), &b, strings.NewReader(calcEBNF), &config{
+		pkg:  "calc",
+		name: "calcGrammar",
+		src:  "calc.ebnf",
+	})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	got := b.String()
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "calc.go", got, 0); err != nil {
+		t.Fatalf("ParseFile: %v\n%s", err, got)
+	}
+	for _, want := range []string{
+		"// Code generated by parsegen from calc.ebnf; DO NOT EDIT.\n",
+		"package calc\n",
+		"var calcGrammar = &lexparse.Grammar{\n",
+		`Start: "Expr",`,
+		`{Name: "Expr", Symbols: []lexparse.Symbol{` +
+			`lexparse.NonTerm("Expr#1"), lexparse.NonTerm("Term"), lexparse.NonTerm("Expr#3")}},`,
+		`{Name: "Expr#1"},`,
+		`{Name: "Expr#2", Symbols: []lexparse.Symbol{lexparse.Term(plus)}},`,
+		"type calcGrammarNodes[V comparable] struct {\n",
+		"\tFactor func(p *lexparse.Production, children []*lexparse.Node[V]) (V, error)\n",
+		"\t\t\tcase \"factor\":\n\t\t\t\tfn = n.Factor\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("generate: want: output containing %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestGenerate_fieldName(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{"shift = a .", "S = T . T = a . t = b ."} {
+		err := generate(context.Background(), io.Discard, strings.NewReader(input), &config{
+			pkg:  "p",
+			name: "g",
+			src:  "g.ebnf",
+		})
+		if !errors.Is(err, errFieldName) {
+			t.Errorf("generate(%q): want: %v, got: %v", input, errFieldName, err)
+		}
+	}
+}
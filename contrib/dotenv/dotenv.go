@@ -0,0 +1,395 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dotenv implements a parser for .env and Java properties style
+// files using lexparse.
+//
+// Each line of a file contains a key and an optional value separated by '='
+// or ':'. Lines may be prefixed with "export" as in shell scripts. Lines
+// starting with '#' or '!' are comments.
+//
+//	# Database settings.
+//	export DB_HOST=localhost
+//	DB_PASS = "p@ss \"word\""
+//	greeting: Hello, \
+//	          World!
+//
+// Values are one of the following:
+//
+//   - Double quoted values may span lines and support the escapes \n, \r,
+//     \t, \\, \", and \uXXXX.
+//   - Single quoted values may span lines and are taken literally.
+//   - Unquoted values end at the end of the line or at a '#' preceded by
+//     whitespace. Surrounding whitespace is removed. A backslash at the end of
+//     the line continues the value on the next line with leading whitespace
+//     removed. The escapes \n, \r, \t, \f, and \uXXXX are supported and a
+//     backslash before any other character is removed.
+package dotenv
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrMissingKey indicates a line with a value but no key.
+	ErrMissingKey = errors.New("missing key")
+
+	// ErrUnclosedQuote indicates a quoted value that is not terminated.
+	ErrUnclosedQuote = errors.New("unclosed quote")
+
+	// ErrInvalidEscape indicates an invalid escape sequence.
+	ErrInvalidEscape = errors.New("invalid escape sequence")
+
+	// ErrUnexpectedChar indicates an unexpected character after a quoted
+	// value.
+	ErrUnexpectedChar = errors.New("unexpected character")
+)
+
+const (
+	keyType lexparse.LexemeType = iota
+	valueType
+)
+
+// Entry is a key and value in a file.
+type Entry struct {
+	Key   string
+	Value string
+
+	// Line is the line of the key.
+	Line int
+
+	// Column is the column of the key.
+	Column int
+}
+
+var (
+	// blankClass matches whitespace within a line.
+	blankClass = lexparse.NewRuneClass(" \t\f")
+
+	// newlineClass matches a line feed.
+	newlineClass = lexparse.NewRuneClass("\n")
+
+	// spaceClass matches whitespace including newlines.
+	spaceClass = lexparse.NewRuneClass(" \t\f\r\n")
+
+	// keyClass matches the characters of a key.
+	keyClass = lexparse.RuneClassFunc(func(rn rune) bool {
+		return !strings.ContainsRune(" \t\f\r\n=:#", rn)
+	})
+)
+
+// peekRune returns the next rune without advancing the lexer.
+func peekRune(l *lexparse.Lexer) (rune, error) {
+	rns, err := l.Peek(1)
+	if len(rns) == 0 {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return 0, err
+	}
+	return rns[0], nil
+}
+
+// skip advances over runes in the class c and ignores them.
+func skip(l *lexparse.Lexer, c *lexparse.RuneClass) error {
+	_, err := l.AdvanceWhile(c)
+	l.Ignore()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return err
+}
+
+// posError returns an error at the given position.
+func posError(err error, line, col int) error {
+	return fmt.Errorf("%w: line %d, column %d", err, line+1, col+1)
+}
+
+// lexLine lexes a comment or a key and value.
+func lexLine(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	if err := skip(l, spaceClass); err != nil {
+		return nil, err
+	}
+	rn, err := peekRune(l)
+	if err != nil {
+		return nil, err
+	}
+
+	if rn == '#' || rn == '!' {
+		if _, err := l.SkipTo([]string{"\n"}); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		return lexparse.StateFn(lexLine), nil
+	}
+
+	if rns, _ := l.Peek(7); len(rns) == 7 && string(rns[:6]) == "export" && blankClass.Contains(rns[6]) {
+		if _, err := l.Discard(7); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		if err := skip(l, blankClass); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := l.AdvanceWhile(keyClass); err != nil && !errors.Is(err, io.EOF) {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	key := l.Lexeme(keyType)
+	if key.Value == "" {
+		return nil, posError(ErrMissingKey, key.Line, key.Column)
+	}
+	l.Emit(key)
+
+	// Skip the separator.
+	if err := skip(l, blankClass); err != nil {
+		return nil, err
+	}
+	if rn, err := peekRune(l); err == nil && (rn == '=' || rn == ':') {
+		if _, err := l.Discard(1); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		if err := skip(l, blankClass); err != nil {
+			return nil, err
+		}
+	}
+
+	return lexparse.StateFn(lexLine), lexValue(l)
+}
+
+// lexValue lexes a quoted or unquoted value.
+func lexValue(l *lexparse.Lexer) error {
+	line, col := l.Line(), l.Column()
+	rn, err := peekRune(l)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	var value string
+	switch rn {
+	case '"', '\'':
+		if _, err := l.Advance(1); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+		value, err = lexQuoted(l, rn, line, col)
+		if err != nil {
+			return err
+		}
+		if err := lexLineEnd(l); err != nil {
+			return err
+		}
+	default:
+		value, err = lexUnquoted(l)
+		if err != nil {
+			return err
+		}
+	}
+
+	lexeme := l.Lexeme(valueType)
+	lexeme.Value = value
+	l.Emit(lexeme)
+	return nil
+}
+
+// lexQuoted lexes the rest of a value quoted with the given quote character.
+func lexQuoted(l *lexparse.Lexer, quote rune, line, col int) (string, error) {
+	var b strings.Builder
+	for {
+		rn, _, err := l.ReadRune()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", posError(ErrUnclosedQuote, line, col)
+			}
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return "", err
+		}
+		switch {
+		case rn == quote:
+			return b.String(), nil
+		case rn == '\\' && quote == '"':
+			if err := lexEscape(l, &b, `"\`); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteRune(rn)
+		}
+	}
+}
+
+// lexLineEnd lexes optional whitespace and a comment after a quoted value.
+func lexLineEnd(l *lexparse.Lexer) error {
+	if _, err := l.AdvanceWhile(blankClass); err != nil && !errors.Is(err, io.EOF) {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	rn, err := peekRune(l)
+	switch {
+	case errors.Is(err, io.EOF):
+		return nil
+	case err != nil:
+		return err
+	case rn == '#':
+		if _, err := l.Find([]string{"\n"}); err != nil && !errors.Is(err, io.EOF) {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+		return nil
+	case rn == '\r' || rn == '\n':
+		return nil
+	default:
+		return posError(fmt.Errorf("%w: %q", ErrUnexpectedChar, rn), l.Line(), l.Column())
+	}
+}
+
+// lexUnquoted lexes an unquoted value up to the end of the line or an inline
+// comment.
+func lexUnquoted(l *lexparse.Lexer) (string, error) {
+	var b strings.Builder
+	// trimmed is the length of the value without trailing whitespace.
+	trimmed := 0
+	for {
+		rn, err := peekRune(l)
+		if errors.Is(err, io.EOF) || rn == '\r' || rn == '\n' {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if rn == '#' && b.Len() > trimmed {
+			// A comment preceded by whitespace.
+			if _, err := l.Find([]string{"\n"}); err != nil && !errors.Is(err, io.EOF) {
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return "", err
+			}
+			break
+		}
+		if _, _, err := l.ReadRune(); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return "", err
+		}
+
+		switch {
+		case rn == '\\':
+			if err := lexEscape(l, &b, ""); err != nil {
+				return "", err
+			}
+			trimmed = b.Len()
+		case blankClass.Contains(rn):
+			b.WriteRune(rn)
+		default:
+			b.WriteRune(rn)
+			trimmed = b.Len()
+		}
+	}
+	return b.String()[:trimmed], nil
+}
+
+// lexEscape lexes an escape sequence after a backslash and writes the result
+// to b. If literal is empty, any character may be escaped and a newline
+// continues the value on the next line. Otherwise only the characters in
+// literal and the standard escapes are allowed.
+func lexEscape(l *lexparse.Lexer, b *strings.Builder, literal string) error {
+	line, col := l.Line(), l.Column()-1
+	rn, _, err := l.ReadRune()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			if literal == "" {
+				return nil
+			}
+			return posError(ErrInvalidEscape, line, col)
+		}
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+
+	switch rn {
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case 'f':
+		b.WriteByte('\f')
+	case 'u':
+		rns, _ := l.Peek(4)
+		code, err := strconv.ParseUint(string(rns), 16, 16)
+		if len(rns) != 4 || err != nil {
+			return posError(ErrInvalidEscape, line, col)
+		}
+		if _, err := l.Advance(4); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+		b.WriteRune(rune(code))
+	case '\r', '\n':
+		if literal != "" {
+			return posError(ErrInvalidEscape, line, col)
+		}
+		if rn == '\r' {
+			if _, err := l.Accept(newlineClass); err != nil && !errors.Is(err, io.EOF) {
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return err
+			}
+		}
+		if _, err := l.AdvanceWhile(blankClass); err != nil && !errors.Is(err, io.EOF) {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+	default:
+		if literal != "" && !strings.ContainsRune(literal, rn) {
+			return posError(ErrInvalidEscape, line, col)
+		}
+		b.WriteRune(rn)
+	}
+	return nil
+}
+
+// Parse parses a .env or properties file and returns its entries in order.
+func Parse(ctx context.Context, r io.Reader) ([]Entry, error) {
+	l := lexparse.NewLexer(runeio.NewReader(bufio.NewReader(r)), lexparse.StateFn(lexLine))
+
+	var entries []Entry
+	for lexeme := range l.Lex(ctx) {
+		switch lexeme.Type {
+		case keyType:
+			entries = append(entries, Entry{
+				Key:    lexeme.Value,
+				Line:   lexeme.Line,
+				Column: lexeme.Column,
+			})
+		case valueType:
+			entries[len(entries)-1].Value = lexeme.Value
+		}
+	}
+	<-l.Done()
+	if err := l.Err(); err != nil {
+		return nil, fmt.Errorf("parsing dotenv: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dotenv
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		want  []Entry
+	}{
+		"empty": {
+			input: "",
+		},
+		"simple": {
+			input: "A=1\nB=two\n",
+			want: []Entry{
+				{Key: "A", Value: "1"},
+				{Key: "B", Value: "two", Line: 1},
+			},
+		},
+		"separators": {
+			input: "a = 1\nb: 2\nc 3\nd\n",
+			want: []Entry{
+				{Key: "a", Value: "1"},
+				{Key: "b", Value: "2", Line: 1},
+				{Key: "c", Value: "3", Line: 2},
+				{Key: "d", Line: 3},
+			},
+		},
+		"export": {
+			input: "export  KEY=value\nexported=1",
+			want: []Entry{
+				{Key: "KEY", Value: "value", Column: 8},
+				{Key: "exported", Value: "1", Line: 1},
+			},
+		},
+		"comments": {
+			input: "# comment\n! comment\n  KEY=value # comment\nURL=http://x/#anchor",
+			want: []Entry{
+				{Key: "KEY", Value: "value", Line: 2, Column: 2},
+				{Key: "URL", Value: "http://x/#anchor", Line: 3},
+			},
+		},
+		"double quotes": {
+			input: `KEY="a \"quoted\"\tvalue\né" # comment`,
+			want:  []Entry{{Key: "KEY", Value: "a \"quoted\"\tvalue\né"}},
+		},
+		"multiline double quotes": {
+			input: "KEY=\"line 1\nline 2\"\nNEXT=1",
+			want: []Entry{
+				{Key: "KEY", Value: "line 1\nline 2"},
+				{Key: "NEXT", Value: "1", Line: 2},
+			},
+		},
+		"single quotes": {
+			input: `KEY='no \n escapes # here'`,
+			want:  []Entry{{Key: "KEY", Value: `no \n escapes # here`}},
+		},
+		"unquoted escapes": {
+			input: `KEY=a\=b\:c\\d\te\ `,
+			want:  []Entry{{Key: "KEY", Value: "a=b:c\\d\te "}},
+		},
+		"continuation": {
+			input: "greeting: Hello, \\\n          World!\nnext=1",
+			want: []Entry{
+				{Key: "greeting", Value: "Hello, World!"},
+				{Key: "next", Value: "1", Line: 2},
+			},
+		},
+		"continuation crlf": {
+			input: "k=a\\\r\n  b\r\nnext=1\r\n",
+			want: []Entry{
+				{Key: "k", Value: "ab"},
+				{Key: "next", Value: "1", Line: 2},
+			},
+		},
+		"trailing backslash": {
+			input: `k=a\`,
+			want:  []Entry{{Key: "k", Value: "a"}},
+		},
+		"trailing whitespace": {
+			input: "k = value  \t\n",
+			want:  []Entry{{Key: "k", Value: "value"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(context.Background(), strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Parse: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		err   error
+		msg   string
+	}{
+		"missing key": {
+			input: "A=1\n=2",
+			err:   ErrMissingKey,
+			msg:   "line 2, column 1",
+		},
+		"unclosed double quote": {
+			input: `KEY="value`,
+			err:   ErrUnclosedQuote,
+			msg:   "line 1, column 5",
+		},
+		"unclosed single quote": {
+			input: "A=1\nKEY='value\n",
+			err:   ErrUnclosedQuote,
+			msg:   "line 2, column 5",
+		},
+		"invalid escape": {
+			input: `KEY="\q"`,
+			err:   ErrInvalidEscape,
+			msg:   "line 1, column 6",
+		},
+		"invalid unicode": {
+			input: `KEY=\u12`,
+			err:   ErrInvalidEscape,
+			msg:   "line 1, column 5",
+		},
+		"text after quote": {
+			input: `KEY="value" x`,
+			err:   ErrUnexpectedChar,
+			msg:   "line 1, column 13",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(context.Background(), strings.NewReader(tc.input))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Parse: want: %v, got: %v", tc.err, err)
+			}
+			if !strings.Contains(err.Error(), tc.msg) {
+				t.Errorf("Parse: want: %q in %q", tc.msg, err.Error())
+			}
+		})
+	}
+}
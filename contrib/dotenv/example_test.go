@@ -0,0 +1,46 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dotenv_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ianlewis/lexparse/contrib/dotenv"
+)
+
+func Example() {
+	input := `# Database settings.
+export DB_HOST=localhost
+DB_PASS = "p@ss \"word\""
+greeting: Hello, \
+          World!
+`
+
+	entries, err := dotenv.Parse(context.Background(), strings.NewReader(input))
+	if err != nil {
+		panic(err)
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%d: %s=%s\n", e.Line+1, e.Key, e.Value)
+	}
+
+	// Output:
+	// 2: DB_HOST=localhost
+	// 3: DB_PASS=p@ss "word"
+	// 4: greeting=Hello, World!
+}
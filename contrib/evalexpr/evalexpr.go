@@ -0,0 +1,240 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package evalexpr implements an arithmetic expression evaluator using
+// lexparse.
+//
+// Expressions consist of numbers, variables, parentheses, and the operators
+// below, listed from lowest to highest precedence.
+//
+//	a + b, a - b           addition and subtraction, left associative
+//	a * b, a / b, a % b    multiplication, division, and remainder, left associative
+//	-a                     unary minus
+//	a ^ b                  exponentiation, right associative
+//
+// Exponentiation binds more tightly than unary minus so -2^2 is -4.
+package evalexpr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrUndefined indicates that a variable is not defined.
+	ErrUndefined = errors.New("undefined variable")
+
+	// ErrDivisionByZero indicates division by zero.
+	ErrDivisionByZero = errors.New("division by zero")
+)
+
+// scanMode is the text/scanner mode used to lex expressions.
+const scanMode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats
+
+// node is the value of a node in the expression tree.
+type node struct {
+	lexeme *lexparse.Lexeme
+
+	// num is the value of a number.
+	num float64
+}
+
+// exprParser parses expressions.
+var exprParser = newExprParser()
+
+// newExprParser returns a new parser for expressions.
+func newExprParser() *lexparse.ExprParser[*node] {
+	e := &lexparse.ExprParser[*node]{
+		Operators: lexparse.NewOperatorTable().
+			Infix('+', 1, lexparse.AssocLeft).
+			Infix('-', 1, lexparse.AssocLeft).
+			Infix('*', 2, lexparse.AssocLeft).
+			Infix('/', 2, lexparse.AssocLeft).
+			Infix('%', 2, lexparse.AssocLeft).
+			Prefix('-', 3).
+			Infix('^', 4, lexparse.AssocRight),
+		Value: func(l *lexparse.Lexeme) (*node, error) {
+			return &node{lexeme: l}, nil
+		},
+	}
+	e.Operand = func(ctx context.Context, p *lexparse.Parser[*node]) (*lexparse.Node[*node], error) {
+		return parseOperand(ctx, p, e)
+	}
+	return e
+}
+
+// parseOperand parses a number, variable, or parenthesized expression.
+func parseOperand(
+	ctx context.Context,
+	p *lexparse.Parser[*node],
+	e *lexparse.ExprParser[*node],
+) (*lexparse.Node[*node], error) {
+	l := p.Next()
+	if l == nil {
+		return nil, fmt.Errorf("%w: expected operand", io.ErrUnexpectedEOF)
+	}
+
+	switch l.Type {
+	case scanner.Int, scanner.Float:
+		num, err := parseNumber(l.Value)
+		if err != nil {
+			return nil, &lexparse.LexemeError{
+				Err:    lexparse.ErrUnexpectedLexeme,
+				Detail: "invalid number",
+				Lexeme: l,
+			}
+		}
+		return p.NewNode(&node{lexeme: l, num: num}), nil
+	case scanner.Ident:
+		return p.NewNode(&node{lexeme: l}), nil
+	case '(':
+		n, err := e.Expr(ctx, p, 0)
+		if err != nil {
+			return nil, err
+		}
+		r := p.Next()
+		if r == nil {
+			return nil, fmt.Errorf("%w: expected ')'", io.ErrUnexpectedEOF)
+		}
+		if r.Type != ')' {
+			return nil, &lexparse.LexemeError{
+				Err:    lexparse.ErrUnexpectedLexeme,
+				Lexeme: r,
+			}
+		}
+		return n, nil
+	default:
+		return nil, &lexparse.LexemeError{
+			Err:    lexparse.ErrUnexpectedLexeme,
+			Lexeme: l,
+		}
+	}
+}
+
+// parseNumber parses a decimal, hexadecimal, octal, or binary number.
+func parseNumber(s string) (float64, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	i, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing number: %w", err)
+	}
+	return float64(i), nil
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	root *lexparse.Node[*node]
+}
+
+// Parse parses an expression.
+func Parse(ctx context.Context, s string) (*Expr, error) {
+	l := lexparse.NewScanningLexer(strings.NewReader(s), scanMode)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	p := lexparse.NewParser[*node](l.Lex(ctx))
+	p.SetLexerErr(l.Err)
+	p.SetRequireEOF(true)
+	_, pErr := p.Parse(ctx, parseExpr)
+	cancel(pErr)
+
+	<-l.Done()
+
+	err := pErr
+	if lErr := l.Err(); lErr != nil && !errors.Is(lErr, context.Canceled) {
+		err = lErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing expression: %w", err)
+	}
+
+	return &Expr{root: p.Root().Children[0]}, nil
+}
+
+// parseExpr parses a single expression.
+func parseExpr(ctx context.Context, p *lexparse.Parser[*node]) (lexparse.ParseFn[*node], error) {
+	_, err := exprParser.Parse(ctx, p)
+	return nil, err
+}
+
+// Eval evaluates the expression with the given variable values.
+func (e *Expr) Eval(vars map[string]float64) (float64, error) {
+	return eval(e.root, vars)
+}
+
+// Eval parses and evaluates the expression s with the given variable values.
+func Eval(ctx context.Context, s string, vars map[string]float64) (float64, error) {
+	e, err := Parse(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+	return e.Eval(vars)
+}
+
+// eval evaluates the expression tree rooted at n.
+func eval(n *lexparse.Node[*node], vars map[string]float64) (float64, error) {
+	l := n.Value.lexeme
+	switch l.Type {
+	case scanner.Int, scanner.Float:
+		return n.Value.num, nil
+	case scanner.Ident:
+		v, ok := vars[l.Value]
+		if !ok {
+			return 0, fmt.Errorf("%w: %q: line %d, column %d", ErrUndefined, l.Value, l.Line+1, l.Column+1)
+		}
+		return v, nil
+	}
+
+	left, err := eval(n.Left(), vars)
+	if err != nil {
+		return 0, err
+	}
+	if len(n.Children) == 1 {
+		// Unary minus is the only prefix operator.
+		return -left, nil
+	}
+	right, err := eval(n.Right(), vars)
+	if err != nil {
+		return 0, err
+	}
+
+	switch l.Type {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	case '/', '%':
+		if right == 0 {
+			return 0, fmt.Errorf("%w: line %d, column %d", ErrDivisionByZero, l.Line+1, l.Column+1)
+		}
+		if l.Type == '%' {
+			return math.Mod(left, right), nil
+		}
+		return left / right, nil
+	default:
+		return math.Pow(left, right), nil
+	}
+}
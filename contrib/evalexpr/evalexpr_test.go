@@ -0,0 +1,179 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evalexpr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ianlewis/lexparse"
+)
+
+func TestEval(t *testing.T) {
+	t.Parallel()
+
+	vars := map[string]float64{
+		"x":  3,
+		"y":  4,
+		"pi": 3.14,
+	}
+
+	testCases := map[string]struct {
+		expr string
+		want float64
+	}{
+		"number": {
+			expr: "42",
+			want: 42,
+		},
+		"float": {
+			expr: "1.5e2",
+			want: 150,
+		},
+		"hex": {
+			expr: "0x10",
+			want: 16,
+		},
+		"precedence": {
+			expr: "1 + 2 * 3",
+			want: 7,
+		},
+		"left associative": {
+			expr: "10 - 4 - 3",
+			want: 3,
+		},
+		"parentheses": {
+			expr: "(1 + 2) * 3",
+			want: 9,
+		},
+		"division": {
+			expr: "7 / 2",
+			want: 3.5,
+		},
+		"remainder": {
+			expr: "7 % 4",
+			want: 3,
+		},
+		"unary minus": {
+			expr: "-x * -2",
+			want: 6,
+		},
+		"double unary minus": {
+			expr: "--x",
+			want: 3,
+		},
+		"exponent": {
+			expr: "2 ^ 10",
+			want: 1024,
+		},
+		"right associative exponent": {
+			expr: "2 ^ 3 ^ 2",
+			want: 512,
+		},
+		"exponent binds tighter than unary minus": {
+			expr: "-2 ^ 2",
+			want: -4,
+		},
+		"variables": {
+			expr: "(x^2 + y^2) ^ 0.5",
+			want: 5,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Eval(context.Background(), tc.expr, vars)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Eval: want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExpr_Eval(t *testing.T) {
+	t.Parallel()
+
+	e, err := Parse(context.Background(), "x * 2")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	for x, want := range map[float64]float64{1: 2, 2: 4, -3: -6} {
+		got, err := e.Eval(map[string]float64{"x": x})
+		if err != nil {
+			t.Fatalf("Eval: %v", err)
+		}
+		if got != want {
+			t.Errorf("Eval(%v): want: %v, got: %v", x, want, got)
+		}
+	}
+}
+
+func TestEval_error(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		expr string
+		err  error
+	}{
+		"empty": {
+			expr: "",
+			err:  io.ErrUnexpectedEOF,
+		},
+		"missing operand": {
+			expr: "1 +",
+			err:  io.ErrUnexpectedEOF,
+		},
+		"unclosed paren": {
+			expr: "(1 + 2",
+			err:  io.ErrUnexpectedEOF,
+		},
+		"unexpected lexeme": {
+			expr: "1 + * 2",
+			err:  lexparse.ErrUnexpectedLexeme,
+		},
+		"trailing input": {
+			expr: "1 2",
+			err:  lexparse.ErrTrailingInput,
+		},
+		"undefined": {
+			expr: "x + 1",
+			err:  ErrUndefined,
+		},
+		"division by zero": {
+			expr: "1 / (2 - 2)",
+			err:  ErrDivisionByZero,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Eval(context.Background(), tc.expr, nil)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Eval: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evalexpr_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ianlewis/lexparse/contrib/evalexpr"
+)
+
+func Example() {
+	v, err := evalexpr.Eval(context.Background(), "-(x + 1) ^ 2 * 3", map[string]float64{"x": 1})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(v)
+
+	// Output: -12
+}
@@ -0,0 +1,47 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ianlewis/lexparse/contrib/filter"
+)
+
+func Example() {
+	f, err := filter.Parse(context.Background(), `age > 30 AND name LIKE "a%"`)
+	if err != nil {
+		panic(err)
+	}
+
+	rows := []map[string]any{
+		{"name": "alice", "age": 31},
+		{"name": "adam", "age": 25},
+		{"name": "bob", "age": 42},
+	}
+	for _, row := range rows {
+		ok, err := f.Match(row)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(row["name"], ok)
+	}
+
+	// Output:
+	// alice true
+	// adam false
+	// bob false
+}
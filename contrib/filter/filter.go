@@ -0,0 +1,729 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filter implements a filter language similar to SQL WHERE clauses
+// using lexparse.
+//
+// Filters are evaluated against rows of type map[string]any:
+//
+//	age > 30 AND name LIKE "a%"
+//	status IN ("active", "pending") OR NOT (score < 0.5)
+//	email IS NOT NULL
+//
+// Values are numbers, strings in single or double quotes, the booleans TRUE
+// and FALSE, NULL, and column names. Keywords are case-insensitive. The
+// operators, from lowest to highest precedence, are:
+//
+//	OR
+//	AND
+//	NOT
+//	=, !=, <>, <, <=, >, >=, LIKE, NOT LIKE, IN, NOT IN, IS NULL, IS NOT NULL
+//	- (negation)
+//
+// LIKE patterns match any sequence of characters with '%' and any single
+// character with '_'. Columns that are missing from a row are NULL.
+// Comparisons with NULL are false. Comparing values of different types is an
+// error. Column values must be nil, strings, bools, or one of Go's integer or
+// floating-point types.
+package filter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrUnexpectedChar indicates an invalid character in a filter.
+	ErrUnexpectedChar = errors.New("unexpected character")
+
+	// ErrUnclosedString indicates a string that is not terminated.
+	ErrUnclosedString = errors.New("unclosed string")
+
+	// ErrType indicates that a value has the wrong type for an operator.
+	ErrType = errors.New("type mismatch")
+)
+
+const (
+	identType lexparse.LexemeType = iota
+	numberType
+	stringType
+	trueType
+	falseType
+	nullType
+	lparenType
+	rparenType
+	commaType
+	orType
+	andType
+	notType
+	eqType
+	neType
+	ltType
+	leType
+	gtType
+	geType
+	likeType
+	notLikeType
+	inType
+	notInType
+	isType
+	minusType
+)
+
+// keywords maps upper case keywords to their lexeme types.
+var keywords = map[string]lexparse.LexemeType{
+	"TRUE":  trueType,
+	"FALSE": falseType,
+	"NULL":  nullType,
+	"OR":    orType,
+	"AND":   andType,
+	"NOT":   notType,
+	"LIKE":  likeType,
+	"IN":    inType,
+	"IS":    isType,
+}
+
+// symbols maps operators to their lexeme types. Longer operators are listed
+// first.
+var symbols = []struct {
+	symbol string
+	typ    lexparse.LexemeType
+}{
+	{"!=", neType},
+	{"<>", neType},
+	{"<=", leType},
+	{">=", geType},
+	{"=", eqType},
+	{"<", ltType},
+	{">", gtType},
+	{"(", lparenType},
+	{")", rparenType},
+	{",", commaType},
+	{"-", minusType},
+}
+
+var (
+	spaceClass  = lexparse.RuneClassFunc(unicode.IsSpace)
+	identStart  = lexparse.NewRuneClass("_", unicode.Letter)
+	identClass  = lexparse.NewRuneClass("_.", unicode.Letter, unicode.Digit)
+	digitClass  = lexparse.RuneClassFunc(unicode.IsDigit)
+	numberClass = lexparse.NewRuneClass(".", unicode.Digit)
+)
+
+// lexFilter lexes a single lexeme.
+func lexFilter(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	if _, err := l.AdvanceWhile(spaceClass); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	l.Ignore()
+
+	rns, err := l.Peek(2)
+	if len(rns) == 0 {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+
+	for _, s := range symbols {
+		if strings.HasPrefix(string(rns), s.symbol) {
+			if _, err := l.Advance(len(s.symbol)); err != nil {
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return nil, err
+			}
+			l.Emit(l.Lexeme(s.typ))
+			return lexparse.StateFn(lexFilter), nil
+		}
+	}
+
+	switch rn := rns[0]; {
+	case rn == '"' || rn == '\'':
+		return lexparse.StateFn(lexFilter), lexString(l, rn)
+	case digitClass.Contains(rn):
+		if _, err := l.AdvanceWhile(numberClass); err != nil && !errors.Is(err, io.EOF) {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		l.Emit(l.Lexeme(numberType))
+		return lexparse.StateFn(lexFilter), nil
+	case identStart.Contains(rn):
+		return lexparse.StateFn(lexFilter), lexIdent(l)
+	default:
+		return nil, fmt.Errorf("%w: %q: column %d", ErrUnexpectedChar, rn, l.Column()+1)
+	}
+}
+
+// lexIdent lexes a column name or keyword. NOT followed by LIKE or IN is
+// lexed as a single operator.
+func lexIdent(l *lexparse.Lexer) error {
+	if _, err := l.AdvanceWhile(identClass); err != nil && !errors.Is(err, io.EOF) {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	lexeme := l.Lexeme(identType)
+	typ, ok := keywords[strings.ToUpper(lexeme.Value)]
+	if !ok {
+		l.Emit(lexeme)
+		return nil
+	}
+	lexeme.Type = typ
+
+	if typ == notType {
+		word := peekWord(l)
+		switch {
+		case strings.EqualFold(word, "LIKE"):
+			lexeme.Type = notLikeType
+		case strings.EqualFold(word, "IN"):
+			lexeme.Type = notInType
+		default:
+			l.Emit(lexeme)
+			return nil
+		}
+		// Consume the second word of the operator.
+		if _, err := l.AdvanceWhile(spaceClass); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+		if _, err := l.AdvanceWhile(identClass); err != nil && !errors.Is(err, io.EOF) {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+		lexeme.Value = strings.ToUpper(lexeme.Value + " " + word)
+	}
+	l.Emit(lexeme)
+	return nil
+}
+
+// peekWord returns the next word after whitespace without advancing the
+// lexer.
+func peekWord(l *lexparse.Lexer) string {
+	rns, _ := l.Peek(16)
+	i := 0
+	for i < len(rns) && spaceClass.Contains(rns[i]) {
+		i++
+	}
+	j := i
+	for j < len(rns) && identClass.Contains(rns[j]) {
+		j++
+	}
+	return string(rns[i:j])
+}
+
+// lexString lexes a string quoted with the given quote character. A quote
+// character is escaped with a backslash.
+func lexString(l *lexparse.Lexer, quote rune) error {
+	col := l.Column()
+	if _, err := l.Discard(1); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+
+	var b strings.Builder
+	for {
+		rn, _, err := l.ReadRune()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("%w: column %d", ErrUnclosedString, col+1)
+			}
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+		switch rn {
+		case '\\':
+			rn, _, err = l.ReadRune()
+			if err != nil {
+				return fmt.Errorf("%w: column %d", ErrUnclosedString, col+1)
+			}
+		case quote:
+			lexeme := l.Lexeme(stringType)
+			lexeme.Value = b.String()
+			lexeme.Column = col
+			lexeme.Pos--
+			l.Emit(lexeme)
+			return nil
+		}
+		b.WriteRune(rn)
+	}
+}
+
+// node is the value of a node in the filter tree.
+type node struct {
+	lexeme *lexparse.Lexeme
+
+	// value is the value of a literal.
+	value any
+
+	// like is the compiled pattern of a LIKE operator with a literal
+	// pattern.
+	like *regexp.Regexp
+}
+
+// exprParser parses filter expressions.
+var exprParser = newExprParser()
+
+// newExprParser returns a new parser for filter expressions.
+func newExprParser() *lexparse.ExprParser[*node] {
+	e := &lexparse.ExprParser[*node]{
+		Operators: lexparse.NewOperatorTable().
+			Infix(orType, 1, lexparse.AssocLeft).
+			Infix(andType, 2, lexparse.AssocLeft).
+			Prefix(notType, 3).
+			Infix(eqType, 4, lexparse.AssocNone).
+			Infix(neType, 4, lexparse.AssocNone).
+			Infix(ltType, 4, lexparse.AssocNone).
+			Infix(leType, 4, lexparse.AssocNone).
+			Infix(gtType, 4, lexparse.AssocNone).
+			Infix(geType, 4, lexparse.AssocNone).
+			Infix(likeType, 4, lexparse.AssocNone).
+			Infix(notLikeType, 4, lexparse.AssocNone).
+			Postfix(inType, 4).
+			Postfix(notInType, 4).
+			Postfix(isType, 4).
+			Prefix(minusType, 5),
+		Value: func(l *lexparse.Lexeme) (*node, error) {
+			return &node{lexeme: l}, nil
+		},
+		Led: map[lexparse.LexemeType]lexparse.LedFn[*node]{
+			inType:    parseIn,
+			notInType: parseIn,
+			isType:    parseIs,
+		},
+	}
+	e.Operand = func(ctx context.Context, p *lexparse.Parser[*node]) (*lexparse.Node[*node], error) {
+		return parseOperand(ctx, p, e)
+	}
+	return e
+}
+
+// parseOperand parses a literal, column, or parenthesized expression.
+func parseOperand(
+	ctx context.Context,
+	p *lexparse.Parser[*node],
+	e *lexparse.ExprParser[*node],
+) (*lexparse.Node[*node], error) {
+	l := p.Next()
+	if l == nil {
+		return nil, fmt.Errorf("%w: expected operand", io.ErrUnexpectedEOF)
+	}
+
+	switch l.Type {
+	case identType:
+		return p.NewNode(&node{lexeme: l}), nil
+	case numberType, stringType, trueType, falseType, nullType:
+		v, err := literal(l)
+		if err != nil {
+			return nil, err
+		}
+		return p.NewNode(&node{lexeme: l, value: v}), nil
+	case lparenType:
+		n, err := e.Expr(ctx, p, 0)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := expect(p, rparenType); err != nil {
+			return nil, err
+		}
+		return n, nil
+	default:
+		return nil, &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+	}
+}
+
+// literal returns the value of a literal lexeme.
+func literal(l *lexparse.Lexeme) (any, error) {
+	switch l.Type {
+	case numberType:
+		f, err := strconv.ParseFloat(l.Value, 64)
+		if err != nil {
+			return nil, &lexparse.LexemeError{
+				Err:    lexparse.ErrUnexpectedLexeme,
+				Detail: "invalid number",
+				Lexeme: l,
+			}
+		}
+		return f, nil
+	case stringType:
+		return l.Value, nil
+	case trueType:
+		return true, nil
+	case falseType:
+		return false, nil
+	default:
+		return nil, nil
+	}
+}
+
+// parseIn parses the list of values of an IN operator.
+func parseIn(_ context.Context, p *lexparse.Parser[*node], op *lexparse.Node[*node]) (*lexparse.Node[*node], error) {
+	if _, err := expect(p, lparenType); err != nil {
+		return nil, err
+	}
+	for {
+		l := p.Next()
+		if l == nil {
+			return nil, fmt.Errorf("%w: expected value", io.ErrUnexpectedEOF)
+		}
+		v, err := literal(l)
+		if err != nil {
+			return nil, err
+		}
+		if l.Type != numberType && l.Type != stringType && l.Type != trueType && l.Type != falseType {
+			return nil, &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+		}
+		n := p.NewNode(&node{lexeme: l, value: v})
+		op.Children = append(op.Children, n)
+		n.Parent = op
+
+		sep, err := expect(p, commaType, rparenType)
+		if err != nil {
+			return nil, err
+		}
+		if sep.Type == rparenType {
+			return op, nil
+		}
+	}
+}
+
+// parseIs parses the rest of an IS NULL or IS NOT NULL operator. The value of
+// the operator node is true for IS NOT NULL.
+func parseIs(_ context.Context, p *lexparse.Parser[*node], op *lexparse.Node[*node]) (*lexparse.Node[*node], error) {
+	l, err := expect(p, notType, nullType)
+	if err != nil {
+		return nil, err
+	}
+	if l.Type == notType {
+		op.Value.value = true
+		if _, err := expect(p, nullType); err != nil {
+			return nil, err
+		}
+	}
+	return op, nil
+}
+
+// expect consumes the next lexeme and returns an error if it is not one of
+// the given types.
+func expect(p *lexparse.Parser[*node], types ...lexparse.LexemeType) (*lexparse.Lexeme, error) {
+	l := p.Next()
+	if l == nil {
+		return nil, fmt.Errorf("%w: parsing filter", io.ErrUnexpectedEOF)
+	}
+	for _, typ := range types {
+		if l.Type == typ {
+			return l, nil
+		}
+	}
+	return nil, &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+}
+
+// parseFilter parses a filter expression.
+func parseFilter(ctx context.Context, p *lexparse.Parser[*node]) (lexparse.ParseFn[*node], error) {
+	_, err := exprParser.Parse(ctx, p)
+	return nil, err
+}
+
+// Filter is a parsed filter expression.
+type Filter struct {
+	root *lexparse.Node[*node]
+}
+
+// Parse parses a filter expression.
+func Parse(ctx context.Context, s string) (*Filter, error) {
+	l := lexparse.NewLexer(runeio.NewReader(bufio.NewReader(strings.NewReader(s))), lexparse.StateFn(lexFilter))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	p := lexparse.NewParser[*node](l.Lex(ctx))
+	p.SetLexerErr(l.Err)
+	p.SetRequireEOF(true)
+	_, pErr := p.Parse(ctx, parseFilter)
+	cancel(pErr)
+
+	<-l.Done()
+
+	err := pErr
+	if lErr := l.Err(); lErr != nil && !errors.Is(lErr, context.Canceled) {
+		err = lErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing filter: %w", err)
+	}
+
+	root := p.Root().Children[0]
+	compileLike(root)
+	return &Filter{root: root}, nil
+}
+
+// compileLike compiles the patterns of LIKE operators with literal patterns.
+func compileLike(n *lexparse.Node[*node]) {
+	for _, c := range n.Children {
+		compileLike(c)
+	}
+	switch n.Value.lexeme.Type {
+	case likeType, notLikeType:
+		if s, ok := n.Right().Value.value.(string); ok && n.Right().Value.lexeme.Type == stringType {
+			n.Value.like = likePattern(s)
+		}
+	}
+}
+
+// likePattern compiles a LIKE pattern to a regular expression.
+func likePattern(s string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString(`^(?s:`)
+	for _, rn := range s {
+		switch rn {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(rn)))
+		}
+	}
+	b.WriteString(`)$`)
+	return regexp.MustCompile(b.String())
+}
+
+// Match evaluates the filter for the row and returns the result.
+func (f *Filter) Match(row map[string]any) (bool, error) {
+	v, err := eval(f.root, row)
+	if err != nil {
+		return false, err
+	}
+	return truthy(f.root, v)
+}
+
+// typeError returns an ErrType error for the operator or operand at n.
+func typeError(n *lexparse.Node[*node], detail string) error {
+	return &lexparse.LexemeError{
+		Err:    ErrType,
+		Detail: detail,
+		Lexeme: n.Value.lexeme,
+	}
+}
+
+// truthy returns the boolean value of v, the result of evaluating n. NULL is
+// false.
+func truthy(n *lexparse.Node[*node], v any) (bool, error) {
+	switch b := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return b, nil
+	default:
+		return false, typeError(n, "expected boolean")
+	}
+}
+
+// eval evaluates the expression tree rooted at n.
+func eval(n *lexparse.Node[*node], row map[string]any) (any, error) {
+	switch n.Value.lexeme.Type {
+	case identType:
+		return normalize(n, row[n.Value.lexeme.Value])
+	case numberType, stringType, trueType, falseType, nullType:
+		return n.Value.value, nil
+	case orType, andType:
+		return evalLogical(n, row)
+	}
+
+	left, err := eval(n.Left(), row)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Value.lexeme.Type {
+	case notType:
+		b, err := truthy(n.Left(), left)
+		return !b, err
+	case minusType:
+		f, ok := left.(float64)
+		if !ok {
+			return nil, typeError(n, "expected number")
+		}
+		return -f, nil
+	case isType:
+		return (left == nil) != (n.Value.value == true), nil
+	case inType, notInType:
+		found := false
+		for _, c := range n.Children[1:] {
+			if eq, err := equal(n, left, c.Value.value); err != nil {
+				return nil, err
+			} else if eq {
+				found = true
+			}
+		}
+		return left != nil && found == (n.Value.lexeme.Type == inType), nil
+	}
+
+	right, err := eval(n.Right(), row)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		// Comparisons with NULL are false.
+		return false, nil
+	}
+
+	switch n.Value.lexeme.Type {
+	case eqType:
+		return equal(n, left, right)
+	case neType:
+		eq, err := equal(n, left, right)
+		return !eq, err
+	case likeType, notLikeType:
+		return like(n, left, right)
+	default:
+		c, err := compare(n, left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Value.lexeme.Type {
+		case ltType:
+			return c < 0, nil
+		case leType:
+			return c <= 0, nil
+		case gtType:
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+}
+
+// evalLogical evaluates an AND or OR operator.
+func evalLogical(n *lexparse.Node[*node], row map[string]any) (any, error) {
+	left, err := eval(n.Left(), row)
+	if err != nil {
+		return nil, err
+	}
+	l, err := truthy(n.Left(), left)
+	if err != nil {
+		return nil, err
+	}
+	if l == (n.Value.lexeme.Type == orType) {
+		return l, nil
+	}
+	right, err := eval(n.Right(), row)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(n.Right(), right)
+}
+
+// normalize converts the value of a column to a float64, string, bool, or
+// nil.
+func normalize(n *lexparse.Node[*node], v any) (any, error) {
+	switch x := v.(type) {
+	case nil, string, bool, float64:
+		return x, nil
+	case int:
+		return float64(x), nil
+	case int8:
+		return float64(x), nil
+	case int16:
+		return float64(x), nil
+	case int32:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case uint:
+		return float64(x), nil
+	case uint8:
+		return float64(x), nil
+	case uint16:
+		return float64(x), nil
+	case uint32:
+		return float64(x), nil
+	case uint64:
+		return float64(x), nil
+	case uintptr:
+		return float64(x), nil
+	case float32:
+		return float64(x), nil
+	default:
+		return nil, typeError(n, fmt.Sprintf("unsupported column type %T", v))
+	}
+}
+
+// equal returns true if a and b are equal. It returns an error if they have
+// different types.
+func equal(n *lexparse.Node[*node], a, b any) (bool, error) {
+	if a == nil || b == nil {
+		return false, nil
+	}
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x == y, nil
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return x == y, nil
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			return x == y, nil
+		}
+	}
+	return false, typeError(n, fmt.Sprintf("cannot compare %T and %T", a, b))
+}
+
+// compare compares numbers or strings.
+func compare(n *lexparse.Node[*node], a, b any) (int, error) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	}
+	return 0, typeError(n, fmt.Sprintf("cannot order %T and %T", a, b))
+}
+
+// like evaluates a LIKE or NOT LIKE operator.
+func like(n *lexparse.Node[*node], a, b any) (bool, error) {
+	s, ok := a.(string)
+	if !ok {
+		return false, typeError(n, "expected string")
+	}
+	re := n.Value.like
+	if re == nil {
+		pattern, ok := b.(string)
+		if !ok {
+			return false, typeError(n, "expected string pattern")
+		}
+		re = likePattern(pattern)
+	}
+	return re.MatchString(s) == (n.Value.lexeme.Type == likeType), nil
+}
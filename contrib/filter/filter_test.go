@@ -0,0 +1,211 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ianlewis/lexparse"
+)
+
+func TestFilter_Match(t *testing.T) {
+	t.Parallel()
+
+	row := map[string]any{
+		"name":   "alice",
+		"age":    31,
+		"score":  float32(0.75),
+		"count":  uint16(2),
+		"id":     int64(-7),
+		"active": true,
+		"email":  nil,
+		"tags":   "a_b",
+	}
+
+	testCases := map[string]struct {
+		filter string
+		want   bool
+	}{
+		"number":              {filter: "age > 30", want: true},
+		"number equal":        {filter: "age = 31.0", want: true},
+		"less equal":          {filter: "age <= 30", want: false},
+		"negative":            {filter: "-age < -30", want: true},
+		"float32":             {filter: "score >= 0.75", want: true},
+		"uint16":              {filter: "count IN (1, 2)", want: true},
+		"int64":               {filter: "id = -7", want: true},
+		"string":              {filter: `name = "alice"`, want: true},
+		"single quotes":       {filter: `name != 'bob'`, want: true},
+		"not equal":           {filter: `name <> "alice"`, want: false},
+		"string order":        {filter: `name < "bob"`, want: true},
+		"escaped quote":       {filter: `name = "al\"ice"`, want: false},
+		"and":                 {filter: `age > 30 AND name LIKE "a%"`, want: true},
+		"or":                  {filter: `age > 40 or name like "%e"`, want: true},
+		"not":                 {filter: "NOT age > 40", want: true},
+		"parentheses":         {filter: `NOT (age > 30 AND active)`, want: false},
+		"precedence":          {filter: `age > 40 AND active OR name = "alice"`, want: true},
+		"bool column":         {filter: "active", want: true},
+		"bool literal":        {filter: "active = FALSE", want: false},
+		"like single":         {filter: `name LIKE "_lic_"`, want: true},
+		"like literal":        {filter: `tags LIKE "a_b"`, want: true},
+		"like regexp chars":   {filter: `name LIKE "a.*"`, want: false},
+		"like column":         {filter: "name LIKE tags", want: false},
+		"not like":            {filter: `name NOT LIKE "b%"`, want: true},
+		"in":                  {filter: `name IN ("bob", "alice")`, want: true},
+		"in numbers":          {filter: "age IN (1, 2, 3)", want: false},
+		"not in":              {filter: `name NOT IN ("bob")`, want: true},
+		"is null":             {filter: "email IS NULL", want: true},
+		"is not null":         {filter: "name IS NOT NULL", want: true},
+		"missing is null":     {filter: "missing IS NULL", want: true},
+		"null comparison":     {filter: `email = "x" OR email != "x"`, want: false},
+		"missing column":      {filter: "missing", want: false},
+		"not in null":         {filter: `missing NOT IN ("a")`, want: false},
+		"null literal":        {filter: "NULL IS NULL", want: true},
+		"short circuit and":   {filter: `FALSE AND name > 1`, want: false},
+		"short circuit or":    {filter: `TRUE OR name > 1`, want: true},
+		"identifier with dot": {filter: "user.id IS NULL", want: true},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := Parse(context.Background(), tc.filter)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got, err := f.Match(row)
+			if err != nil {
+				t.Fatalf("Match: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Match: want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFilter_Match_errors(t *testing.T) {
+	t.Parallel()
+
+	row := map[string]any{
+		"name":  "alice",
+		"age":   31,
+		"tags":  []string{"a"},
+		"score": 0.5,
+	}
+
+	testCases := map[string]struct {
+		filter string
+		line   int
+		column int
+	}{
+		"compare types": {filter: `age = "31"`, column: 4},
+		"order types":   {filter: "name > 1", column: 5},
+		"not boolean":   {filter: "name AND age > 1", column: 0},
+		"like number":   {filter: `age LIKE "3%"`, column: 4},
+		"negate string": {filter: "-name = 1", column: 0},
+		"column type":   {filter: "tags IS NULL", column: 0},
+		"in types":      {filter: `score IN ("a")`, column: 6},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := Parse(context.Background(), tc.filter)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			_, err = f.Match(row)
+			if !errors.Is(err, ErrType) {
+				t.Fatalf("Match: want: %v, got: %v", ErrType, err)
+			}
+			var lErr *lexparse.LexemeError
+			if !errors.As(err, &lErr) {
+				t.Fatalf("Match: want: *lexparse.LexemeError, got: %v", err)
+			}
+			if got, want := lErr.Lexeme.Column, tc.column; got != want {
+				t.Errorf("Column: want: %v, got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		filter string
+		err    error
+	}{
+		"empty": {
+			filter: "",
+			err:    io.ErrUnexpectedEOF,
+		},
+		"unexpected char": {
+			filter: "age > 30 & name",
+			err:    ErrUnexpectedChar,
+		},
+		"unclosed string": {
+			filter: `name = "alice`,
+			err:    ErrUnclosedString,
+		},
+		"unclosed paren": {
+			filter: "(age > 30",
+			err:    io.ErrUnexpectedEOF,
+		},
+		"chained comparison": {
+			filter: "1 < age < 40",
+			err:    lexparse.ErrUnexpectedLexeme,
+		},
+		"trailing and": {
+			filter: "age > 30 AND",
+			err:    io.ErrUnexpectedEOF,
+		},
+		"in column": {
+			filter: "age IN (x)",
+			err:    lexparse.ErrUnexpectedLexeme,
+		},
+		"in without parens": {
+			filter: "age IN 1",
+			err:    lexparse.ErrUnexpectedLexeme,
+		},
+		"is value": {
+			filter: "age IS 1",
+			err:    lexparse.ErrUnexpectedLexeme,
+		},
+		"invalid number": {
+			filter: "age > 1.2.3",
+			err:    lexparse.ErrUnexpectedLexeme,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(context.Background(), tc.filter)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
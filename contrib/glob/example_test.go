@@ -0,0 +1,39 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glob_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ianlewis/lexparse/contrib/glob"
+)
+
+func Example() {
+	g, err := glob.Compile(context.Background(), "src/**/[!_]*.go")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, name := range []string{"src/main.go", "src/pkg/util/util.go", "src/_tmp.go", "src/main.c"} {
+		fmt.Println(name, g.Match(name))
+	}
+
+	// Output:
+	// src/main.go true
+	// src/pkg/util/util.go true
+	// src/_tmp.go false
+	// src/main.c false
+}
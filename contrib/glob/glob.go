@@ -0,0 +1,468 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package glob implements a compiler for glob patterns using lexparse.
+//
+// Patterns match slash separated paths and have the following syntax:
+//
+//	?       matches any single character except '/'
+//	*       matches any sequence of characters except '/'
+//	**      matches any sequence of characters including '/'
+//	**/     at the start of the pattern or after '/', matches zero or more
+//	        directories
+//	[abc]   matches one of the characters in the class
+//	[a-z]   matches one of the characters in the range
+//	[!a-z]  matches one character that is not in the class, '^' may be used
+//	        in place of '!'
+//	\c      matches the character c
+//
+// Character classes never match '/'. Patterns are parsed into a tree that is
+// compiled to a matcher.
+package glob
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrUnclosedClass indicates that a character class was not closed.
+	ErrUnclosedClass = errors.New("unclosed character class")
+
+	// ErrInvalidRange indicates a range in a character class whose start is
+	// after its end.
+	ErrInvalidRange = errors.New("invalid character range")
+
+	// ErrTrailingEscape indicates a pattern ending with a backslash.
+	ErrTrailingEscape = errors.New("trailing backslash")
+)
+
+// separator is the path separator.
+const separator = '/'
+
+const (
+	literalType lexparse.LexemeType = iota
+	starType
+	doubleStarType
+	dirsType
+	questionType
+	lbrackType
+	rbrackType
+	negateType
+	charType
+	dashType
+)
+
+// Kind is the kind of a node in the pattern tree.
+type Kind int
+
+const (
+	// LiteralKind matches literal text.
+	LiteralKind Kind = iota
+
+	// StarKind matches any sequence of characters except '/'.
+	StarKind
+
+	// DoubleStarKind matches any sequence of characters.
+	DoubleStarKind
+
+	// DirsKind matches zero or more directories, i.e. the empty string or a
+	// sequence of characters ending in '/'.
+	DirsKind
+
+	// AnyKind matches any single character except '/'.
+	AnyKind
+
+	// ClassKind matches a character in a class. Its children are the
+	// characters and ranges in the class.
+	ClassKind
+
+	// RangeKind is a range of characters in a class. Single characters are
+	// ranges with the same start and end.
+	RangeKind
+)
+
+// Elem is the value of a node in the pattern tree.
+type Elem struct {
+	Kind Kind
+
+	// Value is the text of a literal.
+	Value string
+
+	// Negated is true for negated classes.
+	Negated bool
+
+	// Lo and Hi are the start and end of a range.
+	Lo, Hi rune
+}
+
+// lexGlob lexes the pattern outside of character classes.
+func lexGlob(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	rns, err := l.Peek(3)
+	if len(rns) == 0 {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+
+	switch s := string(rns); {
+	case strings.HasPrefix(s, "**/") && atSegmentStart(l):
+		return lexparse.StateFn(lexGlob), emit(l, dirsType, 3)
+	case strings.HasPrefix(s, "**"):
+		return lexparse.StateFn(lexGlob), emit(l, doubleStarType, 2)
+	case rns[0] == '*':
+		return lexparse.StateFn(lexGlob), emit(l, starType, 1)
+	case rns[0] == '?':
+		return lexparse.StateFn(lexGlob), emit(l, questionType, 1)
+	case rns[0] == '[':
+		if err := emit(l, lbrackType, 1); err != nil {
+			return nil, err
+		}
+		return lexClassStart(l)
+	case rns[0] == '\\':
+		return lexparse.StateFn(lexGlob), lexEscape(l, literalType)
+	}
+
+	for {
+		rns, err := l.Peek(1)
+		if err != nil || strings.ContainsRune(`*?[\`, rns[0]) {
+			l.Emit(l.Lexeme(literalType))
+			if err != nil {
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return nil, err
+			}
+			return lexparse.StateFn(lexGlob), nil
+		}
+		if _, err := l.Advance(1); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+	}
+}
+
+// atSegmentStart returns true if the lexer is at the start of the pattern or
+// after a separator.
+func atSegmentStart(l *lexparse.Lexer) bool {
+	last := l.Last()
+	return last == nil || last.Type == dirsType ||
+		(last.Type == literalType && strings.HasSuffix(last.Value, string(separator)))
+}
+
+// lexClassStart lexes the start of a character class after the '['.
+func lexClassStart(l *lexparse.Lexer) (lexparse.State, error) {
+	rns, err := l.Peek(1)
+	if err != nil {
+		return nil, unclosedClass(err)
+	}
+	if rns[0] == '!' || rns[0] == '^' {
+		if err := emit(l, negateType, 1); err != nil {
+			return nil, err
+		}
+		if rns, err = l.Peek(1); err != nil {
+			return nil, unclosedClass(err)
+		}
+	}
+	if rns[0] == ']' {
+		// A ']' at the start of a class is a literal character.
+		if err := emit(l, charType, 1); err != nil {
+			return nil, err
+		}
+	}
+	return lexparse.StateFn(lexClass), nil
+}
+
+// lexClass lexes the characters and ranges in a character class.
+func lexClass(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	rns, err := l.Peek(2)
+	if len(rns) == 0 {
+		return nil, unclosedClass(err)
+	}
+
+	switch {
+	case rns[0] == ']':
+		return lexparse.StateFn(lexGlob), emit(l, rbrackType, 1)
+	case rns[0] == '\\':
+		return lexparse.StateFn(lexClass), lexEscape(l, charType)
+	case rns[0] == '-' && l.Last().Type == charType && len(rns) == 2 && rns[1] != ']':
+		return lexparse.StateFn(lexClass), emit(l, dashType, 1)
+	default:
+		return lexparse.StateFn(lexClass), emit(l, charType, 1)
+	}
+}
+
+// lexEscape lexes an escaped character as a lexeme of the given type.
+func lexEscape(l *lexparse.Lexer, typ lexparse.LexemeType) error {
+	if _, err := l.Discard(1); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	if _, err := l.Advance(1); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: column %d", ErrTrailingEscape, l.Column())
+		}
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	l.Emit(l.Lexeme(typ))
+	return nil
+}
+
+// emit advances the lexer n runes and emits a lexeme of the given type.
+func emit(l *lexparse.Lexer, typ lexparse.LexemeType, n int) error {
+	if _, err := l.Advance(n); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	l.Emit(l.Lexeme(typ))
+	return nil
+}
+
+// unclosedClass returns an ErrUnclosedClass error if err is io.EOF.
+func unclosedClass(err error) error {
+	if errors.Is(err, io.EOF) {
+		return ErrUnclosedClass
+	}
+	return err
+}
+
+// parseGlob parses the elements of a pattern.
+func parseGlob(_ context.Context, p *lexparse.Parser[*Elem]) (lexparse.ParseFn[*Elem], error) {
+	for l := p.Next(); l != nil; l = p.Next() {
+		switch l.Type {
+		case literalType:
+			// Merge adjacent literals, e.g. around escaped characters.
+			if children := p.Pos().Children; len(children) > 0 && children[len(children)-1].Value.Kind == LiteralKind {
+				children[len(children)-1].Value.Value += l.Value
+				continue
+			}
+			_ = p.Node(&Elem{Kind: LiteralKind, Value: l.Value})
+		case starType:
+			_ = p.Node(&Elem{Kind: StarKind})
+		case doubleStarType:
+			_ = p.Node(&Elem{Kind: DoubleStarKind})
+		case dirsType:
+			_ = p.Node(&Elem{Kind: DirsKind})
+		case questionType:
+			_ = p.Node(&Elem{Kind: AnyKind})
+		case lbrackType:
+			if err := parseClass(p); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+		}
+	}
+	return nil, nil
+}
+
+// parseClass parses a character class after the '['.
+func parseClass(p *lexparse.Parser[*Elem]) error {
+	class := &Elem{Kind: ClassKind}
+	_ = p.Push(class)
+	defer p.Climb()
+
+	for {
+		l := p.Next()
+		if l == nil {
+			return ErrUnclosedClass
+		}
+
+		switch l.Type {
+		case negateType:
+			class.Negated = true
+		case rbrackType:
+			return nil
+		case charType:
+			lo := []rune(l.Value)[0]
+			hi := lo
+			if dash := p.Peek(); dash != nil && dash.Type == dashType {
+				_ = p.Next()
+				end := p.Next()
+				if end == nil || end.Type != charType {
+					return ErrUnclosedClass
+				}
+				hi = []rune(end.Value)[0]
+				if hi < lo {
+					return &lexparse.LexemeError{Err: ErrInvalidRange, Lexeme: end}
+				}
+			}
+			_ = p.Node(&Elem{Kind: RangeKind, Lo: lo, Hi: hi})
+		default:
+			return &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+		}
+	}
+}
+
+// matcher is a compiled element of a pattern.
+type matcher struct {
+	kind Kind
+
+	// lit is the text of a literal.
+	lit []rune
+
+	// class is the class of characters matched by a class.
+	class *lexparse.RuneClass
+}
+
+// Glob is a compiled glob pattern.
+type Glob struct {
+	root     *lexparse.Node[*Elem]
+	matchers []matcher
+}
+
+// Compile parses and compiles a glob pattern.
+func Compile(ctx context.Context, pattern string) (*Glob, error) {
+	l := lexparse.NewLexer(runeio.NewReader(bufio.NewReader(strings.NewReader(pattern))), lexparse.StateFn(lexGlob))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	p := lexparse.NewParser[*Elem](l.Lex(ctx))
+	p.SetLexerErr(l.Err)
+	root, pErr := p.Parse(ctx, parseGlob)
+	cancel(pErr)
+
+	<-l.Done()
+
+	err := pErr
+	if lErr := l.Err(); lErr != nil && !errors.Is(lErr, context.Canceled) {
+		err = lErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("compiling glob %q: %w", pattern, err)
+	}
+
+	g := &Glob{root: root}
+	for _, n := range root.Children {
+		g.matchers = append(g.matchers, compile(n))
+	}
+	return g, nil
+}
+
+// compile compiles an element of the pattern tree.
+func compile(n *lexparse.Node[*Elem]) matcher {
+	m := matcher{kind: n.Value.Kind}
+	switch n.Value.Kind {
+	case LiteralKind:
+		m.lit = []rune(n.Value.Value)
+	case ClassKind:
+		var single []rune
+		classes := []*lexparse.RuneClass{}
+		for _, c := range n.Children {
+			lo, hi := c.Value.Lo, c.Value.Hi
+			if lo == hi {
+				single = append(single, lo)
+				continue
+			}
+			classes = append(classes, lexparse.RuneClassFunc(func(rn rune) bool {
+				return rn >= lo && rn <= hi
+			}))
+		}
+		class := lexparse.NewRuneClass(string(single)).Union(classes...)
+		negated := n.Value.Negated
+		m.class = lexparse.RuneClassFunc(func(rn rune) bool {
+			return rn != separator && class.Contains(rn) != negated
+		})
+	}
+	return m
+}
+
+// Tree returns the root of the pattern tree. The children of the root are the
+// elements of the pattern in order.
+func (g *Glob) Tree() *lexparse.Node[*Elem] {
+	return g.root
+}
+
+// Match returns true if name matches the pattern.
+func (g *Glob) Match(name string) bool {
+	m := &match{
+		matchers: g.matchers,
+		name:     []rune(name),
+		failed:   map[[2]int]bool{},
+	}
+	return m.match(0, 0)
+}
+
+// match holds the state of matching a name.
+type match struct {
+	matchers []matcher
+	name     []rune
+
+	// failed records the matcher and name positions that are known not to
+	// match so that patterns with many stars match in polynomial time.
+	failed map[[2]int]bool
+}
+
+// match returns true if the matchers starting at i match the name starting at
+// j.
+func (m *match) match(i, j int) bool {
+	if i == len(m.matchers) {
+		return j == len(m.name)
+	}
+	if m.failed[[2]int{i, j}] {
+		return false
+	}
+
+	ok := m.matchAt(i, j)
+	if !ok {
+		m.failed[[2]int{i, j}] = true
+	}
+	return ok
+}
+
+// matchAt matches the matcher i at position j of the name and the rest of the
+// matchers after it.
+func (m *match) matchAt(i, j int) bool {
+	rest := m.name[j:]
+	switch mt := m.matchers[i]; mt.kind {
+	case LiteralKind:
+		if len(rest) < len(mt.lit) || string(rest[:len(mt.lit)]) != string(mt.lit) {
+			return false
+		}
+		return m.match(i+1, j+len(mt.lit))
+	case AnyKind, ClassKind:
+		if len(rest) == 0 || rest[0] == separator || (mt.class != nil && !mt.class.Contains(rest[0])) {
+			return false
+		}
+		return m.match(i+1, j+1)
+	case StarKind, DoubleStarKind:
+		for k := 0; k <= len(rest); k++ {
+			if m.match(i+1, j+k) {
+				return true
+			}
+			if k < len(rest) && rest[k] == separator && mt.kind == StarKind {
+				return false
+			}
+		}
+		return false
+	case DirsKind:
+		if m.match(i+1, j) {
+			return true
+		}
+		for k := range rest {
+			if rest[k] == separator && m.match(i+1, j+k+1) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
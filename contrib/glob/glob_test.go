@@ -0,0 +1,181 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glob
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/lexparse"
+)
+
+func TestCompile_tree(t *testing.T) {
+	t.Parallel()
+
+	g, err := Compile(context.Background(), `**/a\*b?[!x-z_]*`)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	var got []Elem
+	lexparse.PreOrder(g.Tree())(func(n *lexparse.Node[*Elem]) bool {
+		if n.Value != nil {
+			got = append(got, *n.Value)
+		}
+		return true
+	})
+	want := []Elem{
+		{Kind: DirsKind},
+		{Kind: LiteralKind, Value: "a*b"},
+		{Kind: AnyKind},
+		{Kind: ClassKind, Negated: true},
+		{Kind: RangeKind, Lo: 'x', Hi: 'z'},
+		{Kind: RangeKind, Lo: '_', Hi: '_'},
+		{Kind: StarKind},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Tree: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestGlob_Match(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		"literal": {
+			pattern: "a/b.go",
+			match:   []string{"a/b.go"},
+			noMatch: []string{"a/b.g", "a/b.goo", "A/b.go"},
+		},
+		"star": {
+			pattern: "*.go",
+			match:   []string{"a.go", ".go", "main_test.go"},
+			noMatch: []string{"a/b.go", "a.gox"},
+		},
+		"double star": {
+			pattern: "a**z",
+			match:   []string{"az", "a/b/z", "abz"},
+			noMatch: []string{"a/b/y"},
+		},
+		"dirs": {
+			pattern: "src/**/*.go",
+			match:   []string{"src/a.go", "src/x/a.go", "src/x/y/a.go"},
+			noMatch: []string{"src.go", "srcx/a.go", "src/x/a.c"},
+		},
+		"leading dirs": {
+			pattern: "**/*_test.go",
+			match:   []string{"a_test.go", "x/y/a_test.go"},
+			noMatch: []string{"a_test.gox"},
+		},
+		"question": {
+			pattern: "?.txt",
+			match:   []string{"a.txt", "世.txt"},
+			noMatch: []string{"ab.txt", "/.txt", ".txt"},
+		},
+		"class": {
+			pattern: "[a-c0_]x",
+			match:   []string{"ax", "bx", "cx", "0x", "_x"},
+			noMatch: []string{"dx", "1x", "/x"},
+		},
+		"negated class": {
+			pattern: "[^a-c]x",
+			match:   []string{"dx", "世x"},
+			noMatch: []string{"ax", "/x"},
+		},
+		"class special characters": {
+			pattern: `[]\]-]`,
+			match:   []string{"]", "-"},
+			noMatch: []string{"\\", "a"},
+		},
+		"escape": {
+			pattern: `\*\?`,
+			match:   []string{"*?"},
+			noMatch: []string{"a?", `\*\?`},
+		},
+		"many stars": {
+			pattern: strings.Repeat("a*", 20) + "b",
+			match:   []string{strings.Repeat("a", 30) + "b"},
+			noMatch: []string{strings.Repeat("a", 30)},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			g, err := Compile(context.Background(), tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			for _, s := range tc.match {
+				if !g.Match(s) {
+					t.Errorf("Match(%q): want: true, got: false", s)
+				}
+			}
+			for _, s := range tc.noMatch {
+				if g.Match(s) {
+					t.Errorf("Match(%q): want: false, got: true", s)
+				}
+			}
+		})
+	}
+}
+
+func TestCompile_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		pattern string
+		err     error
+	}{
+		"unclosed class": {
+			pattern: "[ab",
+			err:     ErrUnclosedClass,
+		},
+		"unclosed empty class": {
+			pattern: "[]",
+			err:     ErrUnclosedClass,
+		},
+		"invalid range": {
+			pattern: "[z-a]",
+			err:     ErrInvalidRange,
+		},
+		"trailing escape": {
+			pattern: `a\`,
+			err:     ErrTrailingEscape,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Compile(context.Background(), tc.pattern)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Compile: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ianlewis/lexparse/contrib/json"
+)
+
+func Example() {
+	input := `{
+	// Comments and trailing commas are allowed.
+	name: 'lexparse',
+	ratio: +Infinity,
+}`
+
+	if _, err := json.Parse(context.Background(), input); err != nil {
+		fmt.Println(err)
+	}
+
+	doc, err := json.Parse(context.Background(), input, json.JSON5())
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(doc.Decode())
+
+	// Output:
+	// parsing json: unexpected character: '/': line 2, column 2
+	// map[name:lexparse ratio:+Inf]
+}
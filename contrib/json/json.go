@@ -0,0 +1,662 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package json implements a JSON parser with support for tolerant dialects
+// such as JSONC and JSON5 using lexparse.
+//
+// By default documents must be strict JSON as defined by RFC 8259. Options
+// relax the grammar by changing the tables used by the lexer and parser:
+//
+//	doc, err := json.Parse(ctx, `{
+//		// Comments and trailing commas are allowed.
+//		name: 'lexparse',
+//		ratio: +Infinity,
+//	}`, json.JSON5())
+//
+// Documents are parsed into a tree of elements which keeps the positions of
+// values in the input. Decode converts the tree to Go values.
+package json
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrUnexpectedChar indicates a character that is not valid in the
+	// dialect.
+	ErrUnexpectedChar = errors.New("unexpected character")
+
+	// ErrUnclosed indicates a string or comment that is not terminated.
+	ErrUnclosed = errors.New("unclosed")
+
+	// ErrInvalidEscape indicates an invalid escape sequence in a string.
+	ErrInvalidEscape = errors.New("invalid escape sequence")
+
+	// ErrInvalidNumber indicates a number that is not valid in the dialect.
+	ErrInvalidNumber = errors.New("invalid number")
+)
+
+const (
+	punctType lexparse.LexemeType = iota
+	stringType
+	numberType
+	keywordType
+	identType
+)
+
+// Kind is the kind of an element.
+type Kind int
+
+const (
+	// NullKind is null.
+	NullKind Kind = iota
+
+	// BoolKind is true or false.
+	BoolKind
+
+	// NumberKind is a number.
+	NumberKind
+
+	// StringKind is a string.
+	StringKind
+
+	// ArrayKind is an array. Its children are its elements.
+	ArrayKind
+
+	// ObjectKind is an object. Its children are its members.
+	ObjectKind
+
+	// MemberKind is an object member. Its value is the member's key and its
+	// only child is the member's value.
+	MemberKind
+)
+
+// Elem is the value of a node in the document tree.
+type Elem struct {
+	Kind Kind
+
+	// Value is the value of a scalar element, a float64, string, or bool, or
+	// the key of a member. It is nil for null, arrays, and objects.
+	Value any
+}
+
+// dialect holds the tables and switches that define a JSON dialect.
+type dialect struct {
+	comments       bool
+	trailingCommas bool
+	unquotedKeys   bool
+	singleQuotes   bool
+
+	// keywords maps keywords to their values.
+	keywords map[string]any
+
+	// number matches the valid numbers of the dialect.
+	number *regexp.Regexp
+}
+
+var (
+	strictNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+	json5Number  = regexp.MustCompile(`^[+-]?((0|[1-9][0-9]*)(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+)
+
+// Option is an option that configures the dialect accepted by Parse.
+type Option func(*dialect)
+
+// AllowComments allows // line comments and /* block comments */.
+func AllowComments() Option {
+	return func(d *dialect) {
+		d.comments = true
+	}
+}
+
+// AllowTrailingCommas allows a comma after the last element of an array or
+// member of an object.
+func AllowTrailingCommas() Option {
+	return func(d *dialect) {
+		d.trailingCommas = true
+	}
+}
+
+// AllowUnquotedKeys allows object keys that are identifiers or keywords.
+func AllowUnquotedKeys() Option {
+	return func(d *dialect) {
+		d.unquotedKeys = true
+	}
+}
+
+// AllowSingleQuotes allows strings in single quotes.
+func AllowSingleQuotes() Option {
+	return func(d *dialect) {
+		d.singleQuotes = true
+	}
+}
+
+// WithKeyword adds a keyword with the given value. The value must be nil or a
+// float64, string, or bool. Keywords with float64 values may be signed.
+func WithKeyword(name string, v any) Option {
+	return func(d *dialect) {
+		d.keywords[name] = v
+	}
+}
+
+// JSONC selects the JSON with comments dialect which allows comments and
+// trailing commas.
+func JSONC() Option {
+	return func(d *dialect) {
+		AllowComments()(d)
+		AllowTrailingCommas()(d)
+	}
+}
+
+// JSON5 selects a dialect similar to JSON5. In addition to JSONC it allows
+// unquoted keys, single quoted strings, the keywords Infinity and NaN, and
+// numbers with a leading '+' or leading or trailing decimal point.
+func JSON5() Option {
+	return func(d *dialect) {
+		JSONC()(d)
+		AllowUnquotedKeys()(d)
+		AllowSingleQuotes()(d)
+		WithKeyword("Infinity", math.Inf(1))(d)
+		WithKeyword("NaN", math.NaN())(d)
+		d.number = json5Number
+	}
+}
+
+// newDialect returns the strict JSON dialect with the options applied.
+func newDialect(opts ...Option) *dialect {
+	d := &dialect{
+		keywords: map[string]any{
+			"true":  true,
+			"false": false,
+			"null":  nil,
+		},
+		number: strictNumber,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+const punct = "{}[]:,"
+
+var (
+	spaceClass  = lexparse.NewRuneClass(" \t\r\n")
+	identStart  = lexparse.NewRuneClass("_$", unicode.Letter)
+	identClass  = lexparse.NewRuneClass("_$", unicode.Letter, unicode.Digit)
+	signClass   = lexparse.NewRuneClass("+-")
+	numberClass = lexparse.NewRuneClass("0123456789.eE+-")
+)
+
+// posError returns an error at the current position of the lexer.
+func posError(l *lexparse.Lexer, err error) error {
+	return fmt.Errorf("%w: line %d, column %d", err, l.Line()+1, l.Column()+1)
+}
+
+// lexToken lexes a single token and any whitespace and comments before it.
+func (d *dialect) lexToken(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	if _, err := l.AdvanceWhile(spaceClass); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	l.Ignore()
+
+	rns, err := l.Peek(2)
+	if len(rns) == 0 {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+
+	state := lexparse.StateFn(d.lexToken)
+	switch rn := rns[0]; {
+	case strings.ContainsRune(punct, rn):
+		if _, err := l.Advance(1); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		l.Emit(l.Lexeme(punctType))
+		return state, nil
+	case rn == '/' && d.comments && len(rns) == 2 && (rns[1] == '/' || rns[1] == '*'):
+		return state, lexComment(l, rns[1] == '*')
+	case rn == '"' || (rn == '\'' && d.singleQuotes):
+		return state, d.lexString(l, rn)
+	case rn == '-' || rn == '+' || rn == '.' || (rn >= '0' && rn <= '9'):
+		return state, lexNumber(l)
+	case identStart.Contains(rn):
+		if _, err := l.AdvanceWhile(identClass); err != nil && !errors.Is(err, io.EOF) {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		lexeme := l.Lexeme(identType)
+		if _, ok := d.keywords[lexeme.Value]; ok {
+			lexeme.Type = keywordType
+		}
+		l.Emit(lexeme)
+		return state, nil
+	default:
+		return nil, posError(l, fmt.Errorf("%w: %q", ErrUnexpectedChar, rn))
+	}
+}
+
+// lexComment skips a line or block comment.
+func lexComment(l *lexparse.Lexer, block bool) error {
+	line, col := l.Line(), l.Column()
+	if _, err := l.Discard(2); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	if !block {
+		if _, err := l.SkipTo([]string{"\n"}); err != nil && !errors.Is(err, io.EOF) {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+		return nil
+	}
+
+	if _, err := l.SkipTo([]string{"*/"}); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w comment: line %d, column %d", ErrUnclosed, line+1, col+1)
+		}
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	if _, err := l.Discard(2); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	return nil
+}
+
+// lexNumber lexes a number or a signed keyword such as -Infinity. Numbers
+// are validated by the parser.
+func lexNumber(l *lexparse.Lexer) error {
+	if _, err := l.Accept(signClass); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	c := numberClass
+	if rns, _ := l.Peek(1); len(rns) > 0 && identStart.Contains(rns[0]) {
+		c = identClass
+	}
+	if _, err := l.AdvanceWhile(c); err != nil && !errors.Is(err, io.EOF) {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	l.Emit(l.Lexeme(numberType))
+	return nil
+}
+
+// escapes maps escape characters to the characters they represent.
+var escapes = map[rune]rune{
+	'"':  '"',
+	'\\': '\\',
+	'/':  '/',
+	'b':  '\b',
+	'f':  '\f',
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+}
+
+// lexString lexes a string and emits its decoded value at the position of
+// its opening quote.
+func (d *dialect) lexString(l *lexparse.Lexer, quote rune) error {
+	line, col := l.Line(), l.Column()
+	if _, err := l.Advance(1); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+
+	var b strings.Builder
+	for {
+		rn, _, err := l.ReadRune()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("%w string: line %d, column %d", ErrUnclosed, line+1, col+1)
+			}
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+
+		switch {
+		case rn == quote:
+			lexeme := l.Lexeme(stringType)
+			lexeme.Value = b.String()
+			l.Emit(lexeme)
+			return nil
+		case rn < 0x20:
+			return fmt.Errorf("%w: %q in string: line %d, column %d", ErrUnexpectedChar, rn, l.Line()+1, l.Column())
+		case rn == '\\':
+			if err := d.lexEscape(l, &b); err != nil {
+				return err
+			}
+		default:
+			b.WriteRune(rn)
+		}
+	}
+}
+
+// lexEscape lexes an escape sequence after a backslash.
+func (d *dialect) lexEscape(l *lexparse.Lexer, b *strings.Builder) error {
+	line, col := l.Line(), l.Column()-1
+	invalid := fmt.Errorf("%w: line %d, column %d", ErrInvalidEscape, line+1, col+1)
+
+	rn, _, err := l.ReadRune()
+	if err != nil {
+		return invalid
+	}
+	if r, ok := escapes[rn]; ok {
+		b.WriteRune(r)
+		return nil
+	}
+	if rn == '\'' && d.singleQuotes {
+		b.WriteRune(rn)
+		return nil
+	}
+	if rn != 'u' {
+		return invalid
+	}
+
+	r1, ok := readHex(l)
+	if !ok {
+		return invalid
+	}
+	if utf16.IsSurrogate(r1) {
+		// Decode a surrogate pair written as two escapes.
+		if rns, _ := l.Peek(2); len(rns) == 2 && rns[0] == '\\' && rns[1] == 'u' {
+			if _, err := l.Advance(2); err != nil {
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return err
+			}
+			r2, ok := readHex(l)
+			if !ok {
+				return invalid
+			}
+			b.WriteRune(utf16.DecodeRune(r1, r2))
+			return nil
+		}
+	}
+	b.WriteRune(r1)
+	return nil
+}
+
+// readHex reads four hexadecimal digits.
+func readHex(l *lexparse.Lexer) (rune, bool) {
+	rns, _ := l.Peek(4)
+	if len(rns) != 4 {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(string(rns), 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	if _, err := l.Advance(4); err != nil {
+		return 0, false
+	}
+	return rune(v), true
+}
+
+// parser parses documents in a dialect.
+type parser struct {
+	d *dialect
+}
+
+// parseDocument parses a single value.
+func (ps *parser) parseDocument(_ context.Context, p *lexparse.Parser[*Elem]) (lexparse.ParseFn[*Elem], error) {
+	return nil, ps.parseValue(p)
+}
+
+// parseValue parses a value and adds it to the current node.
+func (ps *parser) parseValue(p *lexparse.Parser[*Elem]) error {
+	l := p.Next()
+	if l == nil {
+		return fmt.Errorf("%w: expected value", io.ErrUnexpectedEOF)
+	}
+
+	switch l.Type {
+	case stringType:
+		_ = p.Node(&Elem{Kind: StringKind, Value: l.Value})
+	case numberType:
+		f, err := ps.parseNumber(l)
+		if err != nil {
+			return err
+		}
+		_ = p.Node(&Elem{Kind: NumberKind, Value: f})
+	case keywordType:
+		_ = p.Node(newElem(ps.d.keywords[l.Value]))
+	case punctType:
+		switch l.Value {
+		case "[":
+			return ps.parseArray(p)
+		case "{":
+			return ps.parseObject(p)
+		}
+		return &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+	default:
+		return &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+	}
+	return nil
+}
+
+// newElem returns the element for a keyword value.
+func newElem(v any) *Elem {
+	switch v.(type) {
+	case nil:
+		return &Elem{Kind: NullKind}
+	case bool:
+		return &Elem{Kind: BoolKind, Value: v}
+	case float64:
+		return &Elem{Kind: NumberKind, Value: v}
+	default:
+		return &Elem{Kind: StringKind, Value: v}
+	}
+}
+
+// parseNumber parses a number or signed numeric keyword.
+func (ps *parser) parseNumber(l *lexparse.Lexeme) (float64, error) {
+	invalid := &lexparse.LexemeError{Err: ErrInvalidNumber, Lexeme: l}
+
+	sign, word := 1.0, l.Value
+	if strings.HasPrefix(word, "-") || strings.HasPrefix(word, "+") {
+		if word[0] == '-' {
+			sign = -1
+		}
+		word = word[1:]
+	}
+	if v, ok := ps.d.keywords[word]; ok {
+		if f, ok := v.(float64); ok {
+			return sign * f, nil
+		}
+		return 0, invalid
+	}
+
+	if !ps.d.number.MatchString(l.Value) {
+		return 0, invalid
+	}
+	f, err := strconv.ParseFloat(l.Value, 64)
+	if err != nil {
+		return 0, invalid
+	}
+	return f, nil
+}
+
+// parseArray parses the elements of an array after the opening bracket.
+func (ps *parser) parseArray(p *lexparse.Parser[*Elem]) error {
+	_ = p.Push(&Elem{Kind: ArrayKind})
+	if err := ps.parseList(p, "]", ps.parseValue); err != nil {
+		return err
+	}
+	_ = p.Climb()
+	return nil
+}
+
+// parseObject parses the members of an object after the opening brace.
+func (ps *parser) parseObject(p *lexparse.Parser[*Elem]) error {
+	_ = p.Push(&Elem{Kind: ObjectKind})
+	if err := ps.parseList(p, "}", ps.parseMember); err != nil {
+		return err
+	}
+	_ = p.Climb()
+	return nil
+}
+
+// parseMember parses an object member.
+func (ps *parser) parseMember(p *lexparse.Parser[*Elem]) error {
+	key := p.Next()
+	if key == nil {
+		return fmt.Errorf("%w: expected key", io.ErrUnexpectedEOF)
+	}
+	switch {
+	case key.Type == stringType:
+	case ps.d.unquotedKeys && (key.Type == identType || key.Type == keywordType):
+	default:
+		return &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Detail: "expected key", Lexeme: key}
+	}
+
+	_ = p.Push(&Elem{Kind: MemberKind, Value: key.Value})
+	if err := expect(p, ":"); err != nil {
+		return err
+	}
+	if err := ps.parseValue(p); err != nil {
+		return err
+	}
+	_ = p.Climb()
+	return nil
+}
+
+// parseList parses comma separated items up to the closing punctuation.
+func (ps *parser) parseList(p *lexparse.Parser[*Elem], closing string, item func(*lexparse.Parser[*Elem]) error) error {
+	if accept(p, closing) {
+		return nil
+	}
+	for {
+		if err := item(p); err != nil {
+			return err
+		}
+		if accept(p, closing) {
+			return nil
+		}
+		if err := expect(p, ","); err != nil {
+			return err
+		}
+		if ps.d.trailingCommas && accept(p, closing) {
+			return nil
+		}
+	}
+}
+
+// expect consumes the next lexeme and returns an error if it is not the
+// given punctuation.
+func expect(p *lexparse.Parser[*Elem], value string) error {
+	l := p.Next()
+	if l == nil {
+		return fmt.Errorf("%w: expected %q", io.ErrUnexpectedEOF, value)
+	}
+	if l.Type != punctType || l.Value != value {
+		return &lexparse.LexemeError{
+			Err:    lexparse.ErrUnexpectedLexeme,
+			Detail: fmt.Sprintf("expected %q", value),
+			Lexeme: l,
+		}
+	}
+	return nil
+}
+
+// accept consumes the next lexeme if it is the given punctuation.
+func accept(p *lexparse.Parser[*Elem], value string) bool {
+	if l := p.Peek(); l != nil && l.Type == punctType && l.Value == value {
+		_ = p.Next()
+		return true
+	}
+	return false
+}
+
+// Document is a parsed JSON document.
+type Document struct {
+	root *lexparse.Node[*Elem]
+}
+
+// Parse parses a JSON document. By default the document must be strict JSON.
+// Options select a more tolerant dialect.
+func Parse(ctx context.Context, s string, opts ...Option) (*Document, error) {
+	d := newDialect(opts...)
+	l := lexparse.NewLexer(runeio.NewReader(bufio.NewReader(strings.NewReader(s))), lexparse.StateFn(d.lexToken))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	p := lexparse.NewParser[*Elem](l.Lex(ctx))
+	p.SetLexerErr(l.Err)
+	p.SetRequireEOF(true)
+	ps := &parser{d: d}
+	root, pErr := p.Parse(ctx, ps.parseDocument)
+	cancel(pErr)
+
+	<-l.Done()
+
+	err := pErr
+	if lErr := l.Err(); lErr != nil && !errors.Is(lErr, context.Canceled) {
+		err = lErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing json: %w", err)
+	}
+
+	return &Document{root: root.Children[0]}, nil
+}
+
+// Tree returns the root element of the document.
+func (doc *Document) Tree() *lexparse.Node[*Elem] {
+	return doc.root
+}
+
+// Decode returns the document as Go values. Objects are decoded as
+// map[string]any, arrays as []any, and scalars as float64, string, bool, or
+// nil. If an object has duplicate keys the last value is used.
+func (doc *Document) Decode() any {
+	return decode(doc.root)
+}
+
+// decode returns the Go value of the element at n.
+func decode(n *lexparse.Node[*Elem]) any {
+	switch n.Value.Kind {
+	case ArrayKind:
+		a := make([]any, 0, len(n.Children))
+		for _, c := range n.Children {
+			a = append(a, decode(c))
+		}
+		return a
+	case ObjectKind:
+		m := make(map[string]any, len(n.Children))
+		for _, c := range n.Children {
+			m[c.Value.Value.(string)] = decode(c.Children[0])
+		}
+		return m
+	default:
+		return n.Value.Value
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/lexparse"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		opts  []Option
+		want  any
+	}{
+		"null": {
+			input: "null",
+			want:  nil,
+		},
+		"scalars": {
+			input: `[true, false, 0, -1.5e3, "a\"\\\/\b\f\n\r\té😀"]`,
+			want:  []any{true, false, 0.0, -1500.0, "a\"\\/\b\f\n\r\té\U0001f600"},
+		},
+		"nested": {
+			input: ` {"a": [1, {"b": null}], "c": {}, "d": []} `,
+			want: map[string]any{
+				"a": []any{1.0, map[string]any{"b": nil}},
+				"c": map[string]any{},
+				"d": []any{},
+			},
+		},
+		"duplicate keys": {
+			input: `{"a": 1, "a": 2}`,
+			want:  map[string]any{"a": 2.0},
+		},
+		"jsonc": {
+			input: "// comment\n{\n  \"a\": 1, /* block\n comment */\n  \"b\": [2,],\n}",
+			opts:  []Option{JSONC()},
+			want:  map[string]any{"a": 1.0, "b": []any{2.0}},
+		},
+		"json5": {
+			input: `{unquoted: 'single \'quoted\'', null: +1, n: .5, m: 5., inf: -Infinity, "x": [NaN]}`,
+			opts:  []Option{JSON5()},
+			want: map[string]any{
+				"unquoted": "single 'quoted'",
+				"null":     1.0,
+				"n":        0.5,
+				"m":        5.0,
+				"inf":      math.Inf(-1),
+				"x":        []any{math.NaN()},
+			},
+		},
+		"custom keyword": {
+			input: `[undefined, "undefined"]`,
+			opts:  []Option{WithKeyword("undefined", nil)},
+			want:  []any{nil, "undefined"},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			doc, err := Parse(context.Background(), tc.input, tc.opts...)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, doc.Decode(), cmpopts.EquateNaNs()); diff != "" {
+				t.Errorf("Decode: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		opts  []Option
+		err   error
+	}{
+		"empty": {
+			input: "",
+			err:   io.ErrUnexpectedEOF,
+		},
+		"comment": {
+			input: "// comment\n1",
+			err:   ErrUnexpectedChar,
+		},
+		"trailing comma": {
+			input: "[1,]",
+			err:   lexparse.ErrUnexpectedLexeme,
+		},
+		"double trailing comma": {
+			input: "[1,,]",
+			opts:  []Option{JSONC()},
+			err:   lexparse.ErrUnexpectedLexeme,
+		},
+		"unquoted key": {
+			input: "{a: 1}",
+			opts:  []Option{JSONC()},
+			err:   lexparse.ErrUnexpectedLexeme,
+		},
+		"single quotes": {
+			input: "'a'",
+			err:   ErrUnexpectedChar,
+		},
+		"infinity": {
+			input: "Infinity",
+			err:   lexparse.ErrUnexpectedLexeme,
+		},
+		"leading zero": {
+			input: "01",
+			err:   ErrInvalidNumber,
+		},
+		"leading plus": {
+			input: "+1",
+			err:   ErrInvalidNumber,
+		},
+		"signed keyword": {
+			input: "-true",
+			opts:  []Option{JSON5()},
+			err:   ErrInvalidNumber,
+		},
+		"invalid escape": {
+			input: `"\x41"`,
+			err:   ErrInvalidEscape,
+		},
+		"control character": {
+			input: "\"a\nb\"",
+			err:   ErrUnexpectedChar,
+		},
+		"unclosed string": {
+			input: `["a`,
+			err:   ErrUnclosed,
+		},
+		"unclosed comment": {
+			input: "1 /* comment",
+			opts:  []Option{JSONC()},
+			err:   ErrUnclosed,
+		},
+		"unclosed array": {
+			input: "[1, 2",
+			err:   io.ErrUnexpectedEOF,
+		},
+		"missing colon": {
+			input: `{"a" 1}`,
+			err:   lexparse.ErrUnexpectedLexeme,
+		},
+		"trailing value": {
+			input: "1 2",
+			err:   lexparse.ErrTrailingInput,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(context.Background(), tc.input, tc.opts...)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestDocument_Tree(t *testing.T) {
+	t.Parallel()
+
+	doc, err := Parse(context.Background(), "{\n  \"a\": [true]\n}")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	root := doc.Tree()
+	member := root.Children[0]
+	arr := member.Children[0]
+	got := []*Elem{root.Value, member.Value, arr.Value, arr.Children[0].Value}
+	want := []*Elem{
+		{Kind: ObjectKind},
+		{Kind: MemberKind, Value: "a"},
+		{Kind: ArrayKind},
+		{Kind: BoolKind, Value: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Tree: (-want, +got): \n%s", diff)
+	}
+
+	if got, want := [2]int{arr.Line, arr.Column}, [2]int{1, 7}; got != want {
+		t.Errorf("array position: want: %v, got: %v", want, got)
+	}
+}
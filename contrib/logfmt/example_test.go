@@ -0,0 +1,50 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logfmt_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ianlewis/lexparse/contrib/logfmt"
+)
+
+func Example() {
+	logs := `level=info msg="request handled" path=/users status=200
+level=warn msg="slow request" duration=1.5s cached
+`
+
+	records, err := logfmt.Parse(context.Background(), strings.NewReader(logs))
+	if err != nil {
+		panic(err)
+	}
+
+	for _, r := range records {
+		for _, p := range r {
+			fmt.Printf("%d:%d %s=%q\n", p.Line+1, p.Column+1, p.Key, p.Value)
+		}
+	}
+
+	// Output:
+	// 1:1 level="info"
+	// 1:12 msg="request handled"
+	// 1:34 path="/users"
+	// 1:46 status="200"
+	// 2:1 level="warn"
+	// 2:12 msg="slow request"
+	// 2:31 duration="1.5s"
+	// 2:45 cached=""
+}
@@ -0,0 +1,288 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logfmt implements a lexer and parser for logfmt formatted logs
+// using lexparse.
+//
+// Each line of the input is a record of space separated key/value pairs:
+//
+//	level=info msg="request handled" path=/users status=200 cached
+//
+// A key without '=' has an empty value. Values may be double quoted, in which
+// case they may contain spaces, '=', and escape sequences such as \" and \n.
+// Keys and unquoted values may not contain spaces, '=', or '"'.
+package logfmt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrUnexpectedChar indicates an invalid character in a key or value.
+	ErrUnexpectedChar = errors.New("unexpected character")
+
+	// ErrUnclosedQuote indicates a quoted value that is not terminated
+	// before the end of the line.
+	ErrUnclosedQuote = errors.New("unclosed quote")
+
+	// ErrInvalidEscape indicates an invalid escape sequence in a quoted
+	// value.
+	ErrInvalidEscape = errors.New("invalid escape sequence")
+)
+
+// Lexeme types emitted by the lexer. The positions of lexemes are the
+// positions of their text in the input, including quotes.
+const (
+	// KeyType is a key.
+	KeyType lexparse.LexemeType = iota
+
+	// ValueType is the value of the preceding key. Quoted values are
+	// unquoted. A key without '=' is not followed by a value.
+	ValueType
+
+	// NewlineType is the end of a line.
+	NewlineType
+)
+
+var (
+	// blankClass matches the whitespace separating pairs.
+	blankClass = lexparse.NewRuneClass(" \t\r")
+
+	// wordClass matches the runes of keys and unquoted values.
+	wordClass = lexparse.RuneClassFunc(func(rn rune) bool {
+		return rn > ' ' && rn != '=' && rn != '"'
+	})
+)
+
+// NewLexer returns a new lexer for the logfmt input read from r.
+func NewLexer(r io.Reader, opts ...lexparse.LexerOption) *lexparse.Lexer {
+	return lexparse.NewLexer(runeio.NewReader(bufio.NewReader(r)), lexparse.StateFn(lexSpace), opts...)
+}
+
+// unexpected returns an ErrUnexpectedChar error for rn at the current
+// position.
+func unexpected(l *lexparse.Lexer, rn rune) error {
+	return fmt.Errorf("%w: %q: line %d, column %d", ErrUnexpectedChar, rn, l.Line()+1, l.Column()+1)
+}
+
+// lexSpace lexes whitespace and newlines between pairs.
+func lexSpace(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	if _, err := l.AdvanceWhile(blankClass); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	l.Ignore()
+
+	rns, err := l.Peek(1)
+	if err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	if rns[0] == '\n' {
+		if _, err := l.Advance(1); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		l.Emit(l.Lexeme(NewlineType))
+		return lexparse.StateFn(lexSpace), nil
+	}
+	return lexparse.StateFn(lexKey), nil
+}
+
+// lexKey lexes a key and the '=' following it.
+func lexKey(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	if _, err := l.AdvanceWhile(wordClass); err != nil && !errors.Is(err, io.EOF) {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	key := l.Lexeme(KeyType)
+	rns, err := l.Peek(1)
+	if key.Value == "" {
+		return nil, unexpected(l, rns[0])
+	}
+	l.Emit(key)
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return nil, nil
+	case err != nil:
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	case rns[0] == '=':
+		if _, err := l.Discard(1); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		return lexparse.StateFn(lexValue), nil
+	case rns[0] == '"':
+		return nil, unexpected(l, rns[0])
+	default:
+		return lexparse.StateFn(lexSpace), nil
+	}
+}
+
+// lexValue lexes a quoted or unquoted value.
+func lexValue(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	rns, err := l.Peek(1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+
+	if len(rns) > 0 && rns[0] == '"' {
+		if err := lexQuoted(l); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := l.AdvanceWhile(wordClass); err != nil && !errors.Is(err, io.EOF) {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		l.Emit(l.Lexeme(ValueType))
+	}
+
+	// The value must be followed by whitespace or the end of the input.
+	rns, err = l.Peek(1)
+	switch {
+	case errors.Is(err, io.EOF):
+		return nil, nil
+	case err != nil:
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	case rns[0] != '\n' && !blankClass.Contains(rns[0]):
+		return nil, unexpected(l, rns[0])
+	default:
+		return lexparse.StateFn(lexSpace), nil
+	}
+}
+
+// lexQuoted lexes a quoted value.
+func lexQuoted(l *lexparse.Lexer) error {
+	line, col := l.Line(), l.Column()
+	if _, err := l.Advance(1); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	for escaped := false; ; {
+		rn, _, err := l.ReadRune()
+		if errors.Is(err, io.EOF) || rn == '\n' {
+			return fmt.Errorf("%w: line %d, column %d", ErrUnclosedQuote, line+1, col+1)
+		}
+		if err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case rn == '\\':
+			escaped = true
+		case rn == '"':
+			lexeme := l.Lexeme(ValueType)
+			v, err := strconv.Unquote(lexeme.Value)
+			if err != nil {
+				return fmt.Errorf("%w: %s: line %d, column %d", ErrInvalidEscape, lexeme.Value, line+1, col+1)
+			}
+			lexeme.Value = v
+			l.Emit(lexeme)
+			return nil
+		}
+	}
+}
+
+// Pair is a key/value pair.
+type Pair struct {
+	Key   string
+	Value string
+
+	// Pos is the position of the key in the input in runes.
+	Pos int
+
+	// Line is the line of the key.
+	Line int
+
+	// Column is the column of the key.
+	Column int
+}
+
+// Record is the ordered list of pairs on a line.
+type Record []Pair
+
+// Get returns the value of the first pair with the given key.
+func (r Record) Get(key string) (string, bool) {
+	for _, p := range r {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Parse parses logfmt input and returns a record for each non-empty line.
+func Parse(ctx context.Context, r io.Reader) ([]Record, error) {
+	l := NewLexer(r)
+
+	var records []Record
+	var record Record
+	for lexeme := range l.Lex(ctx) {
+		switch lexeme.Type {
+		case KeyType:
+			record = append(record, Pair{
+				Key:    lexeme.Value,
+				Pos:    lexeme.Pos,
+				Line:   lexeme.Line,
+				Column: lexeme.Column,
+			})
+		case ValueType:
+			record[len(record)-1].Value = lexeme.Value
+		case NewlineType:
+			if record != nil {
+				records = append(records, record)
+				record = nil
+			}
+		}
+	}
+	if record != nil {
+		records = append(records, record)
+	}
+	<-l.Done()
+	if err := l.Err(); err != nil {
+		return nil, fmt.Errorf("parsing logfmt: %w", err)
+	}
+
+	return records, nil
+}
+
+// ParseLine parses a single logfmt line. If s contains more than one
+// non-empty line only the first record is returned.
+func ParseLine(ctx context.Context, s string) (Record, error) {
+	records, err := Parse(ctx, strings.NewReader(s))
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0], nil
+}
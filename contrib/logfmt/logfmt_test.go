@@ -0,0 +1,208 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logfmt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/lexparse"
+)
+
+func TestNewLexer(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(strings.NewReader(`a=1 b="x y"` + "\nc"))
+	var got []*lexparse.Lexeme
+	for lexeme := range l.Lex(context.Background()) {
+		got = append(got, lexeme)
+	}
+	<-l.Done()
+	if err := l.Err(); err != nil {
+		t.Fatalf("Lex: %v", err)
+	}
+
+	want := []*lexparse.Lexeme{
+		{Type: KeyType, Value: "a"},
+		{Type: ValueType, Value: "1", Pos: 2, Column: 2},
+		{Type: KeyType, Value: "b", Pos: 4, Column: 4},
+		{Type: ValueType, Value: "x y", Pos: 6, Column: 6},
+		{Type: NewlineType, Value: "\n", Pos: 11, Column: 11},
+		{Type: KeyType, Value: "c", Pos: 12, Line: 1},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		want  []Record
+	}{
+		"empty": {
+			input: "",
+		},
+		"blank lines": {
+			input: "\n  \n\t\n",
+		},
+		"pairs": {
+			input: "level=info msg=hello",
+			want: []Record{{
+				{Key: "level", Value: "info"},
+				{Key: "msg", Value: "hello", Pos: 11, Column: 11},
+			}},
+		},
+		"quoted": {
+			input: `msg="request \"handled\"\tok"`,
+			want: []Record{{
+				{Key: "msg", Value: "request \"handled\"\tok"},
+			}},
+		},
+		"quoted with equals": {
+			input: `q="a=b c"`,
+			want:  []Record{{{Key: "q", Value: "a=b c"}}},
+		},
+		"empty values": {
+			input: `a= b="" c`,
+			want: []Record{{
+				{Key: "a"},
+				{Key: "b", Pos: 3, Column: 3},
+				{Key: "c", Pos: 8, Column: 8},
+			}},
+		},
+		"duplicate keys": {
+			input: "a=1 a=2",
+			want: []Record{{
+				{Key: "a", Value: "1"},
+				{Key: "a", Value: "2", Pos: 4, Column: 4},
+			}},
+		},
+		"lines": {
+			input: "a=1\r\n\nb=2 c=3\n",
+			want: []Record{
+				{{Key: "a", Value: "1"}},
+				{
+					{Key: "b", Value: "2", Pos: 6, Line: 2},
+					{Key: "c", Value: "3", Pos: 10, Line: 2, Column: 4},
+				},
+			},
+		},
+		"unicode": {
+			input: `名前="山田 太郎" ok=✓`,
+			want: []Record{{
+				{Key: "名前", Value: "山田 太郎"},
+				{Key: "ok", Value: "✓", Pos: 11, Column: 11},
+			}},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(context.Background(), strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Parse: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		err   error
+		msg   string
+	}{
+		"missing key": {
+			input: "a=1 =2",
+			err:   ErrUnexpectedChar,
+			msg:   "line 1, column 5",
+		},
+		"quote in key": {
+			input: `a"b"=1`,
+			err:   ErrUnexpectedChar,
+			msg:   "line 1, column 2",
+		},
+		"equals in value": {
+			input: "a=1\nb=c=d",
+			err:   ErrUnexpectedChar,
+			msg:   "line 2, column 4",
+		},
+		"text after quote": {
+			input: `a="b"c`,
+			err:   ErrUnexpectedChar,
+			msg:   "line 1, column 6",
+		},
+		"unclosed quote": {
+			input: `a=1 b="c` + "\nd=1",
+			err:   ErrUnclosedQuote,
+			msg:   "line 1, column 7",
+		},
+		"escaped quote at end": {
+			input: `a="b\"`,
+			err:   ErrUnclosedQuote,
+			msg:   "line 1, column 3",
+		},
+		"invalid escape": {
+			input: `a="\q"`,
+			err:   ErrInvalidEscape,
+			msg:   "line 1, column 3",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(context.Background(), strings.NewReader(tc.input))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Parse: want: %v, got: %v", tc.err, err)
+			}
+			if !strings.Contains(err.Error(), tc.msg) {
+				t.Errorf("Parse: want: %q in %q", tc.msg, err.Error())
+			}
+		})
+	}
+}
+
+func TestRecord_Get(t *testing.T) {
+	t.Parallel()
+
+	r, err := ParseLine(context.Background(), "a=1 b=2 a=3\nc=4")
+	if err != nil {
+		t.Fatalf("ParseLine: %v", err)
+	}
+	if got, ok := r.Get("a"); !ok || got != "1" {
+		t.Errorf("Get(%q): want: %q, true, got: %q, %v", "a", "1", got, ok)
+	}
+	if got, ok := r.Get("c"); ok {
+		t.Errorf("Get(%q): want: false, got: %q, %v", "c", got, ok)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package markup_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ianlewis/lexparse"
+	"github.com/ianlewis/lexparse/contrib/markup"
+)
+
+func Example() {
+	names := map[lexparse.LexemeType]string{
+		markup.TextType:      "Text",
+		markup.StartTagType:  "StartTag",
+		markup.AttrNameType:  "AttrName",
+		markup.AttrValueType: "AttrValue",
+		markup.TagEndType:    "TagEnd",
+		markup.EndTagType:    "EndTag",
+	}
+
+	src := `<a href="/?a=1&amp;b=2">Tom &amp; Jerry</a>`
+	l := markup.NewLexer(strings.NewReader(src), true)
+	for lexeme := range l.Lex(context.Background()) {
+		fmt.Printf("%d:%d\t%s\t%q\n", lexeme.Line+1, lexeme.Column+1, names[lexeme.Type], lexeme.Value)
+	}
+	<-l.Done()
+	if err := l.Err(); err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// 1:2	StartTag	"a"
+	// 1:4	AttrName	"href"
+	// 1:10	AttrValue	"/?a=1&b=2"
+	// 1:24	TagEnd	">"
+	// 1:25	Text	"Tom & Jerry"
+	// 1:42	EndTag	"a"
+}
@@ -0,0 +1,374 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package markup implements a lexer for HTML and XML like markup using
+// lexparse.
+//
+// The lexer switches between modes as it reads the input. In text mode it
+// emits text until the start of a tag. In tag mode it emits the tag name and
+// attributes. The contents of script and style elements are lexed in raw text
+// mode so that they may contain '<'. Comments, CDATA sections, and
+// directives such as <!DOCTYPE html> and <?xml version="1.0"?> are also
+// recognized.
+//
+// The lexer is not a validating parser. It doesn't check that tags are
+// balanced or that attributes are unique.
+package markup
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrUnclosed indicates that a tag, comment, or other markup was not
+	// closed before the end of the input.
+	ErrUnclosed = errors.New("unclosed markup")
+
+	// ErrUnexpectedChar indicates an invalid character in a tag.
+	ErrUnexpectedChar = errors.New("unexpected character")
+)
+
+// Lexeme types emitted by the lexer. The values of lexemes don't include
+// delimiters such as '<', '=', or quotes and their positions are the
+// positions of their values in the input.
+const (
+	// TextType is text outside of tags or the contents of a CDATA section.
+	TextType lexparse.LexemeType = iota
+
+	// StartTagType is the name of a start tag, e.g. "a" for <a href="/">.
+	StartTagType
+
+	// AttrNameType is the name of an attribute.
+	AttrNameType
+
+	// AttrValueType is the value of an attribute without quotes.
+	AttrValueType
+
+	// TagEndType is the ">" at the end of a start tag.
+	TagEndType
+
+	// SelfCloseType is the "/>" at the end of a self-closing tag.
+	SelfCloseType
+
+	// EndTagType is the name of an end tag, e.g. "a" for </a>.
+	EndTagType
+
+	// CommentType is the text of a comment.
+	CommentType
+
+	// DirectiveType is the text of a directive or processing instruction,
+	// e.g. "DOCTYPE html" or `xml version="1.0"`.
+	DirectiveType
+)
+
+const (
+	commentOpen  = "<!--"
+	commentClose = "-->"
+	cdataOpen    = "<![CDATA["
+	cdataClose   = "]]>"
+)
+
+// rawTextElements are the elements whose contents are lexed as raw text.
+var rawTextElements = map[string]bool{
+	"script": true,
+	"style":  true,
+}
+
+var (
+	spaceClass = lexparse.RuneClassFunc(unicode.IsSpace)
+	nameClass  = lexparse.NewRuneClass("-_:.", unicode.Letter, unicode.Digit)
+
+	// unquotedClass are the runes allowed in unquoted attribute values.
+	unquotedClass = lexparse.RuneClassFunc(func(rn rune) bool {
+		return !unicode.IsSpace(rn) && !strings.ContainsRune(`"'<>=`+"`", rn)
+	})
+)
+
+// NewLexer returns a new lexer for the markup read from r. If decodeEntities
+// is true, character references such as "&amp;" in text and attribute values
+// are decoded. The positions of lexemes are not affected.
+func NewLexer(r io.Reader, decodeEntities bool, opts ...lexparse.LexerOption) *lexparse.Lexer {
+	m := &markupLexer{decode: decodeEntities}
+	m.text = lexparse.NamedState("lexText", m.lexText)
+	m.tag = lexparse.NamedState("lexTag", m.lexTag)
+	return lexparse.NewLexer(runeio.NewReader(bufio.NewReader(r)), m.text, opts...)
+}
+
+// markupLexer holds the lexer's states and configuration.
+type markupLexer struct {
+	decode bool
+
+	// text and tag are the states for text and tag mode.
+	text lexparse.State
+	tag  lexparse.State
+
+	// tagName is the name of the current start tag.
+	tagName string
+}
+
+// lexText lexes text until the start of a tag.
+func (m *markupLexer) lexText(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	for {
+		if _, err := l.Find([]string{"<"}); err != nil {
+			m.emitText(l, TextType)
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("lexing text: %w", err)
+		}
+
+		rns, err := l.Peek(2)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("lexing text: %w", err)
+		}
+		if len(rns) == 2 && (nameClass.Contains(rns[1]) || strings.ContainsRune("/!?", rns[1])) {
+			m.emitText(l, TextType)
+			return m.lexMarkup(l)
+		}
+
+		// A '<' that doesn't start a tag is text.
+		if _, err := l.Advance(1); err != nil {
+			return nil, fmt.Errorf("lexing text: %w", err)
+		}
+	}
+}
+
+// lexMarkup lexes the start of a tag, comment, CDATA section, or directive.
+func (m *markupLexer) lexMarkup(l *lexparse.Lexer) (lexparse.State, error) {
+	line, col := l.Line(), l.Column()
+
+	rns, err := l.Peek(len(cdataOpen))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("lexing markup: %w", err)
+	}
+	s := string(rns)
+
+	switch {
+	case strings.HasPrefix(s, commentOpen):
+		return m.text, lexDelimited(l, line, col, commentOpen, commentClose, CommentType)
+	case strings.HasPrefix(s, cdataOpen):
+		return m.text, lexDelimited(l, line, col, cdataOpen, cdataClose, TextType)
+	case strings.HasPrefix(s, "<!"):
+		return m.text, lexDelimited(l, line, col, "<!", ">", DirectiveType)
+	case strings.HasPrefix(s, "<?"):
+		return m.text, lexDelimited(l, line, col, "<?", "?>", DirectiveType)
+	case strings.HasPrefix(s, "</"):
+		return m.lexEndTag(l, line, col)
+	default:
+		if _, err := l.Discard(1); err != nil {
+			return nil, fmt.Errorf("lexing tag: %w", err)
+		}
+		if _, err := l.AdvanceWhile(nameClass); err != nil {
+			return nil, unclosed(err, "tag", line, col)
+		}
+		lexeme := l.Lexeme(StartTagType)
+		m.tagName = strings.ToLower(lexeme.Value)
+		l.Emit(lexeme)
+		return m.tag, nil
+	}
+}
+
+// lexDelimited lexes text between the open and close delimiters and emits it
+// as a lexeme of the given type.
+func lexDelimited(l *lexparse.Lexer, line, col int, open, closing string, typ lexparse.LexemeType) error {
+	if _, err := l.Discard(len(open)); err != nil {
+		return fmt.Errorf("lexing markup: %w", err)
+	}
+	if _, err := l.Find([]string{closing}); err != nil {
+		return unclosed(err, "markup", line, col)
+	}
+	l.Emit(l.Lexeme(typ))
+	if _, err := l.Discard(len(closing)); err != nil {
+		return fmt.Errorf("lexing markup: %w", err)
+	}
+	return nil
+}
+
+// lexEndTag lexes an end tag.
+func (m *markupLexer) lexEndTag(l *lexparse.Lexer, line, col int) (lexparse.State, error) {
+	if _, err := l.Discard(2); err != nil {
+		return nil, fmt.Errorf("lexing end tag: %w", err)
+	}
+	if _, err := l.AdvanceWhile(nameClass); err != nil {
+		return nil, unclosed(err, "tag", line, col)
+	}
+	l.Emit(l.Lexeme(EndTagType))
+
+	if err := skipSpace(l); err != nil {
+		return nil, unclosed(err, "tag", line, col)
+	}
+	rns, err := l.Peek(1)
+	if err != nil {
+		return nil, unclosed(err, "tag", line, col)
+	}
+	if rns[0] != '>' {
+		return nil, unexpected(l, rns[0])
+	}
+	if _, err := l.Discard(1); err != nil {
+		return nil, fmt.Errorf("lexing end tag: %w", err)
+	}
+	return m.text, nil
+}
+
+// lexTag lexes the attributes and end of a start tag.
+func (m *markupLexer) lexTag(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	line, col := l.Line(), l.Column()
+	if err := skipSpace(l); err != nil {
+		return nil, unclosed(err, "tag", line, col)
+	}
+
+	rns, err := l.Peek(2)
+	if len(rns) == 0 {
+		return nil, unclosed(err, "tag", line, col)
+	}
+
+	switch {
+	case rns[0] == '>':
+		if _, err := l.Advance(1); err != nil {
+			return nil, fmt.Errorf("lexing tag: %w", err)
+		}
+		l.Emit(l.Lexeme(TagEndType))
+		if rawTextElements[m.tagName] {
+			return lexparse.NamedState("lexRawText", m.lexRawText), nil
+		}
+		return m.text, nil
+	case string(rns) == "/>":
+		if _, err := l.Advance(2); err != nil {
+			return nil, fmt.Errorf("lexing tag: %w", err)
+		}
+		l.Emit(l.Lexeme(SelfCloseType))
+		return m.text, nil
+	case nameClass.Contains(rns[0]):
+		return m.tag, m.lexAttr(l)
+	default:
+		return nil, unexpected(l, rns[0])
+	}
+}
+
+// lexAttr lexes an attribute name and its optional value.
+func (m *markupLexer) lexAttr(l *lexparse.Lexer) error {
+	line, col := l.Line(), l.Column()
+	if _, err := l.AdvanceWhile(nameClass); err != nil {
+		return unclosed(err, "tag", line, col)
+	}
+	l.Emit(l.Lexeme(AttrNameType))
+
+	if err := skipSpace(l); err != nil {
+		return unclosed(err, "tag", line, col)
+	}
+	rns, err := l.Peek(1)
+	if err != nil {
+		return unclosed(err, "tag", line, col)
+	}
+	if rns[0] != '=' {
+		// The attribute has no value.
+		return nil
+	}
+	if _, err := l.Discard(1); err != nil {
+		return fmt.Errorf("lexing attribute: %w", err)
+	}
+	if err := skipSpace(l); err != nil {
+		return unclosed(err, "tag", line, col)
+	}
+
+	rns, err = l.Peek(1)
+	if err != nil {
+		return unclosed(err, "tag", line, col)
+	}
+	switch quote := rns[0]; quote {
+	case '"', '\'':
+		if _, err := l.Discard(1); err != nil {
+			return fmt.Errorf("lexing attribute: %w", err)
+		}
+		if _, err := l.Find([]string{string(quote)}); err != nil {
+			return unclosed(err, "attribute value", line, col)
+		}
+		m.emitText(l, AttrValueType)
+		if _, err := l.Discard(1); err != nil {
+			return fmt.Errorf("lexing attribute: %w", err)
+		}
+	default:
+		n, err := l.AdvanceWhile(unquotedClass)
+		if err != nil {
+			return unclosed(err, "tag", line, col)
+		}
+		if n == 0 {
+			return unexpected(l, quote)
+		}
+		m.emitText(l, AttrValueType)
+	}
+	return nil
+}
+
+// lexRawText lexes the contents of a raw text element up to its end tag.
+func (m *markupLexer) lexRawText(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	_, err := l.FindFold([]string{"</" + m.tagName})
+	m.emitText(l, TextType)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("lexing text: %w", err)
+	}
+	return m.lexEndTag(l, l.Line(), l.Column())
+}
+
+// emitText emits the current lexeme with the given type if it is not empty,
+// decoding entities if configured.
+func (m *markupLexer) emitText(l *lexparse.Lexer, typ lexparse.LexemeType) {
+	lexeme := l.Lexeme(typ)
+	if lexeme.Value == "" && typ == TextType {
+		return
+	}
+	if m.decode {
+		lexeme.Value = html.UnescapeString(lexeme.Value)
+	}
+	l.Emit(lexeme)
+}
+
+// skipSpace discards whitespace.
+func skipSpace(l *lexparse.Lexer) error {
+	if _, err := l.AdvanceWhile(spaceClass); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	l.Ignore()
+	return nil
+}
+
+// unclosed returns an error for err while lexing the markup starting at the
+// given line and column. io.EOF is reported as ErrUnclosed.
+func unclosed(err error, what string, line, col int) error {
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: %s: line %d, column %d", ErrUnclosed, what, line+1, col+1)
+	}
+	return fmt.Errorf("lexing %s: %w", what, err)
+}
+
+// unexpected returns an ErrUnexpectedChar error for rn at the current
+// position.
+func unexpected(l *lexparse.Lexer, rn rune) error {
+	return fmt.Errorf("%w: %q: line %d, column %d", ErrUnexpectedChar, rn, l.Line()+1, l.Column()+1)
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package markup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/lexparse"
+)
+
+// lex returns the lexemes in input and the lexer's error.
+func lex(input string, decode bool) ([]*lexparse.Lexeme, error) {
+	l := NewLexer(strings.NewReader(input), decode)
+	var lexemes []*lexparse.Lexeme
+	for lexeme := range l.Lex(context.Background()) {
+		lexemes = append(lexemes, lexeme)
+	}
+	<-l.Done()
+	return lexemes, l.Err()
+}
+
+func TestNewLexer(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input  string
+		decode bool
+		want   []*lexparse.Lexeme
+	}{
+		"text": {
+			input: "a < b &amp; c",
+			want: []*lexparse.Lexeme{
+				{Type: TextType, Value: "a < b &amp; c"},
+			},
+		},
+		"decode text": {
+			input:  "a < b &amp; c",
+			decode: true,
+			want: []*lexparse.Lexeme{
+				{Type: TextType, Value: "a < b & c"},
+			},
+		},
+		"tags": {
+			input: `<p class="x">Hi</p>`,
+			want: []*lexparse.Lexeme{
+				{Type: StartTagType, Value: "p", Pos: 1, Column: 1},
+				{Type: AttrNameType, Value: "class", Pos: 3, Column: 3},
+				{Type: AttrValueType, Value: "x", Pos: 10, Column: 10},
+				{Type: TagEndType, Value: ">", Pos: 12, Column: 12},
+				{Type: TextType, Value: "Hi", Pos: 13, Column: 13},
+				{Type: EndTagType, Value: "p", Pos: 17, Column: 17},
+			},
+		},
+		"attributes": {
+			input:  "<input\n  type=text disabled value='a&lt;b' />",
+			decode: true,
+			want: []*lexparse.Lexeme{
+				{Type: StartTagType, Value: "input", Pos: 1, Column: 1},
+				{Type: AttrNameType, Value: "type", Pos: 9, Line: 1, Column: 2},
+				{Type: AttrValueType, Value: "text", Pos: 14, Line: 1, Column: 7},
+				{Type: AttrNameType, Value: "disabled", Pos: 19, Line: 1, Column: 12},
+				{Type: AttrNameType, Value: "value", Pos: 28, Line: 1, Column: 21},
+				{Type: AttrValueType, Value: "a<b", Pos: 35, Line: 1, Column: 28},
+				{Type: SelfCloseType, Value: "/>", Pos: 43, Line: 1, Column: 36},
+			},
+		},
+		"comment": {
+			input: "a<!-- <b> -->c",
+			want: []*lexparse.Lexeme{
+				{Type: TextType, Value: "a"},
+				{Type: CommentType, Value: " <b> ", Pos: 5, Column: 5},
+				{Type: TextType, Value: "c", Pos: 13, Column: 13},
+			},
+		},
+		"cdata": {
+			input:  "<![CDATA[<a>&amp;]]>",
+			decode: true,
+			want: []*lexparse.Lexeme{
+				{Type: TextType, Value: "<a>&amp;", Pos: 9, Column: 9},
+			},
+		},
+		"directives": {
+			input: `<?xml version="1.0"?><!DOCTYPE html>`,
+			want: []*lexparse.Lexeme{
+				{Type: DirectiveType, Value: `xml version="1.0"`, Pos: 2, Column: 2},
+				{Type: DirectiveType, Value: "DOCTYPE html", Pos: 23, Column: 23},
+			},
+		},
+		"raw text": {
+			input: "<script>if (a<b) {}</SCRIPT >",
+			want: []*lexparse.Lexeme{
+				{Type: StartTagType, Value: "script", Pos: 1, Column: 1},
+				{Type: TagEndType, Value: ">", Pos: 7, Column: 7},
+				{Type: TextType, Value: "if (a<b) {}", Pos: 8, Column: 8},
+				{Type: EndTagType, Value: "SCRIPT", Pos: 21, Column: 21},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := lex(tc.input, tc.decode)
+			if err != nil {
+				t.Fatalf("Err: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Lex: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewLexer_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		err   error
+	}{
+		"unclosed tag": {
+			input: `<a href="/"`,
+			err:   ErrUnclosed,
+		},
+		"unclosed attribute value": {
+			input: `<a href="/>`,
+			err:   ErrUnclosed,
+		},
+		"unclosed comment": {
+			input: "<!-- a",
+			err:   ErrUnclosed,
+		},
+		"unexpected char": {
+			input: "<a !>",
+			err:   ErrUnexpectedChar,
+		},
+		"missing value": {
+			input: "<a href=>",
+			err:   ErrUnexpectedChar,
+		},
+		"end tag": {
+			input: "</a b>",
+			err:   ErrUnexpectedChar,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := lex(tc.input, false)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Err: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
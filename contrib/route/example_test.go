@@ -0,0 +1,38 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ianlewis/lexparse/contrib/route"
+)
+
+func Example() {
+	pt, err := route.Parse(context.Background(), "/users/{id:int}/posts/{slug}")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, path := range []string{"/users/42/posts/hello", "/users/gopher/posts/hello"} {
+		params, ok := pt.Match(path)
+		fmt.Println(path, ok, params["id"], params["slug"])
+	}
+
+	// Output:
+	// /users/42/posts/hello true 42 hello
+	// /users/gopher/posts/hello false
+}
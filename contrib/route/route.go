@@ -0,0 +1,387 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package route implements a parser for URL route patterns using lexparse.
+//
+// A pattern is a sequence of path segments, each starting with '/'. Segments
+// contain literal text and parameters in braces. A parameter has a name and
+// an optional type, which is "string" by default:
+//
+//	/users/{id:int}/posts/{slug}
+//	/files/{name}.{ext}
+//
+// A final segment consisting of '*' matches the rest of the path:
+//
+//	/static/*
+//
+// Patterns are parsed into a tree of segments and their parts that can be
+// inspected, e.g. by a router, or used to match paths with Match.
+package route
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrUnknownType indicates that a parameter has an unknown type.
+	ErrUnknownType = errors.New("unknown parameter type")
+
+	// ErrDuplicateParam indicates that a parameter name is used more than
+	// once.
+	ErrDuplicateParam = errors.New("duplicate parameter")
+
+	// ErrWildcard indicates that a wildcard is not the entire last segment.
+	ErrWildcard = errors.New("wildcard must be the last segment")
+)
+
+const (
+	slashType lexparse.LexemeType = iota
+	literalType
+	lbraceType
+	rbraceType
+	colonType
+	nameType
+	wildcardType
+)
+
+// Kind is the kind of a node in the pattern tree.
+type Kind int
+
+const (
+	// SegmentKind is a path segment. Its children are the parts of the
+	// segment.
+	SegmentKind Kind = iota
+
+	// LiteralKind is literal text.
+	LiteralKind
+
+	// ParamKind is a parameter.
+	ParamKind
+
+	// WildcardKind is a wildcard matching the rest of the path.
+	WildcardKind
+)
+
+// Part is the value of a node in the pattern tree.
+type Part struct {
+	Kind Kind
+
+	// Value is the text of a literal or the name of a parameter.
+	Value string
+
+	// Type is the type of a parameter, e.g. "int".
+	Type string
+}
+
+// types are the parameter types and functions reporting whether a value is
+// valid for the type.
+var types = map[string]func(string) bool{
+	"string": func(string) bool {
+		return true
+	},
+	"int": func(s string) bool {
+		_, err := strconv.ParseInt(s, 10, 64)
+		return err == nil
+	},
+}
+
+var nameClass = lexparse.NewRuneClass("_", unicode.Letter, unicode.Digit)
+
+// lexPath lexes the parts of path segments outside of braces.
+func lexPath(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	rns, err := l.Peek(1)
+	if err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+
+	switch rns[0] {
+	case '/':
+		return lexparse.StateFn(lexPath), emit(l, slashType)
+	case '*':
+		return lexparse.StateFn(lexPath), emit(l, wildcardType)
+	case '{':
+		return lexparse.StateFn(lexParam), emit(l, lbraceType)
+	}
+
+	for {
+		rns, err := l.Peek(1)
+		if err != nil || strings.ContainsRune("/*{}", rns[0]) {
+			if lexeme := l.Lexeme(literalType); lexeme.Value != "" {
+				l.Emit(lexeme)
+			}
+			if err != nil {
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return nil, err
+			}
+			if rns[0] == '}' {
+				return nil, fmt.Errorf("%w: unexpected '}': column %d", lexparse.ErrUnexpectedLexeme, l.Column()+1)
+			}
+			return lexparse.StateFn(lexPath), nil
+		}
+		if _, err := l.Advance(1); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+	}
+}
+
+// lexParam lexes the contents of a parameter.
+func lexParam(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	rns, err := l.Peek(1)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: unclosed parameter", io.ErrUnexpectedEOF)
+		}
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+
+	switch {
+	case rns[0] == ':':
+		return lexparse.StateFn(lexParam), emit(l, colonType)
+	case rns[0] == '}':
+		return lexparse.StateFn(lexPath), emit(l, rbraceType)
+	case nameClass.Contains(rns[0]):
+		if _, err := l.AdvanceWhile(nameClass); err != nil && !errors.Is(err, io.EOF) {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		l.Emit(l.Lexeme(nameType))
+		return lexparse.StateFn(lexParam), nil
+	default:
+		return nil, fmt.Errorf("%w: %q: column %d", lexparse.ErrUnexpectedLexeme, rns[0], l.Column()+1)
+	}
+}
+
+// emit advances the lexer one rune and emits a lexeme of the given type.
+func emit(l *lexparse.Lexer, typ lexparse.LexemeType) error {
+	if _, err := l.Advance(1); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	l.Emit(l.Lexeme(typ))
+	return nil
+}
+
+// parser holds the state used while parsing a pattern.
+type parser struct {
+	// params are the names of the parameters parsed so far.
+	params map[string]bool
+}
+
+// parsePattern parses a pattern consisting of segments.
+func (ps *parser) parsePattern(_ context.Context, p *lexparse.Parser[*Part]) (lexparse.ParseFn[*Part], error) {
+	if _, err := expect(p, slashType); err != nil {
+		return nil, err
+	}
+	for {
+		if err := ps.parseSegment(p); err != nil {
+			return nil, err
+		}
+		if accept(p, slashType) == nil {
+			return nil, nil
+		}
+	}
+}
+
+// parseSegment parses the parts of a segment after the '/'.
+func (ps *parser) parseSegment(p *lexparse.Parser[*Part]) error {
+	seg := p.Push(&Part{Kind: SegmentKind})
+	defer p.Climb()
+
+	for {
+		l := p.Peek()
+		if l == nil || l.Type == slashType {
+			return nil
+		}
+		l = p.Next()
+
+		switch l.Type {
+		case literalType:
+			_ = p.Node(&Part{Kind: LiteralKind, Value: l.Value})
+		case wildcardType:
+			if len(seg.Children) > 0 || !p.AtEOF() {
+				return &lexparse.LexemeError{Err: ErrWildcard, Lexeme: l}
+			}
+			_ = p.Node(&Part{Kind: WildcardKind, Value: l.Value})
+		case lbraceType:
+			if err := ps.parseParam(p); err != nil {
+				return err
+			}
+		default:
+			return &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+		}
+	}
+}
+
+// parseParam parses a parameter after the '{'.
+func (ps *parser) parseParam(p *lexparse.Parser[*Part]) error {
+	name, err := expect(p, nameType)
+	if err != nil {
+		return err
+	}
+	if ps.params[name.Value] {
+		return &lexparse.LexemeError{Err: ErrDuplicateParam, Lexeme: name}
+	}
+	ps.params[name.Value] = true
+
+	typ := "string"
+	if accept(p, colonType) != nil {
+		t, err := expect(p, nameType)
+		if err != nil {
+			return err
+		}
+		if _, ok := types[t.Value]; !ok {
+			return &lexparse.LexemeError{Err: ErrUnknownType, Lexeme: t}
+		}
+		typ = t.Value
+	}
+	if _, err := expect(p, rbraceType); err != nil {
+		return err
+	}
+
+	n := p.Node(&Part{Kind: ParamKind, Value: name.Value, Type: typ})
+	n.Pos, n.Line, n.Column = name.Pos, name.Line, name.Column
+	return nil
+}
+
+// expect consumes the next lexeme and returns an error if it is not of the
+// given type.
+func expect(p *lexparse.Parser[*Part], typ lexparse.LexemeType) (*lexparse.Lexeme, error) {
+	l := p.Next()
+	if l == nil {
+		return nil, fmt.Errorf("%w: parsing pattern", io.ErrUnexpectedEOF)
+	}
+	if l.Type != typ {
+		return nil, &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+	}
+	return l, nil
+}
+
+// accept consumes and returns the next lexeme if it is of the given type.
+// Otherwise it returns nil and consumes nothing.
+func accept(p *lexparse.Parser[*Part], typ lexparse.LexemeType) *lexparse.Lexeme {
+	if l := p.Peek(); l == nil || l.Type != typ {
+		return nil
+	}
+	return p.Next()
+}
+
+// Pattern is a parsed route pattern.
+type Pattern struct {
+	root *lexparse.Node[*Part]
+}
+
+// Parse parses a route pattern.
+func Parse(ctx context.Context, pattern string) (*Pattern, error) {
+	l := lexparse.NewLexer(runeio.NewReader(bufio.NewReader(strings.NewReader(pattern))), lexparse.StateFn(lexPath))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	p := lexparse.NewParser[*Part](l.Lex(ctx))
+	p.SetLexerErr(l.Err)
+	p.SetRequireEOF(true)
+	ps := &parser{params: map[string]bool{}}
+	root, pErr := p.Parse(ctx, ps.parsePattern)
+	cancel(pErr)
+
+	<-l.Done()
+
+	err := pErr
+	if lErr := l.Err(); lErr != nil && !errors.Is(lErr, context.Canceled) {
+		err = lErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing route pattern: %w", err)
+	}
+
+	return &Pattern{root: root}, nil
+}
+
+// Tree returns the root of the pattern tree. The children of the root are
+// segment nodes whose children are the literal, parameter, and wildcard parts
+// of the segment.
+func (pt *Pattern) Tree() *lexparse.Node[*Part] {
+	return pt.root
+}
+
+// Match matches path against the pattern. If the path matches it returns the
+// values of the parameters by name and true. The value of a wildcard is the
+// rest of the path and is returned with the name "*".
+func (pt *Pattern) Match(path string) (map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+	segs := strings.Split(path[1:], "/")
+
+	params := map[string]string{}
+	for i, seg := range pt.root.Children {
+		if len(seg.Children) == 1 && seg.Children[0].Value.Kind == WildcardKind {
+			params["*"] = strings.Join(segs[i:], "/")
+			return params, true
+		}
+		if i >= len(segs) || !matchParts(seg.Children, segs[i], params) {
+			return nil, false
+		}
+	}
+	if len(segs) != len(pt.root.Children) {
+		return nil, false
+	}
+	return params, true
+}
+
+// matchParts matches the parts of a segment against s, setting the values of
+// parameters in params. Parameters match the shortest valid non-empty value
+// for which the rest of the segment matches.
+func matchParts(parts []*lexparse.Node[*Part], s string, params map[string]string) bool {
+	if len(parts) == 0 {
+		return s == ""
+	}
+
+	part := parts[0].Value
+	switch part.Kind {
+	case LiteralKind:
+		return strings.HasPrefix(s, part.Value) && matchParts(parts[1:], s[len(part.Value):], params)
+	case ParamKind:
+		// Try the shortest non-empty value first.
+		for i := 1; i <= len(s); i++ {
+			if i < len(s) && !utf8.RuneStart(s[i]) {
+				continue
+			}
+			if !types[part.Type](s[:i]) {
+				continue
+			}
+			if matchParts(parts[1:], s[i:], params) {
+				params[part.Value] = s[:i]
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ianlewis/lexparse"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	pt, err := Parse(context.Background(), "/users/{id:int}/f-{name}.txt/*")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	type node struct {
+		Part     Part
+		Column   int
+		Children []node
+	}
+	var convert func(n *lexparse.Node[*Part]) node
+	convert = func(n *lexparse.Node[*Part]) node {
+		c := node{Column: n.Column}
+		if n.Value != nil {
+			c.Part = *n.Value
+		}
+		for _, child := range n.Children {
+			c.Children = append(c.Children, convert(child))
+		}
+		return c
+	}
+
+	want := node{
+		Children: []node{
+			{
+				Part:   Part{Kind: SegmentKind},
+				Column: 0,
+				Children: []node{
+					{Part: Part{Kind: LiteralKind, Value: "users"}, Column: 1},
+				},
+			},
+			{
+				Part:   Part{Kind: SegmentKind},
+				Column: 6,
+				Children: []node{
+					{Part: Part{Kind: ParamKind, Value: "id", Type: "int"}, Column: 8},
+				},
+			},
+			{
+				Part:   Part{Kind: SegmentKind},
+				Column: 15,
+				Children: []node{
+					{Part: Part{Kind: LiteralKind, Value: "f-"}, Column: 16},
+					{Part: Part{Kind: ParamKind, Value: "name", Type: "string"}, Column: 19},
+					{Part: Part{Kind: LiteralKind, Value: ".txt"}, Column: 24},
+				},
+			},
+			{
+				Part:   Part{Kind: SegmentKind},
+				Column: 28,
+				Children: []node{
+					{Part: Part{Kind: WildcardKind, Value: "*"}, Column: 29},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, convert(pt.Tree())); diff != "" {
+		t.Errorf("Tree: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestPattern_Match(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		pattern string
+		path    string
+		params  map[string]string
+		ok      bool
+	}{
+		"root": {
+			pattern: "/",
+			path:    "/",
+			params:  map[string]string{},
+			ok:      true,
+		},
+		"literal": {
+			pattern: "/users/list",
+			path:    "/users/list",
+			params:  map[string]string{},
+			ok:      true,
+		},
+		"literal mismatch": {
+			pattern: "/users/list",
+			path:    "/users/lists",
+		},
+		"params": {
+			pattern: "/users/{id:int}/posts/{slug}",
+			path:    "/users/42/posts/hello-world",
+			params:  map[string]string{"id": "42", "slug": "hello-world"},
+			ok:      true,
+		},
+		"int mismatch": {
+			pattern: "/users/{id:int}",
+			path:    "/users/abc",
+		},
+		"mixed segment": {
+			pattern: "/files/{name}.{ext}",
+			path:    "/files/résumé.pdf",
+			params:  map[string]string{"name": "résumé", "ext": "pdf"},
+			ok:      true,
+		},
+		"empty param": {
+			pattern: "/users/{id}",
+			path:    "/users/",
+		},
+		"too many segments": {
+			pattern: "/users/{id}",
+			path:    "/users/1/posts",
+		},
+		"too few segments": {
+			pattern: "/users/{id}",
+			path:    "/users",
+		},
+		"wildcard": {
+			pattern: "/static/*",
+			path:    "/static/css/site.css",
+			params:  map[string]string{"*": "css/site.css"},
+			ok:      true,
+		},
+		"relative path": {
+			pattern: "/",
+			path:    "users",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pt, err := Parse(context.Background(), tc.pattern)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			params, ok := pt.Match(tc.path)
+			if got, want := ok, tc.ok; got != want {
+				t.Errorf("Match: want: %v, got: %v", want, got)
+			}
+			if diff := cmp.Diff(tc.params, params, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Match: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		pattern string
+		err     error
+	}{
+		"relative": {
+			pattern: "users",
+			err:     lexparse.ErrUnexpectedLexeme,
+		},
+		"unknown type": {
+			pattern: "/{id:float}",
+			err:     ErrUnknownType,
+		},
+		"duplicate param": {
+			pattern: "/{id}/{id}",
+			err:     ErrDuplicateParam,
+		},
+		"wildcard not last": {
+			pattern: "/*/users",
+			err:     ErrWildcard,
+		},
+		"wildcard in segment": {
+			pattern: "/a*",
+			err:     ErrWildcard,
+		},
+		"unclosed param": {
+			pattern: "/{id",
+			err:     io.ErrUnexpectedEOF,
+		},
+		"missing name": {
+			pattern: "/{}",
+			err:     lexparse.ErrUnexpectedLexeme,
+		},
+		"unexpected char": {
+			pattern: "/{id-x}",
+			err:     lexparse.ErrUnexpectedLexeme,
+		},
+		"unmatched brace": {
+			pattern: "/a}",
+			err:     lexparse.ErrUnexpectedLexeme,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(context.Background(), tc.pattern)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ianlewis/lexparse/contrib/semver"
+)
+
+func Example() {
+	r, err := semver.Parse(context.Background(), ">=1.2.0 <2.0.0 || ~3.1")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, v := range []string{"1.4.2", "2.1.0", "3.1.7", "3.2.0"} {
+		ok, err := r.Check(v)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(v, ok)
+	}
+
+	// Output:
+	// 1.4.2 true
+	// 2.1.0 false
+	// 3.1.7 true
+	// 3.2.0 false
+}
@@ -0,0 +1,480 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package semver implements a parser for semantic version range expressions
+// using lexparse.
+//
+// A range is a set of comparators, each an operator followed by a version.
+// Comparators separated by whitespace or commas must all be satisfied.
+// Ranges separated by "||" are alternatives:
+//
+//	>=1.2.0 <2.0.0 || ~3.1
+//
+// The operators are =, !=, >, >=, <, <=, ~ (patch updates), and ^ (updates
+// that don't change the left-most non-zero component). A comparator without
+// an operator is an equality comparator. Versions may be partial, e.g. "1.2",
+// or use "x" or "*" wildcards, e.g. "1.x", and may be prefixed with 'v'.
+// "a - b" is the inclusive range between versions a and b.
+//
+// Versions are compared by semantic versioning precedence. Prerelease
+// versions are not excluded from ranges.
+package semver
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrInvalidVersion indicates that a version is not valid.
+	ErrInvalidVersion = errors.New("invalid version")
+
+	// ErrUnexpectedChar indicates an invalid character in a range.
+	ErrUnexpectedChar = errors.New("unexpected character")
+)
+
+const (
+	versionType lexparse.LexemeType = iota
+	orType
+	andType
+	hyphenType
+	eqType
+	neType
+	gtType
+	geType
+	ltType
+	leType
+	tildeType
+	caretType
+)
+
+// operators maps operators to their lexeme types. Longer operators are
+// listed first.
+var operators = []struct {
+	op  string
+	typ lexparse.LexemeType
+}{
+	{"||", orType},
+	{"!=", neType},
+	{">=", geType},
+	{"<=", leType},
+	{"=", eqType},
+	{">", gtType},
+	{"<", ltType},
+	{"~", tildeType},
+	{"^", caretType},
+}
+
+var (
+	spaceClass   = lexparse.NewRuneClass(", \t\r\n")
+	versionClass = lexparse.NewRuneClass(".-+*", unicode.Letter, unicode.Digit)
+)
+
+// lexRange lexes a range expression.
+func lexRange(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	if err := lexSpace(l); err != nil {
+		return nil, err
+	}
+
+	rns, err := l.Peek(2)
+	if len(rns) == 0 {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+
+	for _, o := range operators {
+		if strings.HasPrefix(string(rns), o.op) {
+			if _, err := l.Advance(len(o.op)); err != nil {
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return nil, err
+			}
+			l.Emit(l.Lexeme(o.typ))
+			return lexparse.StateFn(lexRange), nil
+		}
+	}
+
+	if !versionClass.Contains(rns[0]) {
+		return nil, fmt.Errorf("%w: %q: column %d", ErrUnexpectedChar, rns[0], l.Column()+1)
+	}
+	if _, err := l.AdvanceWhile(versionClass); err != nil && !errors.Is(err, io.EOF) {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	lexeme := l.Lexeme(versionType)
+	if lexeme.Value == "-" {
+		lexeme.Type = hyphenType
+	}
+	l.Emit(lexeme)
+	return lexparse.StateFn(lexRange), nil
+}
+
+// lexSpace skips whitespace and commas. Whitespace and commas between a
+// version and the start of another comparator are emitted as an and
+// operator.
+func lexSpace(l *lexparse.Lexer) error {
+	n, err := l.AdvanceWhile(spaceClass)
+	if err != nil && !errors.Is(err, io.EOF) {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	lexeme := l.Lexeme(andType)
+	if last := l.Last(); err == nil && last != nil && last.Type == versionType {
+		rns, pErr := l.Peek(2)
+		if pErr != nil && !errors.Is(pErr, io.EOF) {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return pErr
+		}
+		if s := string(rns); !strings.HasPrefix(s, "||") && s != "-" && !strings.HasPrefix(s, "- ") {
+			l.Emit(lexeme)
+			return nil
+		}
+	}
+	l.Ignore()
+	return nil
+}
+
+// version is a possibly partial version.
+type version struct {
+	// parts is the number of components that are specified. The remaining
+	// components are wildcards.
+	parts int
+
+	major, minor, patch int
+
+	// pre are the prerelease identifiers.
+	pre []string
+}
+
+// parseVersion parses a possibly partial version.
+func parseVersion(s string) (version, error) {
+	var v version
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		// Build metadata is ignored.
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.pre = strings.Split(s[i+1:], ".")
+		s = s[:i]
+		for _, id := range v.pre {
+			if id == "" {
+				return v, ErrInvalidVersion
+			}
+		}
+	}
+
+	components := strings.Split(s, ".")
+	if len(components) > 3 {
+		return v, ErrInvalidVersion
+	}
+	nums := []*int{&v.major, &v.minor, &v.patch}
+	wildcard := false
+	for i, c := range components {
+		if c == "x" || c == "X" || c == "*" {
+			wildcard = true
+			continue
+		}
+		if wildcard {
+			// Wildcards must be trailing.
+			return v, ErrInvalidVersion
+		}
+		n, err := strconv.Atoi(c)
+		if err != nil || n < 0 || (len(c) > 1 && c[0] == '0') {
+			return v, ErrInvalidVersion
+		}
+		*nums[i] = n
+		v.parts = i + 1
+	}
+	if v.pre != nil && v.parts < 3 {
+		return v, ErrInvalidVersion
+	}
+	return v, nil
+}
+
+// next returns the first version after all the versions matching the partial
+// version v. ok is false if v matches all versions.
+func (v version) next() (version, bool) {
+	n := version{parts: 3, major: v.major, minor: v.minor, patch: v.patch}
+	switch v.parts {
+	case 0:
+		return n, false
+	case 1:
+		n.major, n.minor, n.patch = v.major+1, 0, 0
+	case 2:
+		n.minor, n.patch = v.minor+1, 0
+	default:
+		n.patch = v.patch + 1
+	}
+	return n, true
+}
+
+// compare compares versions by semantic versioning precedence.
+func compare(a, b version) int {
+	for _, c := range [][2]int{{a.major, b.major}, {a.minor, b.minor}, {a.patch, b.patch}} {
+		if c[0] != c[1] {
+			if c[0] < c[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case a.pre == nil && b.pre == nil:
+		return 0
+	case a.pre == nil:
+		return 1
+	case b.pre == nil:
+		return -1
+	}
+	for i := 0; i < len(a.pre) && i < len(b.pre); i++ {
+		if c := compareIdent(a.pre[i], b.pre[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a.pre) < len(b.pre):
+		return -1
+	case len(a.pre) > len(b.pre):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareIdent compares prerelease identifiers.
+func compareIdent(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an == bn {
+			return 0
+		}
+		if an < bn {
+			return -1
+		}
+		return 1
+	case aErr == nil:
+		// Numeric identifiers have lower precedence.
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// term is the value of a node in the range tree.
+type term struct {
+	lexeme *lexparse.Lexeme
+
+	// version is the parsed version of a version node.
+	version version
+}
+
+// exprParser parses range expressions.
+var exprParser = &lexparse.ExprParser[*term]{
+	Operators: lexparse.NewOperatorTable().
+		Infix(orType, 1, lexparse.AssocLeft).
+		Infix(andType, 2, lexparse.AssocLeft).
+		Infix(hyphenType, 3, lexparse.AssocNone).
+		Prefix(eqType, 4).
+		Prefix(neType, 4).
+		Prefix(gtType, 4).
+		Prefix(geType, 4).
+		Prefix(ltType, 4).
+		Prefix(leType, 4).
+		Prefix(tildeType, 4).
+		Prefix(caretType, 4),
+	Value: func(l *lexparse.Lexeme) (*term, error) {
+		return &term{lexeme: l}, nil
+	},
+	Operand: parseOperand,
+}
+
+// parseOperand parses a version.
+func parseOperand(_ context.Context, p *lexparse.Parser[*term]) (*lexparse.Node[*term], error) {
+	l := p.Next()
+	if l == nil {
+		return nil, fmt.Errorf("%w: expected version", io.ErrUnexpectedEOF)
+	}
+	if l.Type != versionType {
+		return nil, &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+	}
+	v, err := parseVersion(l.Value)
+	if err != nil {
+		return nil, &lexparse.LexemeError{Err: ErrInvalidVersion, Lexeme: l}
+	}
+	return p.NewNode(&term{lexeme: l, version: v}), nil
+}
+
+// parseRange parses a range expression.
+func parseRange(ctx context.Context, p *lexparse.Parser[*term]) (lexparse.ParseFn[*term], error) {
+	_, err := exprParser.Parse(ctx, p)
+	return nil, err
+}
+
+// Range is a parsed version range.
+type Range struct {
+	root *lexparse.Node[*term]
+}
+
+// Parse parses a version range expression.
+func Parse(ctx context.Context, s string) (*Range, error) {
+	l := lexparse.NewLexer(runeio.NewReader(bufio.NewReader(strings.NewReader(s))), lexparse.StateFn(lexRange))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	p := lexparse.NewParser[*term](l.Lex(ctx))
+	p.SetLexerErr(l.Err)
+	p.SetRequireEOF(true)
+	_, pErr := p.Parse(ctx, parseRange)
+	cancel(pErr)
+
+	<-l.Done()
+
+	err := pErr
+	if lErr := l.Err(); lErr != nil && !errors.Is(lErr, context.Canceled) {
+		err = lErr
+	}
+	if err == nil {
+		err = check(p.Root().Children[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing range %q: %w", s, err)
+	}
+
+	return &Range{root: p.Root().Children[0]}, nil
+}
+
+// check verifies that comparator and hyphen operators are applied to
+// versions.
+func check(n *lexparse.Node[*term]) error {
+	switch n.Value.lexeme.Type {
+	case versionType:
+		return nil
+	case orType, andType:
+		for _, c := range n.Children {
+			if err := check(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		for _, c := range n.Children {
+			if c.Value.lexeme.Type != versionType {
+				return &lexparse.LexemeError{
+					Err:    lexparse.ErrUnexpectedLexeme,
+					Detail: "expected version",
+					Lexeme: c.Value.lexeme,
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// Check returns true if the version v satisfies the range.
+func (r *Range) Check(v string) (bool, error) {
+	ver, err := parseVersion(v)
+	if err != nil || ver.parts < 3 {
+		return false, fmt.Errorf("%w: %q", ErrInvalidVersion, v)
+	}
+	return eval(r.root, ver), nil
+}
+
+// eval returns true if v satisfies the range rooted at n.
+func eval(n *lexparse.Node[*term], v version) bool {
+	switch n.Value.lexeme.Type {
+	case orType:
+		return eval(n.Left(), v) || eval(n.Right(), v)
+	case andType:
+		return eval(n.Left(), v) && eval(n.Right(), v)
+	case hyphenType:
+		lo, hi := n.Left().Value.version, n.Right().Value.version
+		return compare(v, lo) >= 0 && matchLE(v, hi)
+	case versionType:
+		return matchEQ(v, n.Value.version)
+	}
+
+	c := n.Left().Value.version
+	switch n.Value.lexeme.Type {
+	case eqType:
+		return matchEQ(v, c)
+	case neType:
+		return !matchEQ(v, c)
+	case gtType:
+		if c.parts == 3 {
+			return compare(v, c) > 0
+		}
+		next, ok := c.next()
+		return ok && compare(v, next) >= 0
+	case geType:
+		return compare(v, c) >= 0
+	case ltType:
+		return c.parts > 0 && compare(v, c) < 0
+	case leType:
+		return matchLE(v, c)
+	case tildeType:
+		if c.parts == 3 {
+			c.parts = 2
+		}
+		return matchEQ(v, c) && compare(v, c) >= 0
+	default:
+		// Caret ranges allow changes that don't modify the left-most
+		// non-zero component.
+		caret := c
+		switch {
+		case c.major > 0 || c.parts <= 1:
+			caret.parts = 1
+		case c.minor > 0 || c.parts == 2:
+			caret.parts = 2
+		}
+		return matchEQ(v, caret) && compare(v, c) >= 0
+	}
+}
+
+// matchEQ returns true if v matches the possibly partial version c.
+func matchEQ(v, c version) bool {
+	if c.parts == 3 {
+		return compare(v, c) == 0
+	}
+	next, ok := c.next()
+	return compare(v, c) >= 0 && (!ok || compare(v, next) < 0)
+}
+
+// matchLE returns true if v is less than or equal to the possibly partial
+// version c.
+func matchLE(v, c version) bool {
+	if c.parts == 3 {
+		return compare(v, c) <= 0
+	}
+	next, ok := c.next()
+	return !ok || compare(v, next) < 0
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ianlewis/lexparse"
+)
+
+func TestRange_Check(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		rng     string
+		match   []string
+		noMatch []string
+	}{
+		"exact": {
+			rng:     "1.2.3",
+			match:   []string{"1.2.3", "v1.2.3", "1.2.3+build"},
+			noMatch: []string{"1.2.4", "1.2.3-beta"},
+		},
+		"partial": {
+			rng:     "=1.2",
+			match:   []string{"1.2.0", "1.2.99"},
+			noMatch: []string{"1.3.0", "1.1.9", "1.2.0-rc.1"},
+		},
+		"wildcard": {
+			rng:     "1.x",
+			match:   []string{"1.0.0", "1.9.9"},
+			noMatch: []string{"2.0.0", "0.9.0"},
+		},
+		"not equal": {
+			rng:     "!=1.2.3",
+			match:   []string{"1.2.4"},
+			noMatch: []string{"1.2.3"},
+		},
+		"comparators": {
+			rng:     ">=1.2.0 <2.0.0",
+			match:   []string{"1.2.0", "1.9.9"},
+			noMatch: []string{"1.1.9", "2.0.0"},
+		},
+		"commas": {
+			rng:     ">1.2.3, <=1.4",
+			match:   []string{"1.2.4", "1.4.9"},
+			noMatch: []string{"1.2.3", "1.5.0"},
+		},
+		"greater partial": {
+			rng:     ">1.2",
+			match:   []string{"1.3.0"},
+			noMatch: []string{"1.2.9"},
+		},
+		"or": {
+			rng:     ">=1.2.0 <2.0.0 || ~3.1",
+			match:   []string{"1.5.0", "3.1.0", "3.1.9"},
+			noMatch: []string{"2.5.0", "3.2.0"},
+		},
+		"tilde": {
+			rng:     "~1.2.3",
+			match:   []string{"1.2.3", "1.2.9"},
+			noMatch: []string{"1.2.2", "1.3.0"},
+		},
+		"tilde major": {
+			rng:     "~1",
+			match:   []string{"1.0.0", "1.9.0"},
+			noMatch: []string{"2.0.0"},
+		},
+		"caret": {
+			rng:     "^1.2.3",
+			match:   []string{"1.2.3", "1.9.0"},
+			noMatch: []string{"1.2.2", "2.0.0"},
+		},
+		"caret zero major": {
+			rng:     "^0.2.3",
+			match:   []string{"0.2.3", "0.2.9"},
+			noMatch: []string{"0.3.0"},
+		},
+		"caret zero minor": {
+			rng:     "^0.0.3",
+			match:   []string{"0.0.3"},
+			noMatch: []string{"0.0.4"},
+		},
+		"hyphen": {
+			rng:     "1.2.3 - 2.3",
+			match:   []string{"1.2.3", "2.3.9"},
+			noMatch: []string{"1.2.2", "2.4.0"},
+		},
+		"prerelease": {
+			rng:     ">=1.0.0-alpha.2 <1.0.0",
+			match:   []string{"1.0.0-alpha.10", "1.0.0-beta"},
+			noMatch: []string{"1.0.0-alpha.1", "1.0.0-1", "1.0.0"},
+		},
+		"any": {
+			rng:   "*",
+			match: []string{"0.0.0", "9.9.9"},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := Parse(context.Background(), tc.rng)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			for _, v := range tc.match {
+				if ok, err := r.Check(v); err != nil || !ok {
+					t.Errorf("Check(%q): want: true, got: %v, %v", v, ok, err)
+				}
+			}
+			for _, v := range tc.noMatch {
+				if ok, err := r.Check(v); err != nil || ok {
+					t.Errorf("Check(%q): want: false, got: %v, %v", v, ok, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		rng string
+		err error
+	}{
+		"empty": {
+			rng: "",
+			err: io.ErrUnexpectedEOF,
+		},
+		"invalid version": {
+			rng: ">=1.2.a",
+			err: ErrInvalidVersion,
+		},
+		"leading zero": {
+			rng: "01.2.3",
+			err: ErrInvalidVersion,
+		},
+		"unexpected char": {
+			rng: "1.2.3 @",
+			err: ErrUnexpectedChar,
+		},
+		"trailing or": {
+			rng: "1.2.3 ||",
+			err: io.ErrUnexpectedEOF,
+		},
+		"nested operators": {
+			rng: ">=<1.2.3",
+			err: lexparse.ErrUnexpectedLexeme,
+		},
+		"chained hyphen": {
+			rng: "1 - 2 - 3",
+			err: lexparse.ErrUnexpectedLexeme,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(context.Background(), tc.rng)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRange_Check_invalid(t *testing.T) {
+	t.Parallel()
+
+	r, err := Parse(context.Background(), "1.x")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	for _, v := range []string{"1.2", "1.2.x", "a.b.c"} {
+		if _, err := r.Check(v); !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("Check(%q): want: %v, got: %v", v, ErrInvalidVersion, err)
+		}
+	}
+}
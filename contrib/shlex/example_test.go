@@ -0,0 +1,39 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shlex_test
+
+import (
+	"fmt"
+
+	"github.com/ianlewis/lexparse/contrib/shlex"
+)
+
+func ExampleSplit() {
+	args, err := shlex.Split(`git commit -m "Fix \"quoted\" bug" --author='A. Person'`)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, arg := range args {
+		fmt.Println(arg)
+	}
+
+	// Output:
+	// git
+	// commit
+	// -m
+	// Fix "quoted" bug
+	// --author=A. Person
+}
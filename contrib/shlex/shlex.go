@@ -0,0 +1,203 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shlex splits command strings into arguments using lexparse,
+// following the quoting rules of POSIX shells.
+//
+// Arguments are separated by whitespace. Quoting is handled as follows:
+//
+//   - Characters in single quotes are taken literally.
+//   - In double quotes a backslash escapes '$', '`', '"', '\', and newline.
+//     Other backslashes are taken literally.
+//   - Outside of quotes a backslash escapes any character. A backslash
+//     followed by a newline is removed.
+//   - A '#' at the start of an argument starts a comment that continues to
+//     the end of the line.
+//
+// Unlike a shell, no expansion of variables, globs, or other special
+// characters is performed.
+package shlex
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrUnclosedQuote indicates a quoted string that is not terminated.
+	ErrUnclosedQuote = errors.New("unclosed quote")
+
+	// ErrTrailingEscape indicates a backslash at the end of the input.
+	ErrTrailingEscape = errors.New("trailing backslash")
+)
+
+const wordType lexparse.LexemeType = 0
+
+// spaceClass matches the whitespace separating arguments.
+var spaceClass = lexparse.NewRuneClass(" \t\r\n")
+
+// lexSpace lexes whitespace and comments between arguments.
+func lexSpace(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	if _, err := l.AdvanceWhile(spaceClass); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	l.Ignore()
+
+	rns, err := l.Peek(1)
+	if err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	if rns[0] == '#' {
+		if _, err := l.SkipTo([]string{"\n"}); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		return lexparse.StateFn(lexSpace), nil
+	}
+	return lexparse.StateFn(lexWord), nil
+}
+
+// lexWord lexes a single argument.
+func lexWord(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	var b strings.Builder
+	for {
+		rns, err := l.Peek(1)
+		if errors.Is(err, io.EOF) || (err == nil && spaceClass.Contains(rns[0])) {
+			break
+		}
+		if err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+
+		line, col := l.Line(), l.Column()
+		if _, err := l.Advance(1); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+
+		switch rns[0] {
+		case '\'':
+			if err := lexSingleQuoted(l, &b, line, col); err != nil {
+				return nil, err
+			}
+		case '"':
+			if err := lexDoubleQuoted(l, &b, line, col); err != nil {
+				return nil, err
+			}
+		case '\\':
+			rn, _, err := l.ReadRune()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil, posError(ErrTrailingEscape, line, col)
+				}
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return nil, err
+			}
+			if rn != '\n' {
+				b.WriteRune(rn)
+			}
+		default:
+			b.WriteRune(rns[0])
+		}
+	}
+
+	lexeme := l.Lexeme(wordType)
+	lexeme.Value = b.String()
+	l.Emit(lexeme)
+	return lexparse.StateFn(lexSpace), nil
+}
+
+// lexSingleQuoted lexes the rest of a single quoted string.
+func lexSingleQuoted(l *lexparse.Lexer, b *strings.Builder, line, col int) error {
+	for {
+		rn, _, err := l.ReadRune()
+		if err != nil {
+			return unclosed(err, line, col)
+		}
+		if rn == '\'' {
+			return nil
+		}
+		b.WriteRune(rn)
+	}
+}
+
+// lexDoubleQuoted lexes the rest of a double quoted string.
+func lexDoubleQuoted(l *lexparse.Lexer, b *strings.Builder, line, col int) error {
+	for {
+		rn, _, err := l.ReadRune()
+		if err != nil {
+			return unclosed(err, line, col)
+		}
+		switch rn {
+		case '"':
+			return nil
+		case '\\':
+			next, _, err := l.ReadRune()
+			if err != nil {
+				return unclosed(err, line, col)
+			}
+			switch next {
+			case '$', '`', '"', '\\':
+				b.WriteRune(next)
+			case '\n':
+			default:
+				b.WriteRune(rn)
+				b.WriteRune(next)
+			}
+		default:
+			b.WriteRune(rn)
+		}
+	}
+}
+
+// unclosed returns an ErrUnclosedQuote error for a quote at the given
+// position if err is io.EOF.
+func unclosed(err error, line, col int) error {
+	if errors.Is(err, io.EOF) {
+		return posError(ErrUnclosedQuote, line, col)
+	}
+	return err
+}
+
+// posError returns an error at the given position.
+func posError(err error, line, col int) error {
+	return fmt.Errorf("%w: line %d, column %d", err, line+1, col+1)
+}
+
+// Split splits the command string s into arguments.
+func Split(s string) ([]string, error) {
+	l := lexparse.NewLexer(runeio.NewReader(bufio.NewReader(strings.NewReader(s))), lexparse.StateFn(lexSpace))
+
+	args := []string{}
+	for lexeme := range l.Lex(context.Background()) {
+		args = append(args, lexeme.Value)
+	}
+	<-l.Done()
+	if err := l.Err(); err != nil {
+		return nil, fmt.Errorf("splitting command: %w", err)
+	}
+
+	return args, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shlex
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		want  []string
+	}{
+		"empty": {
+			input: "",
+			want:  []string{},
+		},
+		"whitespace": {
+			input: " \t\n ",
+			want:  []string{},
+		},
+		"words": {
+			input: "ls -la  /tmp\n",
+			want:  []string{"ls", "-la", "/tmp"},
+		},
+		"single quotes": {
+			input: `echo 'a "b" \c $d'`,
+			want:  []string{"echo", `a "b" \c $d`},
+		},
+		"double quotes": {
+			input: `echo "a 'b' \"c\" \$d \e \\"`,
+			want:  []string{"echo", `a 'b' "c" $d \e \`},
+		},
+		"adjacent quotes": {
+			input: `a"b c"'d e'f`,
+			want:  []string{"ab cd ef"},
+		},
+		"empty quotes": {
+			input: `a "" ''`,
+			want:  []string{"a", "", ""},
+		},
+		"escapes": {
+			input: `a\ b \'c\" \\`,
+			want:  []string{"a b", `'c"`, `\`},
+		},
+		"line continuation": {
+			input: "a \\\nb c\\\nd \"e\\\nf\"",
+			want:  []string{"a", "b", "cd", "ef"},
+		},
+		"comments": {
+			input: "a # comment\nb c#d",
+			want:  []string{"a", "b", "c#d"},
+		},
+		"unicode": {
+			input: "echo 'héllo wörld' 日本",
+			want:  []string{"echo", "héllo wörld", "日本"},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Split(tc.input)
+			if err != nil {
+				t.Fatalf("Split: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Split: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSplit_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		err   error
+		msg   string
+	}{
+		"unclosed single quote": {
+			input: "echo 'abc",
+			err:   ErrUnclosedQuote,
+			msg:   "line 1, column 6",
+		},
+		"unclosed double quote": {
+			input: "echo\n  \"abc\\\"",
+			err:   ErrUnclosedQuote,
+			msg:   "line 2, column 3",
+		},
+		"trailing escape": {
+			input: `echo abc\`,
+			err:   ErrTrailingEscape,
+			msg:   "line 1, column 9",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Split(tc.input)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Split: want: %v, got: %v", tc.err, err)
+			}
+			if !strings.Contains(err.Error(), tc.msg) {
+				t.Errorf("Split: want: %q in %q", tc.msg, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template_test
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/ianlewis/lexparse/contrib/template"
+)
+
+func Example() {
+	src := `{% for user in users %}{{ user | upper }}{% if user == "root" %} (admin){% endif %}
+{% endfor %}`
+
+	tmpl, err := template.Parse(context.Background(), strings.NewReader(src))
+	if err != nil {
+		panic(err)
+	}
+	if err := tmpl.Execute(os.Stdout, map[string]any{
+		"users": []string{"root", "gopher"},
+	}); err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// ROOT (admin)
+	// GOPHER
+}
@@ -0,0 +1,766 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package template implements a small text templating language using
+// lexparse.
+//
+// Text is copied to the output as-is. Output actions print the value of an
+// expression optionally passed through a pipeline of filters:
+//
+//	Hello, {{ name | upper }}!
+//
+// Tags control the structure of the output:
+//
+//	{% if admin and not (name == "root") %}...{% else %}...{% endif %}
+//	{% for item in items %}{{ item }}{% endfor %}
+//
+// Expressions consist of variables, string literals, the boolean literals true
+// and false, the operators ==, !=, and, or, not, and parentheses.
+package template
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrUnclosedAction indicates that an action or tag was not closed
+	// before the end of the input.
+	ErrUnclosedAction = errors.New("unclosed action")
+
+	// ErrUnexpectedChar indicates an invalid character in an action or tag.
+	ErrUnexpectedChar = errors.New("unexpected character")
+
+	// ErrUnclosedBlock indicates that an if or for block was not closed
+	// before the end of the input.
+	ErrUnclosedBlock = errors.New("unclosed block")
+
+	// ErrUndefined indicates that a variable is not defined.
+	ErrUndefined = errors.New("undefined variable")
+
+	// ErrUnknownFilter indicates that a filter is not defined.
+	ErrUnknownFilter = errors.New("unknown filter")
+
+	// ErrNotIterable indicates that the value in a for tag is not a
+	// supported slice type.
+	ErrNotIterable = errors.New("value is not iterable")
+
+	// ErrUnsupportedType indicates that the truth value of a value in a
+	// condition can't be determined because its type is not supported.
+	ErrUnsupportedType = errors.New("unsupported value type")
+)
+
+const (
+	textType lexparse.LexemeType = iota
+	outputOpenType
+	outputCloseType
+	tagOpenType
+	tagCloseType
+	identType
+	stringType
+	boolType
+	pipeType
+	lparenType
+	rparenType
+	eqType
+	neType
+	andType
+	orType
+	notType
+	ifType
+	elseType
+	endifType
+	forType
+	inType
+	endforType
+)
+
+const (
+	outputOpen  = "{{"
+	outputClose = "}}"
+	tagOpen     = "{%"
+	tagClose    = "%}"
+)
+
+// keywords maps keywords to their lexeme types.
+var keywords = map[string]lexparse.LexemeType{
+	"and":    andType,
+	"or":     orType,
+	"not":    notType,
+	"if":     ifType,
+	"else":   elseType,
+	"endif":  endifType,
+	"for":    forType,
+	"in":     inType,
+	"endfor": endforType,
+	"true":   boolType,
+	"false":  boolType,
+}
+
+var (
+	spaceClass = lexparse.RuneClassFunc(unicode.IsSpace)
+	identClass = lexparse.NewRuneClass("_", unicode.Letter, unicode.Digit)
+)
+
+// lexText lexes text outside of actions and tags.
+func lexText(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	token, err := l.Find([]string{outputOpen, tagOpen})
+	if lexeme := l.Lexeme(textType); lexeme.Value != "" {
+		l.Emit(lexeme)
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("lexing text: %w", err)
+	}
+
+	typ := outputOpenType
+	if token == tagOpen {
+		typ = tagOpenType
+	}
+	if _, err := l.Advance(len(token)); err != nil {
+		return nil, fmt.Errorf("lexing text: %w", err)
+	}
+	l.Emit(l.Lexeme(typ))
+	return lexparse.StateFn(lexAction), nil
+}
+
+// lexAction lexes a single lexeme inside an action or tag.
+func lexAction(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	if _, err := l.AdvanceWhile(spaceClass); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("lexing action: %w", err)
+	}
+	l.Ignore()
+
+	rns, err := l.Peek(2)
+	if len(rns) == 0 {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: line %d, column %d", ErrUnclosedAction, l.Line()+1, l.Column()+1)
+		}
+		return nil, fmt.Errorf("lexing action: %w", err)
+	}
+
+	switch s := string(rns); s {
+	case outputClose, tagClose:
+		typ := outputCloseType
+		if s == tagClose {
+			typ = tagCloseType
+		}
+		return lexparse.StateFn(lexText), emit(l, typ, 2)
+	case "==":
+		return lexparse.StateFn(lexAction), emit(l, eqType, 2)
+	case "!=":
+		return lexparse.StateFn(lexAction), emit(l, neType, 2)
+	}
+
+	switch rn := rns[0]; {
+	case rn == '|':
+		return lexparse.StateFn(lexAction), emit(l, pipeType, 1)
+	case rn == '(':
+		return lexparse.StateFn(lexAction), emit(l, lparenType, 1)
+	case rn == ')':
+		return lexparse.StateFn(lexAction), emit(l, rparenType, 1)
+	case rn == '"':
+		return lexString(l)
+	case identClass.Contains(rn):
+		if _, err := l.AdvanceWhile(identClass); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("lexing action: %w", err)
+		}
+		lexeme := l.Lexeme(identType)
+		if typ, ok := keywords[lexeme.Value]; ok {
+			lexeme.Type = typ
+		}
+		l.Emit(lexeme)
+		return lexparse.StateFn(lexAction), nil
+	default:
+		return nil, fmt.Errorf("%w: %q: line %d, column %d", ErrUnexpectedChar, rn, l.Line()+1, l.Column()+1)
+	}
+}
+
+// lexString lexes a double quoted string literal.
+func lexString(l *lexparse.Lexer) (lexparse.State, error) {
+	line, col := l.Line(), l.Column()
+	if _, err := l.Advance(1); err != nil {
+		return nil, fmt.Errorf("lexing string: %w", err)
+	}
+	for {
+		rn, _, err := l.ReadRune()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("%w: unterminated string: line %d, column %d", ErrUnclosedAction, line+1, col+1)
+			}
+			return nil, fmt.Errorf("lexing string: %w", err)
+		}
+		switch rn {
+		case '\\':
+			if _, err := l.Advance(1); err != nil {
+				return nil, fmt.Errorf("%w: unterminated string: line %d, column %d", ErrUnclosedAction, line+1, col+1)
+			}
+		case '"':
+			l.Emit(l.Lexeme(stringType))
+			return lexparse.StateFn(lexAction), nil
+		}
+	}
+}
+
+// emit advances the lexer n runes and emits a lexeme of the given type.
+func emit(l *lexparse.Lexer, typ lexparse.LexemeType, n int) error {
+	if _, err := l.Advance(n); err != nil {
+		return fmt.Errorf("lexing action: %w", err)
+	}
+	l.Emit(l.Lexeme(typ))
+	return nil
+}
+
+// kind is the kind of a node in the template tree.
+type kind int
+
+const (
+	textKind kind = iota
+	outputKind
+	ifKind
+	forKind
+	blockKind
+	varKind
+	stringKind
+	boolKind
+	opKind
+)
+
+// item is the value of a node in the template tree.
+type item struct {
+	kind kind
+
+	// value is the text of a text node, the name of a variable or the loop
+	// variable of a for node, the value of a literal, or the operator.
+	value string
+
+	// filters are the filters applied to an output node.
+	filters []string
+}
+
+// exprKinds are the kinds of expression nodes.
+var exprKinds = []kind{varKind, stringKind, boolKind, opKind}
+
+// contentKinds are the kinds of nodes in the template body and blocks.
+var contentKinds = []kind{textKind, outputKind, ifKind, forKind}
+
+// schema describes a valid template tree. The executor relies on the
+// children of each node matching the schema.
+var schema = lexparse.NewSchema(func(v *item) kind { return v.kind }).
+	Root(lexparse.Many(contentKinds...)).
+	Node(textKind).
+	Node(outputKind, lexparse.One(exprKinds...)).
+	Node(ifKind, lexparse.One(exprKinds...), lexparse.One(blockKind), lexparse.Optional(blockKind)).
+	Node(forKind, lexparse.One(exprKinds...), lexparse.One(blockKind)).
+	Node(blockKind, lexparse.Many(contentKinds...)).
+	Node(varKind).
+	Node(stringKind).
+	Node(boolKind).
+	Node(opKind, lexparse.One(exprKinds...), lexparse.Optional(exprKinds...))
+
+// exprParser parses expressions in output actions and tags.
+var exprParser = newExprParser()
+
+// newExprParser returns a new parser for expressions.
+func newExprParser() *lexparse.ExprParser[*item] {
+	e := &lexparse.ExprParser[*item]{
+		Operators: lexparse.NewOperatorTable().
+			Infix(orType, 1, lexparse.AssocLeft).
+			Infix(andType, 2, lexparse.AssocLeft).
+			Infix(eqType, 3, lexparse.AssocNone).
+			Infix(neType, 3, lexparse.AssocNone).
+			Prefix(notType, 4),
+		Value: func(l *lexparse.Lexeme) (*item, error) {
+			return &item{kind: opKind, value: l.Value}, nil
+		},
+	}
+	e.Operand = func(ctx context.Context, p *lexparse.Parser[*item]) (*lexparse.Node[*item], error) {
+		return parseOperand(ctx, p, e)
+	}
+	return e
+}
+
+// parseOperand parses a variable, literal, or parenthesized expression.
+func parseOperand(
+	ctx context.Context,
+	p *lexparse.Parser[*item],
+	e *lexparse.ExprParser[*item],
+) (*lexparse.Node[*item], error) {
+	l := p.Next()
+	if l == nil {
+		return nil, fmt.Errorf("%w: expected operand", io.ErrUnexpectedEOF)
+	}
+
+	switch l.Type {
+	case identType:
+		return p.NewNode(&item{kind: varKind, value: l.Value}), nil
+	case boolType:
+		return p.NewNode(&item{kind: boolKind, value: l.Value}), nil
+	case stringType:
+		s, err := strconv.Unquote(l.Value)
+		if err != nil {
+			return nil, &lexparse.LexemeError{
+				Err:    lexparse.ErrUnexpectedLexeme,
+				Detail: "invalid string",
+				Lexeme: l,
+			}
+		}
+		return p.NewNode(&item{kind: stringKind, value: s}), nil
+	case lparenType:
+		n, err := e.Expr(ctx, p, 0)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := expect(p, rparenType); err != nil {
+			return nil, err
+		}
+		return n, nil
+	default:
+		return nil, unexpected(l)
+	}
+}
+
+// expect consumes the next lexeme and returns an error if it is not of the
+// given type.
+func expect(p *lexparse.Parser[*item], typ lexparse.LexemeType) (*lexparse.Lexeme, error) {
+	l := p.Next()
+	if l == nil {
+		return nil, fmt.Errorf("%w: parsing template", io.ErrUnexpectedEOF)
+	}
+	if l.Type != typ {
+		return nil, unexpected(l)
+	}
+	return l, nil
+}
+
+// unexpected returns an error for an unexpected lexeme.
+func unexpected(l *lexparse.Lexeme) error {
+	return &lexparse.LexemeError{
+		Err:    lexparse.ErrUnexpectedLexeme,
+		Lexeme: l,
+	}
+}
+
+// parseContent parses text, output actions, and tags.
+func parseContent(_ context.Context, p *lexparse.Parser[*item]) (lexparse.ParseFn[*item], error) {
+	l := p.Peek()
+	if l == nil {
+		if n := p.Pos(); n.Value != nil {
+			// The current node is the block of an unclosed if or for tag.
+			return nil, fmt.Errorf("%w: line %d, column %d", ErrUnclosedBlock, n.Parent.Line+1, n.Parent.Column+1)
+		}
+		return nil, nil
+	}
+
+	switch l.Type {
+	case textType:
+		_ = p.Next()
+		p.Node(&item{kind: textKind, value: l.Value})
+		return parseContent, nil
+	case outputOpenType:
+		return parseOutput, nil
+	case tagOpenType:
+		return parseTag, nil
+	default:
+		return nil, unexpected(l)
+	}
+}
+
+// parseOutput parses an output action (e.g. {{ name | upper }}).
+func parseOutput(ctx context.Context, p *lexparse.Parser[*item]) (lexparse.ParseFn[*item], error) {
+	_ = p.Next()
+	out := &item{kind: outputKind}
+	p.Push(out)
+	if _, err := exprParser.Parse(ctx, p); err != nil {
+		return nil, err
+	}
+
+	for {
+		l := p.Next()
+		if l == nil {
+			return nil, fmt.Errorf("%w: parsing output", io.ErrUnexpectedEOF)
+		}
+		switch l.Type {
+		case outputCloseType:
+			p.Climb()
+			return parseContent, nil
+		case pipeType:
+			name, err := expect(p, identType)
+			if err != nil {
+				return nil, err
+			}
+			out.filters = append(out.filters, name.Value)
+		default:
+			return nil, unexpected(l)
+		}
+	}
+}
+
+// parseTag parses an if, else, endif, for, or endfor tag.
+func parseTag(ctx context.Context, p *lexparse.Parser[*item]) (lexparse.ParseFn[*item], error) {
+	_ = p.Next()
+	l := p.Next()
+	if l == nil {
+		return nil, fmt.Errorf("%w: parsing tag", io.ErrUnexpectedEOF)
+	}
+
+	switch l.Type {
+	case ifType:
+		p.Push(&item{kind: ifKind})
+		if _, err := exprParser.Parse(ctx, p); err != nil {
+			return nil, err
+		}
+	case elseType:
+		// else is only valid in the first block of an if tag.
+		if !inBlock(p, ifKind) || len(p.Pos().Parent.Children) != 2 {
+			return nil, unexpected(l)
+		}
+		p.Climb()
+	case endifType:
+		if !inBlock(p, ifKind) {
+			return nil, unexpected(l)
+		}
+		p.Climb()
+		p.Climb()
+	case forType:
+		name, err := expect(p, identType)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := expect(p, inType); err != nil {
+			return nil, err
+		}
+		p.Push(&item{kind: forKind, value: name.Value})
+		if _, err := exprParser.Parse(ctx, p); err != nil {
+			return nil, err
+		}
+	case endforType:
+		if !inBlock(p, forKind) {
+			return nil, unexpected(l)
+		}
+		p.Climb()
+		p.Climb()
+	default:
+		return nil, unexpected(l)
+	}
+
+	if _, err := expect(p, tagCloseType); err != nil {
+		return nil, err
+	}
+	if t := l.Type; t == ifType || t == elseType || t == forType {
+		p.Push(&item{kind: blockKind})
+	}
+	return parseContent, nil
+}
+
+// inBlock returns true if the current node is a block of a tag of kind k.
+func inBlock(p *lexparse.Parser[*item], k kind) bool {
+	n := p.Pos()
+	return n.Value != nil && n.Value.kind == blockKind && n.Parent.Value.kind == k
+}
+
+// Filter transforms the string value of an output action.
+type Filter func(string) string
+
+// defaultFilters are the filters available to all templates.
+var defaultFilters = map[string]Filter{
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+	"trim":   strings.TrimSpace,
+	"escape": html.EscapeString,
+}
+
+// Template is a parsed template.
+type Template struct {
+	root    *lexparse.Node[*item]
+	filters map[string]Filter
+}
+
+// Parse parses a template read from r.
+func Parse(ctx context.Context, r io.Reader) (*Template, error) {
+	root, err := lexparse.LexParse(
+		ctx,
+		runeio.NewReader(bufio.NewReader(r)),
+		lexparse.StateFn(lexText),
+		parseContent,
+	)
+	if err == nil {
+		err = lexparse.Validate(root, schema)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	return &Template{
+		root:    root,
+		filters: map[string]Filter{},
+	}, nil
+}
+
+// Filter adds a filter to the template. It replaces any existing filter with
+// the same name including the default filters upper, lower, trim, and escape.
+// The template is returned.
+func (t *Template) Filter(name string, f Filter) *Template {
+	t.filters[name] = f
+	return t
+}
+
+// Execute renders the template with the given data and writes the output to
+// w. The values of variables are printed using fmt.Sprint. Values are
+// considered true in conditions unless they are false, nil, zero, or empty.
+// Conditions support bools, strings, ints, int64s, float64s, maps with string
+// keys and any or string values, and the slice types supported by for tags:
+// []any, []string, []int, []float64, []bool, and []map[string]any. Values
+// are compared by their printed representation.
+func (t *Template) Execute(w io.Writer, data map[string]any) error {
+	e := &executor{
+		w:       w,
+		filters: t.filters,
+	}
+	return e.block(t.root, &scope{vars: data})
+}
+
+// scope is a set of variables. Loop variables are defined in a new scope.
+type scope struct {
+	vars   map[string]any
+	parent *scope
+}
+
+// lookup returns the value of the named variable.
+func (s *scope) lookup(name string) (any, bool) {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// executor renders a template tree.
+type executor struct {
+	w       io.Writer
+	filters map[string]Filter
+}
+
+// block renders the children of n.
+func (e *executor) block(n *lexparse.Node[*item], s *scope) error {
+	for _, c := range n.Children {
+		if err := e.node(c, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// node renders a text, output, if, or for node.
+func (e *executor) node(n *lexparse.Node[*item], s *scope) error {
+	switch n.Value.kind {
+	case textKind:
+		return e.write(n.Value.value)
+	case outputKind:
+		return e.output(n, s)
+	case ifKind:
+		v, err := eval(n.Children[0], s)
+		if err != nil {
+			return err
+		}
+		ok, err := truthy(n.Children[0], v)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return e.block(n.Children[1], s)
+		}
+		if len(n.Children) > 2 {
+			return e.block(n.Children[2], s)
+		}
+		return nil
+	case forKind:
+		v, err := eval(n.Children[0], s)
+		if err != nil {
+			return err
+		}
+		elems, ok := iterable(v)
+		if !ok {
+			return fmt.Errorf("%w: %T: line %d, column %d", ErrNotIterable, v, n.Line+1, n.Column+1)
+		}
+		for _, elem := range elems {
+			loop := &scope{
+				vars:   map[string]any{n.Value.value: elem},
+				parent: s,
+			}
+			if err := e.block(n.Children[1], loop); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// output renders an output node.
+func (e *executor) output(n *lexparse.Node[*item], s *scope) error {
+	v, err := eval(n.Children[0], s)
+	if err != nil {
+		return err
+	}
+	str := fmt.Sprint(v)
+	for _, name := range n.Value.filters {
+		f, ok := e.filters[name]
+		if !ok {
+			f, ok = defaultFilters[name]
+		}
+		if !ok {
+			return fmt.Errorf("%w: %q: line %d, column %d", ErrUnknownFilter, name, n.Line+1, n.Column+1)
+		}
+		str = f(str)
+	}
+	return e.write(str)
+}
+
+// write writes s to the output.
+func (e *executor) write(s string) error {
+	if _, err := io.WriteString(e.w, s); err != nil {
+		return fmt.Errorf("executing template: %w", err)
+	}
+	return nil
+}
+
+// eval evaluates an expression.
+func eval(n *lexparse.Node[*item], s *scope) (any, error) {
+	switch n.Value.kind {
+	case varKind:
+		v, ok := s.lookup(n.Value.value)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q: line %d, column %d", ErrUndefined, n.Value.value, n.Line+1, n.Column+1)
+		}
+		return v, nil
+	case stringKind:
+		return n.Value.value, nil
+	case boolKind:
+		return n.Value.value == "true", nil
+	}
+
+	left, err := eval(n.Left(), s)
+	if err != nil {
+		return nil, err
+	}
+	switch n.Value.value {
+	case "not", "and", "or":
+		l, err := truthy(n.Left(), left)
+		if err != nil {
+			return nil, err
+		}
+		if n.Value.value == "not" {
+			return !l, nil
+		}
+		if l == (n.Value.value == "or") {
+			return l, nil
+		}
+	}
+
+	right, err := eval(n.Right(), s)
+	if err != nil {
+		return nil, err
+	}
+	switch n.Value.value {
+	case "==":
+		return fmt.Sprint(left) == fmt.Sprint(right), nil
+	case "!=":
+		return fmt.Sprint(left) != fmt.Sprint(right), nil
+	default:
+		return truthy(n.Right(), right)
+	}
+}
+
+// iterable returns the elements of v if it is a slice that can be iterated
+// over by a for tag.
+func iterable(v any) ([]any, bool) {
+	switch x := v.(type) {
+	case []any:
+		return x, true
+	case []string:
+		return toAny(x), true
+	case []int:
+		return toAny(x), true
+	case []float64:
+		return toAny(x), true
+	case []bool:
+		return toAny(x), true
+	case []map[string]any:
+		return toAny(x), true
+	default:
+		return nil, false
+	}
+}
+
+// toAny returns the elements of s as a []any.
+func toAny[T any](s []T) []any {
+	elems := make([]any, 0, len(s))
+	for _, e := range s {
+		elems = append(elems, e)
+	}
+	return elems
+}
+
+// truthy returns the truth value of the value v of the expression n. It
+// returns an error wrapping ErrUnsupportedType if the type of v is not
+// supported.
+func truthy(n *lexparse.Node[*item], v any) (bool, error) {
+	switch x := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return x, nil
+	case string:
+		return x != "", nil
+	case int:
+		return x != 0, nil
+	case int64:
+		return x != 0, nil
+	case float64:
+		return x != 0, nil
+	case []any:
+		return len(x) > 0, nil
+	case []string:
+		return len(x) > 0, nil
+	case []int:
+		return len(x) > 0, nil
+	case []float64:
+		return len(x) > 0, nil
+	case []bool:
+		return len(x) > 0, nil
+	case []map[string]any:
+		return len(x) > 0, nil
+	case map[string]any:
+		return len(x) > 0, nil
+	case map[string]string:
+		return len(x) > 0, nil
+	default:
+		return false, fmt.Errorf("%w: %T: line %d, column %d", ErrUnsupportedType, v, n.Line+1, n.Column+1)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/lexparse"
+)
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{
+		"name":  "gopher",
+		"admin": true,
+		"role":  "dev",
+		"items": []string{"a", "b", "c"},
+		"empty": []int{},
+		"count": 0,
+		"users": []map[string]any{{"name": "a"}, {"name": "b"}},
+		"mixed": []any{1, "x", true},
+	}
+
+	testCases := map[string]struct {
+		tmpl string
+		want string
+	}{
+		"text": {
+			tmpl: "Hello, 世界",
+			want: "Hello, 世界",
+		},
+		"output": {
+			tmpl: "Hello, {{ name }}!",
+			want: "Hello, gopher!",
+		},
+		"filters": {
+			tmpl: `{{ name | upper }} {{ " x " | trim | upper }}`,
+			want: "GOPHER X",
+		},
+		"if": {
+			tmpl: "{% if admin %}yes{% endif %}",
+			want: "yes",
+		},
+		"if else": {
+			tmpl: "{% if count %}yes{% else %}no{% endif %}",
+			want: "no",
+		},
+		"boolean expression": {
+			tmpl: `{% if admin and not (role == "ops" or name != "gopher") %}ok{% endif %}`,
+			want: "ok",
+		},
+		"precedence": {
+			tmpl: `{% if true or false and false %}ok{% endif %}`,
+			want: "ok",
+		},
+		"for": {
+			tmpl: "{% for item in items %}[{{ item }}]{% endfor %}",
+			want: "[a][b][c]",
+		},
+		"for maps": {
+			tmpl: "{% for user in users %}{% if user %}+{% endif %}{% endfor %}",
+			want: "++",
+		},
+		"for any": {
+			tmpl: "{% for v in mixed %}[{{ v }}]{% endfor %}",
+			want: "[1][x][true]",
+		},
+		"empty for": {
+			tmpl: "{% for item in empty %}[{{ item }}]{% endfor %}",
+			want: "",
+		},
+		"nested": {
+			tmpl: `{% for item in items %}{% if item == "b" %}{{ item | upper }}{% else %}{{ item }}{% endif %}{% endfor %}`,
+			want: "aBc",
+		},
+		"shadowing": {
+			tmpl: "{% for name in items %}{{ name }}{% endfor %}{{ name }}",
+			want: "abcgopher",
+		},
+		"string escapes": {
+			tmpl: `{{ "say \"hi\"" | escape }}`,
+			want: "say &#34;hi&#34;",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := Parse(context.Background(), strings.NewReader(tc.tmpl))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			var b strings.Builder
+			if err := tmpl.Execute(&b, data); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, b.String()); diff != "" {
+				t.Errorf("Execute: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTemplate_Filter(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := Parse(context.Background(), strings.NewReader("{{ name | upper | exclaim }}"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	tmpl.Filter("exclaim", func(s string) string { return s + "!" })
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, map[string]any{"name": "gopher"}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got, want := b.String(), "GOPHER!"; got != want {
+		t.Errorf("Execute: want: %q, got: %q", want, got)
+	}
+}
+
+func TestParse_error(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		tmpl string
+		err  error
+	}{
+		"unclosed action": {
+			tmpl: "Hello, {{ name",
+			err:  ErrUnclosedAction,
+		},
+		"unterminated string": {
+			tmpl: `{{ "name }}`,
+			err:  ErrUnclosedAction,
+		},
+		"unexpected char": {
+			tmpl: "{{ name + 1 }}",
+			err:  ErrUnexpectedChar,
+		},
+		"unclosed block": {
+			tmpl: "{% if name %}",
+			err:  ErrUnclosedBlock,
+		},
+		"unmatched endif": {
+			tmpl: "{% for x in items %}{% endif %}",
+			err:  lexparse.ErrUnexpectedLexeme,
+		},
+		"double else": {
+			tmpl: "{% if x %}{% else %}{% else %}{% endif %}",
+			err:  lexparse.ErrUnexpectedLexeme,
+		},
+		"missing operand": {
+			tmpl: "{% if x and %}{% endif %}",
+			err:  lexparse.ErrUnexpectedLexeme,
+		},
+		"non-associative": {
+			tmpl: "{% if a == b == c %}{% endif %}",
+			err:  lexparse.ErrUnexpectedLexeme,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(context.Background(), strings.NewReader(tc.tmpl))
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestExecute_error(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		tmpl string
+		err  error
+	}{
+		"undefined": {
+			tmpl: "{{ missing }}",
+			err:  ErrUndefined,
+		},
+		"unknown filter": {
+			tmpl: "{{ name | reverse }}",
+			err:  ErrUnknownFilter,
+		},
+		"not iterable": {
+			tmpl: "{% for x in name %}{% endfor %}",
+			err:  ErrNotIterable,
+		},
+		"unsupported type": {
+			tmpl: "{% if ptr %}x{% endif %}",
+			err:  ErrUnsupportedType,
+		},
+		"unsupported operand": {
+			tmpl: "{{ not ptr }}",
+			err:  ErrUnsupportedType,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := Parse(context.Background(), strings.NewReader(tc.tmpl))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			err = tmpl.Execute(&strings.Builder{}, map[string]any{"name": "gopher", "ptr": new(int)})
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Execute: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timefmt_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ianlewis/lexparse/contrib/timefmt"
+)
+
+func Example() {
+	lt, err := timefmt.Parse(context.Background(), "%a, %-d %b %Y %H:%M")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, tok := range lt.Tokens() {
+		if tok.Kind == timefmt.DirectiveKind {
+			fmt.Printf("directive %s at column %d\n", tok.Value, tok.Column+1)
+		}
+	}
+	fmt.Println(lt.Format(time.Date(2024, time.March, 5, 7, 8, 0, 0, time.UTC)))
+
+	// Output:
+	// directive a at column 1
+	// directive d at column 5
+	// directive b at column 9
+	// directive Y at column 12
+	// directive H at column 15
+	// directive M at column 18
+	// Tue, 5 Mar 2024 07:08
+}
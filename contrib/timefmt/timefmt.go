@@ -0,0 +1,276 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timefmt implements a parser for strftime-like time format strings
+// using lexparse.
+//
+// A format string consists of literal text and directives starting with
+// '%'. A directive may have a flag between the '%' and the directive
+// character which changes the padding of numeric values: '-' removes padding
+// and '_' pads with spaces.
+//
+//	%Y-%m-%d %H:%M:%S    2006-01-02 15:04:05
+//	%a, %-d %b %Y        Mon, 2 Jan 2006
+//
+// The supported directives are:
+//
+//	%a  abbreviated weekday name       %A  full weekday name
+//	%b  abbreviated month name         %B  full month name
+//	%h  same as %b                     %p  AM or PM
+//	%Y  year                           %y  year without century
+//	%m  month                          %d  day of the month
+//	%e  space padded day of the month  %j  day of the year
+//	%H  hour (24 hour clock)           %I  hour (12 hour clock)
+//	%M  minute                         %S  second
+//	%f  microseconds                   %s  seconds since the Unix epoch
+//	%Z  time zone abbreviation         %z  time zone offset
+//	%n  newline                        %t  tab
+//	%%  a literal '%'
+//
+// Format strings are parsed into a list of tokens which can be used by
+// formatters, or used to format times with Format.
+package timefmt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+var (
+	// ErrUnknownDirective indicates a directive that is not supported.
+	ErrUnknownDirective = errors.New("unknown directive")
+
+	// ErrIncomplete indicates a '%' or flag at the end of the format string.
+	ErrIncomplete = errors.New("incomplete directive")
+)
+
+const (
+	literalType lexparse.LexemeType = iota
+	directiveType
+)
+
+// Kind is the kind of a token.
+type Kind int
+
+const (
+	// LiteralKind is literal text.
+	LiteralKind Kind = iota
+
+	// DirectiveKind is a directive.
+	DirectiveKind
+)
+
+// Token is a token of a format string.
+type Token struct {
+	Kind Kind
+
+	// Value is the text of a literal or the directive character of a
+	// directive. The "%%" directive is the literal "%".
+	Value string
+
+	// Flag is the flag of a directive, or zero if there is none.
+	Flag rune
+
+	// Pos is the position of the token in the format string in runes.
+	Pos int
+
+	// Column is the column of the token in the format string.
+	Column int
+}
+
+// directive describes how a directive is formatted.
+type directive struct {
+	// layout is the Go layout of a textual directive.
+	layout string
+
+	// num returns the value of a numeric directive.
+	num func(t time.Time) int64
+
+	// width is the padded width of a numeric directive.
+	width int
+
+	// pad is the default padding character of a numeric directive.
+	pad byte
+}
+
+// directives are the supported directives.
+var directives = map[rune]directive{
+	'a': {layout: "Mon"},
+	'A': {layout: "Monday"},
+	'b': {layout: "Jan"},
+	'B': {layout: "January"},
+	'h': {layout: "Jan"},
+	'p': {layout: "PM"},
+	'Z': {layout: "MST"},
+	'z': {layout: "-0700"},
+	'n': {layout: "\n"},
+	't': {layout: "\t"},
+	'Y': {num: func(t time.Time) int64 { return int64(t.Year()) }, width: 4, pad: '0'},
+	'y': {num: func(t time.Time) int64 { return int64(t.Year() % 100) }, width: 2, pad: '0'},
+	'm': {num: func(t time.Time) int64 { return int64(t.Month()) }, width: 2, pad: '0'},
+	'd': {num: func(t time.Time) int64 { return int64(t.Day()) }, width: 2, pad: '0'},
+	'e': {num: func(t time.Time) int64 { return int64(t.Day()) }, width: 2, pad: ' '},
+	'j': {num: func(t time.Time) int64 { return int64(t.YearDay()) }, width: 3, pad: '0'},
+	'H': {num: func(t time.Time) int64 { return int64(t.Hour()) }, width: 2, pad: '0'},
+	'I': {num: hour12, width: 2, pad: '0'},
+	'M': {num: func(t time.Time) int64 { return int64(t.Minute()) }, width: 2, pad: '0'},
+	'S': {num: func(t time.Time) int64 { return int64(t.Second()) }, width: 2, pad: '0'},
+	'f': {num: func(t time.Time) int64 { return int64(t.Nanosecond() / 1000) }, width: 6, pad: '0'},
+	's': {num: func(t time.Time) int64 { return t.Unix() }},
+}
+
+// hour12 returns the hour of t on a 12 hour clock.
+func hour12(t time.Time) int64 {
+	h := t.Hour() % 12
+	if h == 0 {
+		h = 12
+	}
+	return int64(h)
+}
+
+// lexText lexes literal text up to the next directive.
+func lexText(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	_, err := l.Find([]string{"%"})
+	if lexeme := l.Lexeme(literalType); lexeme.Value != "" {
+		l.Emit(lexeme)
+	}
+	if err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	return lexparse.StateFn(lexDirective), nil
+}
+
+// lexDirective lexes a directive including its optional flag.
+func lexDirective(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	rns, _ := l.Peek(3)
+	n := 2
+	if len(rns) > 1 && (rns[1] == '-' || rns[1] == '_') {
+		n = 3
+	}
+	if len(rns) < n {
+		if _, err := l.Advance(len(rns)); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		return nil, &lexparse.LexemeError{Err: ErrIncomplete, Lexeme: l.Lexeme(directiveType)}
+	}
+
+	if _, err := l.Advance(n); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	lexeme := l.Lexeme(directiveType)
+	if c := rns[n-1]; c != '%' || n != 2 {
+		if _, ok := directives[c]; !ok {
+			return nil, &lexparse.LexemeError{Err: ErrUnknownDirective, Lexeme: lexeme}
+		}
+	}
+	l.Emit(lexeme)
+	return lexparse.StateFn(lexText), nil
+}
+
+// Layout is a parsed format string.
+type Layout struct {
+	tokens []Token
+}
+
+// Parse parses a format string.
+func Parse(ctx context.Context, format string) (*Layout, error) {
+	l := lexparse.NewLexer(runeio.NewReader(bufio.NewReader(strings.NewReader(format))), lexparse.StateFn(lexText))
+
+	var tokens []Token
+	for lexeme := range l.Lex(ctx) {
+		tokens = append(tokens, newToken(lexeme))
+	}
+	<-l.Done()
+	if err := l.Err(); err != nil {
+		return nil, fmt.Errorf("parsing format: %w", err)
+	}
+
+	return &Layout{tokens: tokens}, nil
+}
+
+// newToken returns the token for a lexeme.
+func newToken(l *lexparse.Lexeme) Token {
+	tok := Token{
+		Kind:   LiteralKind,
+		Value:  l.Value,
+		Pos:    l.Pos,
+		Column: l.Column,
+	}
+	if l.Type != directiveType {
+		return tok
+	}
+
+	if l.Value == "%%" {
+		tok.Value = "%"
+		return tok
+	}
+	tok.Kind = DirectiveKind
+	rns := []rune(l.Value)
+	tok.Value = string(rns[len(rns)-1])
+	if len(rns) == 3 {
+		tok.Flag = rns[1]
+	}
+	return tok
+}
+
+// Tokens returns the tokens of the format string. Adjacent literal tokens are
+// not merged.
+func (lt *Layout) Tokens() []Token {
+	return lt.tokens
+}
+
+// Format returns t formatted according to the layout.
+func (lt *Layout) Format(t time.Time) string {
+	var b strings.Builder
+	for _, tok := range lt.tokens {
+		if tok.Kind == LiteralKind {
+			b.WriteString(tok.Value)
+			continue
+		}
+
+		d := directives[[]rune(tok.Value)[0]]
+		if d.num == nil {
+			b.WriteString(t.Format(d.layout))
+			continue
+		}
+
+		s := strconv.FormatInt(d.num(t), 10)
+		pad := d.pad
+		switch tok.Flag {
+		case '-':
+			pad = 0
+		case '_':
+			pad = ' '
+		}
+		if pad != 0 {
+			for i := len(s); i < d.width; i++ {
+				b.WriteByte(pad)
+			}
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
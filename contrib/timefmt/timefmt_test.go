@@ -0,0 +1,186 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timefmt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/lexparse"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		format string
+		want   []Token
+	}{
+		"empty": {
+			format: "",
+		},
+		"literal": {
+			format: "date",
+			want:   []Token{{Kind: LiteralKind, Value: "date"}},
+		},
+		"directives": {
+			format: "%Y-%m",
+			want: []Token{
+				{Kind: DirectiveKind, Value: "Y"},
+				{Kind: LiteralKind, Value: "-", Pos: 2, Column: 2},
+				{Kind: DirectiveKind, Value: "m", Pos: 3, Column: 3},
+			},
+		},
+		"flags": {
+			format: "%-d/%_H",
+			want: []Token{
+				{Kind: DirectiveKind, Value: "d", Flag: '-'},
+				{Kind: LiteralKind, Value: "/", Pos: 3, Column: 3},
+				{Kind: DirectiveKind, Value: "H", Flag: '_', Pos: 4, Column: 4},
+			},
+		},
+		"percent": {
+			format: "100%%",
+			want: []Token{
+				{Kind: LiteralKind, Value: "100"},
+				{Kind: LiteralKind, Value: "%", Pos: 3, Column: 3},
+			},
+		},
+		"unicode": {
+			format: "年%Y",
+			want: []Token{
+				{Kind: LiteralKind, Value: "年"},
+				{Kind: DirectiveKind, Value: "Y", Pos: 1, Column: 1},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lt, err := Parse(context.Background(), tc.format)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, lt.Tokens()); diff != "" {
+				t.Errorf("Tokens: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		format string
+		err    error
+		value  string
+		column int
+	}{
+		"unknown": {
+			format: "%Y-%Q",
+			err:    ErrUnknownDirective,
+			value:  "%Q",
+			column: 3,
+		},
+		"unknown with flag": {
+			format: "%-%",
+			err:    ErrUnknownDirective,
+			value:  "%-%",
+		},
+		"trailing percent": {
+			format: "%H:%",
+			err:    ErrIncomplete,
+			value:  "%",
+			column: 3,
+		},
+		"trailing flag": {
+			format: "%H:%-",
+			err:    ErrIncomplete,
+			value:  "%-",
+			column: 3,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(context.Background(), tc.format)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Parse: want: %v, got: %v", tc.err, err)
+			}
+			var lErr *lexparse.LexemeError
+			if !errors.As(err, &lErr) {
+				t.Fatalf("Parse: want: *lexparse.LexemeError, got: %v", err)
+			}
+			if got, want := lErr.Lexeme.Value, tc.value; got != want {
+				t.Errorf("Value: want: %q, got: %q", want, got)
+			}
+			if got, want := lErr.Lexeme.Column, tc.column; got != want {
+				t.Errorf("Column: want: %v, got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestLayout_Format(t *testing.T) {
+	t.Parallel()
+
+	tm := time.Date(2024, time.March, 5, 7, 8, 9, 123456789, time.FixedZone("JST", 9*60*60))
+
+	testCases := map[string]struct {
+		format string
+		want   string
+	}{
+		"date":         {format: "%Y-%m-%d", want: "2024-03-05"},
+		"time":         {format: "%H:%M:%S.%f", want: "07:08:09.123456"},
+		"names":        {format: "%a %A %b %B %h", want: "Tue Tuesday Mar March Mar"},
+		"twelve hour":  {format: "%I %p", want: "07 AM"},
+		"short year":   {format: "%y", want: "24"},
+		"day of year":  {format: "%j", want: "065"},
+		"space padded": {format: "%e", want: " 5"},
+		"no padding":   {format: "%-m/%-d %-H", want: "3/5 7"},
+		"space flag":   {format: "%_m", want: " 3"},
+		"zone":         {format: "%Z %z", want: "JST +0900"},
+		"unix":         {format: "%s", want: "1709590089"},
+		"whitespace":   {format: "a%nb%tc", want: "a\nb\tc"},
+		"percent":      {format: "%d%%", want: "05%"},
+		"literal":      {format: "2006-01-02", want: "2006-01-02"},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lt, err := Parse(context.Background(), tc.format)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := lt.Format(tm); got != tc.want {
+				t.Errorf("Format: want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Coverage records how many times each lexer State and named parse state is
+// run. It is intended to be shared by the Lexers and Parsers that run a test
+// corpus so that states that are never run can be found. States are
+// identified by name. States created by StateFn are identified by their
+// function so that States created by separate calls to StateFn for the same
+// function are counted together. See StateName and NamedParseState.
+//
+// A Coverage is safe for concurrent use.
+type Coverage struct {
+	mu          sync.Mutex
+	lexStates   map[string]int
+	parseStates map[string]int
+}
+
+// NewCoverage returns a new empty Coverage.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		lexStates:   map[string]int{},
+		parseStates: map[string]int{},
+	}
+}
+
+// AddStates registers lexer States that are expected to be run so that they
+// are reported by Missed if they are never run.
+func (c *Coverage) AddStates(states ...State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range states {
+		name := StateName(s)
+		c.lexStates[name] += 0
+	}
+}
+
+// AddParseStates registers the names of parse states that are expected to be
+// run so that they are reported by Missed if they are never run.
+func (c *Coverage) AddParseStates(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range names {
+		c.parseStates[name] += 0
+	}
+}
+
+// recordState records a run of the lexer State s.
+func (c *Coverage) recordState(s State) {
+	name := StateName(s)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lexStates[name]++
+}
+
+// recordParseState records a run of the named parse state.
+func (c *Coverage) recordParseState(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.parseStates[name]++
+}
+
+// LexStates returns the number of times each lexer State was run keyed by
+// name. Registered States that were never run have a count of zero.
+func (c *Coverage) LexStates() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copyCounts(c.lexStates)
+}
+
+// ParseStates returns the number of times each named parse state was run
+// keyed by name. Registered parse states that were never run have a count of
+// zero.
+func (c *Coverage) ParseStates() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copyCounts(c.parseStates)
+}
+
+// Missed returns the sorted names of the registered lexer States and parse
+// states that were never run.
+func (c *Coverage) Missed() (lexStates, parseStates []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return missed(c.lexStates), missed(c.parseStates)
+}
+
+// Report writes the number of times each lexer State and parse state was run
+// to w sorted by name. States that were never run are marked.
+//
+//	lex states:
+//	       3 lexWord
+//	       0 lexString (never run)
+//	parse states:
+//	       2 stmt
+func (c *Coverage) Report(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, section := range []struct {
+		title  string
+		counts map[string]int
+	}{
+		{"lex states", c.lexStates},
+		{"parse states", c.parseStates},
+	} {
+		if _, err := fmt.Fprintf(w, "%s:\n", section.title); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return err
+		}
+		for _, name := range sortedNames(section.counts) {
+			n := section.counts[name]
+			suffix := ""
+			if n == 0 {
+				suffix = " (never run)"
+			}
+			if _, err := fmt.Fprintf(w, "%8d %s%s\n", n, name, suffix); err != nil {
+				//nolint:wrapcheck // Error doesn't need to be wrapped.
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyCounts returns a copy of counts.
+func copyCounts(counts map[string]int) map[string]int {
+	m := make(map[string]int, len(counts))
+	for name, n := range counts {
+		m[name] = n
+	}
+	return m
+}
+
+// missed returns the sorted names in counts with a count of zero.
+func missed(counts map[string]int) []string {
+	var names []string
+	for _, name := range sortedNames(counts) {
+		if counts[name] == 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// sortedNames returns the keys of counts in sorted order.
+func sortedNames(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ianlewis/runeio"
+)
+
+func TestCoverage(t *testing.T) {
+	t.Parallel()
+
+	lexUnused := NamedState("lexUnused", func(_ context.Context, _ *Lexer) (State, error) {
+		return nil, nil
+	})
+	parseWords := NamedParseState("words", func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		for !p.AtEOF() {
+			p.Next()
+		}
+		return nil, nil
+	})
+
+	c := NewCoverage()
+	c.AddStates(&wordState{}, lexUnused)
+	c.AddParseStates("words", "unused")
+
+	for _, input := range []string{"A B", "C"} {
+		_, err := LexParseWithOptions(
+			context.Background(),
+			runeio.NewReader(strings.NewReader(input)),
+			&wordState{},
+			parseWords,
+			&Options{Coverage: c},
+		)
+		if err != nil {
+			t.Fatalf("LexParseWithOptions: %v", err)
+		}
+	}
+
+	lexStates := c.LexStates()
+	if lexStates["*lexparse.wordState"] == 0 {
+		t.Errorf("LexStates: want: wordState run, got: %v", lexStates)
+	}
+	if diff := cmp.Diff(map[string]int{"words": 2, "unused": 0}, c.ParseStates()); diff != "" {
+		t.Errorf("ParseStates: (-want, +got): \n%s", diff)
+	}
+
+	missedLex, missedParse := c.Missed()
+	if diff := cmp.Diff([]string{"lexUnused"}, missedLex); diff != "" {
+		t.Errorf("Missed: (-want, +got): \n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"unused"}, missedParse); diff != "" {
+		t.Errorf("Missed: (-want, +got): \n%s", diff)
+	}
+
+	var b strings.Builder
+	if err := c.Report(&b); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	want := "lex states:\n" +
+		fmt.Sprintf("%8d *lexparse.wordState\n", lexStates["*lexparse.wordState"]) +
+		"       0 lexUnused (never run)\n" +
+		"parse states:\n" +
+		"       0 unused (never run)\n" +
+		"       2 words\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("Report: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestCoverage_lexer(t *testing.T) {
+	t.Parallel()
+
+	lexA := NamedState("lexA", func(_ context.Context, l *Lexer) (State, error) {
+		if _, err := l.Advance(1); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		return nil, nil
+	})
+
+	c := NewCoverage()
+	l := NewLexer(runeio.NewReader(strings.NewReader("a")), lexA, WithCoverage(c))
+	Drain(l.Lex(context.Background()))
+	<-l.Done()
+
+	if diff := cmp.Diff(map[string]int{"lexA": 1}, c.LexStates()); diff != "" {
+		t.Errorf("LexStates: (-want, +got): \n%s", diff)
+	}
+}
+
+// lexCoverageA advances one rune and continues with lexCoverageB.
+func lexCoverageA(_ context.Context, l *Lexer) (State, error) {
+	if _, err := l.Advance(1); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	return StateFn(lexCoverageB), nil
+}
+
+// lexCoverageB advances one rune and continues with lexCoverageA.
+func lexCoverageB(_ context.Context, l *Lexer) (State, error) {
+	if _, err := l.Advance(1); err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	return StateFn(lexCoverageA), nil
+}
+
+func TestCoverage_stateFn(t *testing.T) {
+	t.Parallel()
+
+	c := NewCoverage()
+	c.AddStates(StateFn(lexCoverageA), StateFn(lexCoverageB), StateFn(lexNothing))
+
+	l := NewLexer(runeio.NewReader(strings.NewReader("abc")), StateFn(lexCoverageA), WithCoverage(c))
+	Drain(l.Lex(context.Background()))
+	<-l.Done()
+
+	// NOTE: lexCoverageB runs again at the end of the input.
+	want := map[string]int{
+		"github.com/ianlewis/lexparse.lexCoverageA": 2,
+		"github.com/ianlewis/lexparse.lexCoverageB": 2,
+		"github.com/ianlewis/lexparse.lexNothing":   0,
+	}
+	if diff := cmp.Diff(want, c.LexStates()); diff != "" {
+		t.Errorf("LexStates: (-want, +got): \n%s", diff)
+	}
+
+	missedLex, _ := c.Missed()
+	if diff := cmp.Diff([]string{"github.com/ianlewis/lexparse.lexNothing"}, missedLex); diff != "" {
+		t.Errorf("Missed: (-want, +got): \n%s", diff)
+	}
+}
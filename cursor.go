@@ -0,0 +1,177 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+// Cursor is a position in a tree that can be moved and used to make edits
+// without modifying the original tree (i.e. a zipper). Cursors are immutable;
+// each method returns a new Cursor.
+//
+// Edits copy the edited node and its ancestors when the cursor moves up.
+// Nodes of the original tree and nodes passed to Replace are never modified.
+// The tree returned by Root shares unchanged subtrees with the original tree.
+// The Parent field of the root of each shared subtree refers to the original
+// tree.
+type Cursor[V comparable] struct {
+	// node is the node at the cursor.
+	node *Node[V]
+
+	// parent is the cursor for the parent node.
+	parent *Cursor[V]
+
+	// index is the index of node in its parent's children.
+	index int
+
+	// changed is true if node has been edited.
+	changed bool
+
+	// owned is true if node is a copy made by the Cursor that can be
+	// modified.
+	owned bool
+}
+
+// NewCursor returns a Cursor at the root of the tree.
+func NewCursor[V comparable](root *Node[V]) *Cursor[V] {
+	return &Cursor[V]{node: root}
+}
+
+// Node returns the node at the cursor. The node must not be modified.
+func (c *Cursor[V]) Node() *Node[V] {
+	return c.node
+}
+
+// Up returns a Cursor at the parent node or nil if the cursor is at the
+// root.
+func (c *Cursor[V]) Up() *Cursor[V] {
+	if c.parent == nil {
+		return nil
+	}
+	if !c.changed {
+		return c.parent
+	}
+
+	// NOTE: Nodes not owned by the Cursor are copied so that their Parent
+	//       can be set without modifying the original tree.
+	node := c.node
+	if node != nil && !c.owned {
+		n := *node
+		n.Meta = copyMeta(n.Meta)
+		node = &n
+	}
+
+	children := append([]*Node[V](nil), c.parent.node.Children...)
+	children[c.index] = node
+	up := c.parent.with(children)
+	if node != nil {
+		node.Parent = up.node
+	}
+	return up
+}
+
+// with returns a Cursor at a copy of c's node with the given children.
+func (c *Cursor[V]) with(children []*Node[V]) *Cursor[V] {
+	n := *c.node
+	n.Children = children
+	n.Meta = copyMeta(n.Meta)
+	if c.owned {
+		// Children that refer to a node owned by the Cursor as their parent
+		// are also copies owned by the Cursor and can be moved to the new
+		// copy.
+		for _, child := range children {
+			if child != nil && child.Parent == c.node {
+				child.Parent = &n
+			}
+		}
+	}
+	return &Cursor[V]{
+		node:    &n,
+		parent:  c.parent,
+		index:   c.index,
+		changed: true,
+		owned:   true,
+	}
+}
+
+// Down returns a Cursor at the ith child or nil if there is no such child.
+func (c *Cursor[V]) Down(i int) *Cursor[V] {
+	if i < 0 || i >= len(c.node.Children) || c.node.Children[i] == nil {
+		return nil
+	}
+	child := c.node.Children[i]
+	return &Cursor[V]{
+		node:   child,
+		parent: c,
+		index:  i,
+		owned:  c.owned && child.Parent == c.node,
+	}
+}
+
+// NextSibling returns a Cursor at the next sibling or nil if there is no
+// next sibling.
+func (c *Cursor[V]) NextSibling() *Cursor[V] {
+	if c.parent == nil {
+		return nil
+	}
+	return c.Up().Down(c.index + 1)
+}
+
+// PrevSibling returns a Cursor at the previous sibling or nil if there is no
+// previous sibling.
+func (c *Cursor[V]) PrevSibling() *Cursor[V] {
+	if c.parent == nil {
+		return nil
+	}
+	return c.Up().Down(c.index - 1)
+}
+
+// Replace returns a Cursor at n which replaces the node at the cursor.
+func (c *Cursor[V]) Replace(n *Node[V]) *Cursor[V] {
+	return &Cursor[V]{
+		node:    n,
+		parent:  c.parent,
+		index:   c.index,
+		changed: true,
+	}
+}
+
+// SetValue returns a Cursor at a copy of the node with the value v.
+func (c *Cursor[V]) SetValue(v V) *Cursor[V] {
+	n := *c.node
+	n.Value = v
+	n.Meta = copyMeta(n.Meta)
+	up := c.Replace(&n)
+	up.owned = true
+	return up
+}
+
+// Remove returns a Cursor at the parent node with the node at the cursor
+// removed from its children. Returns nil if the cursor is at the root.
+func (c *Cursor[V]) Remove() *Cursor[V] {
+	if c.parent == nil {
+		return nil
+	}
+	siblings := c.parent.node.Children
+	children := make([]*Node[V], 0, len(siblings)-1)
+	children = append(children, siblings[:c.index]...)
+	children = append(children, siblings[c.index+1:]...)
+	return c.parent.with(children)
+}
+
+// Root returns the root of the tree including any edits.
+func (c *Cursor[V]) Root() *Node[V] {
+	for c.parent != nil {
+		c = c.Up()
+	}
+	return c.node
+}
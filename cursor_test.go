@@ -0,0 +1,167 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCursor_navigation(t *testing.T) {
+	t.Parallel()
+
+	root := queryTestTree()
+	c := NewCursor(root)
+
+	if got := c.Up(); got != nil {
+		t.Errorf("Up: want: nil, got: %v", got.Node())
+	}
+
+	b := c.Down(0).Down(0)
+	if got, want := b.Node().Value, "b"; got != want {
+		t.Errorf("Down: want: %v, got: %v", want, got)
+	}
+	if got, want := b.NextSibling().Node().Value, "c"; got != want {
+		t.Errorf("NextSibling: want: %v, got: %v", want, got)
+	}
+	if got := b.PrevSibling(); got != nil {
+		t.Errorf("PrevSibling: want: nil, got: %v", got.Node())
+	}
+	if got, want := b.NextSibling().PrevSibling().Node(), b.Node(); got != want {
+		t.Errorf("PrevSibling: want: %v, got: %v", want, got)
+	}
+	if got, want := b.Up().Up().Node(), root; got != want {
+		t.Errorf("Up: want: %v, got: %v", want, got)
+	}
+	if got := c.Down(5); got != nil {
+		t.Errorf("Down: want: nil, got: %v", got.Node())
+	}
+}
+
+func TestCursor_edits(t *testing.T) {
+	t.Parallel()
+
+	root := queryTestTree()
+	before := root.String()
+
+	c := NewCursor(root).Down(0).Down(0).SetValue("B")
+	c = c.NextSibling().Down(0).Remove()
+	got := c.Root()
+
+	// The original tree is unchanged.
+	if diff := cmp.Diff(before, root.String()); diff != "" {
+		t.Errorf("original tree: (-want, +got): \n%s", diff)
+	}
+
+	want := newTree(
+		&Node[string]{
+			Value: "a",
+			Children: []*Node[string]{
+				{
+					Value:    "B",
+					Children: []*Node[string]{{Value: "x"}},
+				},
+				{
+					Value: "c",
+				},
+			},
+		},
+		&Node[string]{
+			Value:    "b",
+			Children: []*Node[string]{{Value: "z"}},
+		},
+	)
+	if diff := cmp.Diff(want.String(), got.String()); diff != "" {
+		t.Errorf("Root: (-want, +got): \n%s", diff)
+	}
+
+	// Unchanged subtrees are shared.
+	if got.Children[1] != root.Children[1] {
+		t.Errorf("Root: unchanged subtree was copied")
+	}
+	if got.Children[0].Children[0].Parent != got.Children[0] {
+		t.Errorf("Root: edited node has wrong parent")
+	}
+}
+
+// parents returns the parent of each node in the tree rooted at n.
+func parents[V comparable](n *Node[V]) map[*Node[V]]*Node[V] {
+	m := map[*Node[V]]*Node[V]{n: n.Parent}
+	for _, child := range n.Children {
+		for k, v := range parents(child) {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+func TestCursor_edits_original(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]func(c *Cursor[string]) *Cursor[string]{
+		"replace": func(c *Cursor[string]) *Cursor[string] {
+			return c.Down(0).Down(1).Replace(&Node[string]{Value: "new"})
+		},
+		"replace with original node": func(c *Cursor[string]) *Cursor[string] {
+			return c.Down(0).Down(1).Replace(c.Down(1).Node())
+		},
+		"set value": func(c *Cursor[string]) *Cursor[string] {
+			return c.Down(0).Down(0).SetValue("B").Up().Down(1).SetValue("C")
+		},
+		"remove": func(c *Cursor[string]) *Cursor[string] {
+			return c.Down(0).Down(1).Down(0).Remove()
+		},
+	}
+
+	for name, edit := range testCases {
+		edit := edit
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			root := queryTestTree()
+			before := root.String()
+			wantParents := parents(root)
+
+			got := edit(NewCursor(root)).Root()
+
+			if diff := cmp.Diff(before, root.String()); diff != "" {
+				t.Errorf("original tree: (-want, +got): \n%s", diff)
+			}
+			if diff := cmp.Diff(wantParents, parents(root)); diff != "" {
+				t.Errorf("original tree Parent: (-want, +got): \n%s", diff)
+			}
+
+			// Nodes copied by the edit refer to their copied parents.
+			for n, p := range parents(got) {
+				if p != nil && !containsNode(p.Children, n) {
+					if _, shared := wantParents[n]; !shared {
+						t.Errorf("Root: %q has wrong parent %q", n.Value, p.Value)
+					}
+				}
+			}
+		})
+	}
+}
+
+// containsNode returns true if nodes contains n.
+func containsNode[V comparable](nodes []*Node[V], n *Node[V]) bool {
+	for _, c := range nodes {
+		if c == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ParseDecorator wraps a ParseFn to add behavior such as tracing or timing
+// without modifying it. Decorators can be combined using ChainParse.
+type ParseDecorator[V comparable] func(fn ParseFn[V]) ParseFn[V]
+
+// ChainParse returns a ParseDecorator that applies each of the given
+// decorators in order. The first decorator is the outermost.
+func ChainParse[V comparable](d ...ParseDecorator[V]) ParseDecorator[V] {
+	return func(fn ParseFn[V]) ParseFn[V] {
+		for i := len(d) - 1; i >= 0; i-- {
+			fn = d[i](fn)
+		}
+		return fn
+	}
+}
+
+// Propagate returns a ParseDecorator that applies d to a ParseFn and to each
+// ParseFn it returns so that decorating the initial ParseFn passed to Parse
+// decorates every ParseFn run by Parse.
+func Propagate[V comparable](d ParseDecorator[V]) ParseDecorator[V] {
+	var propagate ParseDecorator[V]
+	propagate = func(fn ParseFn[V]) ParseFn[V] {
+		decorated := d(fn)
+		return func(ctx context.Context, p *Parser[V]) (ParseFn[V], error) {
+			next, err := decorated(ctx, p)
+			if next != nil {
+				next = propagate(next)
+			}
+			return next, err
+		}
+	}
+	return propagate
+}
+
+// Recovered returns a ParseFn that calls fn and returns a *PanicError if fn
+// panics. The error has the name of the innermost named parse state (see
+// NamedParseState) or of fn and the position of the current lexeme. Unlike
+// Parser.SetRecoverPanics, it recovers panics in fn even if it is called
+// directly by another ParseFn.
+func Recovered[V comparable](fn ParseFn[V]) ParseFn[V] {
+	return func(ctx context.Context, p *Parser[V]) (next ParseFn[V], err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				next, err = nil, p.panicError(r, fn)
+			}
+		}()
+		return fn(ctx, p)
+	}
+}
+
+// Traced returns a ParseFn that writes a line to w with the position of the
+// next lexeme and name each time fn is called, as well as a line with the
+// error if fn returns an error other than io.EOF.
+func Traced[V comparable](w io.Writer, name string, fn ParseFn[V]) ParseFn[V] {
+	return func(ctx context.Context, p *Parser[V]) (ParseFn[V], error) {
+		if l := p.Peek(); l != nil {
+			fmt.Fprintf(w, "%s%d:%d: %s\n", filePrefix(l.File), l.Line+1, l.Column+1, name)
+		} else {
+			fmt.Fprintf(w, "EOF: %s\n", name)
+		}
+
+		next, err := fn(ctx, p)
+		if err != nil && !errors.Is(err, io.EOF) {
+			fmt.Fprintf(w, "%s: error: %v\n", name, err)
+		}
+		return next, err
+	}
+}
+
+// Timed returns a ParseFn that calls record with the time taken by each call
+// to fn, including any ParseFns that fn calls directly.
+func Timed[V comparable](record func(d time.Duration), fn ParseFn[V]) ParseFn[V] {
+	return func(ctx context.Context, p *Parser[V]) (ParseFn[V], error) {
+		start := time.Now()
+		next, err := fn(ctx, p)
+		record(time.Since(start))
+		return next, err
+	}
+}
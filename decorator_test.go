@@ -0,0 +1,197 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// parseWords returns a ParseFn that adds a node for each word, one word per
+// call.
+func parseWords() ParseFn[string] {
+	var fn ParseFn[string]
+	fn = func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		l := p.Next()
+		if l == nil {
+			return nil, nil
+		}
+		p.Node(l.Value)
+		return fn, nil
+	}
+	return fn
+}
+
+func TestChainParse(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	decorator := func(name string) ParseDecorator[string] {
+		return func(fn ParseFn[string]) ParseFn[string] {
+			return func(ctx context.Context, p *Parser[string]) (ParseFn[string], error) {
+				calls = append(calls, name)
+				return fn(ctx, p)
+			}
+		}
+	}
+
+	lexemes, cancel := testLexer(t, "a b")
+	defer cancel()
+
+	p := NewParser[string](lexemes)
+	fn := ChainParse(decorator("outer"), decorator("inner"))(parseWords())
+	if _, err := p.Parse(context.Background(), fn); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	// Only the initial ParseFn is decorated.
+	if diff := cmp.Diff([]string{"outer", "inner"}, calls); diff != "" {
+		t.Errorf("calls: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	count := func(fn ParseFn[string]) ParseFn[string] {
+		return func(ctx context.Context, p *Parser[string]) (ParseFn[string], error) {
+			calls++
+			return fn(ctx, p)
+		}
+	}
+
+	lexemes, cancel := testLexer(t, "a b c")
+	defer cancel()
+
+	p := NewParser[string](lexemes)
+	root, err := p.Parse(context.Background(), Propagate(count)(parseWords()))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, want := len(root.Children), 3; got != want {
+		t.Errorf("Children: want: %v, got: %v", want, got)
+	}
+	// One call for each word and one at the end of the input.
+	if got, want := calls, 4; got != want {
+		t.Errorf("calls: want: %v, got: %v", want, got)
+	}
+}
+
+func TestRecovered(t *testing.T) {
+	t.Parallel()
+
+	parseItem := Recovered(NamedParseState("item", func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		if l := p.Next(); l != nil && l.Value == "bad" {
+			panic("bad item")
+		}
+		return nil, nil
+	}))
+
+	lexemes, cancel := testLexer(t, "a bad")
+	defer cancel()
+
+	p := NewParser[string](lexemes)
+	p.SetRecoverPanics(false)
+	var itemErrs []error
+	_, err := p.Parse(context.Background(), func(ctx context.Context, p *Parser[string]) (ParseFn[string], error) {
+		for p.Peek() != nil {
+			if _, err := parseItem(ctx, p); err != nil {
+				// Errors in items are collected rather than stopping.
+				itemErrs = append(itemErrs, err)
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if got, want := len(itemErrs), 1; got != want {
+		t.Fatalf("errors: want: %v, got: %v", want, got)
+	}
+	var panicErr *PanicError
+	if !errors.As(itemErrs[0], &panicErr) {
+		t.Fatalf("error: want: *PanicError, got: %T", itemErrs[0])
+	}
+	panicErr.Stack = nil
+	want := &PanicError{
+		Value:  "bad item",
+		State:  "item",
+		Column: 2,
+	}
+	if diff := cmp.Diff(want, panicErr); diff != "" {
+		t.Errorf("error: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestTraced(t *testing.T) {
+	t.Parallel()
+
+	errBad := errors.New("bad word")
+
+	lexemes, cancel := testLexer(t, "a\nbad")
+	defer cancel()
+
+	var b strings.Builder
+	p := NewParser[string](lexemes)
+	var fn ParseFn[string]
+	fn = Traced(&b, "word", func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		if l := p.Next(); l.Value == "bad" {
+			return nil, errBad
+		}
+		return fn, nil
+	})
+	if _, err := p.Parse(context.Background(), fn); !errors.Is(err, errBad) {
+		t.Fatalf("Parse: want: %v, got: %v", errBad, err)
+	}
+
+	want := "1:1: word\n2:1: word\nword: error: bad word\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("trace: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestTimed(t *testing.T) {
+	t.Parallel()
+
+	lexemes, cancel := testLexer(t, "a b")
+	defer cancel()
+
+	var durations []time.Duration
+	p := NewParser[string](lexemes)
+	fn := Propagate(func(fn ParseFn[string]) ParseFn[string] {
+		return Timed(func(d time.Duration) {
+			durations = append(durations, d)
+		}, fn)
+	})(parseWords())
+	if _, err := p.Parse(context.Background(), fn); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if got, want := len(durations), 3; got != want {
+		t.Fatalf("durations: want: %v, got: %v", want, got)
+	}
+	for _, d := range durations {
+		if d < 0 {
+			t.Errorf("duration: want: >= 0, got: %v", d)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"io"
+)
+
+// Severity is the severity of a Diagnostic. The values match those used by
+// the Language Server Protocol.
+type Severity int
+
+const (
+	// SeverityError reports an error.
+	SeverityError Severity = iota + 1
+
+	// SeverityWarning reports a warning.
+	SeverityWarning
+
+	// SeverityInformation reports information.
+	SeverityInformation
+
+	// SeverityHint reports a hint.
+	SeverityHint
+)
+
+// Range is a range in the input. The end position is exclusive.
+type Range struct {
+	Start Position
+	End   Position
+}
+
+// Diagnostic describes a problem in the input such as a parse error.
+type Diagnostic struct {
+	// Range is the range of the input the diagnostic applies to. Only the
+	// line and column of the positions are set by NewDiagnostic.
+	Range Range
+
+	// Severity is the severity of the diagnostic.
+	Severity Severity
+
+	// Message is a human readable message.
+	Message string
+
+	// Code is a machine readable code for the diagnostic.
+	Code string
+
+	// File is the name of the file the diagnostic applies to, if known.
+	File string
+}
+
+// NewDiagnostic returns an error Diagnostic for err. The range of the
+// diagnostic is taken from a *LexemeError, *LimitError, *ScanError,
+// *LexicalError, *IOError, *PanicError, or *StuckError in err's chain.
+func NewDiagnostic(err error) *Diagnostic {
+	d := &Diagnostic{
+		Severity: SeverityError,
+		Message:  err.Error(),
+		Code:     errorCode(err),
+	}
+
+	var lexemeErr *LexemeError
+	var limitErr *LimitError
+	var scanErr *ScanError
+	var lexicalErr *LexicalError
+	var ioErr *IOError
+	var panicErr *PanicError
+	var stuckErr *StuckError
+	switch {
+	case errors.As(err, &lexemeErr):
+		d.File = lexemeErr.Lexeme.File
+		d.Range = lexemeRange(lexemeErr.Lexeme)
+	case errors.As(err, &limitErr):
+		d.File = limitErr.File
+		d.Range.Start = Position{Line: limitErr.Line, Column: limitErr.Column}
+		d.Range.End = d.Range.Start
+	case errors.As(err, &scanErr):
+		d.Range.Start = Position{Line: scanErr.Line, Column: scanErr.Column}
+		d.Range.End = d.Range.Start
+	case errors.As(err, &lexicalErr):
+		d.File = lexicalErr.File
+		d.Range.Start = Position{Line: lexicalErr.Line, Column: lexicalErr.Column}
+		d.Range.End = d.Range.Start
+	case errors.As(err, &ioErr):
+		d.File = ioErr.File
+		d.Range.Start = Position{Line: ioErr.Line, Column: ioErr.Column}
+		d.Range.End = d.Range.Start
+	case errors.As(err, &panicErr):
+		d.File = panicErr.File
+		d.Range.Start = Position{Line: panicErr.Line, Column: panicErr.Column}
+		d.Range.End = d.Range.Start
+	case errors.As(err, &stuckErr):
+		d.File = stuckErr.File
+		d.Range.Start = Position{Line: stuckErr.Line, Column: stuckErr.Column}
+		d.Range.End = d.Range.Start
+	}
+
+	return d
+}
+
+// lexemeRange returns the range of the input covered by l.
+func lexemeRange(l *Lexeme) Range {
+	r := Range{Start: Position{Line: l.Line, Column: l.Column}}
+	r.End = r.Start
+	// NOTE: Lexemes spanning multiple lines are truncated at the end of the
+	//       first line.
+	for _, rn := range l.Value {
+		if rn == '\n' {
+			break
+		}
+		r.End.Column++
+	}
+	return r
+}
+
+// errorCode returns the diagnostic code for err.
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrUnexpectedLexeme):
+		return "unexpected-lexeme"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "unexpected-eof"
+	case errors.Is(err, ErrTrailingInput):
+		return "trailing-input"
+	case errors.Is(err, ErrLimitExceeded):
+		return "limit-exceeded"
+	case errors.Is(err, ErrScanner):
+		return "scanner-error"
+	case errors.Is(err, ErrNoProgress):
+		return "no-progress"
+	case errors.Is(err, ErrZeroValue):
+		return "zero-value"
+	case errors.Is(err, ErrStuck):
+		return "stuck"
+	case errors.Is(err, ErrPanic):
+		return "panic"
+	case errors.Is(err, ErrUnexpectedRune):
+		return "unexpected-rune"
+	case errors.Is(err, ErrIO):
+		return "io-error"
+	case errors.Is(err, ErrLexical):
+		return "lexical-error"
+	default:
+		return ""
+	}
+}
+
+// LSPPosition is a position in the Language Server Protocol.
+type LSPPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// LSPRange is a range in the Language Server Protocol.
+type LSPRange struct {
+	Start LSPPosition `json:"start"`
+	End   LSPPosition `json:"end"`
+}
+
+// LSPDiagnostic is a diagnostic in the Language Server Protocol. It can be
+// marshaled using encoding/json.
+type LSPDiagnostic struct {
+	Range    LSPRange `json:"range"`
+	Severity Severity `json:"severity,omitempty"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// LSP converts the diagnostic to a Language Server Protocol diagnostic with
+// the given source. The Language Server Protocol counts characters in UTF-16
+// code units by default. If idx is not nil, it is used to convert columns to
+// UTF-16 code units; otherwise columns are used as-is.
+func (d *Diagnostic) LSP(source string, idx *LineIndex) LSPDiagnostic {
+	return LSPDiagnostic{
+		Range: LSPRange{
+			Start: lspPosition(d.Range.Start, idx),
+			End:   lspPosition(d.Range.End, idx),
+		},
+		Severity: d.Severity,
+		Code:     d.Code,
+		Source:   source,
+		Message:  d.Message,
+	}
+}
+
+// lspPosition converts pos to an LSPPosition.
+func lspPosition(pos Position, idx *LineIndex) LSPPosition {
+	lspPos := LSPPosition{
+		Line:      pos.Line,
+		Character: pos.Column,
+	}
+	if idx != nil {
+		lspPos.Character = idx.utf16Column(pos.Line, pos.Column)
+	}
+	return lspPos
+}
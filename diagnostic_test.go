@@ -0,0 +1,144 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewDiagnostic(t *testing.T) {
+	t.Parallel()
+
+	tp, err := NewTableParser(exprGrammar, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewTableParser: %v", err)
+	}
+
+	_, err = tp.Parse(context.Background(), exprLexemes("1++2"))
+	if err == nil {
+		t.Fatalf("Parse: want error")
+	}
+
+	got := NewDiagnostic(err)
+	want := &Diagnostic{
+		Range: Range{
+			Start: Position{Column: 2},
+			End:   Position{Column: 3},
+		},
+		Severity: SeverityError,
+		Message:  `unexpected lexeme: "+": line 1, column 3`,
+		Code:     "unexpected-lexeme",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("NewDiagnostic: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestNewDiagnostic_position(t *testing.T) {
+	t.Parallel()
+
+	errBad := errors.New("bad")
+
+	testCases := map[string]struct {
+		err  error
+		code string
+	}{
+		"lexical": {
+			err:  &LexicalError{Err: errBad, Line: 1, Column: 2, File: "a.txt"},
+			code: "lexical-error",
+		},
+		"io": {
+			err:  &IOError{Err: errBad, Line: 1, Column: 2, File: "a.txt"},
+			code: "io-error",
+		},
+		"panic": {
+			err:  &PanicError{Value: "bad", State: "lexA", Line: 1, Column: 2, File: "a.txt"},
+			code: "panic",
+		},
+		"stuck": {
+			err:  &StuckError{State: "lexA", Runs: 3, Line: 1, Column: 2, File: "a.txt"},
+			code: "stuck",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := fmt.Errorf("wrapped: %w", tc.err)
+			got := NewDiagnostic(err)
+			want := &Diagnostic{
+				Range: Range{
+					Start: Position{Line: 1, Column: 2},
+					End:   Position{Line: 1, Column: 2},
+				},
+				Severity: SeverityError,
+				Message:  err.Error(),
+				Code:     tc.code,
+				File:     "a.txt",
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("NewDiagnostic: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewDiagnostic_unknown(t *testing.T) {
+	t.Parallel()
+
+	got := NewDiagnostic(errors.New("other"))
+	want := &Diagnostic{
+		Severity: SeverityError,
+		Message:  "other",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("NewDiagnostic: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestDiagnostic_LSP(t *testing.T) {
+	t.Parallel()
+
+	// "𝐱" is encoded as a surrogate pair in UTF-16.
+	idx := NewLineIndex([]byte("a\n𝐱 é b"))
+	d := &Diagnostic{
+		Range: Range{
+			Start: Position{Line: 1, Column: 4},
+			End:   Position{Line: 1, Column: 5},
+		},
+		Severity: SeverityWarning,
+		Message:  "bad b",
+		Code:     "bad",
+	}
+
+	b, err := json.Marshal(d.LSP("test", idx))
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	want := `{"range":{"start":{"line":1,"character":5},"end":{"line":1,"character":6}},` +
+		`"severity":2,"code":"bad","source":"test","message":"bad b"}`
+	if got := string(b); got != want {
+		t.Errorf("LSP: want: %v, got: %v", want, got)
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+)
+
+// EarleyParser is a chart parser that accepts any context-free Grammar,
+// including ambiguous grammars that cannot be handled by TableParser. It
+// returns every parse tree for the input and is intended as a slow but simple
+// reference implementation for prototyping grammars.
+type EarleyParser[V comparable] struct {
+	g   *compiledGrammar
+	fns TableFuncs[V]
+
+	// maxTrees is the maximum number of trees returned by Parse.
+	maxTrees int
+
+	// lexerErr returns the lexer's error when the lexemes channel is closed.
+	lexerErr func() error
+}
+
+// earleyItem is a production with a position in its right hand side and the
+// index of the chart set where matching of the production started.
+type earleyItem struct {
+	prod   int
+	dot    int
+	origin int
+}
+
+// earleySpan identifies a nonterminal matched over a range of lexemes.
+type earleySpan struct {
+	sym        int
+	start, end int
+}
+
+// derivation is a parse tree of compiled productions and lexemes that is
+// converted into a Node tree once parsing completes.
+type derivation struct {
+	// prod is the production for nonterminals.
+	prod int
+
+	// lexeme is the matched lexeme for terminals.
+	lexeme *Lexeme
+
+	// start is the index of the first lexeme covered by the derivation.
+	start int
+
+	children []*derivation
+}
+
+// NewEarleyParser returns a new EarleyParser for the grammar. An error
+// wrapping ErrGrammar is returned if the grammar is malformed.
+func NewEarleyParser[V comparable](g *Grammar, fns TableFuncs[V]) (*EarleyParser[V], error) {
+	c, err := compileGrammar(g)
+	if err != nil {
+		return nil, err
+	}
+	return &EarleyParser[V]{
+		g:   c,
+		fns: fns,
+	}, nil
+}
+
+// SetMaxTrees sets the maximum number of parse trees returned by Parse. The
+// number of parse trees for an ambiguous grammar can grow exponentially with
+// the size of the input. A value of zero or less means no limit.
+func (e *EarleyParser[V]) SetMaxTrees(n int) {
+	e.maxTrees = n
+}
+
+// SetLexerErr sets a function that returns the lexer's error, e.g.
+// Lexer.Err. It is called when the lexemes channel is closed so that lexing
+// errors can be distinguished from the end of the input. If it returns a
+// non-nil error, Parse returns the error.
+func (e *EarleyParser[V]) SetLexerErr(errFn func() error) {
+	e.lexerErr = errFn
+}
+
+// Parse reads all lexemes from the lexemes channel and returns the root of
+// every parse tree for them. Each root has the zero value and a single child
+// which is the node for the grammar's start symbol. Derivations that would
+// repeat a nonterminal over the same span of input (cycles) are omitted.
+// Parsing can be cancelled by ctx.
+func (e *EarleyParser[V]) Parse(ctx context.Context, lexemes <-chan *Lexeme) ([]*Node[V], error) {
+	var input []*Lexeme
+	for {
+		var l *Lexeme
+		select {
+		case <-ctx.Done():
+			//nolint:wrapcheck // We don't need to wrap the context Error.
+			return nil, ctx.Err()
+		case l = <-lexemes:
+		}
+		if l == nil {
+			break
+		}
+		input = append(input, l)
+	}
+	if err := lexerErr(e.lexerErr); err != nil {
+		return nil, err
+	}
+
+	completed, err := e.recognize(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &earleyDeriver{
+		g:         e.g,
+		input:     input,
+		completed: completed,
+		memo:      map[earleySpan][]*derivation{},
+		maxTrees:  e.maxTrees,
+	}
+	derivs := d.derive(e.g.nonterms[e.g.g.Start], 0, len(input))
+
+	roots := make([]*Node[V], 0, len(derivs))
+	for _, deriv := range derivs {
+		n, err := e.node(deriv, input)
+		if err != nil {
+			return nil, err
+		}
+		root := &Node[V]{}
+		root.Children = []*Node[V]{n}
+		n.Parent = root
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+// recognize builds the Earley chart for the input and returns the completed
+// productions for each chart set indexed by nonterminal and origin.
+func (e *EarleyParser[V]) recognize(ctx context.Context, input []*Lexeme) ([]map[earleySpan][]int, error) {
+	c := e.g
+	sets := make([][]earleyItem, len(input)+1)
+	seen := make([]map[earleyItem]bool, len(input)+1)
+	completed := make([]map[earleySpan][]int, len(input)+1)
+	for i := range sets {
+		seen[i] = map[earleyItem]bool{}
+		completed[i] = map[earleySpan][]int{}
+	}
+	add := func(j int, item earleyItem) {
+		if !seen[j][item] {
+			seen[j][item] = true
+			sets[j] = append(sets[j], item)
+		}
+	}
+
+	add(0, earleyItem{0, 0, 0})
+	for j := 0; j <= len(input); j++ {
+		select {
+		case <-ctx.Done():
+			//nolint:wrapcheck // We don't need to wrap the context Error.
+			return nil, ctx.Err()
+		default:
+		}
+
+		if len(sets[j]) == 0 {
+			return nil, unexpectedLexeme(input[j-1])
+		}
+
+		for k := 0; k < len(sets[j]); k++ {
+			item := sets[j][k]
+			p := c.prods[item.prod]
+
+			// Complete
+			if item.dot >= len(p.rhs) {
+				span := earleySpan{p.lhs, item.origin, j}
+				completed[j][span] = append(completed[j][span], item.prod)
+				for _, waiting := range sets[item.origin] {
+					wp := c.prods[waiting.prod]
+					if waiting.dot < len(wp.rhs) && wp.rhs[waiting.dot] == p.lhs {
+						add(j, earleyItem{waiting.prod, waiting.dot + 1, waiting.origin})
+					}
+				}
+				continue
+			}
+
+			sym := p.rhs[item.dot]
+
+			// Scan
+			if c.isTerm(sym) {
+				if j < len(input) && c.terms[input[j].Type] == sym {
+					add(j+1, earleyItem{item.prod, item.dot + 1, item.origin})
+				}
+				continue
+			}
+
+			// Predict
+			for _, prod := range c.prodsByLHS[sym] {
+				add(j, earleyItem{prod, 0, j})
+			}
+			if c.nullable[sym] {
+				add(j, earleyItem{item.prod, item.dot + 1, item.origin})
+			}
+		}
+	}
+
+	if !seen[len(input)][earleyItem{0, 1, 0}] {
+		return nil, unexpectedLexeme(nil)
+	}
+	return completed, nil
+}
+
+// node converts a derivation into a Node tree.
+func (e *EarleyParser[V]) node(d *derivation, input []*Lexeme) (*Node[V], error) {
+	if d.lexeme != nil {
+		return e.fns.shift(d.lexeme)
+	}
+
+	children := make([]*Node[V], len(d.children))
+	for i, child := range d.children {
+		n, err := e.node(child, input)
+		if err != nil {
+			return nil, err
+		}
+		children[i] = n
+	}
+
+	var l *Lexeme
+	if d.start < len(input) {
+		l = input[d.start]
+	}
+	return e.fns.reduce(&e.g.g.Productions[e.g.prods[d.prod].src], children, l)
+}
+
+// earleyDeriver enumerates the derivations recorded in an Earley chart.
+type earleyDeriver struct {
+	g         *compiledGrammar
+	input     []*Lexeme
+	completed []map[earleySpan][]int
+	memo      map[earleySpan][]*derivation
+
+	// maxTrees limits the number of derivations enumerated for each span.
+	maxTrees int
+}
+
+// derive returns the derivations of the nonterminal over the lexemes from
+// start to end.
+func (d *earleyDeriver) derive(sym, start, end int) []*derivation {
+	span := earleySpan{sym, start, end}
+	if derivs, ok := d.memo[span]; ok {
+		return derivs
+	}
+	// Mark the span as in progress so that cycles produce no derivations.
+	d.memo[span] = nil
+
+	var derivs []*derivation
+	for _, prod := range d.completed[end][span] {
+		for _, children := range d.sequences(prod, 0, start, end) {
+			if d.full(len(derivs)) {
+				break
+			}
+			derivs = append(derivs, &derivation{
+				prod:     prod,
+				start:    start,
+				children: children,
+			})
+		}
+	}
+
+	d.memo[span] = derivs
+	return derivs
+}
+
+// sequences returns the possible child derivations for the symbols of the
+// production starting at index i over the lexemes from start to end.
+func (d *earleyDeriver) sequences(prod, i, start, end int) [][]*derivation {
+	rhs := d.g.prods[prod].rhs
+	if i == len(rhs) {
+		if start == end {
+			return [][]*derivation{nil}
+		}
+		return nil
+	}
+
+	var seqs [][]*derivation
+	sym := rhs[i]
+	if d.g.isTerm(sym) {
+		if start >= end || d.g.terms[d.input[start].Type] != sym {
+			return nil
+		}
+		leaf := &derivation{lexeme: d.input[start], start: start}
+		for _, rest := range d.sequences(prod, i+1, start+1, end) {
+			seqs = append(seqs, append([]*derivation{leaf}, rest...))
+		}
+		return seqs
+	}
+
+	for mid := start; mid <= end; mid++ {
+		if len(d.completed[mid][earleySpan{sym, start, mid}]) == 0 {
+			continue
+		}
+		rests := d.sequences(prod, i+1, mid, end)
+		if len(rests) == 0 {
+			continue
+		}
+		for _, first := range d.derive(sym, start, mid) {
+			for _, rest := range rests {
+				if d.full(len(seqs)) {
+					return seqs
+				}
+				seqs = append(seqs, append([]*derivation{first}, rest...))
+			}
+		}
+	}
+	return seqs
+}
+
+// full returns true if n derivations reaches the maximum number of trees.
+func (d *earleyDeriver) full(n int) bool {
+	return d.maxTrees > 0 && n >= d.maxTrees
+}
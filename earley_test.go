@@ -0,0 +1,174 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// ambiguousGrammar is an expression grammar without precedence.
+var ambiguousGrammar = &Grammar{
+	Start: "E",
+	Productions: []Production{
+		{Name: "E", Symbols: []Symbol{NonTerm("E"), Term(plusType), NonTerm("E")}},
+		{Name: "E", Symbols: []Symbol{Term(numType)}},
+	},
+}
+
+// sexpr returns a compact representation of the tree below n.
+func sexpr(n *Node[string]) string {
+	if len(n.Children) == 0 {
+		return n.Value
+	}
+	s := "("
+	for i, c := range n.Children {
+		if i > 0 {
+			s += " "
+		}
+		s += sexpr(c)
+	}
+	return s + ")"
+}
+
+func TestEarleyParser(t *testing.T) {
+	t.Parallel()
+
+	ep, err := NewEarleyParser(ambiguousGrammar, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewEarleyParser: %v", err)
+	}
+
+	roots, err := ep.Parse(context.Background(), exprLexemes("1+2+3"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var got []string
+	for _, root := range roots {
+		got = append(got, sexpr(root))
+	}
+	want := []string{
+		"(((1) + ((2) + (3))))",
+		"((((1) + (2)) + (3)))",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Parse: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestEarleyParser_SetMaxTrees(t *testing.T) {
+	t.Parallel()
+
+	ep, err := NewEarleyParser(ambiguousGrammar, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewEarleyParser: %v", err)
+	}
+	ep.SetMaxTrees(1)
+
+	roots, err := ep.Parse(context.Background(), exprLexemes("1+2+3+4"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, want := len(roots), 1; got != want {
+		t.Errorf("len(roots): want: %v, got: %v", want, got)
+	}
+}
+
+func TestEarleyParser_SetLexerErr(t *testing.T) {
+	t.Parallel()
+
+	ep, err := NewEarleyParser(ambiguousGrammar, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewEarleyParser: %v", err)
+	}
+
+	errTest := errors.New("test error")
+	ep.SetLexerErr(func() error {
+		return errTest
+	})
+
+	// NOTE: The input is valid up to where the lexer stopped.
+	_, err = ep.Parse(context.Background(), exprLexemes("1+2"))
+	if !errors.Is(err, errTest) {
+		t.Errorf("Parse: want: %v, got: %v", errTest, err)
+	}
+}
+
+func TestEarleyParser_unambiguous(t *testing.T) {
+	t.Parallel()
+
+	ep, err := NewEarleyParser(exprGrammar, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewEarleyParser: %v", err)
+	}
+	tp, err := NewTableParser(exprGrammar, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewTableParser: %v", err)
+	}
+
+	input := "(1+2)*3"
+	roots, err := ep.Parse(context.Background(), exprLexemes(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want, err := tp.Parse(context.Background(), exprLexemes(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if diff := cmp.Diff([]*Node[string]{want}, roots); diff != "" {
+		t.Errorf("Parse: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestEarleyParser_errors(t *testing.T) {
+	t.Parallel()
+
+	ep, err := NewEarleyParser(ambiguousGrammar, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewEarleyParser: %v", err)
+	}
+
+	testCases := map[string]struct {
+		input string
+		err   error
+	}{
+		"unexpected lexeme": {
+			input: "1++2",
+			err:   ErrUnexpectedLexeme,
+		},
+		"unexpected eof": {
+			input: "1+",
+			err:   io.ErrUnexpectedEOF,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ep.Parse(context.Background(), exprLexemes(tc.input))
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
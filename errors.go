@@ -0,0 +1,261 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrLimitExceeded indicates that a configured resource limit was exceeded.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// LimitError is the error returned when a resource limit is exceeded. It
+// wraps ErrLimitExceeded.
+type LimitError struct {
+	// Limit is the name of the limit that was exceeded.
+	Limit string
+
+	// Max is the configured maximum value for the limit.
+	Max int
+
+	// Line is the line in the input where the limit was exceeded.
+	Line int
+
+	// Column is the column in the line where the limit was exceeded.
+	Column int
+
+	// File is the name of the file where the limit was exceeded, if known.
+	File string
+}
+
+// Error implements error.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s%v: %s limit of %d: line %d, column %d",
+		filePrefix(e.File), ErrLimitExceeded, e.Limit, e.Max, e.Line+1, e.Column+1)
+}
+
+// Unwrap returns ErrLimitExceeded.
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// ErrStuck indicates that the Lexer stopped making progress.
+var ErrStuck = errors.New("lexer made no progress")
+
+// StuckError is the error returned when the Lexer runs more than the
+// configured number of States without reading input or emitting lexemes. It
+// wraps ErrStuck. See WithMaxStalls.
+type StuckError struct {
+	// State is the name of the State that was running. See StateName.
+	State string
+
+	// Runs is the number of consecutive State runs that made no progress.
+	Runs int
+
+	// Line is the line in the input where the Lexer was stuck.
+	Line int
+
+	// Column is the column in the line where the Lexer was stuck.
+	Column int
+
+	// File is the name of the file where the Lexer was stuck, if known.
+	File string
+}
+
+// Error implements error.
+func (e *StuckError) Error() string {
+	return fmt.Sprintf("%s%v: stuck in state %s after %d runs: line %d, column %d",
+		filePrefix(e.File), ErrStuck, e.State, e.Runs, e.Line+1, e.Column+1)
+}
+
+// Unwrap returns ErrStuck.
+func (e *StuckError) Unwrap() error {
+	return ErrStuck
+}
+
+var (
+	// ErrLexical indicates an error in the input, e.g. an unexpected
+	// character, reported by a State. See LexicalError.
+	ErrLexical = errors.New("lexical error")
+
+	// ErrIO indicates a failure to read the input. Unlike lexical errors, I/O
+	// errors may be transient. See IOError.
+	ErrIO = errors.New("i/o error")
+)
+
+// LexicalError is the error returned by Lexer.Err when a State returns an
+// error that was not caused by a failure to read the input. It wraps
+// ErrLexical and the error returned by the State. Its message is the message
+// of the State's error.
+type LexicalError struct {
+	// Err is the error returned by the State.
+	Err error
+
+	// State is the name of the State that returned the error. See
+	// StateName.
+	State string
+
+	// Line is the line in the input where the error occurred.
+	Line int
+
+	// Column is the column in the line where the error occurred.
+	Column int
+
+	// File is the name of the file where the error occurred, if known.
+	File string
+}
+
+// Error implements error.
+func (e *LexicalError) Error() string {
+	if e.Err == nil {
+		return ErrLexical.Error()
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns ErrLexical and the error returned by the State.
+func (e *LexicalError) Unwrap() []error {
+	return []error{ErrLexical, e.Err}
+}
+
+// IOError is the error returned by Lexer.Err when a State returns an error
+// caused by a failure to read the input. It wraps ErrIO and the error
+// returned by the reader.
+type IOError struct {
+	// Err is the error returned by the State.
+	Err error
+
+	// State is the name of the State that returned the error. See
+	// StateName.
+	State string
+
+	// Line is the line in the input where reading failed.
+	Line int
+
+	// Column is the column in the line where reading failed.
+	Column int
+
+	// File is the name of the file being read, if known.
+	File string
+}
+
+// Error implements error.
+func (e *IOError) Error() string {
+	return fmt.Sprintf("%s%v: line %d, column %d", filePrefix(e.File), e.Err, e.Line+1, e.Column+1)
+}
+
+// Unwrap returns ErrIO and the error returned by the State.
+func (e *IOError) Unwrap() []error {
+	return []error{ErrIO, e.Err}
+}
+
+// ErrPanic indicates that a State or parse function panicked.
+var ErrPanic = errors.New("panic")
+
+// PanicError is the error returned when a State or parse function panics. It
+// wraps ErrPanic and, if the panic value is an error, the panic value. See
+// WithRecoverPanics and Parser.SetRecoverPanics.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// State is the name of the State or parse function that panicked.
+	State string
+
+	// Line is the line in the input where the panic occurred.
+	Line int
+
+	// Column is the column in the line where the panic occurred.
+	Column int
+
+	// File is the name of the file where the panic occurred, if known.
+	File string
+
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+// Error implements error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s%v in state %s: %v: line %d, column %d",
+		filePrefix(e.File), ErrPanic, e.State, e.Value, e.Line+1, e.Column+1)
+}
+
+// Unwrap returns ErrPanic and the panic value if it is an error.
+func (e *PanicError) Unwrap() []error {
+	if err, ok := e.Value.(error); ok {
+		return []error{ErrPanic, err}
+	}
+	return []error{ErrPanic}
+}
+
+// LexemeError is an error that occurred at a lexeme in the input.
+type LexemeError struct {
+	// Err is the underlying error, e.g. ErrUnexpectedLexeme.
+	Err error
+
+	// Detail is an optional description of the lexeme, e.g. "non-associative
+	// operator".
+	Detail string
+
+	// Lexeme is the lexeme where the error occurred.
+	Lexeme *Lexeme
+}
+
+// Error implements error.
+func (e *LexemeError) Error() string {
+	l := e.Lexeme
+	if e.Detail != "" {
+		return fmt.Sprintf("%s%v: %s %q: line %d, column %d",
+			filePrefix(l.File), e.Err, e.Detail, l.Value, l.Line+1, l.Column+1)
+	}
+	return fmt.Sprintf("%s%v: %q: line %d, column %d", filePrefix(l.File), e.Err, l.Value, l.Line+1, l.Column+1)
+}
+
+// Unwrap returns the underlying error.
+func (e *LexemeError) Unwrap() error {
+	return e.Err
+}
+
+// ParseStateError is an error returned by a parse function created by
+// NamedParseState. It wraps the underlying error and records the stack of
+// named parse states that were running when the error occurred.
+type ParseStateError struct {
+	// Err is the underlying error.
+	Err error
+
+	// Stack is the names of the parse states from outermost to innermost.
+	Stack []string
+}
+
+// Error implements error.
+func (e *ParseStateError) Error() string {
+	return fmt.Sprintf("%v (parse states: %s)", e.Err, strings.Join(e.Stack, " > "))
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseStateError) Unwrap() error {
+	return e.Err
+}
+
+// filePrefix returns the prefix for error messages for errors in file.
+func filePrefix(file string) string {
+	if file == "" {
+		return ""
+	}
+	return file + ": "
+}
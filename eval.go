@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoEvalFn indicates that no evaluation function is registered for a
+// node's kind.
+var ErrNoEvalFn = errors.New("no evaluation function")
+
+// EvalFn evaluates a node given the results of evaluating its children in
+// order.
+type EvalFn[V comparable, R any] func(n *Node[V], children []R) (R, error)
+
+// Evaluator is a tree-walking evaluator for parse trees. Evaluation functions
+// are registered for each kind of node, as returned by a kind function, and
+// nodes are evaluated in post-order so that each function is passed the
+// results of evaluating the node's children.
+type Evaluator[V comparable, K comparable, R any] struct {
+	kind     func(V) K
+	fns      map[K]EvalFn[V, R]
+	def      EvalFn[V, R]
+	maxDepth int
+}
+
+// NewEvaluator returns a new Evaluator using kind to get the kind of a node
+// from its value.
+func NewEvaluator[V comparable, K comparable, R any](kind func(V) K) *Evaluator[V, K, R] {
+	return &Evaluator[V, K, R]{
+		kind: kind,
+		fns:  map[K]EvalFn[V, R]{},
+	}
+}
+
+// Register registers the evaluation function for nodes of kind k and returns
+// the Evaluator.
+func (e *Evaluator[V, K, R]) Register(k K, fn EvalFn[V, R]) *Evaluator[V, K, R] {
+	e.fns[k] = fn
+	return e
+}
+
+// SetDefault sets the evaluation function for nodes whose kind has no
+// registered function. If not set, evaluating such a node returns an error
+// wrapping ErrNoEvalFn.
+func (e *Evaluator[V, K, R]) SetDefault(fn EvalFn[V, R]) {
+	e.def = fn
+}
+
+// SetMaxDepth sets the maximum depth of nodes that are evaluated. The node
+// passed to Eval has a depth of zero. If a deeper node is reached, Eval
+// returns a *LimitError. A value of zero or less means no limit.
+func (e *Evaluator[V, K, R]) SetMaxDepth(n int) {
+	e.maxDepth = n
+}
+
+// Eval evaluates the tree rooted at n and returns the result for n.
+// Evaluation can be cancelled by ctx.
+func (e *Evaluator[V, K, R]) Eval(ctx context.Context, n *Node[V]) (R, error) {
+	return e.eval(ctx, n, 0)
+}
+
+func (e *Evaluator[V, K, R]) eval(ctx context.Context, n *Node[V], depth int) (R, error) {
+	var zero R
+
+	select {
+	case <-ctx.Done():
+		//nolint:wrapcheck // We don't need to wrap the context Error.
+		return zero, ctx.Err()
+	default:
+	}
+
+	if e.maxDepth > 0 && depth > e.maxDepth {
+		return zero, &LimitError{
+			Limit:  "depth",
+			Max:    e.maxDepth,
+			Line:   n.Line,
+			Column: n.Column,
+		}
+	}
+
+	k := e.kind(n.Value)
+	fn, ok := e.fns[k]
+	if !ok {
+		fn = e.def
+	}
+	if fn == nil {
+		return zero, fmt.Errorf("%w: kind %v: line %d, column %d", ErrNoEvalFn, k, n.Line+1, n.Column+1)
+	}
+
+	children := make([]R, 0, len(n.Children))
+	for _, c := range n.Children {
+		r, err := e.eval(ctx, c, depth+1)
+		if err != nil {
+			return zero, err
+		}
+		children = append(children, r)
+	}
+
+	return fn(n, children)
+}
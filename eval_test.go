@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+// opKind returns the kind of operator and number nodes.
+func opKind(v string) string {
+	if _, err := strconv.Atoi(v); err == nil {
+		return "num"
+	}
+	return v
+}
+
+func newTestEvaluator() *Evaluator[string, string, int] {
+	return NewEvaluator[string, string, int](opKind).
+		Register("num", func(n *Node[string], _ []int) (int, error) {
+			return strconv.Atoi(n.Value)
+		}).
+		Register("+", func(_ *Node[string], children []int) (int, error) {
+			sum := 0
+			for _, c := range children {
+				sum += c
+			}
+			return sum, nil
+		}).
+		Register("*", func(_ *Node[string], children []int) (int, error) {
+			product := 1
+			for _, c := range children {
+				product *= c
+			}
+			return product, nil
+		})
+}
+
+func TestEvaluator(t *testing.T) {
+	t.Parallel()
+
+	// (+ 1 (* 2 3) 4)
+	root := addParent(&Node[string]{
+		Value: "+",
+		Children: []*Node[string]{
+			{Value: "1"},
+			{
+				Value: "*",
+				Children: []*Node[string]{
+					{Value: "2"},
+					{Value: "3"},
+				},
+			},
+			{Value: "4"},
+		},
+	})
+
+	got, err := newTestEvaluator().Eval(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Eval: %v", err)
+	}
+	if want := 11; got != want {
+		t.Errorf("Eval: want: %v, got: %v", want, got)
+	}
+}
+
+func TestEvaluator_errors(t *testing.T) {
+	t.Parallel()
+
+	// (+ 1 (- 2))
+	root := addParent(&Node[string]{
+		Value: "+",
+		Children: []*Node[string]{
+			{Value: "1"},
+			{
+				Value:    "-",
+				Line:     1,
+				Column:   2,
+				Children: []*Node[string]{{Value: "2"}},
+			},
+		},
+	})
+
+	t.Run("no eval fn", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newTestEvaluator().Eval(context.Background(), root)
+		if !errors.Is(err, ErrNoEvalFn) {
+			t.Errorf("Eval: want: %v, got: %v", ErrNoEvalFn, err)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		e := newTestEvaluator()
+		e.SetDefault(func(_ *Node[string], children []int) (int, error) {
+			return -children[0], nil
+		})
+		got, err := e.Eval(context.Background(), root)
+		if err != nil {
+			t.Fatalf("Eval: %v", err)
+		}
+		if want := -1; got != want {
+			t.Errorf("Eval: want: %v, got: %v", want, got)
+		}
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		t.Parallel()
+
+		e := newTestEvaluator()
+		e.SetDefault(func(_ *Node[string], children []int) (int, error) {
+			return -children[0], nil
+		})
+		e.SetMaxDepth(1)
+		_, err := e.Eval(context.Background(), root)
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("Eval: want: *LimitError, got: %v", err)
+		}
+		if got, want := limitErr.Limit, "depth"; got != want {
+			t.Errorf("Limit: want: %v, got: %v", want, got)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := newTestEvaluator().Eval(ctx, root)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Eval: want: %v, got: %v", context.Canceled, err)
+		}
+	})
+}
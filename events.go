@@ -0,0 +1,147 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import "fmt"
+
+// EventKind is the kind of a parser Event.
+type EventKind int
+
+const (
+	// EventParseFn is recorded before a ParseFn is called.
+	EventParseFn EventKind = iota
+
+	// EventNext is recorded when a lexeme is consumed by Next.
+	EventNext
+
+	// EventNode is recorded when a node is added to the tree.
+	EventNode
+
+	// EventPush is recorded when a node becomes the current node by Push.
+	EventPush
+
+	// EventClimb is recorded when Climb moves to the parent node.
+	EventClimb
+
+	// EventReplace is recorded when the current node is replaced.
+	EventReplace
+
+	// EventRestore is recorded when a checkpoint is restored.
+	EventRestore
+
+	// EventStatePush is recorded when a named parse state starts running.
+	// See NamedParseState.
+	EventStatePush
+
+	// EventStatePop is recorded when a named parse state returns.
+	EventStatePop
+)
+
+// String implements fmt.Stringer.
+func (k EventKind) String() string {
+	switch k {
+	case EventParseFn:
+		return "ParseFn"
+	case EventNext:
+		return "Next"
+	case EventNode:
+		return "Node"
+	case EventPush:
+		return "Push"
+	case EventClimb:
+		return "Climb"
+	case EventReplace:
+		return "Replace"
+	case EventRestore:
+		return "Restore"
+	case EventStatePush:
+		return "StatePush"
+	case EventStatePop:
+		return "StatePop"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+}
+
+// Event is a record of an operation performed by a Parser.
+type Event[V comparable] struct {
+	// Kind is the kind of operation.
+	Kind EventKind
+
+	// Lexeme is the current lexeme at the time of the event. For EventNext
+	// events it is the lexeme consumed.
+	Lexeme *Lexeme
+
+	// Node is the node the operation applied to. For EventClimb events it is
+	// the node climbed from. It is nil for EventParseFn, EventNext,
+	// EventStatePush, and EventStatePop events.
+	Node *Node[V]
+
+	// State is the name of the parse state for EventStatePush and
+	// EventStatePop events.
+	State string
+}
+
+// String returns a description of the event.
+func (e Event[V]) String() string {
+	s := e.Kind.String()
+	if e.Node != nil {
+		s += fmt.Sprintf(" %v", e.Node.Value)
+	}
+	if e.State != "" {
+		s += " " + e.State
+	}
+	if e.Lexeme != nil {
+		s += fmt.Sprintf(" %q: line %d, column %d", e.Lexeme.Value, e.Lexeme.Line+1, e.Lexeme.Column+1)
+	}
+	return s
+}
+
+// SetEventLog enables or disables recording of parser operations. Recorded
+// events can be retrieved with Events and are useful for debugging grammars.
+func (p *Parser[V]) SetEventLog(enabled bool) {
+	p.logEvents = enabled
+}
+
+// Events returns a copy of the events recorded since the event log was
+// enabled.
+func (p *Parser[V]) Events() []Event[V] {
+	return append([]Event[V](nil), p.events...)
+}
+
+// record records an event if the event log is enabled.
+func (p *Parser[V]) record(kind EventKind, n *Node[V]) {
+	if !p.logEvents {
+		return
+	}
+	p.events = append(p.events, Event[V]{
+		Kind:   kind,
+		Lexeme: p.lexeme,
+		Node:   n,
+	})
+}
+
+// recordState records a named parse state event if the event log is
+// enabled.
+func (p *Parser[V]) recordState(kind EventKind, name string) {
+	if !p.logEvents {
+		return
+	}
+	p.events = append(p.events, Event[V]{
+		Kind:   kind,
+		Lexeme: p.lexeme,
+		State:  name,
+	})
+}
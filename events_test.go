@@ -0,0 +1,70 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParser_Events(t *testing.T) {
+	t.Parallel()
+
+	lexemes, cancel := testLexer(t, "A B")
+	defer cancel()
+
+	p := NewParser[string](lexemes)
+	p.SetEventLog(true)
+
+	pFn := NamedParseState("root", func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		_ = p.Push(p.Next().Value)
+		_ = p.Node(p.Next().Value)
+		_ = p.Climb()
+		_ = p.Replace("R")
+		return nil, nil
+	})
+	if _, err := p.Parse(context.Background(), pFn); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var got []string
+	for _, e := range p.Events() {
+		got = append(got, e.String())
+	}
+	want := []string{
+		"ParseFn",
+		"StatePush root",
+		`Next "A": line 1, column 1`,
+		`Node A "A": line 1, column 1`,
+		`Push A "A": line 1, column 1`,
+		`Next "B": line 1, column 3`,
+		`Node B "B": line 1, column 3`,
+		`Climb A "B": line 1, column 3`,
+		`Replace R "B": line 1, column 3`,
+		`StatePop root "B": line 1, column 3`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Events: (-want, +got): \n%s", diff)
+	}
+
+	// The returned events are a copy.
+	events := p.Events()
+	events[0].Kind = EventRestore
+	if got, want := p.Events()[0].Kind, EventParseFn; got != want {
+		t.Errorf("Events: want: %v, got: %v", want, got)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Associativity is the associativity of a binary operator.
+type Associativity int
+
+const (
+	// AssocLeft operators group left to right, e.g. (a - b) - c.
+	AssocLeft Associativity = iota
+
+	// AssocRight operators group right to left, e.g. a = (b = c).
+	AssocRight
+
+	// AssocNone operators cannot be chained, e.g. a < b < c is an error.
+	AssocNone
+)
+
+// Operator describes the syntax of an operator.
+type Operator struct {
+	// Precedence is the binding power of the operator. Operators with higher
+	// precedence bind more tightly. Precedence must be greater than zero.
+	Precedence int
+
+	// Assoc is the associativity of binary operators.
+	Assoc Associativity
+
+	// Arity is the number of operands. It is 1 for prefix and postfix
+	// operators and 2 for binary infix operators.
+	Arity int
+}
+
+// OperatorTable maps lexeme types to operators. A lexeme type can be both a
+// prefix and an infix or postfix operator (e.g. '-' or '('). Keeping precedence and
+// associativity in a table allows a language's precedence rules to be changed
+// without modifying the parsing code.
+type OperatorTable struct {
+	prefix  map[LexemeType]Operator
+	infix   map[LexemeType]Operator
+	postfix map[LexemeType]Operator
+}
+
+// NewOperatorTable returns a new empty OperatorTable.
+func NewOperatorTable() *OperatorTable {
+	return &OperatorTable{
+		prefix:  map[LexemeType]Operator{},
+		infix:   map[LexemeType]Operator{},
+		postfix: map[LexemeType]Operator{},
+	}
+}
+
+// Prefix adds a prefix operator to the table and returns the table.
+func (t *OperatorTable) Prefix(typ LexemeType, precedence int) *OperatorTable {
+	t.prefix[typ] = Operator{
+		Precedence: precedence,
+		Assoc:      AssocRight,
+		Arity:      1,
+	}
+	return t
+}
+
+// Infix adds a binary infix operator to the table and returns the table.
+func (t *OperatorTable) Infix(typ LexemeType, precedence int, assoc Associativity) *OperatorTable {
+	t.infix[typ] = Operator{
+		Precedence: precedence,
+		Assoc:      assoc,
+		Arity:      2,
+	}
+	return t
+}
+
+// Postfix adds a postfix operator to the table and returns the table. Postfix
+// operators include the opening lexemes of function calls (e.g. '(' in
+// "f(x)") and index expressions (e.g. '[' in "a[i]") which are parsed by an
+// ExprParser's Led hooks.
+func (t *OperatorTable) Postfix(typ LexemeType, precedence int) *OperatorTable {
+	t.postfix[typ] = Operator{
+		Precedence: precedence,
+		Assoc:      AssocLeft,
+		Arity:      1,
+	}
+	return t
+}
+
+// PrefixOp returns the prefix operator for the lexeme type.
+func (t *OperatorTable) PrefixOp(typ LexemeType) (Operator, bool) {
+	op, ok := t.prefix[typ]
+	return op, ok
+}
+
+// InfixOp returns the infix operator for the lexeme type.
+func (t *OperatorTable) InfixOp(typ LexemeType) (Operator, bool) {
+	op, ok := t.infix[typ]
+	return op, ok
+}
+
+// PostfixOp returns the postfix operator for the lexeme type.
+func (t *OperatorTable) PostfixOp(typ LexemeType) (Operator, bool) {
+	op, ok := t.postfix[typ]
+	return op, ok
+}
+
+// GroupsLeft returns true if the expression "a left b right c" groups as
+// "(a left b) right c" and false if it groups as "a left (b right c)". It can
+// be used to decide whether to rotate the tree when building binary
+// expressions with Parser.RotateLeft and Parser.RotateRight.
+func (t *OperatorTable) GroupsLeft(left, right LexemeType) bool {
+	l := t.infix[left]
+	r := t.infix[right]
+	if l.Precedence != r.Precedence {
+		return l.Precedence > r.Precedence
+	}
+	return l.Assoc != AssocRight
+}
+
+// ExprParser parses expressions using top-down operator precedence (Pratt)
+// parsing driven by an OperatorTable. Operator nodes are created with their
+// operands as children.
+type ExprParser[V comparable] struct {
+	// Operators is the operator table.
+	Operators *OperatorTable
+
+	// Value returns the node value for an operator or operand lexeme.
+	Value func(l *Lexeme) (V, error)
+
+	// Operand parses an operand and returns it without adding it to the
+	// tree. It can be used to parse parenthesized expressions by calling
+	// Expr. If nil, operands are single lexemes.
+	Operand func(ctx context.Context, p *Parser[V]) (*Node[V], error)
+
+	// Led maps postfix operator lexeme types to functions that parse the
+	// rest of the postfix expression (the "left denotation" in Pratt
+	// parsing), e.g. the arguments and closing parenthesis of a function
+	// call. The function is called after the operator lexeme is consumed
+	// with the operator node, whose left child is the operand, and returns
+	// the root node of the expression. Postfix operators without a Led
+	// function are single lexemes.
+	Led map[LexemeType]LedFn[V]
+}
+
+// LedFn parses the remainder of a postfix expression. op is the node for the
+// postfix operator lexeme and has the operand as its left child.
+type LedFn[V comparable] func(ctx context.Context, p *Parser[V], op *Node[V]) (*Node[V], error)
+
+// Parse parses an expression and adds it as a child of the current node. The
+// root node of the expression is returned.
+func (e *ExprParser[V]) Parse(ctx context.Context, p *Parser[V]) (*Node[V], error) {
+	n, err := e.Expr(ctx, p, 0)
+	if err != nil {
+		return nil, err
+	}
+	p.addChild(n)
+	return n, nil
+}
+
+// Expr parses an expression containing only operators with precedence
+// greater than prec and returns it without adding it to the tree.
+func (e *ExprParser[V]) Expr(ctx context.Context, p *Parser[V], prec int) (*Node[V], error) {
+	left, err := e.prefix(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	// nonAssoc is the precedence of the last non-associative operator.
+	nonAssoc := -1
+	for {
+		l := p.Peek()
+		if l == nil {
+			return left, nil
+		}
+		if op, ok := e.Operators.PostfixOp(l.Type); ok {
+			if op.Precedence <= prec {
+				return left, nil
+			}
+			if left, err = e.postfix(ctx, p, left); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		op, ok := e.Operators.InfixOp(l.Type)
+		if !ok || op.Precedence <= prec {
+			return left, nil
+		}
+		if op.Assoc == AssocNone && op.Precedence == nonAssoc {
+			return nil, &LexemeError{
+				Err:    ErrUnexpectedLexeme,
+				Detail: "non-associative operator",
+				Lexeme: l,
+			}
+		}
+
+		n, err := e.node(p)
+		if err != nil {
+			return nil, err
+		}
+
+		rightPrec := op.Precedence
+		if op.Assoc == AssocRight {
+			rightPrec--
+		}
+		right, err := e.Expr(ctx, p, rightPrec)
+		if err != nil {
+			return nil, err
+		}
+
+		n.SetLeft(left)
+		n.SetRight(right)
+		left = n
+
+		nonAssoc = -1
+		if op.Assoc == AssocNone {
+			nonAssoc = op.Precedence
+		}
+	}
+}
+
+// prefix parses a prefix operator expression or an operand.
+func (e *ExprParser[V]) prefix(ctx context.Context, p *Parser[V]) (*Node[V], error) {
+	select {
+	case <-ctx.Done():
+		//nolint:wrapcheck // We don't need to wrap the context Error.
+		return nil, ctx.Err()
+	default:
+	}
+
+	l := p.Peek()
+	if l == nil {
+		return nil, fmt.Errorf("%w: expected operand", io.ErrUnexpectedEOF)
+	}
+
+	if op, ok := e.Operators.PrefixOp(l.Type); ok {
+		n, err := e.node(p)
+		if err != nil {
+			return nil, err
+		}
+		operand, err := e.Expr(ctx, p, op.Precedence)
+		if err != nil {
+			return nil, err
+		}
+		n.SetLeft(operand)
+		return n, nil
+	}
+
+	if e.Operand != nil {
+		return e.Operand(ctx, p)
+	}
+
+	if _, ok := e.Operators.InfixOp(l.Type); ok {
+		return nil, unexpectedLexeme(l)
+	}
+	if _, ok := e.Operators.PostfixOp(l.Type); ok {
+		return nil, unexpectedLexeme(l)
+	}
+	return e.node(p)
+}
+
+// postfix parses a postfix operator expression with the given operand.
+func (e *ExprParser[V]) postfix(ctx context.Context, p *Parser[V], operand *Node[V]) (*Node[V], error) {
+	typ := p.Peek().Type
+	n, err := e.node(p)
+	if err != nil {
+		return nil, err
+	}
+	n.SetLeft(operand)
+	if led, ok := e.Led[typ]; ok {
+		return led(ctx, p, n)
+	}
+	return n, nil
+}
+
+// node consumes the next lexeme and returns a new node for it.
+func (e *ExprParser[V]) node(p *Parser[V]) (*Node[V], error) {
+	l := p.Next()
+	v, err := e.Value(l)
+	if err != nil {
+		return nil, err
+	}
+	return p.NewNode(v), nil
+}
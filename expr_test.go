@@ -0,0 +1,257 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+var testOperators = NewOperatorTable().
+	Infix(lessType, 1, AssocNone).
+	Infix(plusType, 2, AssocLeft).
+	Infix(minusType, 2, AssocLeft).
+	Infix(starType, 3, AssocLeft).
+	Prefix(minusType, 4).
+	Infix(caretType, 5, AssocRight).
+	Postfix(bangType, 6).
+	Postfix(lparenType, 7).
+	Postfix(lbrackType, 7)
+
+// opString returns the tree below n in prefix notation.
+func opString(n *Node[string]) string {
+	if len(n.Children) == 0 {
+		return n.Value
+	}
+	parts := []string{n.Value}
+	for _, c := range n.Children {
+		parts = append(parts, opString(c))
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func newTestExprParser() *ExprParser[string] {
+	e := &ExprParser[string]{
+		Operators: testOperators,
+		Value: func(l *Lexeme) (string, error) {
+			return l.Value, nil
+		},
+	}
+	e.Operand = func(ctx context.Context, p *Parser[string]) (*Node[string], error) {
+		l := p.Next()
+		if l.Type != lparenType {
+			return p.NewNode(l.Value), nil
+		}
+		n, err := e.Expr(ctx, p, 0)
+		if err != nil {
+			return nil, err
+		}
+		if r := p.Next(); r == nil || r.Type != rparenType {
+			return nil, unexpectedLexeme(r)
+		}
+		return n, nil
+	}
+	e.Led = map[LexemeType]LedFn[string]{
+		// Function calls, e.g. f(1,2).
+		lparenType: func(ctx context.Context, p *Parser[string], op *Node[string]) (*Node[string], error) {
+			op.Value = "call"
+			if l := p.Peek(); l != nil && l.Type == rparenType {
+				_ = p.Next()
+				return op, nil
+			}
+			for {
+				arg, err := e.Expr(ctx, p, 0)
+				if err != nil {
+					return nil, err
+				}
+				arg.Parent = op
+				op.Children = append(op.Children, arg)
+
+				l := p.Next()
+				if l == nil || (l.Type != commaType && l.Type != rparenType) {
+					return nil, unexpectedLexeme(l)
+				}
+				if l.Type == rparenType {
+					return op, nil
+				}
+			}
+		},
+		// Index expressions, e.g. a[1].
+		lbrackType: func(ctx context.Context, p *Parser[string], op *Node[string]) (*Node[string], error) {
+			op.Value = "index"
+			idx, err := e.Expr(ctx, p, 0)
+			if err != nil {
+				return nil, err
+			}
+			op.SetRight(idx)
+			if l := p.Next(); l == nil || l.Type != rbrackType {
+				return nil, unexpectedLexeme(l)
+			}
+			return op, nil
+		},
+	}
+	return e
+}
+
+func TestExprParser(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		want  string
+	}{
+		"precedence": {
+			input: "1+2*3",
+			want:  "(+ 1 (* 2 3))",
+		},
+		"left associative": {
+			input: "1-2-3",
+			want:  "(- (- 1 2) 3)",
+		},
+		"right associative": {
+			input: "2^3^4",
+			want:  "(^ 2 (^ 3 4))",
+		},
+		"prefix": {
+			input: "-1*-2^3",
+			want:  "(* (- 1) (- (^ 2 3)))",
+		},
+		"operand": {
+			input: "(1+2)*3",
+			want:  "(* (+ 1 2) 3)",
+		},
+		"non-associative": {
+			input: "1<2+3",
+			want:  "(< 1 (+ 2 3))",
+		},
+		"postfix": {
+			input: "1+2!",
+			want:  "(+ 1 (! 2))",
+		},
+		"postfix binds tighter than prefix": {
+			input: "-2!!",
+			want:  "(- (! (! 2)))",
+		},
+		"call": {
+			input: "f(1,2+3)*4",
+			want:  "(* (call f 1 (+ 2 3)) 4)",
+		},
+		"call without arguments": {
+			input: "f()",
+			want:  "(call f)",
+		},
+		"index": {
+			input: "a[1+2]^2",
+			want:  "(^ (index a (+ 1 2)) 2)",
+		},
+		"chained postfix": {
+			input: "f(1)[2]!",
+			want:  "(! (index (call f 1) 2))",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewParser[string](exprLexemes(tc.input))
+			n, err := newTestExprParser().Parse(context.Background(), p)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got, want := opString(n), tc.want; got != want {
+				t.Errorf("Parse: want: %q, got: %q", want, got)
+			}
+			if n.Parent != p.Root() {
+				t.Errorf("Parent: want: %v, got: %v", p.Root(), n.Parent)
+			}
+		})
+	}
+}
+
+func TestExprParser_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		err   error
+	}{
+		"non-associative": {
+			input: "1<2<3",
+			err:   ErrUnexpectedLexeme,
+		},
+		"missing operand": {
+			input: "1+",
+			err:   io.ErrUnexpectedEOF,
+		},
+		"unclosed call": {
+			input: "f(1",
+			err:   io.ErrUnexpectedEOF,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewParser[string](exprLexemes(tc.input))
+			_, err := newTestExprParser().Parse(context.Background(), p)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestExprParser_postfixOperand(t *testing.T) {
+	t.Parallel()
+
+	// Without an Operand function, postfix operators are not valid operands.
+	e := &ExprParser[string]{
+		Operators: testOperators,
+		Value: func(l *Lexeme) (string, error) {
+			return l.Value, nil
+		},
+	}
+	p := NewParser[string](exprLexemes("!1"))
+	if _, err := e.Parse(context.Background(), p); !errors.Is(err, ErrUnexpectedLexeme) {
+		t.Errorf("Parse: want: %v, got: %v", ErrUnexpectedLexeme, err)
+	}
+}
+
+func TestOperatorTable_GroupsLeft(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		left, right LexemeType
+		want        bool
+	}{
+		{plusType, plusType, true},
+		{plusType, starType, false},
+		{starType, plusType, true},
+		{caretType, caretType, false},
+	}
+
+	for _, tc := range testCases {
+		if got := testOperators.GroupsLeft(tc.left, tc.right); got != tc.want {
+			t.Errorf("GroupsLeft(%v, %v): want: %v, got: %v", tc.left, tc.right, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+)
+
+// Feeder is a push-style interface to a Lexer. Input is written to the
+// Feeder as it arrives, e.g. as frames received from a network connection,
+// and lexemes are emitted as soon as they are complete. Closing the Feeder
+// marks the end of the input.
+type Feeder struct {
+	in      *IncrementalInput
+	l       *Lexer
+	lexemes <-chan *Lexeme
+}
+
+// NewFeeder returns a new Feeder and starts lexing starting with the given
+// state. States must follow the requirements for States used with
+// NewIncrementalLexer. Lexing stops when the Feeder is closed and all input
+// is lexed, an error occurs, or ctx is cancelled.
+func NewFeeder(ctx context.Context, startingState State, opts ...LexerOption) *Feeder {
+	in := NewIncrementalInput()
+	l := NewIncrementalLexer(in, startingState, opts...)
+	return &Feeder{
+		in:      in,
+		l:       l,
+		lexemes: l.Lex(ctx),
+	}
+}
+
+// Write implements io.Writer and adds p to the input. If the lexer has
+// stopped with an error, the error is returned and p is not written.
+func (f *Feeder) Write(p []byte) (int, error) {
+	select {
+	case <-f.l.Done():
+		if err := f.l.Err(); err != nil {
+			return 0, err
+		}
+	default:
+	}
+	return f.in.Write(p)
+}
+
+// Close implements io.Closer and marks the end of the input.
+func (f *Feeder) Close() error {
+	return f.in.Close()
+}
+
+// Lexemes returns the channel of lexemes. The channel is closed when lexing
+// stops.
+func (f *Feeder) Lexemes() <-chan *Lexeme {
+	return f.lexemes
+}
+
+// Err returns the lexer's error.
+func (f *Feeder) Err() error {
+	return f.l.Err()
+}
+
+// Done returns a channel that is closed when the lexer is finished running.
+func (f *Feeder) Done() <-chan struct{} {
+	return f.l.Done()
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFeeder(t *testing.T) {
+	t.Parallel()
+
+	f := NewFeeder(context.Background(), &wordState{})
+
+	var got []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for l := range f.Lexemes() {
+			got = append(got, l.Value)
+		}
+	}()
+
+	for _, chunk := range []string{"GE", "T /ind", "ex HTTP/1.1"} {
+		if _, err := fmt.Fprint(f, chunk); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	<-done
+	<-f.Done()
+
+	if err := f.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if diff := cmp.Diff([]string{"GET", "/index", "HTTP/1.1"}, got); diff != "" {
+		t.Errorf("Lexemes: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestFeeder_error(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test error")
+	f := NewFeeder(context.Background(), StateFn(func(context.Context, *Lexer) (State, error) {
+		return nil, errTest
+	}))
+	<-f.Done()
+
+	if _, err := f.Write([]byte("A")); !errors.Is(err, errTest) {
+		t.Errorf("Write: want: %v, got: %v", errTest, err)
+	}
+}
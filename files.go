@@ -0,0 +1,212 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ianlewis/runeio"
+)
+
+// NewLexerFS returns a new Lexer that reads the file at path in fsys, e.g.
+// an embed.FS. The file is read into memory so there is nothing for the
+// caller to close. The path is used as the file name of lexemes and errors.
+// Options in opts are applied after the file name is set.
+func NewLexerFS(fsys fs.FS, path string, startingState State, opts ...LexerOption) (*Lexer, error) {
+	b, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	opts = append([]LexerOption{WithFilename(path)}, opts...)
+	return NewLexer(runeio.NewReader(bytes.NewReader(b)), startingState, opts...), nil
+}
+
+// NewLexerFile returns a new Lexer that reads from f. The base name of the
+// file, as returned by its Stat method, is used as the file name of lexemes
+// and errors. Options in opts are applied after the file name is set. The
+// caller is responsible for closing f after lexing is done.
+func NewLexerFile(f fs.File, startingState State, opts ...LexerOption) (*Lexer, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("reading file info: %w", err)
+	}
+	opts = append([]LexerOption{WithFilename(info.Name())}, opts...)
+	return NewLexer(runeio.NewReader(bufio.NewReader(f)), startingState, opts...), nil
+}
+
+// MetaFile is the metadata key under which FileParser stores the path of the
+// file on the root node of each tree.
+const MetaFile = "file"
+
+// FileError is an error that occurred while lexing or parsing a file.
+type FileError struct {
+	// Path is the path of the file.
+	Path string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements error. The path is omitted if the underlying error's
+// message already starts with it, as is the case for lexer errors.
+func (e *FileError) Error() string {
+	msg := e.Err.Error()
+	if strings.HasPrefix(msg, e.Path+": ") {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", e.Path, msg)
+}
+
+// Unwrap returns the underlying error.
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// FileParser lexes and parses multiple files, such as the files of a
+// compilation unit. Each file is lexed and parsed as if by LexParse.
+type FileParser[V comparable] struct {
+	// State is the initial lexer state for each file. It must be safe to use
+	// from multiple goroutines.
+	State State
+
+	// ParseFn is the initial parse function for each file. It must be safe
+	// to use from multiple goroutines.
+	ParseFn ParseFn[V]
+
+	// Workers is the number of files parsed concurrently. If zero or less
+	// runtime.GOMAXPROCS(0) is used.
+	Workers int
+
+	// LexerOptions are the options used for each file's Lexer. The path of
+	// the file is used as the file name of lexemes and errors. Options are
+	// applied after the file name is set.
+	LexerOptions []LexerOption
+}
+
+// ParseFiles lexes and parses the files in fsys matching the given patterns.
+// Patterns use the syntax of fs.Glob. The root node of each file's tree is
+// returned keyed by path and has the path stored in its metadata under
+// MetaFile. Files that fail to parse are not included in the result. Errors
+// for each file are returned as a FileError and joined together in path
+// order.
+func (f *FileParser[V]) ParseFiles(ctx context.Context, fsys fs.FS, patterns []string) (map[string]*Node[V], error) {
+	paths, err := globAll(fsys, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := f.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	trees := make([]*Node[V], len(paths))
+	errs := make([]error, len(paths))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				root, pErr := f.parseFile(ctx, fsys, paths[i])
+				if pErr != nil {
+					errs[i] = &FileError{Path: paths[i], Err: pErr}
+					continue
+				}
+				trees[i] = root
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	result := map[string]*Node[V]{}
+	for i, root := range trees {
+		if root != nil {
+			result[paths[i]] = root
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// parseFile lexes and parses the file at path.
+func (f *FileParser[V]) parseFile(ctx context.Context, fsys fs.FS, path string) (*Node[V], error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	defer file.Close()
+
+	opts := append([]LexerOption{WithFilename(path)}, f.LexerOptions...)
+	root, err := LexParse(ctx, runeio.NewReader(bufio.NewReader(file)), f.State, f.ParseFn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	root.SetMeta(MetaFile, path)
+	return root, nil
+}
+
+// globAll returns the sorted, unique paths in fsys matching any of the
+// patterns.
+func globAll(fsys fs.FS, patterns []string) ([]string, error) {
+	seen := map[string]bool{}
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("matching %q: %w", pattern, err)
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				paths = append(paths, m)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ParseFiles lexes and parses the files in fsys matching the given patterns
+// using a FileParser with the default number of workers.
+func ParseFiles[V comparable](
+	ctx context.Context,
+	fsys fs.FS,
+	patterns []string,
+	initState State,
+	initFn ParseFn[V],
+	opts ...LexerOption,
+) (map[string]*Node[V], error) {
+	f := &FileParser[V]{
+		State:        initState,
+		ParseFn:      initFn,
+		LexerOptions: opts,
+	}
+	return f.ParseFiles(ctx, fsys, patterns)
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseFiles(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("A B")},
+		"b.txt":     {Data: []byte("C")},
+		"sub/c.txt": {Data: []byte("D E")},
+		"d.md":      {Data: []byte("F")},
+	}
+
+	got, err := ParseFiles(context.Background(), fsys, []string{"*.txt", "sub/*.txt", "a.txt"}, &wordState{}, wordsParseFn)
+	if err != nil {
+		t.Fatalf("ParseFiles: %v", err)
+	}
+
+	want := map[string]*Node[string]{
+		"a.txt": newTree(
+			&Node[string]{Value: "A", Pos: 0, Line: 0, Column: 0},
+			&Node[string]{Value: "B", Pos: 2, Line: 0, Column: 2},
+		),
+		"b.txt": newTree(
+			&Node[string]{Value: "C", Pos: 0, Line: 0, Column: 0},
+		),
+		"sub/c.txt": newTree(
+			&Node[string]{Value: "D", Pos: 0, Line: 0, Column: 0},
+			&Node[string]{Value: "E", Pos: 2, Line: 0, Column: 2},
+		),
+	}
+	for path, root := range want {
+		root.SetMeta(MetaFile, path)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseFiles: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestFileParser_error(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test error")
+	f := &FileParser[string]{
+		State: &wordState{},
+		ParseFn: func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+			for l := p.Next(); l != nil; l = p.Next() {
+				if l.Value == "bad" {
+					return nil, errTest
+				}
+			}
+			return nil, nil
+		},
+		Workers: 1,
+	}
+
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("A")},
+		"b.txt": {Data: []byte("bad")},
+		"c.txt": {Data: []byte("C bad")},
+	}
+
+	got, err := f.ParseFiles(context.Background(), fsys, []string{"*.txt"})
+	if !errors.Is(err, errTest) {
+		t.Errorf("ParseFiles: want: %v, got: %v", errTest, err)
+	}
+
+	var fErr *FileError
+	if !errors.As(err, &fErr) {
+		t.Fatalf("ParseFiles: want: *FileError, got: %T", err)
+	}
+	if got, want := fErr.Path, "b.txt"; got != want {
+		t.Errorf("FileError.Path: want: %q, got: %q", want, got)
+	}
+	if got, want := err.Error(), "b.txt: test error\nc.txt: test error"; got != want {
+		t.Errorf("ParseFiles: want: %q, got: %q", want, got)
+	}
+
+	if _, ok := got["a.txt"]; !ok || len(got) != 1 {
+		t.Errorf("ParseFiles: want: [a.txt], got: %v", got)
+	}
+}
+
+func TestFileParser_filename(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	files := map[string]bool{}
+	f := &FileParser[string]{
+		State: &wordState{},
+		ParseFn: func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+			for l := p.Next(); l != nil; l = p.Next() {
+				mu.Lock()
+				files[l.File] = true
+				mu.Unlock()
+			}
+			return nil, nil
+		},
+		LexerOptions: []LexerOption{WithMaxTokens(1)},
+	}
+
+	fsys := fstest.MapFS{
+		"dir/a.txt": {Data: []byte("A B")},
+	}
+
+	_, err := f.ParseFiles(context.Background(), fsys, []string{"dir/*.txt"})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("ParseFiles: want: %v, got: %v", ErrLimitExceeded, err)
+	}
+	if got, want := err.Error(), "dir/a.txt: limit exceeded: token limit of 1: line 1, column 3"; got != want {
+		t.Errorf("ParseFiles: want: %q, got: %q", want, got)
+	}
+	if diff := cmp.Diff(map[string]bool{"dir/a.txt": true}, files); diff != "" {
+		t.Errorf("Lexeme.File: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestParseFiles_badPattern(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseFiles(context.Background(), fstest.MapFS{}, []string{"["}, &wordState{}, wordsParseFn)
+	if err == nil {
+		t.Errorf("ParseFiles: want error, got: %v", err)
+	}
+}
+
+func TestNewLexerFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"dir/a.txt": {Data: []byte("A B C")},
+	}
+
+	l, err := NewLexerFS(fsys, "dir/a.txt", &wordState{}, WithMaxTokens(2))
+	if err != nil {
+		t.Fatalf("NewLexerFS: %v", err)
+	}
+
+	var got []*Lexeme
+	for lexeme := range l.Lex(context.Background()) {
+		got = append(got, lexeme)
+	}
+	<-l.Done()
+
+	want := []*Lexeme{
+		{Type: wordType, Value: "A", Pos: 0, Column: 0, File: "dir/a.txt"},
+		{Type: wordType, Value: "B", Pos: 2, Column: 2, File: "dir/a.txt"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+
+	d := NewDiagnostic(l.Err())
+	if got, want := d.File, "dir/a.txt"; got != want {
+		t.Errorf("Diagnostic.File: want: %q, got: %q", want, got)
+	}
+	if got, want := d.Message, "dir/a.txt: limit exceeded: token limit of 2: line 1, column 5"; got != want {
+		t.Errorf("Diagnostic.Message: want: %q, got: %q", want, got)
+	}
+
+	if _, err := NewLexerFS(fsys, "missing.txt", &wordState{}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("NewLexerFS: want: %v, got: %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestNewLexerFile(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"dir/a.txt": {Data: []byte("A")},
+	}
+
+	f, err := fsys.Open("dir/a.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	l, err := NewLexerFile(f, &wordState{})
+	if err != nil {
+		t.Fatalf("NewLexerFile: %v", err)
+	}
+
+	var got []*Lexeme
+	for lexeme := range l.Lex(context.Background()) {
+		got = append(got, lexeme)
+	}
+
+	want := []*Lexeme{
+		{Type: wordType, Value: "A", File: "a.txt"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrTreeSyntax indicates that the input to ParseTree is not a valid tree
+// representation.
+var ErrTreeSyntax = errors.New("invalid tree syntax")
+
+// treeIndents are the prefixes written for each level of the tree.
+var treeIndents = []string{"├── ", "└── ", "│   ", "    "}
+
+// ColorMode controls whether output is colorized with ANSI escape sequences.
+type ColorMode int
+
+const (
+	// ColorNever disables colorized output.
+	ColorNever ColorMode = iota
+
+	// ColorAlways enables colorized output.
+	ColorAlways
+
+	// ColorAuto enables colorized output when writing to a terminal.
+	ColorAuto
+)
+
+// enabled returns true if output written to w should be colorized.
+func (m ColorMode) enabled(w io.Writer) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorAuto:
+		f, ok := w.(*os.File)
+		if !ok {
+			return false
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	default:
+		return false
+	}
+}
+
+// FormatOptions control how a tree is written by Node.Format.
+type FormatOptions[V comparable] struct {
+	// Value returns the text for a node's value. If nil, values are
+	// formatted using the %v verb.
+	Value func(v V) string
+
+	// MaxDepth is the maximum number of levels of the tree to write. Nodes
+	// below the maximum depth are replaced by "...". A value of zero or less
+	// means no limit.
+	MaxDepth int
+
+	// NoPos suppresses the line and column of each node.
+	NoPos bool
+
+	// Offsets includes the offset of each node in the input (Node.Pos)
+	// after its line and column, e.g. "(1:5#4)".
+	Offsets bool
+
+	// Compact writes the tree on a single line, e.g. "A(B(C) D)".
+	Compact bool
+
+	// Color colorizes values, positions, and tree lines using ANSI escape
+	// sequences. By default output is not colorized.
+	Color ColorMode
+}
+
+// String returns the canonical representation of the tree rooted at n. Each
+// node is written on its own line with its value formatted using the %v verb
+// and quoted, followed by its line, column, and offset. The canonical
+// representation can be read back using ParseTree.
+func (n *Node[V]) String() string {
+	var b strings.Builder
+	_ = n.Format(&b, &FormatOptions[V]{
+		Value: func(v V) string {
+			return strconv.Quote(fmt.Sprintf("%v", v))
+		},
+		Offsets: true,
+	})
+	return b.String()
+}
+
+// Format writes the tree rooted at n to w. By default each node is written on
+// its own line along with its line and column and box-drawing characters
+// showing the structure of the tree. A nil opts uses the default options.
+func (n *Node[V]) Format(w io.Writer, opts *FormatOptions[V]) error {
+	if opts == nil {
+		opts = &FormatOptions[V]{}
+	}
+	f := &formatter[V]{
+		w:     bufio.NewWriter(w),
+		opts:  opts,
+		color: opts.Color.enabled(w),
+	}
+	if opts.Compact {
+		f.compact(n, 1)
+	} else {
+		f.node(n, "", "", 1)
+	}
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return f.w.Flush()
+}
+
+type formatter[V comparable] struct {
+	w    *bufio.Writer
+	opts *FormatOptions[V]
+
+	// color is true if output is colorized.
+	color bool
+}
+
+// paint returns s colorized with the given ANSI color if colorized output
+// is enabled.
+func (f *formatter[V]) paint(color, s string) string {
+	if !f.color || s == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// value returns the text for the node's value.
+func (f *formatter[V]) value(n *Node[V]) string {
+	if n == nil {
+		return "<nil>"
+	}
+	if f.opts.Value != nil {
+		return f.opts.Value(n.Value)
+	}
+	return fmt.Sprintf("%v", n.Value)
+}
+
+// pos returns the node's one-indexed line and column or the empty string if
+// positions are suppressed.
+func (f *formatter[V]) pos(n *Node[V]) string {
+	if n == nil || f.opts.NoPos {
+		return ""
+	}
+	if f.opts.Offsets {
+		return fmt.Sprintf("%d:%d#%d", n.Line+1, n.Column+1, n.Pos)
+	}
+	return fmt.Sprintf("%d:%d", n.Line+1, n.Column+1)
+}
+
+// elided returns true if the children of a node at the given depth should not
+// be written.
+func (f *formatter[V]) elided(n *Node[V], depth int) bool {
+	return n != nil && len(n.Children) > 0 && f.opts.MaxDepth > 0 && depth >= f.opts.MaxDepth
+}
+
+// node writes n using box-drawing characters. prefix is written before the
+// node's label and childPrefix is written before each line of its children.
+func (f *formatter[V]) node(n *Node[V], prefix, childPrefix string, depth int) {
+	label := f.paint(ansiCyan, f.value(n))
+	if pos := f.pos(n); pos != "" {
+		label += " " + f.paint(ansiGray, "("+pos+")")
+	}
+	_, _ = f.w.WriteString(f.paint(ansiGray, prefix) + label + "\n")
+
+	if n == nil {
+		return
+	}
+	if f.elided(n, depth) {
+		_, _ = f.w.WriteString(f.paint(ansiGray, childPrefix+"└── ...") + "\n")
+		return
+	}
+
+	for i, c := range n.Children {
+		if i == len(n.Children)-1 {
+			f.node(c, childPrefix+"└── ", childPrefix+"    ", depth+1)
+		} else {
+			f.node(c, childPrefix+"├── ", childPrefix+"│   ", depth+1)
+		}
+	}
+}
+
+// compact writes n on a single line.
+func (f *formatter[V]) compact(n *Node[V], depth int) {
+	_, _ = f.w.WriteString(f.paint(ansiCyan, f.value(n)))
+	if pos := f.pos(n); pos != "" {
+		_, _ = f.w.WriteString(f.paint(ansiGray, "@"+pos))
+	}
+	if n == nil || len(n.Children) == 0 {
+		return
+	}
+
+	_, _ = f.w.WriteString(f.paint(ansiGray, "("))
+	if f.elided(n, depth) {
+		_, _ = f.w.WriteString(f.paint(ansiGray, "..."))
+	} else {
+		for i, c := range n.Children {
+			if i > 0 {
+				_ = f.w.WriteByte(' ')
+			}
+			f.compact(c, depth+1)
+		}
+	}
+	_, _ = f.w.WriteString(f.paint(ansiGray, ")"))
+}
+
+// ParseTree reads a tree in the representation produced by Node.String and
+// returns its root. The text of each value is unquoted and passed to value to
+// obtain the node value. Positions are optional and default to zero. Trees
+// formatted with a maximum depth cannot be read.
+func ParseTree[V comparable](s string, value func(string) (V, error)) (*Node[V], error) {
+	var root *Node[V]
+
+	// stack holds the most recent node at each depth.
+	var stack []*Node[V]
+	for i, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		depth := 0
+		for {
+			var found bool
+			for _, indent := range treeIndents {
+				if strings.HasPrefix(line, indent) {
+					line = line[len(indent):]
+					found = true
+					break
+				}
+			}
+			if !found {
+				break
+			}
+			depth++
+		}
+
+		if depth > len(stack) || (depth == 0 && root != nil) {
+			return nil, fmt.Errorf("%w: line %d: unexpected indentation", ErrTreeSyntax, i+1)
+		}
+
+		n, err := parseTreeNode(line, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d", err, i+1)
+		}
+
+		stack = stack[:depth]
+		if depth == 0 {
+			root = n
+		} else {
+			parent := stack[depth-1]
+			if parent == nil {
+				return nil, fmt.Errorf("%w: line %d: child of nil node", ErrTreeSyntax, i+1)
+			}
+			if n != nil {
+				n.Parent = parent
+			}
+			parent.Children = append(parent.Children, n)
+		}
+		stack = append(stack, n)
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("%w: missing root node", ErrTreeSyntax)
+	}
+	return root, nil
+}
+
+// parseTreeNode parses a single node in the tree representation.
+func parseTreeNode[V comparable](line string, value func(string) (V, error)) (*Node[V], error) {
+	if line == "<nil>" {
+		return nil, nil
+	}
+
+	quoted, err := strconv.QuotedPrefix(line)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid value %q", ErrTreeSyntax, line)
+	}
+	text, err := strconv.Unquote(quoted)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid value %q", ErrTreeSyntax, quoted)
+	}
+	v, err := value(text)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node[V]{Value: v}
+	pos := line[len(quoted):]
+	if pos == "" {
+		return n, nil
+	}
+
+	var lineNum, col int
+	var want string
+	if strings.Contains(pos, "#") {
+		_, err = fmt.Sscanf(pos, " (%d:%d#%d)", &lineNum, &col, &n.Pos)
+		want = fmt.Sprintf(" (%d:%d#%d)", lineNum, col, n.Pos)
+	} else {
+		_, err = fmt.Sscanf(pos, " (%d:%d)", &lineNum, &col)
+		want = fmt.Sprintf(" (%d:%d)", lineNum, col)
+	}
+	if err != nil || pos != want || lineNum < 1 || col < 1 {
+		return nil, fmt.Errorf("%w: invalid position %q", ErrTreeSyntax, pos)
+	}
+	n.Line = lineNum - 1
+	n.Column = col - 1
+	return n, nil
+}
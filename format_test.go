@@ -0,0 +1,215 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func formatTestTree() *Node[string] {
+	return addParent(&Node[string]{
+		Value: "A",
+		Children: []*Node[string]{
+			{
+				Value:  "B",
+				Column: 2,
+				Children: []*Node[string]{
+					{Value: "D", Line: 1},
+				},
+			},
+			{Value: "C", Column: 4},
+		},
+	})
+}
+
+func TestNode_String(t *testing.T) {
+	t.Parallel()
+
+	want := `"A" (1:1#0)
+├── "B" (1:3#0)
+│   └── "D" (2:1#0)
+└── "C" (1:5#0)
+`
+	if diff := cmp.Diff(want, formatTestTree().String()); diff != "" {
+		t.Errorf("String: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestNode_Format(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		opts *FormatOptions[string]
+		want string
+	}{
+		"value": {
+			opts: &FormatOptions[string]{
+				Value:   strings.ToLower,
+				NoPos:   true,
+				Compact: true,
+			},
+			want: "a(b(d) c)",
+		},
+		"max depth": {
+			opts: &FormatOptions[string]{
+				MaxDepth: 2,
+				NoPos:    true,
+			},
+			want: "A\n├── B\n│   └── ...\n└── C\n",
+		},
+		"compact": {
+			opts: &FormatOptions[string]{
+				Compact: true,
+			},
+			want: "A@1:1(B@1:3(D@2:1) C@1:5)",
+		},
+		"compact max depth": {
+			opts: &FormatOptions[string]{
+				MaxDepth: 1,
+				NoPos:    true,
+				Compact:  true,
+			},
+			want: "A(...)",
+		},
+		"color": {
+			opts: &FormatOptions[string]{
+				MaxDepth: 2,
+				Color:    ColorAlways,
+			},
+			want: "\x1b[36mA\x1b[0m \x1b[90m(1:1)\x1b[0m\n" +
+				"\x1b[90m├── \x1b[0m\x1b[36mB\x1b[0m \x1b[90m(1:3)\x1b[0m\n" +
+				"\x1b[90m│   └── ...\x1b[0m\n" +
+				"\x1b[90m└── \x1b[0m\x1b[36mC\x1b[0m \x1b[90m(1:5)\x1b[0m\n",
+		},
+		"compact color": {
+			opts: &FormatOptions[string]{
+				NoPos:   true,
+				Compact: true,
+				Color:   ColorAlways,
+			},
+			want: "\x1b[36mA\x1b[0m\x1b[90m(\x1b[0m\x1b[36mB\x1b[0m\x1b[90m(\x1b[0m\x1b[36mD\x1b[0m" +
+				"\x1b[90m)\x1b[0m \x1b[36mC\x1b[0m\x1b[90m)\x1b[0m",
+		},
+		"color auto": {
+			opts: &FormatOptions[string]{
+				NoPos:   true,
+				Compact: true,
+				Color:   ColorAuto,
+			},
+			want: "A(B(D) C)",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var b strings.Builder
+			if err := formatTestTree().Format(&b, tc.opts); err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, b.String()); diff != "" {
+				t.Errorf("Format: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	t.Parallel()
+
+	want := addParent(&Node[string]{
+		Value: "root",
+		Children: []*Node[string]{
+			{
+				Value:  "multi\nline",
+				Pos:    6,
+				Line:   1,
+				Column: 2,
+				Children: []*Node[string]{
+					{Value: `"quoted" (1:1)`, Pos: 8},
+				},
+			},
+			{Value: "", Pos: 10},
+		},
+	})
+	// Binary trees may contain nil children.
+	want.Children[0].Children = append(want.Children[0].Children, nil)
+
+	got, err := ParseTree(want.String(), func(s string) (string, error) {
+		return s, nil
+	})
+	if err != nil {
+		t.Fatalf("ParseTree: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseTree: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestParseTree_value(t *testing.T) {
+	t.Parallel()
+
+	want := addParent(&Node[int]{
+		Value:    1,
+		Children: []*Node[int]{{Value: 2}},
+	})
+
+	got, err := ParseTree(want.String(), strconv.Atoi)
+	if err != nil {
+		t.Fatalf("ParseTree: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseTree: (-want, +got): \n%s", diff)
+	}
+
+	if _, err := ParseTree(`"x"`, strconv.Atoi); !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("ParseTree: want: %v, got: %v", strconv.ErrSyntax, err)
+	}
+}
+
+func TestParseTree_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"empty":           "",
+		"unquoted":        "A",
+		"bad position":    `"A" (0:1)`,
+		"trailing text":   `"A" (1:1) B`,
+		"two roots":       "\"A\"\n\"B\"",
+		"bad indentation": "\"A\"\n│   └── \"B\"",
+		"child of nil":    "\"A\"\n└── <nil>\n    └── \"B\"",
+	}
+
+	for name, input := range testCases {
+		input := input
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseTree(input, func(s string) (string, error) {
+				return s, nil
+			})
+			if !errors.Is(err, ErrTreeSyntax) {
+				t.Errorf("ParseTree: want: %v, got: %v", ErrTreeSyntax, err)
+			}
+		})
+	}
+}
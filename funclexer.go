@@ -0,0 +1,133 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// FuncLexer adapts a function that returns lexemes one at a time, e.g. a
+// wrapper around an existing or third-party tokenizer, to a channel of
+// lexemes that can be used as the input to a Parser.
+type FuncLexer struct {
+	next func(context.Context) (*Lexeme, error)
+
+	lexemes chan *Lexeme
+	done    chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// FromFunc returns a new FuncLexer that calls next to get each lexeme. next
+// returns io.EOF, or a nil Lexeme and nil error, at the end of the input.
+// Other errors stop the lexer and are returned by Err.
+func FromFunc(next func(context.Context) (*Lexeme, error)) *FuncLexer {
+	return &FuncLexer{
+		next:    next,
+		lexemes: make(chan *Lexeme),
+		done:    make(chan struct{}),
+	}
+}
+
+// FromSlice returns a new FuncLexer that returns the given lexemes in order.
+// It is useful for tests and for lexemes produced ahead of time.
+func FromSlice(lexemes []*Lexeme) *FuncLexer {
+	i := 0
+	return FromFunc(func(context.Context) (*Lexeme, error) {
+		if i >= len(lexemes) {
+			return nil, io.EOF
+		}
+		i++
+		return lexemes[i-1], nil
+	})
+}
+
+// FromScanner returns a new FuncLexer that returns each token scanned by s as
+// a lexeme of type typ, e.g. the lines or words of the input when s uses
+// bufio.ScanLines or bufio.ScanWords. bufio.Scanner doesn't report where
+// tokens are in the input so the positions of the lexemes are zero.
+func FromScanner(s *bufio.Scanner, typ LexemeType) *FuncLexer {
+	return FromFunc(func(context.Context) (*Lexeme, error) {
+		if !s.Scan() {
+			if err := s.Err(); err != nil {
+				return nil, fmt.Errorf("scanning input: %w", err)
+			}
+			return nil, io.EOF
+		}
+		return &Lexeme{
+			Type:  typ,
+			Value: s.Text(),
+		}, nil
+	})
+}
+
+// Lex starts a new goroutine that calls the FuncLexer's function and returns
+// a channel of lexemes. The channel is closed when the end of the input is
+// reached, an error occurs, or ctx is cancelled.
+func (l *FuncLexer) Lex(ctx context.Context) <-chan *Lexeme {
+	go func() {
+		defer close(l.done)
+		defer close(l.lexemes)
+
+		for {
+			lexeme, err := l.next(ctx)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					l.setErr(err)
+				}
+				return
+			}
+			if lexeme == nil {
+				return
+			}
+
+			select {
+			case l.lexemes <- lexeme:
+			case <-ctx.Done():
+				l.setErr(ctx.Err())
+				return
+			}
+		}
+	}()
+	return l.lexemes
+}
+
+// setErr sets the lexer's error value.
+func (l *FuncLexer) setErr(err error) {
+	l.mu.Lock()
+	if l.err == nil {
+		l.err = err
+	}
+	l.mu.Unlock()
+}
+
+// Err returns the first error encountered.
+func (l *FuncLexer) Err() error {
+	l.mu.Lock()
+	err := l.err
+	l.mu.Unlock()
+	return err
+}
+
+// Done returns a channel that is closed when the lexer is finished running.
+func (l *FuncLexer) Done() <-chan struct{} {
+	return l.done
+}
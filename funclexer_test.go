@@ -0,0 +1,142 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// collect returns the lexemes emitted by l and its error.
+func collect(l *FuncLexer) ([]*Lexeme, error) {
+	var lexemes []*Lexeme
+	for lexeme := range l.Lex(context.Background()) {
+		lexemes = append(lexemes, lexeme)
+	}
+	<-l.Done()
+	return lexemes, l.Err()
+}
+
+func TestFromSlice(t *testing.T) {
+	t.Parallel()
+
+	want := []*Lexeme{
+		{Type: wordType, Value: "a"},
+		{Type: wordType, Value: "b", Pos: 2, Column: 2},
+	}
+	got, err := collect(FromSlice(want))
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestFromScanner(t *testing.T) {
+	t.Parallel()
+
+	s := bufio.NewScanner(strings.NewReader("Hello Lexemes!\nFoo"))
+	s.Split(bufio.ScanWords)
+
+	got, err := collect(FromScanner(s, wordType))
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	want := []*Lexeme{
+		{Type: wordType, Value: "Hello"},
+		{Type: wordType, Value: "Lexemes!"},
+		{Type: wordType, Value: "Foo"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestFromFunc(t *testing.T) {
+	t.Parallel()
+
+	errTokenizer := errors.New("tokenizer error")
+
+	testCases := map[string]struct {
+		err  error
+		want []*Lexeme
+	}{
+		"nil lexeme": {
+			want: []*Lexeme{{Type: wordType, Value: "a"}},
+		},
+		"error": {
+			err:  errTokenizer,
+			want: []*Lexeme{{Type: wordType, Value: "a"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			done := false
+			l := FromFunc(func(context.Context) (*Lexeme, error) {
+				if done {
+					return nil, tc.err
+				}
+				done = true
+				return &Lexeme{Type: wordType, Value: "a"}, nil
+			})
+
+			got, err := collect(l)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Err: want: %v, got: %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Lex: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFromFunc_parser(t *testing.T) {
+	t.Parallel()
+
+	l := FromSlice([]*Lexeme{
+		{Type: wordType, Value: "A"},
+		{Type: wordType, Value: "B"},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := NewParser[string](l.Lex(ctx))
+	p.SetLexerErr(l.Err)
+	root, err := p.Parse(ctx, func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		for lexeme := p.Next(); lexeme != nil; lexeme = p.Next() {
+			_ = p.Node(lexeme.Value)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := newTree(&Node[string]{Value: "A"}, &Node[string]{Value: "B"})
+	if diff := cmp.Diff(want, root); diff != "" {
+		t.Errorf("Parse: (-want, +got): \n%s", diff)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/ianlewis/lexparse
 
-go 1.18
+go 1.20
 
 require (
 	github.com/google/go-cmp v0.6.0
@@ -0,0 +1,94 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// gobNode is the gob encoding of a Node. It omits the Parent reference so
+// that trees can be encoded without cycles.
+type gobNode[V comparable] struct {
+	// Nil is true if the node is a nil child.
+	Nil bool
+
+	Children []*gobNode[V]
+	Value    V
+	Pos      int
+	Line     int
+	Column   int
+	Meta     map[string]any
+}
+
+// toGob returns the gob encoding of the tree rooted at n.
+func toGob[V comparable](n *Node[V]) *gobNode[V] {
+	if n == nil {
+		return &gobNode[V]{Nil: true}
+	}
+	g := &gobNode[V]{
+		Value:  n.Value,
+		Pos:    n.Pos,
+		Line:   n.Line,
+		Column: n.Column,
+		Meta:   n.Meta,
+	}
+	for _, c := range n.Children {
+		g.Children = append(g.Children, toGob(c))
+	}
+	return g
+}
+
+// fromGob sets the fields of n from g and restores Parent references.
+func fromGob[V comparable](n *Node[V], g *gobNode[V]) {
+	n.Value = g.Value
+	n.Pos = g.Pos
+	n.Line = g.Line
+	n.Column = g.Column
+	n.Meta = g.Meta
+	n.Children = nil
+	for _, gc := range g.Children {
+		if gc.Nil {
+			n.Children = append(n.Children, nil)
+			continue
+		}
+		c := &Node[V]{Parent: n}
+		fromGob(c, gc)
+		n.Children = append(n.Children, c)
+	}
+}
+
+// GobEncode implements gob.GobEncoder. The tree rooted at the node is
+// encoded without Parent references. Concrete types stored in Meta must be
+// registered with gob.Register.
+func (p *Node[V]) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(toGob(p)); err != nil {
+		return nil, fmt.Errorf("encoding node: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder. The Parent references of the decoded
+// children are restored. The Parent of the node itself is not changed.
+func (p *Node[V]) GobDecode(b []byte) error {
+	var g gobNode[V]
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&g); err != nil {
+		return fmt.Errorf("decoding node: %w", err)
+	}
+	fromGob(p, &g)
+	return nil
+}
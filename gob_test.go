@@ -0,0 +1,85 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNode_Gob(t *testing.T) {
+	t.Parallel()
+
+	root := newTree(
+		addParent(&Node[string]{
+			Value: "A",
+			Pos:   1,
+			Children: []*Node[string]{
+				{Value: "B", Pos: 2, Line: 1, Column: 3},
+			},
+		}),
+		&Node[string]{Value: "C"},
+	)
+	root.Children[1].SetMeta("type", "int")
+	// Nil children are preserved.
+	root.Children = append(root.Children, nil)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(root); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var got *Node[string]
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if diff := cmp.Diff(root.Strip(), got.Strip()); diff != "" {
+		t.Errorf("Decode: (-want, +got): \n%s", diff)
+	}
+
+	// Parent references are restored.
+	b := got.Children[0].Children[0]
+	if got, want := b.Parent, got.Children[0]; got != want {
+		t.Errorf("Parent: want: %p, got: %p", want, got)
+	}
+	if got.Parent != nil {
+		t.Errorf("Parent: want: nil, got: %v", got.Parent)
+	}
+}
+
+func TestLexeme_Gob(t *testing.T) {
+	t.Parallel()
+
+	want := []*Lexeme{
+		{Type: identType, Value: "a", Pos: 1, Line: 2, Column: 3, File: "a.txt"},
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(want); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var got []*Lexeme
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Decode: (-want, +got): \n%s", diff)
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrGrammar indicates that a Grammar is malformed.
+var ErrGrammar = errors.New("invalid grammar")
+
+// Symbol is a terminal or nonterminal symbol in a Grammar.
+type Symbol struct {
+	// Name is the name of a nonterminal symbol. It is empty for terminal
+	// symbols.
+	Name string
+
+	// Type is the lexeme type matched by a terminal symbol.
+	Type LexemeType
+}
+
+// Term returns a terminal Symbol that matches lexemes of the given type.
+func Term(typ LexemeType) Symbol {
+	return Symbol{Type: typ}
+}
+
+// NonTerm returns a nonterminal Symbol with the given name.
+func NonTerm(name string) Symbol {
+	return Symbol{Name: name}
+}
+
+// IsTerminal returns true if s is a terminal symbol.
+func (s Symbol) IsTerminal() bool {
+	return s.Name == ""
+}
+
+// String returns the name of a nonterminal or the lexeme type of a terminal.
+func (s Symbol) String() string {
+	if s.IsTerminal() {
+		return fmt.Sprintf("<%v>", s.Type)
+	}
+	return s.Name
+}
+
+// Production is a rule in a Grammar. It states that the nonterminal Name may
+// be replaced by the sequence of Symbols. A production with no symbols
+// matches the empty string.
+type Production struct {
+	// Name is the name of the nonterminal defined by the production.
+	Name string
+
+	// Symbols is the sequence of symbols that the nonterminal expands to.
+	Symbols []Symbol
+}
+
+// String returns the production in BNF-like notation.
+func (p *Production) String() string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+	b.WriteString(" ->")
+	if len(p.Symbols) == 0 {
+		b.WriteString(" ε")
+	}
+	for _, s := range p.Symbols {
+		b.WriteString(" ")
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+// Grammar is a context-free grammar over lexeme types. Grammars are used by
+// the table-driven parsers as an alternative to writing ParseFn functions by
+// hand.
+type Grammar struct {
+	// Start is the name of the start symbol.
+	Start string
+
+	// Productions are the rules of the grammar. Alternatives for a
+	// nonterminal are written as multiple productions with the same Name.
+	Productions []Production
+}
+
+// compiledGrammar is a Grammar with its symbols numbered for table
+// construction. Terminals are numbered starting at zero followed by the
+// nonterminals. Terminal zero is the end of input and nonterminal zero is the
+// augmented start symbol.
+type compiledGrammar struct {
+	g *Grammar
+
+	// terms maps lexeme types to terminal ids.
+	terms map[LexemeType]int
+
+	// termTypes maps terminal ids to lexeme types.
+	termTypes []LexemeType
+
+	// nonterms maps nonterminal names to symbol ids.
+	nonterms map[string]int
+
+	// ntNames maps nonterminal indexes to names.
+	ntNames []string
+
+	// prods are the productions. Production zero is the augmented start
+	// production.
+	prods []compiledProduction
+
+	// prodsByLHS maps nonterminal symbol ids to their productions.
+	prodsByLHS map[int][]int
+
+	// nullable reports whether a symbol can derive the empty string.
+	nullable []bool
+
+	// first holds the set of terminals that can start each symbol.
+	first [][]bool
+}
+
+type compiledProduction struct {
+	// lhs is the symbol id of the nonterminal being defined.
+	lhs int
+
+	// rhs are the symbol ids of the production's symbols.
+	rhs []int
+
+	// src is the index of the production in the Grammar or -1 for the
+	// augmented start production.
+	src int
+}
+
+// compileGrammar validates g and numbers its symbols.
+func compileGrammar(g *Grammar) (*compiledGrammar, error) {
+	if g == nil || g.Start == "" {
+		return nil, fmt.Errorf("%w: missing start symbol", ErrGrammar)
+	}
+
+	c := &compiledGrammar{
+		g:          g,
+		terms:      map[LexemeType]int{},
+		termTypes:  []LexemeType{0},
+		nonterms:   map[string]int{},
+		ntNames:    []string{g.Start + "'"},
+		prodsByLHS: map[int][]int{},
+	}
+
+	ntIndex := map[string]int{}
+	for i := range g.Productions {
+		p := &g.Productions[i]
+		if p.Name == "" {
+			return nil, fmt.Errorf("%w: production %d has no name", ErrGrammar, i)
+		}
+		if _, ok := ntIndex[p.Name]; !ok {
+			ntIndex[p.Name] = len(c.ntNames)
+			c.ntNames = append(c.ntNames, p.Name)
+		}
+	}
+	if _, ok := ntIndex[g.Start]; !ok {
+		return nil, fmt.Errorf("%w: undefined start symbol %q", ErrGrammar, g.Start)
+	}
+
+	for i := range g.Productions {
+		for _, s := range g.Productions[i].Symbols {
+			if s.IsTerminal() {
+				if _, ok := c.terms[s.Type]; !ok {
+					c.terms[s.Type] = len(c.termTypes)
+					c.termTypes = append(c.termTypes, s.Type)
+				}
+				continue
+			}
+			if _, ok := ntIndex[s.Name]; !ok {
+				return nil, fmt.Errorf("%w: undefined nonterminal %q", ErrGrammar, s.Name)
+			}
+		}
+	}
+
+	nT := len(c.termTypes)
+	for name, i := range ntIndex {
+		c.nonterms[name] = nT + i
+	}
+
+	c.addProduction(compiledProduction{
+		lhs: nT,
+		rhs: []int{c.nonterms[g.Start]},
+		src: -1,
+	})
+	for i := range g.Productions {
+		p := &g.Productions[i]
+		rhs := make([]int, len(p.Symbols))
+		for j, s := range p.Symbols {
+			rhs[j] = c.symbol(s)
+		}
+		c.addProduction(compiledProduction{
+			lhs: c.nonterms[p.Name],
+			rhs: rhs,
+			src: i,
+		})
+	}
+
+	c.computeFirst()
+
+	return c, nil
+}
+
+func (c *compiledGrammar) addProduction(p compiledProduction) {
+	c.prodsByLHS[p.lhs] = append(c.prodsByLHS[p.lhs], len(c.prods))
+	c.prods = append(c.prods, p)
+}
+
+// numTerms returns the number of terminals including the end of input.
+func (c *compiledGrammar) numTerms() int {
+	return len(c.termTypes)
+}
+
+// numSymbols returns the total number of terminals and nonterminals.
+func (c *compiledGrammar) numSymbols() int {
+	return len(c.termTypes) + len(c.ntNames)
+}
+
+// isTerm returns true if the symbol id is a terminal.
+func (c *compiledGrammar) isTerm(id int) bool {
+	return id < len(c.termTypes)
+}
+
+// symbol returns the symbol id for s.
+func (c *compiledGrammar) symbol(s Symbol) int {
+	if s.IsTerminal() {
+		return c.terms[s.Type]
+	}
+	return c.nonterms[s.Name]
+}
+
+// symbolName returns a human readable name for the symbol id.
+func (c *compiledGrammar) symbolName(id int) string {
+	switch {
+	case id == 0:
+		return "EOF"
+	case c.isTerm(id):
+		return Term(c.termTypes[id]).String()
+	default:
+		return c.ntNames[id-len(c.termTypes)]
+	}
+}
+
+// computeFirst computes the nullable and FIRST sets for all symbols.
+func (c *compiledGrammar) computeFirst() {
+	nT := c.numTerms()
+	c.nullable = make([]bool, c.numSymbols())
+	c.first = make([][]bool, c.numSymbols())
+	for i := range c.first {
+		c.first[i] = make([]bool, nT)
+		if i < nT {
+			c.first[i][i] = true
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, p := range c.prods {
+			nullable := true
+			for _, s := range p.rhs {
+				if union(c.first[p.lhs], c.first[s]) {
+					changed = true
+				}
+				if !c.nullable[s] {
+					nullable = false
+					break
+				}
+			}
+			if nullable && !c.nullable[p.lhs] {
+				c.nullable[p.lhs] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// firstSeq adds the FIRST set of the symbol sequence to set and returns true
+// if the sequence is nullable.
+func (c *compiledGrammar) firstSeq(syms []int, set []bool) bool {
+	for _, s := range syms {
+		_ = union(set, c.first[s])
+		if !c.nullable[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// union adds the members of src to dst and returns true if dst changed. dst
+// must be at least as long as src.
+func union(dst, src []bool) bool {
+	var changed bool
+	for i, ok := range src {
+		if ok && !dst[i] {
+			dst[i] = true
+			changed = true
+		}
+	}
+	return changed
+}
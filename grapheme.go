@@ -0,0 +1,73 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import "unicode"
+
+const (
+	// zwj is the zero width joiner used to join emoji sequences.
+	zwj = '\u200d'
+
+	// riFirst and riLast are the first and last regional indicator runes
+	// used in pairs for flag emoji.
+	riFirst = '\U0001f1e6'
+	riLast  = '\U0001f1ff'
+)
+
+// graphemeExtend are runes that extend the preceding grapheme cluster.
+var graphemeExtend = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		// Zero width non-joiner and joiner.
+		{Lo: 0x200c, Hi: 0x200d, Stride: 1},
+		// Variation selectors.
+		{Lo: 0xfe00, Hi: 0xfe0f, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		// Emoji skin tone modifiers.
+		{Lo: 0x1f3fb, Hi: 0x1f3ff, Stride: 1},
+		// Tags used in emoji tag sequences.
+		{Lo: 0xe0020, Hi: 0xe007f, Stride: 1},
+		// Variation selectors supplement.
+		{Lo: 0xe0100, Hi: 0xe01ef, Stride: 1},
+	},
+}
+
+// graphemeState tracks the state needed to find grapheme cluster boundaries
+// in a sequence of runes.
+//
+// Boundaries are approximated using the Unicode tables in the standard
+// library: combining marks, joiners, variation selectors, and emoji modifiers
+// extend the previous cluster, runes following a zero width joiner are joined
+// to it, and regional indicators are paired. Other rules, such as for Hangul
+// syllables and prepended concatenation marks, are not implemented.
+type graphemeState struct {
+	// prev is the previous rune or zero at the start of a line.
+	prev rune
+
+	// riOdd is true if an odd number of consecutive regional indicators
+	// precede the current rune.
+	riOdd bool
+}
+
+// next returns true if rn starts a new grapheme cluster.
+func (g *graphemeState) next(rn rune) bool {
+	isRI := rn >= riFirst && rn <= riLast
+	extends := g.prev != 0 &&
+		(g.prev == zwj || (isRI && g.riOdd) || unicode.Is(unicode.M, rn) || unicode.Is(graphemeExtend, rn))
+
+	g.riOdd = isRI && !g.riOdd
+	g.prev = rn
+	return !extends
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"context"
+	"html"
+	"io"
+	"unicode/utf8"
+)
+
+// HighlightClass is a category of lexemes used for syntax highlighting.
+type HighlightClass int
+
+const (
+	// HighlightNone is used for lexemes that are not highlighted.
+	HighlightNone HighlightClass = iota
+
+	// HighlightKeyword is used for keywords.
+	HighlightKeyword
+
+	// HighlightString is used for string literals.
+	HighlightString
+
+	// HighlightNumber is used for numeric literals.
+	HighlightNumber
+
+	// HighlightComment is used for comments.
+	HighlightComment
+
+	// HighlightOperator is used for operators and punctuation.
+	HighlightOperator
+)
+
+// String returns the name of the class, e.g. "keyword". The name is used as
+// the HTML class of highlighted lexemes.
+func (c HighlightClass) String() string {
+	switch c {
+	case HighlightNone:
+		return "none"
+	case HighlightKeyword:
+		return "keyword"
+	case HighlightString:
+		return "string"
+	case HighlightNumber:
+		return "number"
+	case HighlightComment:
+		return "comment"
+	case HighlightOperator:
+		return "operator"
+	default:
+		return "unknown"
+	}
+}
+
+// ANSI escape sequences used for highlighting.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+	ansiGray    = "\x1b[90m"
+)
+
+// ansiColors are the colors used by HighlightANSI for each class.
+var ansiColors = map[HighlightClass]string{
+	HighlightKeyword:  ansiMagenta,
+	HighlightString:   ansiGreen,
+	HighlightNumber:   ansiCyan,
+	HighlightComment:  ansiGray,
+	HighlightOperator: ansiYellow,
+}
+
+// Classifier returns the highlight class for a lexeme.
+type Classifier func(l *Lexeme) HighlightClass
+
+// ClassifyTypes returns a Classifier that maps lexeme types to highlight
+// classes. Lexemes with types not in the map are not highlighted.
+func ClassifyTypes(types map[LexemeType]HighlightClass) Classifier {
+	return func(l *Lexeme) HighlightClass {
+		return types[l.Type]
+	}
+}
+
+// HighlightHTML writes the input src to w with each lexeme received from
+// lexemes wrapped in a span element whose class is the name of the lexeme's
+// HighlightClass, e.g. <span class="keyword">func</span>. The text between
+// lexemes, such as whitespace, is written without highlighting. All text is
+// HTML escaped.
+func HighlightHTML(ctx context.Context, w io.Writer, src string, lexemes <-chan *Lexeme, classify Classifier) error {
+	return highlight(ctx, w, src, lexemes, classify, func(c HighlightClass, text string) string {
+		if c == HighlightNone {
+			return html.EscapeString(text)
+		}
+		return `<span class="` + c.String() + `">` + html.EscapeString(text) + "</span>"
+	})
+}
+
+// HighlightANSI writes the input src to w with each lexeme received from
+// lexemes colored using ANSI escape sequences according to its
+// HighlightClass. The text between lexemes, such as whitespace, is written
+// without highlighting.
+func HighlightANSI(ctx context.Context, w io.Writer, src string, lexemes <-chan *Lexeme, classify Classifier) error {
+	return highlight(ctx, w, src, lexemes, classify, func(c HighlightClass, text string) string {
+		color, ok := ansiColors[c]
+		if !ok {
+			return text
+		}
+		return color + text + ansiReset
+	})
+}
+
+// highlight writes src to w rendering each lexeme using render. Lexemes are
+// located in src using their positions.
+func highlight(
+	ctx context.Context,
+	w io.Writer,
+	src string,
+	lexemes <-chan *Lexeme,
+	classify Classifier,
+	render func(HighlightClass, string) string,
+) error {
+	bw := bufio.NewWriter(w)
+	rns := []rune(src)
+
+	// pos is the position in src up to which output has been written.
+	var pos int
+	for {
+		var l *Lexeme
+		var ok bool
+		select {
+		case l, ok = <-lexemes:
+		case <-ctx.Done():
+			//nolint:wrapcheck // We don't need to wrap the context Error.
+			return ctx.Err()
+		}
+		if !ok {
+			break
+		}
+
+		start := l.Pos
+		if start < pos {
+			// Overlapping lexemes are skipped.
+			continue
+		}
+		if start > len(rns) {
+			start = len(rns)
+		}
+		end := start + utf8.RuneCountInString(l.Value)
+		if end > len(rns) {
+			end = len(rns)
+		}
+
+		if start > pos {
+			_, _ = bw.WriteString(render(HighlightNone, string(rns[pos:start])))
+		}
+		if end > start {
+			_, _ = bw.WriteString(render(classify(l), string(rns[start:end])))
+		}
+		pos = end
+	}
+	if pos < len(rns) {
+		_, _ = bw.WriteString(render(HighlightNone, string(rns[pos:])))
+	}
+
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return bw.Flush()
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"text/scanner"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHighlight(t *testing.T) {
+	t.Parallel()
+
+	src := "x := \"<a>\" + 1 // c\n"
+	classify := func(l *Lexeme) HighlightClass {
+		switch {
+		case l.Value == "x":
+			return HighlightKeyword
+		default:
+			return ClassifyTypes(map[LexemeType]HighlightClass{
+				LexemeType(scanner.String):  HighlightString,
+				LexemeType(scanner.Int):     HighlightNumber,
+				LexemeType(scanner.Comment): HighlightComment,
+				LexemeType('+'):             HighlightOperator,
+			})(l)
+		}
+	}
+
+	testCases := map[string]struct {
+		highlight func(context.Context, *strings.Builder, string, <-chan *Lexeme, Classifier) error
+		want      string
+	}{
+		"html": {
+			highlight: func(ctx context.Context, w *strings.Builder, src string, l <-chan *Lexeme, c Classifier) error {
+				return HighlightHTML(ctx, w, src, l, c)
+			},
+			want: `<span class="keyword">x</span> := <span class="string">&#34;&lt;a&gt;&#34;</span> ` +
+				`<span class="operator">+</span> <span class="number">1</span> <span class="comment">// c</span>` + "\n",
+		},
+		"ansi": {
+			highlight: func(ctx context.Context, w *strings.Builder, src string, l <-chan *Lexeme, c Classifier) error {
+				return HighlightANSI(ctx, w, src, l, c)
+			},
+			want: "\x1b[35mx\x1b[0m := \x1b[32m\"<a>\"\x1b[0m \x1b[33m+\x1b[0m \x1b[36m1\x1b[0m \x1b[90m// c\x1b[0m\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewScanningLexer(strings.NewReader(src), scanner.GoTokens&^scanner.SkipComments)
+
+			var b strings.Builder
+			if err := tc.highlight(context.Background(), &b, src, l.Lex(context.Background()), classify); err != nil {
+				t.Fatalf("highlight: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, b.String()); diff != "" {
+				t.Errorf("highlight: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
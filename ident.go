@@ -0,0 +1,111 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+// ErrUnexpectedRune indicates a rune that is not allowed at the current
+// position in the input.
+var ErrUnexpectedRune = errors.New("unexpected rune")
+
+var (
+	// IdentStartClass is the class of runes that may start an identifier.
+	// See IsIdentStart.
+	IdentStartClass = RuneClassFunc(isIdentStart)
+
+	// IdentContinueClass is the class of runes that may follow the first
+	// rune of an identifier. See IsIdentContinue.
+	IdentContinueClass = RuneClassFunc(isIdentContinue)
+)
+
+// IsIdentStart returns true if rn may start an identifier. Identifiers follow
+// the default identifier syntax of Unicode Standard Annex #31 with '_' added
+// to the start characters as most programming languages do. Start characters
+// are the runes with the ID_Start property: letters, letter numbers such as
+// Roman numerals, and a few other runes for backward compatibility. ASCII
+// identifiers are the same as Go's.
+func IsIdentStart(rn rune) bool {
+	return IdentStartClass.Contains(rn)
+}
+
+// IsIdentContinue returns true if rn may follow the first rune of an
+// identifier. Continue characters are the runes with the ID_Continue
+// property: the start characters, combining marks, decimal digits, and
+// connector punctuation such as '_'. See IsIdentStart.
+func IsIdentContinue(rn rune) bool {
+	return IdentContinueClass.Contains(rn)
+}
+
+func isIdentStart(rn rune) bool {
+	if rn == '_' {
+		return true
+	}
+	return unicode.In(rn, unicode.L, unicode.Nl, unicode.Other_ID_Start) && !isPattern(rn)
+}
+
+func isIdentContinue(rn rune) bool {
+	if isIdentStart(rn) {
+		return true
+	}
+	return unicode.In(rn, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue) && !isPattern(rn)
+}
+
+// isPattern returns true if rn is reserved for syntax and is therefore
+// excluded from identifiers.
+func isPattern(rn rune) bool {
+	return unicode.In(rn, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+// AdvanceIdent advances the reader over an identifier and returns the number
+// of runes advanced. Zero is returned if the next rune can't start an
+// identifier. An io.EOF error is returned if the end of the input is reached.
+// See IsIdentStart and IsIdentContinue.
+func (l *Lexer) AdvanceIdent() (int, error) {
+	ok, err := l.Accept(IdentStartClass)
+	if !ok {
+		return 0, err
+	}
+	n, err := l.AdvanceWhile(IdentContinueClass)
+	return n + 1, err
+}
+
+// IdentState returns a State that lexes an identifier, emits it as a lexeme
+// of type typ, and then continues with next. If the input doesn't start with
+// an identifier, the State returns an error wrapping ErrUnexpectedRune, or
+// io.ErrUnexpectedEOF at the end of the input.
+func IdentState(typ LexemeType, next State) State {
+	return NamedState("IdentState", func(_ context.Context, l *Lexer) (State, error) {
+		n, err := l.AdvanceIdent()
+		if err != nil && !errors.Is(err, io.EOF) {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		if n == 0 {
+			rn, ok := l.PeekRune()
+			if !ok {
+				return nil, fmt.Errorf("%w: want identifier", io.ErrUnexpectedEOF)
+			}
+			return nil, fmt.Errorf("%w: %q: line %d, column %d", ErrUnexpectedRune, rn, l.Line()+1, l.Column()+1)
+		}
+		l.Emit(l.Lexeme(typ))
+		return next, nil
+	})
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ianlewis/runeio"
+)
+
+func TestIsIdent(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		rn    rune
+		start bool
+		cont  bool
+	}{
+		"ascii letter":        {rn: 'a', start: true, cont: true},
+		"underscore":          {rn: '_', start: true, cont: true},
+		"digit":               {rn: '1', start: false, cont: true},
+		"dollar":              {rn: '$', start: false, cont: false},
+		"space":               {rn: ' ', start: false, cont: false},
+		"latin letter":        {rn: 'é', start: true, cont: true},
+		"han":                 {rn: '日', start: true, cont: true},
+		"letter number":       {rn: 'Ⅳ', start: true, cont: true},
+		"other id start":      {rn: '℘', start: true, cont: true},
+		"combining mark":      {rn: '\u0301', start: false, cont: true},
+		"arabic digit":        {rn: '٣', start: false, cont: true},
+		"middle dot":          {rn: '·', start: false, cont: true},
+		"connector":           {rn: '‿', start: false, cont: true},
+		"pattern syntax":      {rn: '⸀', start: false, cont: false},
+		"emoji":               {rn: '😀', start: false, cont: false},
+		"non-breaking space":  {rn: '\u00a0', start: false, cont: false},
+		"mathematical letter": {rn: '𝑥', start: true, cont: true},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := IsIdentStart(tc.rn); got != tc.start {
+				t.Errorf("IsIdentStart(%q): want: %v, got: %v", tc.rn, tc.start, got)
+			}
+			if got := IsIdentContinue(tc.rn); got != tc.cont {
+				t.Errorf("IsIdentContinue(%q): want: %v, got: %v", tc.rn, tc.cont, got)
+			}
+		})
+	}
+}
+
+func TestIdentState(t *testing.T) {
+	t.Parallel()
+
+	var lexSpace State
+	lexIdent := IdentState(identType, StateFn(func(ctx context.Context, l *Lexer) (State, error) {
+		return lexSpace.Run(ctx, l)
+	}))
+	lexSpace = StateFn(func(_ context.Context, l *Lexer) (State, error) {
+		if _, err := l.AdvanceWhile(NewRuneClass(" ")); err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return nil, err
+		}
+		l.Ignore()
+		return lexIdent, nil
+	})
+
+	testCases := map[string]struct {
+		input   string
+		lexemes []*Lexeme
+		err     error
+	}{
+		"identifiers": {
+			input: "foo _bar1 日本語 été",
+			lexemes: []*Lexeme{
+				{Type: identType, Value: "foo", Pos: 0, Line: 0, Column: 0},
+				{Type: identType, Value: "_bar1", Pos: 4, Line: 0, Column: 4},
+				{Type: identType, Value: "日本語", Pos: 10, Line: 0, Column: 10},
+				{Type: identType, Value: "été", Pos: 14, Line: 0, Column: 14},
+			},
+		},
+		"digit": {
+			input: "a 1b",
+			lexemes: []*Lexeme{
+				{Type: identType, Value: "a", Pos: 0, Line: 0, Column: 0},
+			},
+			err: ErrUnexpectedRune,
+		},
+		"empty": {
+			input: "",
+			err:   io.ErrUnexpectedEOF,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLexer(runeio.NewReader(strings.NewReader(tc.input)), lexIdent)
+			var got []*Lexeme
+			for lexeme := range l.Lex(context.Background()) {
+				got = append(got, lexeme)
+			}
+			<-l.Done()
+
+			if diff := cmp.Diff(tc.lexemes, got); diff != "" {
+				t.Errorf("Lex: (-want, +got): \n%s", diff)
+			}
+			if err := l.Err(); !errors.Is(err, tc.err) {
+				t.Errorf("Err: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestIdentState_position(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(runeio.NewReader(strings.NewReader("$")), IdentState(identType, nil))
+	Drain(l.Lex(context.Background()))
+	<-l.Done()
+
+	want := `unexpected rune: '$': line 1, column 1`
+	if err := l.Err(); err == nil || err.Error() != want {
+		t.Errorf("Err: want: %q, got: %v", want, err)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ianlewis/runeio"
+)
+
+// ErrNeedMoreInput is returned when reading from an IncrementalInput that has
+// no input available but has not been closed.
+var ErrNeedMoreInput = errors.New("need more input")
+
+// IncrementalInput is input that is still being written, such as the lines
+// typed into an interactive REPL. Reading returns ErrNeedMoreInput rather
+// than io.EOF when all of the input written so far has been read. io.EOF is
+// returned after the input is closed.
+type IncrementalInput struct {
+	mu sync.Mutex
+
+	// buf holds the input that has not been read.
+	buf []byte
+
+	// closed is true if no more input will be written.
+	closed bool
+
+	// more is signaled when input is written or the input is closed.
+	more chan struct{}
+
+	// need is signaled when a Lexer is waiting for more input.
+	need chan struct{}
+}
+
+// NewIncrementalInput returns a new empty IncrementalInput.
+func NewIncrementalInput() *IncrementalInput {
+	return &IncrementalInput{
+		more: make(chan struct{}, 1),
+		need: make(chan struct{}, 1),
+	}
+}
+
+// Write appends p to the input. It returns io.ErrClosedPipe if the input has
+// been closed.
+func (in *IncrementalInput) Write(p []byte) (int, error) {
+	in.mu.Lock()
+	if in.closed {
+		in.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	in.buf = append(in.buf, p...)
+	in.mu.Unlock()
+	signal(in.more)
+	return len(p), nil
+}
+
+// Close marks the end of the input. Input already written can still be
+// read.
+func (in *IncrementalInput) Close() error {
+	in.mu.Lock()
+	in.closed = true
+	in.mu.Unlock()
+	signal(in.more)
+	return nil
+}
+
+// ReadRune implements io.RuneReader. It returns ErrNeedMoreInput if no
+// complete rune is available and the input has not been closed.
+func (in *IncrementalInput) ReadRune() (rune, int, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if len(in.buf) == 0 {
+		if in.closed {
+			return 0, 0, io.EOF
+		}
+		return 0, 0, ErrNeedMoreInput
+	}
+	if !utf8.FullRune(in.buf) && !in.closed {
+		return 0, 0, ErrNeedMoreInput
+	}
+	rn, size := utf8.DecodeRune(in.buf)
+	in.buf = in.buf[size:]
+	return rn, size, nil
+}
+
+// NeedInput returns a channel that receives a value when a Lexer reading the
+// input has consumed all of the input written so far and is waiting for
+// more, e.g. so that a REPL can display a continuation prompt.
+func (in *IncrementalInput) NeedInput() <-chan struct{} {
+	return in.need
+}
+
+// wait blocks until input is available or the input is closed. It returns
+// false if stop is closed first.
+func (in *IncrementalInput) wait(stop <-chan struct{}) bool {
+	for {
+		in.mu.Lock()
+		ready := len(in.buf) > 0 || in.closed
+		in.mu.Unlock()
+		if ready {
+			return true
+		}
+
+		signal(in.need)
+		select {
+		case <-in.more:
+		case <-stop:
+			return false
+		}
+	}
+}
+
+// signal sends a value on ch without blocking.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// NewIncrementalLexer returns a new Lexer that reads from in. When a State
+// returns an error wrapping ErrNeedMoreInput, the Lexer waits until more
+// input is written to in and runs the same State again rather than stopping.
+// The runes advanced by the State are retained as part of the current lexeme
+// so States that advance the input in a loop resume where they left off.
+// States must only return ErrNeedMoreInput before emitting lexemes or
+// transitioning to other states.
+func NewIncrementalLexer(in *IncrementalInput, startingState State, opts ...LexerOption) *Lexer {
+	// NOTE: The buffer of the runeio.RuneReader is filled until an error is
+	//       returned so ErrNeedMoreInput also prevents it from blocking.
+	l := NewLexer(runeio.NewReader(in), startingState, opts...)
+	l.input = in
+	return l
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIncrementalLexer(t *testing.T) {
+	t.Parallel()
+
+	in := NewIncrementalInput()
+	l := NewIncrementalLexer(in, &wordState{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lexemes := l.Lex(ctx)
+
+	if _, err := in.Write([]byte("hello wor")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got, want := (<-lexemes).Value, "hello"; got != want {
+		t.Errorf("lexeme: want: %q, got: %q", want, got)
+	}
+
+	// The lexer waits for the rest of the word.
+	<-in.NeedInput()
+
+	if _, err := in.Write([]byte("ld\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := &Lexeme{Type: wordType, Value: "world", Pos: 6, Column: 6}
+	if diff := cmp.Diff(want, <-lexemes); diff != "" {
+		t.Errorf("lexeme: (-want, +got): \n%s", diff)
+	}
+
+	if err := in.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := in.Write([]byte("more")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Write: want: %v, got: %v", io.ErrClosedPipe, err)
+	}
+
+	// The final empty word is emitted at the end of input.
+	for range lexemes {
+	}
+	<-l.Done()
+	if err := l.Err(); err != nil {
+		t.Errorf("Err: %v", err)
+	}
+}
+
+func TestIncrementalLexer_cancel(t *testing.T) {
+	t.Parallel()
+
+	in := NewIncrementalInput()
+	l := NewIncrementalLexer(in, &wordState{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lexemes := l.Lex(ctx)
+
+	<-in.NeedInput()
+	cancel()
+
+	for range lexemes {
+	}
+	<-l.Done()
+	if err := l.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Err: want: %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestIncrementalInput_ReadRune(t *testing.T) {
+	t.Parallel()
+
+	in := NewIncrementalInput()
+	// Write a partial multi-byte rune.
+	_, _ = in.Write([]byte("é")[:1])
+	if _, _, err := in.ReadRune(); !errors.Is(err, ErrNeedMoreInput) {
+		t.Errorf("ReadRune: want: %v, got: %v", ErrNeedMoreInput, err)
+	}
+
+	_, _ = in.Write([]byte("é")[1:])
+	rn, size, err := in.ReadRune()
+	if err != nil {
+		t.Fatalf("ReadRune: %v", err)
+	}
+	if rn != 'é' || size != 2 {
+		t.Errorf("ReadRune: want: %q, got: %q", 'é', rn)
+	}
+
+	_ = in.Close()
+	if _, _, err := in.ReadRune(); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadRune: want: %v, got: %v", io.EOF, err)
+	}
+}
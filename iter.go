@@ -0,0 +1,99 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+// The iterators in this file have the same signature as iter.Seq[*Node[V]]
+// and can be used with range-over-func in modules that require Go 1.23 or
+// later. They can also be called directly with a yield function that returns
+// false to stop iterating. Nil children are skipped.
+
+// PreOrder returns an iterator over the tree rooted at root that visits each
+// node before its children.
+func PreOrder[V comparable](root *Node[V]) func(yield func(*Node[V]) bool) {
+	return func(yield func(*Node[V]) bool) {
+		_ = preOrder(root, yield)
+	}
+}
+
+func preOrder[V comparable](n *Node[V], yield func(*Node[V]) bool) bool {
+	if n == nil {
+		return true
+	}
+	if !yield(n) {
+		return false
+	}
+	for _, c := range n.Children {
+		if !preOrder(c, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// PostOrder returns an iterator over the tree rooted at root that visits each
+// node after its children.
+func PostOrder[V comparable](root *Node[V]) func(yield func(*Node[V]) bool) {
+	return func(yield func(*Node[V]) bool) {
+		_ = postOrder(root, yield)
+	}
+}
+
+func postOrder[V comparable](n *Node[V], yield func(*Node[V]) bool) bool {
+	if n == nil {
+		return true
+	}
+	for _, c := range n.Children {
+		if !postOrder(c, yield) {
+			return false
+		}
+	}
+	return yield(n)
+}
+
+// BFS returns an iterator over the tree rooted at root that visits nodes in
+// breadth-first order.
+func BFS[V comparable](root *Node[V]) func(yield func(*Node[V]) bool) {
+	return func(yield func(*Node[V]) bool) {
+		if root == nil {
+			return
+		}
+		queue := []*Node[V]{root}
+		for len(queue) > 0 {
+			n := queue[0]
+			queue = queue[1:]
+			if !yield(n) {
+				return
+			}
+			for _, c := range n.Children {
+				if c != nil {
+					queue = append(queue, c)
+				}
+			}
+		}
+	}
+}
+
+// Leaves returns an iterator over the nodes without children in the tree
+// rooted at root, from left to right.
+func Leaves[V comparable](root *Node[V]) func(yield func(*Node[V]) bool) {
+	return func(yield func(*Node[V]) bool) {
+		PreOrder(root)(func(n *Node[V]) bool {
+			if len(n.Children) > 0 {
+				return true
+			}
+			return yield(n)
+		})
+	}
+}
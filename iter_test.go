@@ -0,0 +1,75 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIterators(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		seq  func(*Node[string]) func(func(*Node[string]) bool)
+		want []string
+	}{
+		"PreOrder": {
+			seq:  PreOrder[string],
+			want: []string{"", "a", "b", "x", "c", "b", "y", "b", "z"},
+		},
+		"PostOrder": {
+			seq:  PostOrder[string],
+			want: []string{"x", "b", "y", "b", "c", "a", "z", "b", ""},
+		},
+		"BFS": {
+			seq:  BFS[string],
+			want: []string{"", "a", "b", "b", "c", "z", "x", "b", "y"},
+		},
+		"Leaves": {
+			seq:  Leaves[string],
+			want: []string{"x", "y", "z"},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			seq := tc.seq(queryTestTree())
+
+			var got []string
+			seq(func(n *Node[string]) bool {
+				got = append(got, n.Value)
+				return true
+			})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("iterator: (-want, +got): \n%s", diff)
+			}
+
+			// Stop after the third node.
+			got = nil
+			seq(func(n *Node[string]) bool {
+				got = append(got, n.Value)
+				return len(got) < 3
+			})
+			if diff := cmp.Diff(tc.want[:3], got); diff != "" {
+				t.Errorf("iterator: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
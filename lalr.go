@@ -0,0 +1,520 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrConflict indicates that a Grammar is not LALR(1) because its parse
+	// tables contain a shift/reduce or reduce/reduce conflict.
+	ErrConflict = errors.New("grammar conflict")
+
+	// ErrUnexpectedLexeme indicates that a lexeme was found where the grammar
+	// does not allow it.
+	ErrUnexpectedLexeme = errors.New("unexpected lexeme")
+)
+
+// TableFuncs are the functions used by a TableParser to compute node values.
+type TableFuncs[V comparable] struct {
+	// Shift returns the value of the leaf node created for a lexeme matched
+	// by a terminal symbol. If nil, leaf nodes have the zero value.
+	Shift func(l *Lexeme) (V, error)
+
+	// Reduce returns the value of the node created when a production is
+	// matched. children holds the nodes for each of the production's
+	// symbols. If nil, nodes for nonterminals have the zero value.
+	Reduce func(p *Production, children []*Node[V]) (V, error)
+}
+
+// TableParser is a shift/reduce parser driven by LALR(1) tables that are
+// built at runtime from a Grammar. It handles left-recursive grammars that
+// are awkward to express with ParseFn functions and produces the same Node
+// trees as Parser.
+type TableParser[V comparable] struct {
+	g   *compiledGrammar
+	fns TableFuncs[V]
+
+	// actions maps each state and terminal to a parser action.
+	actions []map[int]lrAction
+
+	// gotos maps each state and nonterminal to the next state.
+	gotos []map[int]int
+
+	// lexerErr returns the lexer's error when the lexemes channel is closed.
+	lexerErr func() error
+}
+
+type lrActionKind int
+
+const (
+	lrShift lrActionKind = iota + 1
+	lrReduce
+	lrAccept
+)
+
+type lrAction struct {
+	kind lrActionKind
+
+	// n is the next state for a shift or the production for a reduce.
+	n int
+}
+
+// lrItem is an LR(0) item; a production with a position in its right hand
+// side.
+type lrItem struct {
+	prod int
+	dot  int
+}
+
+type lrState struct {
+	// kernel are the state's kernel items sorted by production and dot.
+	kernel []lrItem
+
+	// la holds the lookahead set for each kernel item.
+	la [][]bool
+
+	// trans maps symbols to the next state.
+	trans map[int]int
+}
+
+// NewTableParser builds LALR(1) parse tables for the grammar and returns a
+// new TableParser. An error wrapping ErrGrammar is returned if the grammar is
+// malformed and an error wrapping ErrConflict is returned if the grammar is
+// not LALR(1).
+func NewTableParser[V comparable](g *Grammar, fns TableFuncs[V]) (*TableParser[V], error) {
+	c, err := compileGrammar(g)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &TableParser[V]{
+		g:   c,
+		fns: fns,
+	}
+
+	states := buildLALR(c)
+	t.actions = make([]map[int]lrAction, len(states))
+	t.gotos = make([]map[int]int, len(states))
+	for i, s := range states {
+		t.actions[i] = map[int]lrAction{}
+		t.gotos[i] = map[int]int{}
+
+		items, las := c.closure1(s.kernel, s.la)
+		for j, item := range items {
+			p := c.prods[item.prod]
+			if item.dot < len(p.rhs) {
+				if sym := p.rhs[item.dot]; c.isTerm(sym) {
+					if err := t.setAction(i, sym, lrAction{lrShift, s.trans[sym]}); err != nil {
+						return nil, err
+					}
+				}
+				continue
+			}
+
+			if item.prod == 0 {
+				if err := t.setAction(i, 0, lrAction{lrAccept, 0}); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			for term := 0; term < c.numTerms(); term++ {
+				if !las[j][term] {
+					continue
+				}
+				if err := t.setAction(i, term, lrAction{lrReduce, item.prod}); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for sym, next := range s.trans {
+			if !c.isTerm(sym) {
+				t.gotos[i][sym] = next
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// setAction sets the action for the state and terminal and reports conflicts.
+func (t *TableParser[V]) setAction(state, term int, a lrAction) error {
+	old, ok := t.actions[state][term]
+	if !ok || old == a {
+		t.actions[state][term] = a
+		return nil
+	}
+
+	describe := func(a lrAction) string {
+		switch a.kind {
+		case lrShift:
+			return "shift"
+		case lrReduce:
+			return fmt.Sprintf("reduce %s", t.production(a.n))
+		default:
+			return "accept"
+		}
+	}
+	return fmt.Errorf("%w: state %d on %s: %s and %s",
+		ErrConflict, state, t.g.symbolName(term), describe(old), describe(a))
+}
+
+// production returns a description of the compiled production.
+func (t *TableParser[V]) production(i int) string {
+	p := t.g.prods[i]
+	if p.src < 0 {
+		return fmt.Sprintf("%s -> %s", t.g.symbolName(p.lhs), t.g.symbolName(p.rhs[0]))
+	}
+	return t.g.g.Productions[p.src].String()
+}
+
+// SetLexerErr sets a function that returns the lexer's error, e.g.
+// Lexer.Err. It is called when the lexemes channel is closed so that lexing
+// errors can be distinguished from the end of the input. If it returns a
+// non-nil error, Parse returns the error.
+func (t *TableParser[V]) SetLexerErr(errFn func() error) {
+	t.lexerErr = errFn
+}
+
+// Parse parses the lexemes read from the lexemes channel and returns the root
+// of the parse tree. The root node has the zero value and a single child
+// which is the node for the grammar's start symbol. Parsing can be cancelled
+// by ctx.
+func (t *TableParser[V]) Parse(ctx context.Context, lexemes <-chan *Lexeme) (*Node[V], error) {
+	next := func() (*Lexeme, error) {
+		select {
+		case <-ctx.Done():
+			//nolint:wrapcheck // We don't need to wrap the context Error.
+			return nil, ctx.Err()
+		case l, ok := <-lexemes:
+			if !ok {
+				return nil, lexerErr(t.lexerErr)
+			}
+			return l, nil
+		}
+	}
+
+	stack := []int{0}
+	var nodes []*Node[V]
+
+	l, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		term := 0
+		if l != nil {
+			var ok bool
+			term, ok = t.g.terms[l.Type]
+			if !ok {
+				return nil, unexpectedLexeme(l)
+			}
+		}
+
+		a, ok := t.actions[stack[len(stack)-1]][term]
+		if !ok {
+			return nil, unexpectedLexeme(l)
+		}
+
+		switch a.kind {
+		case lrShift:
+			n, err := t.fns.shift(l)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+			stack = append(stack, a.n)
+
+			l, err = next()
+			if err != nil {
+				return nil, err
+			}
+		case lrReduce:
+			p := t.g.prods[a.n]
+			k := len(nodes) - len(p.rhs)
+			children := make([]*Node[V], len(p.rhs))
+			copy(children, nodes[k:])
+			nodes = nodes[:k]
+			stack = stack[:len(stack)-len(p.rhs)]
+
+			n, err := t.fns.reduce(&t.g.g.Productions[p.src], children, l)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+			stack = append(stack, t.gotos[stack[len(stack)-1]][p.lhs])
+		case lrAccept:
+			root := &Node[V]{}
+			root.Children = []*Node[V]{nodes[0]}
+			nodes[0].Parent = root
+			return root, nil
+		}
+	}
+}
+
+// shift creates a leaf node for the lexeme.
+func (fns *TableFuncs[V]) shift(l *Lexeme) (*Node[V], error) {
+	var v V
+	if fns.Shift != nil {
+		var err error
+		v, err = fns.Shift(l)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Node[V]{
+		Value:  v,
+		Pos:    l.Pos,
+		Line:   l.Line,
+		Column: l.Column,
+	}, nil
+}
+
+// reduce creates the node for a matched production. The node is positioned at
+// its first child or at the lookahead lexeme if the production is empty.
+func (fns *TableFuncs[V]) reduce(p *Production, children []*Node[V], l *Lexeme) (*Node[V], error) {
+	var v V
+	if fns.Reduce != nil {
+		var err error
+		v, err = fns.Reduce(p, children)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	n := &Node[V]{Value: v}
+	switch {
+	case len(children) > 0:
+		n.Pos = children[0].Pos
+		n.Line = children[0].Line
+		n.Column = children[0].Column
+		n.Children = children
+		for _, c := range children {
+			c.Parent = n
+		}
+	case l != nil:
+		n.Pos = l.Pos
+		n.Line = l.Line
+		n.Column = l.Column
+	}
+	return n, nil
+}
+
+// unexpectedLexeme returns a syntax error for the lexeme. A nil lexeme
+// indicates the end of input.
+func unexpectedLexeme(l *Lexeme) error {
+	if l == nil {
+		return fmt.Errorf("%w: parsing", io.ErrUnexpectedEOF)
+	}
+	return &LexemeError{
+		Err:    ErrUnexpectedLexeme,
+		Lexeme: l,
+	}
+}
+
+// buildLALR builds the LR(0) automaton for the grammar and computes LALR(1)
+// lookaheads for its kernel items by propagation.
+func buildLALR(c *compiledGrammar) []*lrState {
+	states := []*lrState{{kernel: []lrItem{{0, 0}}}}
+	index := map[string]int{itemsKey(states[0].kernel): 0}
+
+	for i := 0; i < len(states); i++ {
+		s := states[i]
+		s.trans = map[int]int{}
+
+		next := map[int][]lrItem{}
+		var syms []int
+		for _, item := range c.closure0(s.kernel) {
+			p := c.prods[item.prod]
+			if item.dot >= len(p.rhs) {
+				continue
+			}
+			sym := p.rhs[item.dot]
+			if _, ok := next[sym]; !ok {
+				syms = append(syms, sym)
+			}
+			next[sym] = append(next[sym], lrItem{item.prod, item.dot + 1})
+		}
+		sort.Ints(syms)
+
+		for _, sym := range syms {
+			kernel := next[sym]
+			sortItems(kernel)
+			key := itemsKey(kernel)
+			j, ok := index[key]
+			if !ok {
+				j = len(states)
+				index[key] = j
+				states = append(states, &lrState{kernel: kernel})
+			}
+			s.trans[sym] = j
+		}
+	}
+
+	// The marker is a pseudo-terminal used to detect propagated lookaheads.
+	nT := c.numTerms()
+	marker := nT
+	for _, s := range states {
+		s.la = make([][]bool, len(s.kernel))
+		for k := range s.la {
+			s.la[k] = make([]bool, nT+1)
+		}
+	}
+	states[0].la[0][0] = true
+
+	type propagation struct {
+		from, fromItem int
+		to, toItem     int
+	}
+	var props []propagation
+	for i, s := range states {
+		for k, item := range s.kernel {
+			la := make([]bool, nT+1)
+			la[marker] = true
+			items, las := c.closure1([]lrItem{item}, [][]bool{la})
+			for j, it := range items {
+				p := c.prods[it.prod]
+				if it.dot >= len(p.rhs) {
+					continue
+				}
+				to := s.trans[p.rhs[it.dot]]
+				toItem := kernelIndex(states[to].kernel, lrItem{it.prod, it.dot + 1})
+				for term, ok := range las[j] {
+					switch {
+					case !ok:
+					case term == marker:
+						props = append(props, propagation{i, k, to, toItem})
+					default:
+						states[to].la[toItem][term] = true
+					}
+				}
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, p := range props {
+			if union(states[p.to].la[p.toItem][:nT], states[p.from].la[p.fromItem][:nT]) {
+				changed = true
+			}
+		}
+	}
+
+	return states
+}
+
+// closure0 returns the LR(0) closure of the kernel items.
+func (c *compiledGrammar) closure0(kernel []lrItem) []lrItem {
+	items := append([]lrItem(nil), kernel...)
+	seen := map[lrItem]bool{}
+	for _, item := range items {
+		seen[item] = true
+	}
+	for i := 0; i < len(items); i++ {
+		p := c.prods[items[i].prod]
+		if items[i].dot >= len(p.rhs) || c.isTerm(p.rhs[items[i].dot]) {
+			continue
+		}
+		for _, prod := range c.prodsByLHS[p.rhs[items[i].dot]] {
+			item := lrItem{prod, 0}
+			if !seen[item] {
+				seen[item] = true
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// closure1 returns the LR(1) closure of the kernel items with the given
+// lookahead sets. The lookahead sets may be longer than the number of
+// terminals in order to hold marker pseudo-terminals.
+func (c *compiledGrammar) closure1(kernel []lrItem, kernelLA [][]bool) ([]lrItem, [][]bool) {
+	items := append([]lrItem(nil), kernel...)
+	las := make([][]bool, len(kernelLA))
+	index := map[lrItem]int{}
+	for i := range kernel {
+		las[i] = append([]bool(nil), kernelLA[i]...)
+		index[kernel[i]] = i
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(items); i++ {
+			p := c.prods[items[i].prod]
+			if items[i].dot >= len(p.rhs) || c.isTerm(p.rhs[items[i].dot]) {
+				continue
+			}
+
+			la := make([]bool, len(las[i]))
+			if c.firstSeq(p.rhs[items[i].dot+1:], la) {
+				_ = union(la, las[i])
+			}
+
+			for _, prod := range c.prodsByLHS[p.rhs[items[i].dot]] {
+				item := lrItem{prod, 0}
+				j, ok := index[item]
+				if !ok {
+					j = len(items)
+					index[item] = j
+					items = append(items, item)
+					las = append(las, make([]bool, len(la)))
+					changed = true
+				}
+				if union(las[j], la) {
+					changed = true
+				}
+			}
+		}
+	}
+
+	return items, las
+}
+
+func sortItems(items []lrItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].prod != items[j].prod {
+			return items[i].prod < items[j].prod
+		}
+		return items[i].dot < items[j].dot
+	})
+}
+
+func itemsKey(items []lrItem) string {
+	var b strings.Builder
+	for _, item := range items {
+		fmt.Fprintf(&b, "%d.%d,", item.prod, item.dot)
+	}
+	return b.String()
+}
+
+func kernelIndex(kernel []lrItem, item lrItem) int {
+	for i := range kernel {
+		if kernel[i] == item {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,309 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	numType LexemeType = iota + 100
+	plusType
+	starType
+	lparenType
+	rparenType
+	minusType
+	caretType
+	lessType
+	bangType
+	lbrackType
+	rbrackType
+	commaType
+)
+
+// exprGrammar is a left-recursive expression grammar.
+var exprGrammar = &Grammar{
+	Start: "E",
+	Productions: []Production{
+		{Name: "E", Symbols: []Symbol{NonTerm("E"), Term(plusType), NonTerm("T")}},
+		{Name: "E", Symbols: []Symbol{NonTerm("T")}},
+		{Name: "T", Symbols: []Symbol{NonTerm("T"), Term(starType), NonTerm("F")}},
+		{Name: "T", Symbols: []Symbol{NonTerm("F")}},
+		{Name: "F", Symbols: []Symbol{Term(lparenType), NonTerm("E"), Term(rparenType)}},
+		{Name: "F", Symbols: []Symbol{Term(numType)}},
+	},
+}
+
+// exprLexemes returns a closed channel containing lexemes for the
+// single-rune tokens in input.
+func exprLexemes(input string) <-chan *Lexeme {
+	types := map[rune]LexemeType{
+		'+': plusType,
+		'*': starType,
+		'(': lparenType,
+		')': rparenType,
+		'-': minusType,
+		'^': caretType,
+		'<': lessType,
+		'!': bangType,
+		'[': lbrackType,
+		']': rbrackType,
+		',': commaType,
+	}
+
+	ch := make(chan *Lexeme, len(input))
+	for i, rn := range []rune(input) {
+		typ, ok := types[rn]
+		if !ok {
+			typ = numType
+		}
+		ch <- &Lexeme{
+			Type:   typ,
+			Value:  string(rn),
+			Pos:    i,
+			Column: i,
+		}
+	}
+	close(ch)
+	return ch
+}
+
+// exprFuncs builds trees containing only operators and numbers.
+var exprFuncs = TableFuncs[string]{
+	Shift: func(l *Lexeme) (string, error) {
+		return l.Value, nil
+	},
+	Reduce: func(p *Production, _ []*Node[string]) (string, error) {
+		return p.Name, nil
+	},
+}
+
+func TestTableParser(t *testing.T) {
+	t.Parallel()
+
+	tp, err := NewTableParser(exprGrammar, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewTableParser: %v", err)
+	}
+
+	got, err := tp.Parse(context.Background(), exprLexemes("1+2*3"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := newTree(&Node[string]{
+		Value: "E",
+		Children: []*Node[string]{
+			{
+				Value: "E",
+				Children: []*Node[string]{{
+					Value: "T",
+					Children: []*Node[string]{{
+						Value:    "F",
+						Children: []*Node[string]{{Value: "1"}},
+					}},
+				}},
+			},
+			{Value: "+", Pos: 1, Column: 1},
+			{
+				Value:  "T",
+				Pos:    2,
+				Column: 2,
+				Children: []*Node[string]{
+					{
+						Value:  "T",
+						Pos:    2,
+						Column: 2,
+						Children: []*Node[string]{{
+							Value:    "F",
+							Pos:      2,
+							Column:   2,
+							Children: []*Node[string]{{Value: "2", Pos: 2, Column: 2}},
+						}},
+					},
+					{Value: "*", Pos: 3, Column: 3},
+					{
+						Value:    "F",
+						Pos:      4,
+						Column:   4,
+						Children: []*Node[string]{{Value: "3", Pos: 4, Column: 4}},
+					},
+				},
+			},
+		},
+	})
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Parse: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestTableParser_empty(t *testing.T) {
+	t.Parallel()
+
+	// L is a possibly empty list of numbers.
+	g := &Grammar{
+		Start: "L",
+		Productions: []Production{
+			{Name: "L", Symbols: []Symbol{NonTerm("L"), Term(numType)}},
+			{Name: "L"},
+		},
+	}
+
+	tp, err := NewTableParser(g, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewTableParser: %v", err)
+	}
+
+	got, err := tp.Parse(context.Background(), exprLexemes(""))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := newTree(&Node[string]{Value: "L"})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Parse: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestTableParser_errors(t *testing.T) {
+	t.Parallel()
+
+	tp, err := NewTableParser(exprGrammar, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewTableParser: %v", err)
+	}
+
+	testCases := map[string]struct {
+		input string
+		err   error
+	}{
+		"unexpected lexeme": {
+			input: "1+*2",
+			err:   ErrUnexpectedLexeme,
+		},
+		"unexpected eof": {
+			input: "(1+2",
+			err:   io.ErrUnexpectedEOF,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := tp.Parse(context.Background(), exprLexemes(tc.input))
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestTableParser_SetLexerErr(t *testing.T) {
+	t.Parallel()
+
+	tp, err := NewTableParser(exprGrammar, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewTableParser: %v", err)
+	}
+
+	errTest := errors.New("test error")
+	tp.SetLexerErr(func() error {
+		return errTest
+	})
+
+	// NOTE: The input is valid up to where the lexer stopped.
+	_, err = tp.Parse(context.Background(), exprLexemes("1+2"))
+	if !errors.Is(err, errTest) {
+		t.Errorf("Parse: want: %v, got: %v", errTest, err)
+	}
+}
+
+// TestNewTableParser_lalr tests a grammar that is LALR(1) but not SLR(1).
+func TestNewTableParser_lalr(t *testing.T) {
+	t.Parallel()
+
+	g := &Grammar{
+		Start: "S",
+		Productions: []Production{
+			{Name: "S", Symbols: []Symbol{NonTerm("L"), Term(plusType), NonTerm("R")}},
+			{Name: "S", Symbols: []Symbol{NonTerm("R")}},
+			{Name: "L", Symbols: []Symbol{Term(starType), NonTerm("R")}},
+			{Name: "L", Symbols: []Symbol{Term(numType)}},
+			{Name: "R", Symbols: []Symbol{NonTerm("L")}},
+		},
+	}
+
+	tp, err := NewTableParser(g, exprFuncs)
+	if err != nil {
+		t.Fatalf("NewTableParser: %v", err)
+	}
+
+	if _, err := tp.Parse(context.Background(), exprLexemes("*1+2")); err != nil {
+		t.Errorf("Parse: %v", err)
+	}
+}
+
+func TestNewTableParser_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		g   *Grammar
+		err error
+	}{
+		"no start": {
+			g:   &Grammar{},
+			err: ErrGrammar,
+		},
+		"undefined nonterminal": {
+			g: &Grammar{
+				Start: "E",
+				Productions: []Production{
+					{Name: "E", Symbols: []Symbol{NonTerm("X")}},
+				},
+			},
+			err: ErrGrammar,
+		},
+		"ambiguous": {
+			g: &Grammar{
+				Start: "E",
+				Productions: []Production{
+					{Name: "E", Symbols: []Symbol{NonTerm("E"), Term(plusType), NonTerm("E")}},
+					{Name: "E", Symbols: []Symbol{Term(numType)}},
+				},
+			},
+			err: ErrConflict,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewTableParser(tc.g, TableFuncs[string]{})
+			if !errors.Is(err, tc.err) {
+				t.Errorf("NewTableParser: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -19,8 +19,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ianlewis/runeio"
 )
 
 // BufferedRuneReader implements functionality that allows for allow for zero-copy
@@ -59,7 +66,8 @@ type State interface {
 }
 
 type fnState struct {
-	f func(context.Context, *Lexer) (State, error)
+	f    func(context.Context, *Lexer) (State, error)
+	name string
 }
 
 func (s *fnState) Run(ctx context.Context, l *Lexer) (State, error) {
@@ -69,9 +77,51 @@ func (s *fnState) Run(ctx context.Context, l *Lexer) (State, error) {
 	return s.f(ctx, l)
 }
 
+// Name returns the name of the state or the name of its function if it has
+// no name.
+func (s *fnState) Name() string {
+	if s.name != "" || s.f == nil {
+		return s.name
+	}
+	return funcName(s.f)
+}
+
+// funcName returns the name of the function f or an empty string if it is
+// unknown.
+func funcName(f any) string {
+	// NOTE: fmt formats funcs with %p as the address of their code.
+	pc, err := strconv.ParseUint(strings.TrimPrefix(fmt.Sprintf("%p", f), "0x"), 16, 64)
+	if err != nil {
+		return ""
+	}
+	if fn := runtime.FuncForPC(uintptr(pc)); fn != nil {
+		return fn.Name()
+	}
+	return ""
+}
+
 // StateFn creates a State from the given Run function.
 func StateFn(f func(context.Context, *Lexer) (State, error)) State {
-	return &fnState{f}
+	return &fnState{f: f}
+}
+
+// NamedState creates a State with the given name from the given Run function.
+// The name is used in trace output and errors. See StateName.
+func NamedState(name string, f func(context.Context, *Lexer) (State, error)) State {
+	return &fnState{f: f, name: name}
+}
+
+// StateName returns the name of the State. If s implements a
+// Name() string method that returns a non-empty name it is used, otherwise
+// the name is the type of s. States created by NamedState are named by their
+// name and States created by StateFn by the name of their function.
+func StateName(s State) string {
+	if n, ok := s.(interface{ Name() string }); ok {
+		if name := n.Name(); name != "" {
+			return name
+		}
+	}
+	return fmt.Sprintf("%T", s)
 }
 
 // Lexeme is a tokenized input which can be emitted by a Lexer.
@@ -90,6 +140,18 @@ type Lexeme struct {
 
 	// Column is the column in the line where the Lexeme was found.
 	Column int
+
+	// File is the name of the file where the Lexeme was found. It is empty if
+	// the Lexer was not given a file name.
+	File string `json:",omitempty"`
+}
+
+// IsEOF returns true if the lexeme represents the end of the input. Lexers
+// signal the end of input by closing their lexeme channel, so Parser.Peek and
+// Parser.Next return a nil Lexeme at the end of input. IsEOF can be called on
+// a nil Lexeme.
+func (l *Lexeme) IsEOF() bool {
+	return l == nil
 }
 
 // Lexer lexically processes a byte stream. It is implemented as a finite-state
@@ -107,13 +169,60 @@ type Lexer struct {
 	// state is the current state of the Lexer.
 	state State
 
+	// maxTokens is the maximum number of lexemes to emit or zero for no
+	// limit.
+	maxTokens int
+
+	// maxInputBytes is the maximum number of bytes of input to read or zero
+	// for no limit.
+	maxInputBytes int
+
+	// trace is the writer that each State run is traced to or nil.
+	trace io.Writer
+
+	// coverage records each State run or is nil.
+	coverage *Coverage
+
+	// maxStalls is the maximum number of consecutive States that may run
+	// without making progress or zero for no limit.
+	maxStalls int
+
+	// newlines are the runes that end a line or nil if only '\n' ends a
+	// line.
+	newlines *RuneClass
+
+	// graphemes is true if columns are counted in grapheme clusters.
+	graphemes bool
+
+	// bufSize is the size of the buffer the reader is wrapped in or zero to
+	// use the reader as-is.
+	bufSize int
+
+	// src retains the input if not nil.
+	src *Source
+
+	// file is the name of the input file.
+	file string
+
+	// input is the input of a Lexer created by NewIncrementalLexer.
+	input *IncrementalInput
+
+	// errLexemes is true if errors returned by states are emitted as
+	// lexemes of type errType and lexing continues at the resume state.
+	errLexemes bool
+	errType    LexemeType
+	resume     State
+
+	// noRecover is true if panics in States are not recovered.
+	noRecover bool
+
 	// s is the current input/pos/lexeme state.
 	s struct {
 		// Mutex protects the values in s.
 		sync.Mutex
 
 		// r is the underlying reader to read from.
-		r BufferedRuneReader
+		r *ioReader
 
 		// b is a strings builder that stores the current lexeme value.
 		b strings.Builder
@@ -138,21 +247,272 @@ type Lexer struct {
 
 		// err holds the last lexing error.
 		err error
+
+		// tokens is the number of lexemes emitted.
+		tokens int
+
+		// bytes is the number of bytes of input read.
+		bytes int
+
+		// run is incremented each time the Lexer is reset.
+		run int
+
+		// errs are the errors recovered from by emitting error lexemes.
+		errs []error
+
+		// recent holds the most recently read runes.
+		recent runeRing
+
+		// last is the last lexeme emitted.
+		last *Lexeme
+
+		// grapheme is used to count columns in grapheme clusters.
+		grapheme graphemeState
+
+		// cr is true if the last rune read was a carriage return.
+		cr bool
+	}
+
+	// start is the starting state.
+	start State
+}
+
+// LexerOption is an option that configures a Lexer.
+type LexerOption func(*Lexer)
+
+// WithMaxTokens sets the maximum number of lexemes that the Lexer will emit.
+// If a State attempts to emit more lexemes, the Lexer stops and Err returns a
+// *LimitError. A value of zero or less means no limit.
+func WithMaxTokens(n int) LexerOption {
+	return func(l *Lexer) {
+		l.maxTokens = n
+	}
+}
+
+// WithMaxInputBytes sets the maximum number of bytes of input that the Lexer
+// will read. Reading past the limit returns a *LimitError and the Lexer stops.
+// A value of zero or less means no limit.
+func WithMaxInputBytes(n int) LexerOption {
+	return func(l *Lexer) {
+		l.maxInputBytes = n
+	}
+}
+
+// WithFilename sets the name of the input file. The name is set on each
+// emitted Lexeme and included in errors.
+func WithFilename(name string) LexerOption {
+	return func(l *Lexer) {
+		l.file = name
+	}
+}
+
+// WithBufferSize wraps the Lexer's reader in a buffer that holds at most n
+// runes. Input is then read and peeked in chunks of at most n runes. It is
+// intended for tests: a small buffer makes tokens span buffer boundaries even
+// for short inputs and causes Peek calls for more than n runes to fail, so
+// that States can be tested against conditions that otherwise only occur with
+// large inputs. A value of zero or less leaves the reader unchanged.
+func WithBufferSize(n int) LexerOption {
+	return func(l *Lexer) {
+		l.bufSize = n
+	}
+}
+
+// WithTrace configures the Lexer to write a line to w with the current
+// position and the name of the State each time a State is run. See StateName.
+func WithTrace(w io.Writer) LexerOption {
+	return func(l *Lexer) {
+		l.trace = w
+	}
+}
+
+// WithCoverage configures the Lexer to record each State run in c. See
+// Coverage.
+func WithCoverage(c *Coverage) LexerOption {
+	return func(l *Lexer) {
+		l.coverage = c
+	}
+}
+
+// WithMaxStalls configures the Lexer to detect infinite loops. If n
+// consecutive States run without reading input or emitting a lexeme the
+// Lexer stops and Err returns a *StuckError naming the State. A value of zero
+// or less disables the check.
+func WithMaxStalls(n int) LexerOption {
+	return func(l *Lexer) {
+		l.maxStalls = n
+	}
+}
+
+// UnicodeNewlines are the runes that Unicode considers to end a line: line
+// feed, vertical tab, form feed, carriage return, next line (NEL), line
+// separator, and paragraph separator.
+var UnicodeNewlines = []rune{'\n', '\v', '\f', '\r', '\u0085', '\u2028', '\u2029'}
+
+// WithNewlines sets the runes that end a line when counting lines and
+// columns. By default only '\n' ends a line. If both '\r' and '\n' are
+// newlines, "\r\n" ends a single line. UnicodeNewlines can be used to
+// recognize all Unicode line terminators. Note that LineIndex only recognizes
+// '\n'.
+func WithNewlines(newlines ...rune) LexerOption {
+	return func(l *Lexer) {
+		l.newlines = NewRuneClass(string(newlines))
+	}
+}
+
+// WithGraphemeColumns configures the Lexer to count columns in grapheme
+// clusters, i.e. user-perceived characters, rather than runes so that columns
+// match those shown by editors for input containing combining characters or
+// emoji sequences. For example, "e\u0301" and the family emoji made of four
+// people joined with zero width joiners each count as one column. Grapheme
+// cluster boundaries are approximated using the standard library's Unicode
+// tables. Positions (Pos) are still counted in runes. Note that LineIndex and
+// Diagnostic.LSP expect columns counted in runes.
+func WithGraphemeColumns() LexerOption {
+	return func(l *Lexer) {
+		l.graphemes = true
+	}
+}
+
+// WithErrorLexemes configures the Lexer to recover from errors returned by
+// States rather than stopping. For each error a lexeme of type typ is emitted
+// at the position of the current lexeme with the error message as its Value.
+// The text of the current lexeme and the next rune, which is assumed to be
+// where the error occurred, are discarded and lexing continues at the resume
+// State. If
+// resume is nil, the starting State is used. io.EOF, limit, panic, and
+// context errors still stop the Lexer. Recovered errors are returned by Errs.
+func WithErrorLexemes(typ LexemeType, resume State) LexerOption {
+	return func(l *Lexer) {
+		l.errLexemes = true
+		l.errType = typ
+		l.resume = resume
+	}
+}
+
+// WithRecoverPanics sets whether the Lexer recovers from panics in States.
+// By default a panic in a State stops the Lexer and Err returns a *PanicError
+// with the name of the State and the position in the input rather than
+// crashing the program. Recovery can be disabled while debugging so that the
+// panic propagates with its original stack trace.
+func WithRecoverPanics(enabled bool) LexerOption {
+	return func(l *Lexer) {
+		l.noRecover = !enabled
 	}
 }
 
 // NewLexer creates a new Lexer initialized with the given starting state.
-func NewLexer(r BufferedRuneReader, startingState State) *Lexer {
+func NewLexer(r BufferedRuneReader, startingState State, opts ...LexerOption) *Lexer {
 	l := &Lexer{
 		state:   startingState,
+		start:   startingState,
 		lexemes: make(chan *Lexeme),
 		stop:    make(chan struct{}),
 		done:    make(chan struct{}),
 	}
-	l.s.r = r
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.s.r = l.reader(r)
 	return l
 }
 
+// reader returns the reader used by the Lexer for r.
+func (l *Lexer) reader(r BufferedRuneReader) *ioReader {
+	if l.bufSize > 0 {
+		r = runeio.NewReaderSize(r, l.bufSize)
+	}
+	return &ioReader{BufferedRuneReader: r}
+}
+
+// ioReader is a BufferedRuneReader that records the first error returned by
+// the reader it wraps so that errors caused by reading the input can be
+// distinguished from lexical errors.
+type ioReader struct {
+	BufferedRuneReader
+
+	// err is the first error returned by the reader that indicates a
+	// failure to read the input.
+	err error
+}
+
+// record records err if it indicates a failure to read the input and returns
+// it.
+func (r *ioReader) record(err error) error {
+	if err == nil || r.err != nil || errors.Is(err, io.EOF) || errors.Is(err, ErrNeedMoreInput) ||
+		errors.Is(err, runeio.ErrBufferFull) || errors.Is(err, runeio.ErrNegativeCount) {
+		return err
+	}
+	r.err = err
+	return err
+}
+
+// ReadRune implements io.RuneReader.
+func (r *ioReader) ReadRune() (rune, int, error) {
+	rn, size, err := r.BufferedRuneReader.ReadRune()
+	return rn, size, r.record(err)
+}
+
+// Peek implements BufferedRuneReader.
+func (r *ioReader) Peek(n int) ([]rune, error) {
+	p, err := r.BufferedRuneReader.Peek(n)
+	return p, r.record(err)
+}
+
+// Discard implements BufferedRuneReader.
+func (r *ioReader) Discard(n int) (int, error) {
+	d, err := r.BufferedRuneReader.Discard(n)
+	return d, r.record(err)
+}
+
+// Reset resets the Lexer to read from r starting with the starting state so
+// that it can be reused. Options are retained. If a Source was given by
+// WithSource it is cleared so that it retains only the input read from r.
+// Reset must only be called before Lex is called or after the channel
+// returned by Done is closed.
+func (l *Lexer) Reset(r BufferedRuneReader) {
+	l.s.Lock()
+	defer l.s.Unlock()
+
+	l.state = l.start
+	l.lexemes = make(chan *Lexeme)
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+
+	l.s.r = l.reader(r)
+	l.s.b.Reset()
+	l.s.pos = 0
+	l.s.line = 0
+	l.s.column = 0
+	l.s.startPos = 0
+	l.s.startLine = 0
+	l.s.startColumn = 0
+	l.s.err = nil
+	l.s.tokens = 0
+	l.s.bytes = 0
+	l.s.errs = nil
+	l.s.recent = runeRing{}
+	l.s.last = nil
+	l.s.grapheme = graphemeState{}
+	l.s.cr = false
+	l.s.run++
+
+	if l.src != nil {
+		l.src.reset()
+	}
+}
+
+// limitErr returns a *LimitError at the current position.
+func (l *Lexer) limitErr(limit string, n int) error {
+	return &LimitError{
+		Limit:  limit,
+		Max:    n,
+		Line:   l.s.line,
+		Column: l.s.column,
+		File:   l.file,
+	}
+}
+
 // Pos returns the current position of the underlying reader.
 func (l *Lexer) Pos() int {
 	l.s.Lock()
@@ -186,23 +546,51 @@ func (l *Lexer) ReadRune() (rune, int, error) {
 }
 
 func (l *Lexer) readrune() (rune, int, error) {
+	if l.maxInputBytes > 0 {
+		rns, err := l.s.r.Peek(1)
+		if err == nil && l.s.bytes+utf8.RuneLen(rns[0]) > l.maxInputBytes {
+			return 0, 0, l.limitErr("input byte", l.maxInputBytes)
+		}
+	}
+
 	rn, n, err := l.s.r.ReadRune()
 	if err != nil {
 		//nolint:wrapcheck // Error doesn't need to be wrapped.
 		return 0, 0, err
 	}
+	l.s.bytes += n
+	if l.src != nil {
+		l.src.write(rn)
+	}
+	l.s.recent.write(rn)
 
 	l.s.pos++
-	l.s.column++
-	if rn == '\n' {
-		l.s.line++
-		l.s.column = 0
-	}
+	l.updatePos([]rune{rn})
 
 	_, _ = l.s.b.WriteRune(rn)
 	return rn, n, nil
 }
 
+// Buffered returns the number of runes currently buffered by the underlying
+// reader. Up to this many runes can be peeked without reading more input.
+func (l *Lexer) Buffered() int {
+	l.s.Lock()
+	defer l.s.Unlock()
+	return l.s.r.Buffered()
+}
+
+// Fill reads input into the underlying reader's buffer until at least n runes
+// are buffered and returns the number of runes buffered. If fewer than n runes
+// can be buffered, an error is returned indicating why, e.g. io.EOF at the
+// end of the input or an error if n is larger than the reader's buffer.
+func (l *Lexer) Fill(n int) (int, error) {
+	l.s.Lock()
+	defer l.s.Unlock()
+	_, err := l.s.r.Peek(n)
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return l.s.r.Buffered(), err
+}
+
 // Peek returns the next n runes from the buffer without advancing the
 // lexer or underlying reader. The runes stop being valid at the next read
 // call. If Peek returns fewer than n runes, it also returns an error
@@ -215,6 +603,22 @@ func (l *Lexer) Peek(n int) ([]rune, error) {
 	return p, err
 }
 
+// PeekRune returns the next rune without advancing the lexer or underlying
+// reader. It is a fast path for Peek(1) that does not return a slice. If no
+// rune is available, e.g. at the end of the input, it returns false. The
+// reason can be determined by calling Peek.
+func (l *Lexer) PeekRune() (rune, bool) {
+	l.s.Lock()
+	p, _ := l.s.r.Peek(1)
+	if len(p) == 0 {
+		l.s.Unlock()
+		return 0, false
+	}
+	rn := p[0]
+	l.s.Unlock()
+	return rn, true
+}
+
 // Advance attempts to advance the underlying reader n runes and returns the
 // number actually advanced. If the number of runes advanced is different than
 // n, then an error is returned explaining the reason. It also updates the
@@ -254,29 +658,42 @@ func (l *Lexer) advance(n int, discard bool) (int, error) {
 			return advanced, fmt.Errorf("peeking input: %w", err)
 		}
 
+		// Only advance up to the input limit.
+		var limited bool
+		if l.maxInputBytes > 0 {
+			for i, r := range rn {
+				if l.s.bytes+utf8.RuneLen(r) > l.maxInputBytes {
+					rn = rn[:i]
+					limited = true
+					break
+				}
+				l.s.bytes += utf8.RuneLen(r)
+			}
+		}
+
 		// Advance by peeked amount.
 		d, dErr := l.s.r.Discard(len(rn))
 		advanced += d
 		l.s.pos += d
+		if l.src != nil {
+			l.src.write(rn[:d]...)
+		}
 
 		// NOTE: We must be careful since toRead could be different from #
 		//       of runes peeked.
-		for i := 0; i < d; i++ {
-			if rn[i] == '\n' {
-				l.s.line++
-				l.s.column = 0
-			} else {
-				l.s.column++
-			}
-		}
+		l.updatePos(rn[:d])
+		l.s.recent.write(rn[:d]...)
 
 		if !discard {
-			l.s.b.WriteString(string(rn))
+			l.writeRunes(rn[:d])
 		}
 
 		if dErr != nil {
 			return advanced, fmt.Errorf("discarding input: %w", err)
 		}
+		if limited {
+			return advanced, l.limitErr("input byte", l.maxInputBytes)
+		}
 		if err != nil {
 			// EOF from Peek
 			//nolint:wrapcheck // Error doesn't need to be wrapped.
@@ -289,6 +706,64 @@ func (l *Lexer) advance(n int, discard bool) (int, error) {
 	return advanced, nil
 }
 
+// updatePos updates the line and column for the advanced runes rns.
+func (l *Lexer) updatePos(rns []rune) {
+	if l.graphemes || l.newlines != nil {
+		for _, rn := range rns {
+			cr := l.s.cr
+			l.s.cr = false
+			if l.isNewline(rn) {
+				l.s.cr = rn == '\r'
+				if cr && rn == '\n' {
+					// "\r\n" ends a single line.
+					continue
+				}
+				l.s.line++
+				l.s.column = 0
+				l.s.grapheme = graphemeState{}
+				continue
+			}
+			if !l.graphemes || l.s.grapheme.next(rn) {
+				l.s.column++
+			}
+		}
+		return
+	}
+
+	lastNewline := -1
+	for i, rn := range rns {
+		if rn == '\n' {
+			l.s.line++
+			lastNewline = i
+		}
+	}
+	if lastNewline >= 0 {
+		l.s.column = len(rns) - lastNewline - 1
+	} else {
+		l.s.column += len(rns)
+	}
+}
+
+// isNewline returns true if rn ends a line.
+func (l *Lexer) isNewline(rn rune) bool {
+	if l.newlines == nil {
+		return rn == '\n'
+	}
+	return l.newlines.Contains(rn)
+}
+
+// writeRunes writes rns to the current lexeme value.
+func (l *Lexer) writeRunes(rns []rune) {
+	size := 0
+	for _, rn := range rns {
+		size += utf8.RuneLen(rn)
+	}
+	l.s.b.Grow(size)
+	for _, rn := range rns {
+		_, _ = l.s.b.WriteRune(rn)
+	}
+}
+
 // Discard attempts to discard n runes and returns the number actually
 // discarded. If the number of runes discarded is different than n, then an
 // error is returned explaining the reason. It also resets the current lexeme
@@ -300,84 +775,355 @@ func (l *Lexer) Discard(n int) (int, error) {
 	return d, err
 }
 
-// Find searches the input for one of the given tokens, advancing the reader,
-// and stopping when one of the tokens is found. The token found is returned.
-func (l *Lexer) Find(tokens []string) (string, error) {
+// Accept advances the reader one rune if the next rune is in the class c.
+// It returns true if the rune was accepted.
+func (l *Lexer) Accept(c *RuneClass) (bool, error) {
 	l.s.Lock()
 	defer l.s.Unlock()
 
-	var maxLen int
-	for i := range tokens {
-		if len(tokens[i]) > maxLen {
-			maxLen = len(tokens[i])
-		}
+	rns, err := l.s.r.Peek(1)
+	if err != nil {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return false, err
+	}
+	if !c.Contains(rns[0]) {
+		return false, nil
+	}
+	if _, _, err := l.readrune(); err != nil {
+		return false, err
 	}
+	return true, nil
+}
+
+// AdvanceWhile advances the reader while the next rune is in the class c and
+// returns the number of runes advanced. An io.EOF error is returned if the end
+// of the input is reached.
+func (l *Lexer) AdvanceWhile(c *RuneClass) (int, error) {
+	l.s.Lock()
+	defer l.s.Unlock()
 
+	var advanced int
 	for {
-		rns, err := l.s.r.Peek(maxLen)
+		// Check the buffered runes and advance over the matching span at
+		// once.
+		n := l.s.r.Buffered()
+		if n < 1 {
+			n = 1
+		}
+		rns, err := l.s.r.Peek(n)
 		if err != nil && !errors.Is(err, io.EOF) {
-			return "", fmt.Errorf("peeking input: %w", err)
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return advanced, err
+		}
+
+		i := 0
+		for i < len(rns) && c.Contains(rns[i]) {
+			i++
 		}
-		for j := range tokens {
-			if strings.HasPrefix(string(rns), tokens[j]) {
-				return tokens[j], nil
+		if i > 0 {
+			a, advErr := l.advance(i, false)
+			advanced += a
+			if advErr != nil {
+				return advanced, advErr
 			}
 		}
-
-		if _, _, err = l.readrune(); err != nil {
-			return "", err
+		if i < len(rns) {
+			return advanced, nil
+		}
+		if err != nil {
+			//nolint:wrapcheck // Error doesn't need to be wrapped.
+			return advanced, err
 		}
 	}
 }
 
+// Find searches the input for one of the given tokens, advancing the reader,
+// and stopping when one of the tokens is found. The token found is returned.
+func (l *Lexer) Find(tokens []string) (string, error) {
+	return l.find(newTokenMatcher(tokens, false), false)
+}
+
+// FindFold is like Find but tokens are matched case-insensitively using
+// Unicode case-folding. The token from tokens that matched is returned.
+func (l *Lexer) FindFold(tokens []string) (string, error) {
+	return l.find(newTokenMatcher(tokens, true), false)
+}
+
 // SkipTo searches the input for one of the given tokens, advancing the reader,
 // and stopping when one of the tokens is found. The data prior to the token is
 // discarded. The token found is returned.
 func (l *Lexer) SkipTo(tokens []string) (string, error) {
-	l.s.Lock()
-	defer l.s.Unlock()
+	return l.find(newTokenMatcher(tokens, false), true)
+}
+
+// SkipToFold is like SkipTo but tokens are matched case-insensitively using
+// Unicode case-folding. The token from tokens that matched is returned.
+func (l *Lexer) SkipToFold(tokens []string) (string, error) {
+	return l.find(newTokenMatcher(tokens, true), true)
+}
+
+// Quote describes a quoted string, or a comment, that FindQuoted and
+// SkipToQuoted skip over when searching for tokens.
+type Quote struct {
+	// Open and Close are the delimiters of the quoted string, e.g. `"` and
+	// `"` or "/*" and "*/".
+	Open  string
+	Close string
+
+	// Escape is the rune that escapes the following rune inside the quoted
+	// string, e.g. '\\'. If Escape is the same as Close, as in SQL, a doubled
+	// Close is an escaped quote. A value of zero means there are no escapes.
+	Escape rune
+}
+
+// FindQuoted is like Find but tokens inside the quoted strings described by
+// quotes are not matched. For example, searching for "%}" in
+// `"%}" | upper %}` with a Quote for double quoted strings finds the second
+// "%}". If a token and the opening delimiter of a quote both match at the same
+// position, the token is matched. An io.EOF error is returned if the end of
+// the input is reached, including inside an unterminated quoted string.
+func (l *Lexer) FindQuoted(tokens []string, quotes []Quote) (string, error) {
+	return l.findQuoted(tokens, quotes, false)
+}
 
-	var maxLen int
-	for i := range tokens {
-		if len(tokens[i]) > maxLen {
-			maxLen = len(tokens[i])
+// SkipToQuoted is like SkipTo but tokens inside the quoted strings described
+// by quotes are not matched. See FindQuoted.
+func (l *Lexer) SkipToQuoted(tokens []string, quotes []Quote) (string, error) {
+	return l.findQuoted(tokens, quotes, true)
+}
+
+// findQuoted advances the reader to the first of tokens that is not inside a
+// quoted string and returns it. If discard is true, the data prior to the
+// token is discarded.
+func (l *Lexer) findQuoted(tokens []string, quotes []Quote, discard bool) (string, error) {
+	all := make([]string, len(tokens), len(tokens)+len(quotes))
+	copy(all, tokens)
+	for _, q := range quotes {
+		all = append(all, q.Open)
+	}
+	m := newTokenMatcher(all, false)
+
+	for {
+		i, err := l.findIndex(m, discard)
+		if err != nil {
+			return "", err
+		}
+		if i < len(tokens) {
+			return tokens[i], nil
 		}
+		if err := l.skipQuoted(quotes[i-len(tokens)], discard); err != nil {
+			return "", err
+		}
+	}
+}
+
+// skipQuoted advances the reader past the quoted string q at the current
+// position.
+func (l *Lexer) skipQuoted(q Quote, discard bool) error {
+	l.s.Lock()
+	_, err := l.advance(utf8.RuneCountInString(q.Open), discard)
+	l.s.Unlock()
+	if err != nil {
+		return err
 	}
 
+	tokens := []string{q.Close}
+	if q.Escape != 0 {
+		// The escape is matched first so that a doubled Close is found
+		// before the Close itself.
+		tokens = []string{string(q.Escape), q.Close}
+	}
+	m := newTokenMatcher(tokens, false)
 	for {
-		bufS := l.s.r.Buffered()
-		if bufS < maxLen {
-			bufS = maxLen
+		i, err := l.findIndex(m, discard)
+		if err != nil {
+			return err
 		}
 
-		rns, err := l.s.r.Peek(bufS)
+		l.s.Lock()
+		if q.Escape != 0 && i == 0 {
+			// Advance past the escape and the escaped rune. If the escape is
+			// also the close delimiter and isn't doubled, it closes the
+			// string.
+			n := 2
+			if string(q.Escape) == q.Close {
+				if rns, _ := l.s.r.Peek(2); len(rns) < 2 || rns[1] != q.Escape {
+					n = 1
+					i = 1
+				}
+			}
+			_, err = l.advance(n, discard)
+		} else {
+			_, err = l.advance(utf8.RuneCountInString(q.Close), discard)
+		}
+		l.s.Unlock()
+		if err != nil {
+			return err
+		}
+		if i != 0 || q.Escape == 0 {
+			return nil
+		}
+	}
+}
+
+// find advances the reader to the first token matched by m and returns it.
+// If discard is true, the data prior to the token is discarded.
+func (l *Lexer) find(m *tokenMatcher, discard bool) (string, error) {
+	i, err := l.findIndex(m, discard)
+	if err != nil {
+		return "", err
+	}
+	return m.tokens[i], nil
+}
+
+// findIndex advances the reader to the first token matched by m and returns
+// its index. If discard is true, the data prior to the token is discarded.
+func (l *Lexer) findIndex(m *tokenMatcher, discard bool) (int, error) {
+	l.s.Lock()
+	defer l.s.Unlock()
+
+	for {
+		// Peek as much of the input as is buffered so that it can be
+		// searched without advancing the reader rune by rune.
+		n := l.s.r.Buffered()
+		if n < m.maxLen {
+			n = m.maxLen
+		}
+		rns, err := l.s.r.Peek(n)
 		if err != nil && !errors.Is(err, io.EOF) {
-			return "", fmt.Errorf("peeking input: %w", err)
+			return 0, fmt.Errorf("peeking input: %w", err)
 		}
+		eof := err != nil
 
-		for i := 0; i < len(rns)-maxLen+1; i++ {
-			for j := range tokens {
-				if strings.HasPrefix(string(rns[i:i+maxLen]), tokens[j]) {
-					// We have found a match. Discard prior runes and return.
-					if _, advErr := l.advance(i, true); advErr != nil {
-						return "", advErr
-					}
-					return tokens[j], nil
+		// Only search positions where the longest token could fit unless
+		// the end of the input has been reached.
+		end := len(rns)
+		if !eof {
+			end = len(rns) - m.maxLen + 1
+		}
+		for i := 0; i < end; i++ {
+			if !m.empty && !m.isFirst(rns[i]) {
+				continue
+			}
+			if tok := m.match(rns[i:]); tok >= 0 {
+				if _, err := l.advance(i, discard); err != nil {
+					return 0, err
 				}
+				return tok, nil
 			}
 		}
 
-		// Advance the reader by the runes peeked checked.
-		// NOTE: Only advance the reader the number of runes that could never
-		// match the substring. Not the full number peeked.
-		toDiscard := len(rns) - maxLen + 1
-		if toDiscard <= 0 {
-			toDiscard = 1
+		if _, err := l.advance(end, discard); err != nil {
+			return 0, err
 		}
-		if _, err = l.advance(toDiscard, true); err != nil {
-			return "", err
+		if eof {
+			return 0, io.EOF
+		}
+	}
+}
+
+// tokenMatcher matches a set of tokens at the start of a rune slice.
+type tokenMatcher struct {
+	tokens []string
+	runes  [][]rune
+
+	// firstASCII is a bitmap of the ASCII runes that can start a token.
+	firstASCII [2]uint64
+
+	// first are the non-ASCII runes that can start a token.
+	first []rune
+
+	// empty is true if one of the tokens is empty.
+	empty bool
+
+	// fold is true if tokens are matched using Unicode case-folding.
+	fold bool
+
+	// maxLen is the length in runes of the longest token.
+	maxLen int
+}
+
+// newTokenMatcher returns a new tokenMatcher for tokens.
+func newTokenMatcher(tokens []string, fold bool) *tokenMatcher {
+	m := &tokenMatcher{
+		tokens: tokens,
+		fold:   fold,
+		maxLen: 1,
+	}
+
+	for _, tok := range tokens {
+		rns := []rune(tok)
+		m.runes = append(m.runes, rns)
+		if len(rns) > m.maxLen {
+			m.maxLen = len(rns)
+		}
+		if len(rns) == 0 {
+			m.empty = true
+			continue
+		}
+		m.addFirst(rns[0])
+		if fold {
+			for r := unicode.SimpleFold(rns[0]); r != rns[0]; r = unicode.SimpleFold(r) {
+				m.addFirst(r)
+			}
+		}
+	}
+
+	return m
+}
+
+// addFirst adds rn to the runes that can start a token.
+func (m *tokenMatcher) addFirst(rn rune) {
+	if rn < utf8.RuneSelf {
+		m.firstASCII[rn/64] |= 1 << (rn % 64)
+		return
+	}
+	m.first = append(m.first, rn)
+}
+
+// isFirst returns true if rn can start a token.
+func (m *tokenMatcher) isFirst(rn rune) bool {
+	if rn < utf8.RuneSelf {
+		return rn >= 0 && m.firstASCII[rn/64]&(1<<(rn%64)) != 0
+	}
+	for _, r := range m.first {
+		if r == rn {
+			return true
 		}
 	}
+	return false
+}
+
+// match returns the index of the first token that rns begins with or -1 if
+// there is no match.
+func (m *tokenMatcher) match(rns []rune) int {
+	for i, tok := range m.runes {
+		if len(tok) > len(rns) {
+			continue
+		}
+		matched := true
+		for j := range tok {
+			if tok[j] != rns[j] && (!m.fold || !equalFoldRune(tok[j], rns[j])) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
+
+// equalFoldRune returns true if a and b are equal under simple Unicode
+// case-folding.
+func equalFoldRune(a, b rune) bool {
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
 }
 
 // Ignore ignores the previous input and resets the lexeme start position to
@@ -400,20 +1146,37 @@ func (l *Lexer) ignore() {
 // which is run until a state returns nil indicating that lexing has finished.
 //
 // The caller can request that the lexer stop by cancelling ctx. The
-// returned channel is closed when the Lexer is finished running.
+// returned channel is closed when the Lexer is finished running. If ctx is
+// cancelled, Err returns ctx.Err() rather than the cause of the cancellation
+// so that the Lexer's own errors aren't confused with those of the caller.
+// States can get the cause with context.Cause.
 func (l *Lexer) Lex(ctx context.Context) <-chan *Lexeme {
+	l.s.Lock()
+	run := l.s.run
+	l.s.Unlock()
+
 	// This first goroutine ensures that the stop channel is closed when the
 	// given context is done. This requests that the other goroutine stop.
+	stop, done := l.stop, l.done
 	go func() {
-		<-ctx.Done()
-		l.setErr(ctx.Err())
-		close(l.stop)
+		select {
+		case <-ctx.Done():
+			l.s.Lock()
+			// NOTE: Don't set the error if the Lexer has been reset.
+			if l.s.run == run && l.s.err == nil {
+				l.s.err = ctx.Err()
+			}
+			l.s.Unlock()
+			close(stop)
+		case <-done:
+		}
 	}()
 
 	// This goroutine runs the lexer. It will return and close the done and
 	// lexemes channels if stop is requested via the stop channel.
 	go func() {
 		var err error
+		var stalls int
 		defer close(l.done)
 		defer close(l.lexemes)
 		for l.state != nil {
@@ -423,19 +1186,182 @@ func (l *Lexer) Lex(ctx context.Context) <-chan *Lexeme {
 			default:
 			}
 
-			l.state, err = l.state.Run(ctx, l)
+			l.traceState()
+			if l.coverage != nil {
+				l.coverage.recordState(l.state)
+			}
+			var pos, tokens int
+			if l.maxStalls > 0 {
+				pos, tokens = l.progress()
+			}
+
+			var next State
+			next, err = l.runState(ctx)
 			if err != nil {
+				if l.input != nil && errors.Is(err, ErrNeedMoreInput) {
+					// Run the same state again when more input is available.
+					if l.input.wait(l.stop) {
+						continue
+					}
+					return
+				}
+				err = l.classifyErr(err)
+				if l.recoverErr(err) {
+					continue
+				}
+				l.state = nil
 				if !errors.Is(err, io.EOF) {
 					l.setErr(err)
 				}
 				return
 			}
+			if l.stalled(pos, tokens, &stalls) {
+				return
+			}
+			l.state = next
+			if errors.Is(l.Err(), ErrLimitExceeded) {
+				return
+			}
 		}
 	}()
 
 	return l.lexemes
 }
 
+// runState runs the current State. Unless disabled with WithRecoverPanics, a
+// panic in the State is returned as a *PanicError.
+func (l *Lexer) runState(ctx context.Context) (next State, err error) {
+	if !l.noRecover {
+		defer func() {
+			if r := recover(); r != nil {
+				l.s.Lock()
+				line, column := l.s.line, l.s.column
+				l.s.Unlock()
+				next, err = nil, &PanicError{
+					Value:  r,
+					State:  StateName(l.state),
+					Line:   line,
+					Column: column,
+					File:   l.file,
+					Stack:  debug.Stack(),
+				}
+			}
+		}()
+	}
+	return l.state.Run(ctx, l)
+}
+
+// classifyErr returns err returned by the current State as an *IOError if it
+// was caused by a failure to read the input or a *LexicalError otherwise.
+// io.EOF, context, limit, and panic errors are returned as-is.
+func (l *Lexer) classifyErr(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrLimitExceeded) || errors.Is(err, ErrPanic) ||
+		errors.Is(err, ErrLexical) || errors.Is(err, ErrIO) {
+		return err
+	}
+
+	l.s.Lock()
+	line, column, readErr := l.s.line, l.s.column, l.s.r.err
+	l.s.Unlock()
+	state := StateName(l.state)
+	if readErr != nil && errors.Is(err, readErr) {
+		return &IOError{Err: err, State: state, Line: line, Column: column, File: l.file}
+	}
+	return &LexicalError{Err: err, State: state, Line: line, Column: column, File: l.file}
+}
+
+// traceState writes the current position and State to the trace writer.
+func (l *Lexer) traceState() {
+	if l.trace == nil {
+		return
+	}
+	l.s.Lock()
+	line, column := l.s.line, l.s.column
+	l.s.Unlock()
+	fmt.Fprintf(l.trace, "%s%d:%d: %s\n", filePrefix(l.file), line+1, column+1, StateName(l.state))
+}
+
+// progress returns the current position and number of lexemes emitted.
+func (l *Lexer) progress() (int, int) {
+	l.s.Lock()
+	defer l.s.Unlock()
+	return l.s.pos, l.s.tokens
+}
+
+// stalled counts consecutive State runs that did not make progress since the
+// position and number of lexemes emitted were pos and tokens. It sets a
+// *StuckError and returns true if there were more than the maximum number of
+// stalls.
+func (l *Lexer) stalled(pos, tokens int, stalls *int) bool {
+	if l.maxStalls <= 0 {
+		return false
+	}
+	if newPos, newTokens := l.progress(); newPos != pos || newTokens != tokens {
+		*stalls = 0
+		return false
+	}
+	*stalls++
+	if *stalls <= l.maxStalls {
+		return false
+	}
+
+	l.s.Lock()
+	line, column := l.s.line, l.s.column
+	l.s.Unlock()
+	l.setErr(&StuckError{
+		State:  StateName(l.state),
+		Runs:   *stalls,
+		Line:   line,
+		Column: column,
+		File:   l.file,
+	})
+	return true
+}
+
+// recoverErr emits an error lexeme for err and sets the resume state. It returns
+// false if the Lexer is not configured to recover from err.
+func (l *Lexer) recoverErr(err error) bool {
+	if !l.errLexemes || errors.Is(err, io.EOF) || errors.Is(err, ErrLimitExceeded) || errors.Is(err, ErrPanic) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	l.s.Lock()
+	lexeme := &Lexeme{
+		Type:   l.errType,
+		Value:  err.Error(),
+		Pos:    l.s.startPos,
+		Line:   l.s.startLine,
+		Column: l.s.startColumn,
+		File:   l.file,
+	}
+	l.s.errs = append(l.s.errs, err)
+	l.s.Unlock()
+
+	l.Emit(lexeme)
+	l.Ignore()
+	// NOTE: Skipping a rune also ensures that the Lexer makes progress.
+	if _, dErr := l.Discard(1); dErr != nil && !errors.Is(dErr, io.EOF) {
+		return false
+	}
+
+	l.state = l.resume
+	if l.state == nil {
+		l.state = l.start
+	}
+	return true
+}
+
+// Errs returns the errors that the Lexer recovered from by emitting error
+// lexemes. See WithErrorLexemes.
+func (l *Lexer) Errs() []error {
+	l.s.Lock()
+	errs := append([]error(nil), l.s.errs...)
+	l.s.Unlock()
+	return errs
+}
+
 // setErr sets the lexer's error value.
 func (l *Lexer) setErr(err error) {
 	l.s.Lock()
@@ -445,7 +1371,9 @@ func (l *Lexer) setErr(err error) {
 	l.s.Unlock()
 }
 
-// Err returns the last encountered error.
+// Err returns the last encountered error. Errors returned by States are
+// returned as an *IOError wrapping ErrIO if they were caused by a failure to
+// read the input or a *LexicalError wrapping ErrLexical otherwise.
 func (l *Lexer) Err() error {
 	l.s.Lock()
 	err := l.s.err
@@ -467,6 +1395,7 @@ func (l *Lexer) Lexeme(typ LexemeType) *Lexeme {
 		Pos:    l.s.startPos,
 		Line:   l.s.startLine,
 		Column: l.s.startColumn,
+		File:   l.file,
 	}
 	l.s.Unlock()
 	return lexeme
@@ -474,7 +1403,9 @@ func (l *Lexer) Lexeme(typ LexemeType) *Lexeme {
 
 // Emit is used by State implementations to emit a lexeme which will be passed
 // on to the parser. If the lexer is not currently active, this is a no-op.
-// This advances the current lexeme position.
+// This advances the current lexeme position. If the maximum number of lexemes
+// has been emitted, the lexeme is dropped and the Lexer stops after the
+// current State returns.
 func (l *Lexer) Emit(lexeme *Lexeme) {
 	if l.lexemes == nil {
 		return
@@ -482,6 +1413,28 @@ func (l *Lexer) Emit(lexeme *Lexeme) {
 	if lexeme == nil {
 		return
 	}
+
+	l.s.Lock()
+	if l.maxTokens > 0 && l.s.tokens >= l.maxTokens {
+		if l.s.err == nil {
+			l.s.err = &LimitError{
+				Limit:  "token",
+				Max:    l.maxTokens,
+				Line:   lexeme.Line,
+				Column: lexeme.Column,
+				File:   lexeme.File,
+			}
+		}
+		l.s.Unlock()
+		return
+	}
+	l.s.tokens++
+	if l.src != nil {
+		l.src.emit(lexeme.Pos, l.s.pos)
+	}
+	l.s.last = lexeme
+	l.s.Unlock()
+
 	select {
 	case l.lexemes <- lexeme:
 		l.Ignore()
@@ -489,3 +1442,72 @@ func (l *Lexer) Emit(lexeme *Lexeme) {
 		return
 	}
 }
+
+// Last returns the last lexeme emitted by the Lexer or nil if no lexemes have
+// been emitted.
+func (l *Lexer) Last() *Lexeme {
+	l.s.Lock()
+	defer l.s.Unlock()
+	return l.s.last
+}
+
+// Context returns the text surrounding the current position: up to nBefore
+// runes of input before the position followed by up to nAfter runes after it.
+// It can be used to include a source excerpt in error messages. The Lexer
+// retains only the last ContextSize runes read so nBefore is limited to
+// ContextSize. nAfter is limited by the size of the underlying reader's
+// buffer.
+func (l *Lexer) Context(nBefore, nAfter int) string {
+	l.s.Lock()
+	defer l.s.Unlock()
+
+	before := l.s.recent.last(nBefore)
+	var after []rune
+	if nAfter > 0 {
+		// NOTE: Errors are ignored and the runes available are used.
+		after, _ = l.s.r.Peek(nAfter)
+	}
+	return string(before) + string(after)
+}
+
+// ContextSize is the maximum number of runes before the current position
+// returned by Lexer.Context.
+const ContextSize = 128
+
+// runeRing is a ring buffer holding the most recently read runes.
+type runeRing struct {
+	buf [ContextSize]rune
+
+	// n is the total number of runes written.
+	n int
+}
+
+// write writes rns to the ring buffer.
+func (r *runeRing) write(rns ...rune) {
+	if len(rns) > len(r.buf) {
+		r.n += len(rns) - len(r.buf)
+		rns = rns[len(rns)-len(r.buf):]
+	}
+	for _, rn := range rns {
+		r.buf[r.n%len(r.buf)] = rn
+		r.n++
+	}
+}
+
+// last returns the last n runes written.
+func (r *runeRing) last(n int) []rune {
+	if n > r.n {
+		n = r.n
+	}
+	if n > len(r.buf) {
+		n = len(r.buf)
+	}
+	if n <= 0 {
+		return nil
+	}
+	rns := make([]rune, n)
+	for i := range rns {
+		rns[i] = r.buf[(r.n-n+i)%len(r.buf)]
+	}
+	return rns
+}
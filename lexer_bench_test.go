@@ -0,0 +1,225 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"text/scanner"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ianlewis/runeio"
+)
+
+// benchCorpusSize is the approximate size in bytes of the benchmark corpora.
+const benchCorpusSize = 64 * 1024
+
+const (
+	benchWordType LexemeType = iota + 400
+	benchStringType
+	benchPunctType
+	benchTextType
+	benchActionType
+)
+
+var (
+	benchSpace = RuneClassFunc(unicode.IsSpace)
+	benchWord  = NewRuneClass("_.-+", unicode.Letter, unicode.Digit)
+)
+
+// repeatCorpus repeats the output of chunk until the corpus is at least
+// benchCorpusSize bytes.
+func repeatCorpus(chunk func(i int) string) string {
+	var b strings.Builder
+	for i := 0; b.Len() < benchCorpusSize; i++ {
+		b.WriteString(chunk(i))
+	}
+	return b.String()
+}
+
+// benchCorpora are representative inputs for benchmarks.
+var benchCorpora = map[string]string{
+	"json": "[" + repeatCorpus(func(i int) string {
+		return fmt.Sprintf(`{"id": %d, "name": "item %d", "tags": ["a", "b"], "price": %d.5, "ok": true},`+"\n", i, i, i)
+	}) + "{}]",
+	"ini": repeatCorpus(func(i int) string {
+		return fmt.Sprintf("[section%d]\nname = value %d\npath = /usr/local/lib%d\n; comment\n\n", i, i, i)
+	}),
+	"template": repeatCorpus(func(i int) string {
+		return fmt.Sprintf("<li>Some text for item %d: {{ item%d.name }} and "+
+			"{%% if item%d %%}yes{%% endif %%}</li>\n", i, i, i)
+	}),
+}
+
+// benchState lexes words, double quoted strings, and punctuation similar to
+// text/scanner.
+type benchState struct{}
+
+func (s *benchState) Run(_ context.Context, l *Lexer) (State, error) {
+	if _, err := l.AdvanceWhile(benchSpace); err != nil {
+		return nil, ignoreEOF(err)
+	}
+	l.Ignore()
+
+	rn, ok := l.PeekRune()
+	if !ok {
+		// Peek returns the reason no rune is available.
+		_, err := l.Peek(1)
+		return nil, ignoreEOF(err)
+	}
+
+	switch {
+	case benchWord.Contains(rn):
+		if _, err := l.AdvanceWhile(benchWord); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		l.Emit(l.Lexeme(benchWordType))
+	case rn == '"':
+		if _, err := l.Advance(1); err != nil {
+			return nil, err
+		}
+		if _, err := l.Find([]string{`"`}); err != nil {
+			return nil, err
+		}
+		if _, err := l.Advance(1); err != nil {
+			return nil, err
+		}
+		l.Emit(l.Lexeme(benchStringType))
+	default:
+		if _, err := l.Advance(1); err != nil {
+			return nil, err
+		}
+		l.Emit(l.Lexeme(benchPunctType))
+	}
+	return s, nil
+}
+
+// benchFindState lexes template text and actions using Find.
+type benchFindState struct{}
+
+func (s *benchFindState) Run(_ context.Context, l *Lexer) (State, error) {
+	token, err := l.Find([]string{"{{", "{%"})
+	l.Emit(l.Lexeme(benchTextType))
+	if err != nil {
+		return nil, ignoreEOF(err)
+	}
+	if _, err := l.Advance(len(token)); err != nil {
+		return nil, err
+	}
+	if _, err := l.Find([]string{"}}", "%}"}); err != nil {
+		return nil, err
+	}
+	if _, err := l.Advance(2); err != nil {
+		return nil, err
+	}
+	l.Emit(l.Lexeme(benchActionType))
+	return s, nil
+}
+
+func ignoreEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// lexerFunc starts lexing the input and returns the lexemes and a function
+// returning the lexer's error after it is done.
+type lexerFunc func(ctx context.Context, input string) (<-chan *Lexeme, func() error)
+
+// benchmarkLexer benchmarks lexing the input and reports throughput in runes
+// and lexemes per second as well as bytes per second.
+func benchmarkLexer(b *testing.B, input string, lex lexerFunc) {
+	b.Helper()
+
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var lexemes int
+	for i := 0; i < b.N; i++ {
+		ch, errFn := lex(context.Background(), input)
+		for range ch {
+			lexemes++
+		}
+		if err := errFn(); err != nil {
+			b.Fatalf("Lex: %v", err)
+		}
+	}
+
+	b.StopTimer()
+	seconds := b.Elapsed().Seconds()
+	if seconds > 0 {
+		b.ReportMetric(float64(utf8.RuneCountInString(input))*float64(b.N)/seconds, "runes/s")
+		b.ReportMetric(float64(lexemes)/seconds, "lexemes/s")
+	}
+}
+
+func newBenchLexer(state State) lexerFunc {
+	return func(ctx context.Context, input string) (<-chan *Lexeme, func() error) {
+		l := NewLexer(runeio.NewReader(strings.NewReader(input)), state)
+		return l.Lex(ctx), func() error {
+			<-l.Done()
+			return l.Err()
+		}
+	}
+}
+
+func scanningLexer(ctx context.Context, input string) (<-chan *Lexeme, func() error) {
+	l := NewScanningLexer(strings.NewReader(input), scanner.GoTokens)
+	return l.Lex(ctx), func() error {
+		<-l.Done()
+		return l.Err()
+	}
+}
+
+func BenchmarkLexer(b *testing.B) {
+	for _, name := range []string{"json", "ini", "template"} {
+		input := benchCorpora[name]
+		b.Run(name, func(b *testing.B) {
+			benchmarkLexer(b, input, newBenchLexer(&benchState{}))
+		})
+	}
+}
+
+func BenchmarkLexer_Find(b *testing.B) {
+	benchmarkLexer(b, benchCorpora["template"], newBenchLexer(&benchFindState{}))
+}
+
+func BenchmarkLexer_PeekRune(b *testing.B) {
+	l := NewLexer(runeio.NewReader(strings.NewReader("a")), &benchState{})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := l.PeekRune(); !ok {
+			_, err := l.Peek(1)
+			b.Fatalf("PeekRune: want: true, got: false: %v", err)
+		}
+	}
+}
+
+func BenchmarkScanningLexer(b *testing.B) {
+	for _, name := range []string{"json", "ini", "template"} {
+		input := benchCorpora[name]
+		b.Run(name, func(b *testing.B) {
+			benchmarkLexer(b, input, scanningLexer)
+		})
+	}
+}
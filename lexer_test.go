@@ -15,12 +15,15 @@
 package lexparse
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ianlewis/runeio"
@@ -54,6 +57,301 @@ func (w *wordState) Run(_ context.Context, l *Lexer) (State, error) {
 	return w, nil
 }
 
+func TestLexer_Fill(t *testing.T) {
+	t.Parallel()
+
+	input := strings.Repeat("a", 40)
+	l := NewLexer(runeio.NewReaderSize(strings.NewReader(input), 16), &wordState{})
+
+	if got, want := l.Buffered(), 0; got != want {
+		t.Errorf("Buffered: want: %v, got: %v", want, got)
+	}
+
+	n, err := l.Fill(4)
+	if err != nil {
+		t.Fatalf("Fill: %v", err)
+	}
+	if n < 4 {
+		t.Errorf("Fill: want: >= %v, got: %v", 4, n)
+	}
+	if got, want := l.Buffered(), n; got != want {
+		t.Errorf("Buffered: want: %v, got: %v", want, got)
+	}
+
+	// The buffer cannot hold more than 16 runes.
+	if _, err := l.Fill(17); !errors.Is(err, runeio.ErrBufferFull) {
+		t.Errorf("Fill: want: %v, got: %v", runeio.ErrBufferFull, err)
+	}
+
+	if _, err := l.Advance(30); err != nil {
+		t.Fatalf("Advance: %v", err)
+	}
+	n, err = l.Fill(16)
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("Fill: want: %v, got: %v", io.EOF, err)
+	}
+	if got, want := n, 10; got != want {
+		t.Errorf("Fill: want: %v, got: %v", want, got)
+	}
+}
+
+func TestWithBufferSize(t *testing.T) {
+	t.Parallel()
+
+	input := "Hello World! {{ templated }} text"
+	l := NewLexer(runeio.NewReader(strings.NewReader(input)), &wordState{}, WithBufferSize(4))
+
+	if _, err := l.Fill(5); !errors.Is(err, runeio.ErrBufferFull) {
+		t.Errorf("Fill: want: %v, got: %v", runeio.ErrBufferFull, err)
+	}
+
+	// Tokens spanning the buffer boundary are still found.
+	token, err := l.Find([]string{"{{"})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if got, want := token, "{{"; got != want {
+		t.Errorf("Find: want: %q, got: %q", want, got)
+	}
+	if got, want := l.Pos(), 13; got != want {
+		t.Errorf("Pos: want: %v, got: %v", want, got)
+	}
+
+	// The option is retained after Reset.
+	l.Reset(runeio.NewReader(strings.NewReader(input)))
+	var got []string
+	for lexeme := range l.Lex(context.Background()) {
+		got = append(got, lexeme.Value)
+	}
+	want := strings.Split(input, " ")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestLexer_Context(t *testing.T) {
+	t.Parallel()
+
+	input := "first line\nsecond line with an error here"
+	l := NewLexer(runeio.NewReader(strings.NewReader(input)), &wordState{})
+
+	if got, want := l.Context(10, 5), "first"; got != want {
+		t.Errorf("Context: want: %q, got: %q", want, got)
+	}
+
+	if _, err := l.SkipTo([]string{"error"}); err != nil {
+		t.Fatalf("SkipTo: %v", err)
+	}
+	if got, want := l.Context(14, 5), " line with an error"; got != want {
+		t.Errorf("Context: want: %q, got: %q", want, got)
+	}
+
+	// Context is limited to the input read so far.
+	if got, want := l.Context(100, 100), input; got != want {
+		t.Errorf("Context: want: %q, got: %q", want, got)
+	}
+
+	// Only ContextSize runes are kept.
+	long := strings.Repeat("x", 2*ContextSize)
+	l = NewLexer(runeio.NewReader(strings.NewReader(long+"!")), &wordState{})
+	if _, err := l.Advance(len(long)); err != nil {
+		t.Fatalf("Advance: %v", err)
+	}
+	if got, want := l.Context(len(long), 1), long[:ContextSize]+"!"; got != want {
+		t.Errorf("Context: want: %q, got: %q", want, got)
+	}
+}
+
+func TestLexer_cancelCause(t *testing.T) {
+	t.Parallel()
+
+	errCause := errors.New("cause")
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	// The lexeme is never read so Emit blocks until the Lexer is stopped.
+	state := StateFn(func(_ context.Context, l *Lexer) (State, error) {
+		l.Emit(&Lexeme{Value: "x"})
+		return nil, nil
+	})
+	l := NewLexer(runeio.NewReader(strings.NewReader("")), state)
+	_ = l.Lex(ctx)
+	cancel(errCause)
+	<-l.Done()
+
+	// The cause isn't reported as the Lexer's error.
+	if err := l.Err(); !errors.Is(err, context.Canceled) || errors.Is(err, errCause) {
+		t.Errorf("Err: want: %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestLexer_Last(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(runeio.NewReader(strings.NewReader("Hello World")), &wordState{})
+	if got := l.Last(); got != nil {
+		t.Errorf("Last: want: %v, got: %v", nil, got)
+	}
+
+	for range l.Lex(context.Background()) {
+		// Drain the lexemes.
+	}
+	<-l.Done()
+
+	want := &Lexeme{
+		Type:   wordType,
+		Value:  "World",
+		Pos:    6,
+		Column: 6,
+	}
+	if diff := cmp.Diff(want, l.Last()); diff != "" {
+		t.Errorf("Last: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestWithNewlines(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input    string
+		newlines []rune
+		line     int
+		column   int
+	}{
+		"default": {
+			input:  "a\r\nb\u2028c\fd",
+			line:   1,
+			column: 5,
+		},
+		"unicode": {
+			input:    "a\r\nb\u2028c\fd",
+			newlines: UnicodeNewlines,
+			line:     3,
+			column:   1,
+		},
+		"carriage returns": {
+			input:    "\r\r\nab",
+			newlines: UnicodeNewlines,
+			line:     2,
+			column:   2,
+		},
+		"line feed only": {
+			input:    "a\r\nb\r\nc",
+			newlines: []rune{'\n'},
+			line:     2,
+			column:   1,
+		},
+		"custom": {
+			input:    "a;b;c",
+			newlines: []rune{';'},
+			line:     2,
+			column:   1,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []LexerOption
+			if tc.newlines != nil {
+				opts = append(opts, WithNewlines(tc.newlines...))
+			}
+			// Use a small buffer so that "\r\n" may span reads.
+			opts = append(opts, WithBufferSize(2))
+			l := NewLexer(runeio.NewReader(strings.NewReader(tc.input+"|")), &wordState{}, opts...)
+			if _, err := l.Find([]string{"|"}); err != nil {
+				t.Fatalf("Find: %v", err)
+			}
+			if got, want := l.Line(), tc.line; got != want {
+				t.Errorf("Line: want: %v, got: %v", want, got)
+			}
+			if got, want := l.Column(), tc.column; got != want {
+				t.Errorf("Column: want: %v, got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestWithGraphemeColumns(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input  string
+		column int
+	}{
+		"ascii": {
+			input:  "abc",
+			column: 3,
+		},
+		"combining mark": {
+			input:  "e\u0301e\u0301",
+			column: 2,
+		},
+		"zwj sequence": {
+			input:  "\U0001f468\u200d\U0001f469\u200d\U0001f467 ",
+			column: 2,
+		},
+		"skin tone": {
+			input:  "\U0001f44b\U0001f3fd",
+			column: 1,
+		},
+		"regional indicators": {
+			input:  "\U0001f1ef\U0001f1f5\U0001f1fa\U0001f1f8\U0001f1eb",
+			column: 3,
+		},
+		"variation selector": {
+			input:  "\u2764\ufe0f",
+			column: 1,
+		},
+		"new line": {
+			input:  "e\u0301\n\u0301a",
+			column: 2,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLexer(runeio.NewReader(strings.NewReader(tc.input+"|")), &wordState{}, WithGraphemeColumns())
+			if _, err := l.Find([]string{"|"}); err != nil {
+				t.Fatalf("Find: %v", err)
+			}
+			if got, want := l.Column(), tc.column; got != want {
+				t.Errorf("Column: want: %v, got: %v", want, got)
+			}
+			if got, want := l.Pos(), len([]rune(tc.input)); got != want {
+				t.Errorf("Pos: want: %v, got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestLexer_PeekRune(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(runeio.NewReader(strings.NewReader("Hé")), &wordState{})
+
+	for _, want := range []rune{'H', 'é'} {
+		rn, ok := l.PeekRune()
+		if !ok || rn != want {
+			t.Errorf("PeekRune: want: %q, true, got: %q, %v", want, rn, ok)
+		}
+		if _, err := l.Advance(1); err != nil {
+			t.Fatalf("Advance: %v", err)
+		}
+	}
+
+	if rn, ok := l.PeekRune(); ok {
+		t.Errorf("PeekRune: want: 0, false, got: %q, %v", rn, ok)
+	}
+	if got, want := l.Pos(), 2; got != want {
+		t.Errorf("Pos: want: %v, got: %v", want, got)
+	}
+}
+
 func TestLexer_Peek(t *testing.T) {
 	t.Parallel()
 
@@ -388,6 +686,199 @@ func TestLexer_Find(t *testing.T) {
 	})
 }
 
+func TestLexer_AdvanceWhile_buffer(t *testing.T) {
+	t.Parallel()
+
+	input := strings.Repeat("ab\n", 20) + "ab!"
+	l := NewLexer(runeio.NewReaderSize(strings.NewReader(input), 16), &wordState{})
+
+	n, err := l.AdvanceWhile(NewRuneClass("ab\n"))
+	if err != nil {
+		t.Fatalf("AdvanceWhile: %v", err)
+	}
+	if got, want := n, 62; got != want {
+		t.Errorf("AdvanceWhile: want: %v, got: %v", want, got)
+	}
+	if got, want := l.Pos(), 62; got != want {
+		t.Errorf("Pos: want: %v, got: %v", want, got)
+	}
+	if got, want := l.Line(), 20; got != want {
+		t.Errorf("Line: want: %v, got: %v", want, got)
+	}
+	if got, want := l.Column(), 2; got != want {
+		t.Errorf("Column: want: %v, got: %v", want, got)
+	}
+	if got, want := l.Lexeme(wordType).Value, input[:62]; got != want {
+		t.Errorf("lexeme.Value: want: %q, got: %q", want, got)
+	}
+
+	// The end of the input is reached.
+	n, err = l.AdvanceWhile(NewRuneClass("!"))
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("AdvanceWhile: want: %v, got: %v", io.EOF, err)
+	}
+	if got, want := n, 1; got != want {
+		t.Errorf("AdvanceWhile: want: %v, got: %v", want, got)
+	}
+}
+
+func TestLexer_Find_search(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input  string
+		tokens []string
+		fold   bool
+		token  string
+		prefix string
+	}{
+		"across buffer boundary": {
+			input:  strings.Repeat("a", 30) + "{{x",
+			tokens: []string{"{{"},
+			token:  "{{",
+			prefix: strings.Repeat("a", 30),
+		},
+		"multibyte": {
+			input:  "こんにちは世界",
+			tokens: []string{"世界"},
+			token:  "世界",
+			prefix: "こんにちは",
+		},
+		"earliest match": {
+			input:  "a}}b{{",
+			tokens: []string{"{{", "}}"},
+			token:  "}}",
+			prefix: "a",
+		},
+		"token order": {
+			input:  "xabc",
+			tokens: []string{"a", "ab"},
+			token:  "a",
+			prefix: "x",
+		},
+		"partial match": {
+			input:  "{ {{",
+			tokens: []string{"{{"},
+			token:  "{{",
+			prefix: "{ ",
+		},
+		"empty token": {
+			input:  "abc",
+			tokens: []string{""},
+			token:  "",
+			prefix: "",
+		},
+		"fold": {
+			input:  "hello WORLD",
+			tokens: []string{"world"},
+			fold:   true,
+			token:  "world",
+			prefix: "hello ",
+		},
+		"fold non-ascii": {
+			input:  "xΣy",
+			tokens: []string{"σy"},
+			fold:   true,
+			token:  "σy",
+			prefix: "x",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			newLexer := func() *Lexer {
+				return NewLexer(runeio.NewReaderSize(strings.NewReader(tc.input), 16), &wordState{})
+			}
+
+			l := newLexer()
+			find := l.Find
+			if tc.fold {
+				find = l.FindFold
+			}
+			token, err := find(tc.tokens)
+			if err != nil {
+				t.Fatalf("Find: %v", err)
+			}
+			if got, want := token, tc.token; got != want {
+				t.Errorf("Find: want: %q, got: %q", want, got)
+			}
+			if got, want := l.Lexeme(wordType).Value, tc.prefix; got != want {
+				t.Errorf("lexeme.Value: want: %q, got: %q", want, got)
+			}
+
+			l = newLexer()
+			skipTo := l.SkipTo
+			if tc.fold {
+				skipTo = l.SkipToFold
+			}
+			token, err = skipTo(tc.tokens)
+			if err != nil {
+				t.Fatalf("SkipTo: %v", err)
+			}
+			if got, want := token, tc.token; got != want {
+				t.Errorf("SkipTo: want: %q, got: %q", want, got)
+			}
+			if got, want := l.Pos(), len([]rune(tc.prefix)); got != want {
+				t.Errorf("Pos: want: %v, got: %v", want, got)
+			}
+			if got, want := l.Lexeme(wordType).Value, ""; got != want {
+				t.Errorf("lexeme.Value: want: %q, got: %q", want, got)
+			}
+		})
+	}
+}
+
+func TestLexer_AdvanceWhile(t *testing.T) {
+	t.Parallel()
+
+	ident := NewRuneClass("_", unicode.Letter, unicode.Digit)
+
+	l := NewLexer(runeio.NewReader(strings.NewReader("foo_1 bar")), &wordState{})
+
+	ok, err := l.Accept(NewRuneClass("", unicode.Digit))
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if ok {
+		t.Errorf("Accept: want: %v, got: %v", false, ok)
+	}
+
+	n, err := l.AdvanceWhile(ident)
+	if err != nil {
+		t.Fatalf("AdvanceWhile: %v", err)
+	}
+	if got, want := n, 5; got != want {
+		t.Errorf("AdvanceWhile: want: %v, got: %v", want, got)
+	}
+
+	ok, err = l.Accept(NewRuneClass(" "))
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if !ok {
+		t.Errorf("Accept: want: %v, got: %v", true, ok)
+	}
+
+	n, err = l.AdvanceWhile(ident)
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("AdvanceWhile: want: %v, got: %v", io.EOF, err)
+	}
+	if got, want := n, 3; got != want {
+		t.Errorf("AdvanceWhile: want: %v, got: %v", want, got)
+	}
+
+	lexeme := l.Lexeme(wordType)
+	if got, want := lexeme.Value, "foo_1 bar"; got != want {
+		t.Errorf("Lexeme: want: %q, got: %q", want, got)
+	}
+	if got, want := l.Column(), 9; got != want {
+		t.Errorf("Column: want: %v, got: %v", want, got)
+	}
+}
+
 func TestLexer_Ignore(t *testing.T) {
 	t.Parallel()
 
@@ -568,6 +1059,204 @@ func TestLexer_SkipTo(t *testing.T) {
 	})
 }
 
+func TestLexer_FindQuoted(t *testing.T) {
+	t.Parallel()
+
+	quotes := []Quote{
+		{Open: `"`, Close: `"`, Escape: '\\'},
+		{Open: "'", Close: "'", Escape: '\''},
+		{Open: "/*", Close: "*/"},
+	}
+
+	testCases := map[string]struct {
+		input  string
+		tokens []string
+		token  string
+		prefix string
+		err    error
+	}{
+		"no quotes": {
+			input:  "a | b %}",
+			tokens: []string{"%}"},
+			token:  "%}",
+			prefix: "a | b ",
+		},
+		"double quoted": {
+			input:  `"%}" | upper %}`,
+			tokens: []string{"%}"},
+			token:  "%}",
+			prefix: `"%}" | upper `,
+		},
+		"escaped quote": {
+			input:  `"a\"%}" %}`,
+			tokens: []string{"%}"},
+			token:  "%}",
+			prefix: `"a\"%}" `,
+		},
+		"escaped escape": {
+			input:  `"a\\" %}`,
+			tokens: []string{"%}"},
+			token:  "%}",
+			prefix: `"a\\" `,
+		},
+		"doubled quote": {
+			input:  "'it'';s'; x",
+			tokens: []string{";"},
+			token:  ";",
+			prefix: "'it'';s'",
+		},
+		"empty quote": {
+			input:  "'';",
+			tokens: []string{";"},
+			token:  ";",
+			prefix: "''",
+		},
+		"multi-rune delimiters": {
+			input:  "a /* ; */ b; c",
+			tokens: []string{";"},
+			token:  ";",
+			prefix: "a /* ; */ b",
+		},
+		"token before quote": {
+			input:  `x; "y"`,
+			tokens: []string{";"},
+			token:  ";",
+			prefix: "x",
+		},
+		"token matches quote": {
+			input:  `a"b`,
+			tokens: []string{`"`},
+			token:  `"`,
+			prefix: "a",
+		},
+		"across buffer boundary": {
+			input:  `"` + strings.Repeat("a;", 20) + `";`,
+			tokens: []string{";"},
+			token:  ";",
+			prefix: `"` + strings.Repeat("a;", 20) + `"`,
+		},
+		"not found": {
+			input:  `a "; "`,
+			tokens: []string{";"},
+			err:    io.EOF,
+		},
+		"unterminated quote": {
+			input:  `a "; `,
+			tokens: []string{";"},
+			err:    io.EOF,
+		},
+		"escape at end": {
+			input:  `"\`,
+			tokens: []string{";"},
+			err:    io.EOF,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			newLexer := func() *Lexer {
+				return NewLexer(runeio.NewReaderSize(strings.NewReader(tc.input), 16), &wordState{})
+			}
+
+			l := newLexer()
+			token, err := l.FindQuoted(tc.tokens, quotes)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("FindQuoted: want: %v, got: %v", tc.err, err)
+			}
+			if got, want := token, tc.token; got != want {
+				t.Errorf("FindQuoted: want: %q, got: %q", want, got)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := l.Lexeme(wordType).Value, tc.prefix; got != want {
+				t.Errorf("lexeme.Value: want: %q, got: %q", want, got)
+			}
+
+			l = newLexer()
+			token, err = l.SkipToQuoted(tc.tokens, quotes)
+			if err != nil {
+				t.Fatalf("SkipToQuoted: %v", err)
+			}
+			if got, want := token, tc.token; got != want {
+				t.Errorf("SkipToQuoted: want: %q, got: %q", want, got)
+			}
+			if got, want := l.Lexeme(wordType).Value, ""; got != want {
+				t.Errorf("lexeme.Value: want: %q, got: %q", want, got)
+			}
+			if got, want := l.Pos(), utf8.RuneCountInString(tc.prefix); got != want {
+				t.Errorf("Pos: want: %v, got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestLexer_fold(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		fn     func(l *Lexer, tokens []string) (string, error)
+		tokens []string
+		token  string
+		value  string
+		err    error
+	}{
+		"FindFold": {
+			fn:     (*Lexer).FindFold,
+			tokens: []string{"select"},
+			token:  "select",
+			value:  "a ",
+		},
+		"FindFold multiple": {
+			fn:     (*Lexer).FindFold,
+			tokens: []string{"from", "sElEcT"},
+			token:  "sElEcT",
+			value:  "a ",
+		},
+		"FindFold no match": {
+			fn:     (*Lexer).FindFold,
+			tokens: []string{"where"},
+			value:  "a SeLeCt * FROM t",
+			err:    io.EOF,
+		},
+		"SkipToFold": {
+			fn:     (*Lexer).SkipToFold,
+			tokens: []string{"FROM"},
+			token:  "FROM",
+			value:  "",
+		},
+		"SkipToFold no match": {
+			fn:     (*Lexer).SkipToFold,
+			tokens: []string{"where"},
+			value:  "",
+			err:    io.EOF,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLexer(runeio.NewReader(strings.NewReader("a SeLeCt * FROM t")), &wordState{})
+
+			token, err := tc.fn(l, tc.tokens)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error: want: %v, got: %v", tc.err, err)
+			}
+			if got, want := token, tc.token; got != want {
+				t.Errorf("token: want: %q, got: %q", want, got)
+			}
+			if got, want := l.Lexeme(wordType).Value, tc.value; got != want {
+				t.Errorf("lexeme.Value: want: %q, got: %q", want, got)
+			}
+		})
+	}
+}
+
 func TestLexer_lexemes(t *testing.T) {
 	t.Parallel()
 
@@ -604,3 +1293,434 @@ func TestLexer_lexemes(t *testing.T) {
 		t.Errorf("unexpected output (-want +got):\n%s", diff)
 	}
 }
+
+func TestLexer_limits(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		opts   []LexerOption
+		values []string
+		err    *LimitError
+	}{
+		"no limits": {
+			values: []string{"Hello", "Lexemes!", "Foo"},
+		},
+		"max tokens": {
+			opts:   []LexerOption{WithMaxTokens(2)},
+			values: []string{"Hello", "Lexemes!"},
+			err: &LimitError{
+				Limit:  "token",
+				Max:    2,
+				Column: 15,
+			},
+		},
+		"max input bytes": {
+			opts:   []LexerOption{WithMaxInputBytes(8)},
+			values: []string{"Hello"},
+			err: &LimitError{
+				Limit:  "input byte",
+				Max:    8,
+				Column: 8,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLexer(runeio.NewReader(strings.NewReader("Hello Lexemes! Foo")), &wordState{}, tc.opts...)
+
+			var values []string
+			for lexeme := range l.Lex(context.Background()) {
+				values = append(values, lexeme.Value)
+			}
+			if diff := cmp.Diff(tc.values, values); diff != "" {
+				t.Errorf("unexpected output (-want +got):\n%s", diff)
+			}
+
+			err := l.Err()
+			if tc.err == nil {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("Err: want: %v, got: %v", ErrLimitExceeded, err)
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("Err: want: *LimitError, got: %T", err)
+			}
+			if diff := cmp.Diff(tc.err, limitErr); diff != "" {
+				t.Errorf("Err: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+// lexNothing is a State that does nothing.
+func lexNothing(context.Context, *Lexer) (State, error) {
+	return nil, nil
+}
+
+// namedState is a State with a Name method.
+type namedState struct{}
+
+func (*namedState) Run(context.Context, *Lexer) (State, error) {
+	return nil, nil
+}
+
+func (*namedState) Name() string {
+	return "named"
+}
+
+func TestStateName(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		state State
+		want  string
+	}{
+		"named state": {
+			state: NamedState("lexSymbol", lexNothing),
+			want:  "lexSymbol",
+		},
+		"state fn": {
+			state: StateFn(lexNothing),
+			want:  "github.com/ianlewis/lexparse.lexNothing",
+		},
+		"name method": {
+			state: &namedState{},
+			want:  "named",
+		},
+		"type": {
+			state: &wordState{},
+			want:  "*lexparse.wordState",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := StateName(tc.state); got != tc.want {
+				t.Errorf("StateName: want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWithTrace(t *testing.T) {
+	t.Parallel()
+
+	var lexA State
+	lexA = NamedState("lexA", func(_ context.Context, l *Lexer) (State, error) {
+		if _, err := l.Advance(1); err != nil {
+			return nil, err
+		}
+		return lexA, nil
+	})
+
+	var b strings.Builder
+	l := NewLexer(runeio.NewReader(strings.NewReader("a\nb")), lexA, WithTrace(&b), WithFilename("a.txt"))
+	for range l.Lex(context.Background()) {
+	}
+	<-l.Done()
+	if err := l.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+
+	want := "a.txt: 1:1: lexA\na.txt: 1:2: lexA\na.txt: 2:1: lexA\na.txt: 2:2: lexA\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("trace: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestWithMaxStalls(t *testing.T) {
+	t.Parallel()
+
+	// lexSymbol advances over 'a' but never advances over other runes.
+	var lexSymbol State
+	lexSymbol = NamedState("lexSymbol", func(_ context.Context, l *Lexer) (State, error) {
+		rn, err := l.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if rn[0] == 'a' {
+			_, err = l.Advance(1)
+		}
+		return lexSymbol, err
+	})
+
+	l := NewLexer(runeio.NewReader(strings.NewReader("aab")), lexSymbol, WithMaxStalls(3))
+	for range l.Lex(context.Background()) {
+	}
+	<-l.Done()
+
+	err := l.Err()
+	if !errors.Is(err, ErrStuck) {
+		t.Fatalf("Err: want: %v, got: %v", ErrStuck, err)
+	}
+	var stuckErr *StuckError
+	if !errors.As(err, &stuckErr) {
+		t.Fatalf("Err: want: *StuckError, got: %T", err)
+	}
+	want := &StuckError{
+		State:  "lexSymbol",
+		Runs:   4,
+		Column: 2,
+	}
+	if diff := cmp.Diff(want, stuckErr); diff != "" {
+		t.Errorf("Err: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestLexer_Err_categories(t *testing.T) {
+	t.Parallel()
+
+	errBad := errors.New("bad rune")
+	errRead := errors.New("connection reset")
+
+	// lexA advances over 'a' and returns errBad at any other rune.
+	var lexA State
+	lexA = NamedState("lexA", func(_ context.Context, l *Lexer) (State, error) {
+		rn, err := l.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if rn[0] != 'a' {
+			return nil, errBad
+		}
+		_, err = l.Advance(1)
+		return lexA, err
+	})
+
+	testCases := map[string]struct {
+		r        io.Reader
+		category error
+		err      error
+		msg      string
+	}{
+		"lexical": {
+			r:        strings.NewReader("aab"),
+			category: ErrLexical,
+			err:      errBad,
+			msg:      "bad rune",
+		},
+		"io": {
+			r:        io.MultiReader(strings.NewReader("aa"), iotest.ErrReader(errRead)),
+			category: ErrIO,
+			err:      errRead,
+			msg:      "test.txt: connection reset: line 1, column 3",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLexer(runeio.NewReader(bufio.NewReader(tc.r)), lexA, WithFilename("test.txt"))
+			for range l.Lex(context.Background()) {
+			}
+			<-l.Done()
+
+			err := l.Err()
+			if !errors.Is(err, tc.category) {
+				t.Errorf("Err: want: %v, got: %v", tc.category, err)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Err: want: %v, got: %v", tc.err, err)
+			}
+			if got := err.Error(); got != tc.msg {
+				t.Errorf("Error: want: %q, got: %q", tc.msg, got)
+			}
+
+			var lexErr *LexicalError
+			var ioErr *IOError
+			switch {
+			case errors.As(err, &lexErr):
+				if got, want := lexErr.State, "lexA"; got != want {
+					t.Errorf("State: want: %q, got: %q", want, got)
+				}
+				if got, want := lexErr.Column, 2; got != want {
+					t.Errorf("Column: want: %v, got: %v", want, got)
+				}
+			case errors.As(err, &ioErr):
+				if got, want := ioErr.State, "lexA"; got != want {
+					t.Errorf("State: want: %q, got: %q", want, got)
+				}
+			default:
+				t.Errorf("Err: want: *LexicalError or *IOError, got: %T", err)
+			}
+		})
+	}
+}
+
+func TestLexer_panic(t *testing.T) {
+	t.Parallel()
+
+	errBoom := errors.New("boom")
+
+	// lexBoom advances over 'a' and panics at any other rune.
+	var lexBoom State
+	lexBoom = NamedState("lexBoom", func(_ context.Context, l *Lexer) (State, error) {
+		rn, err := l.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if rn[0] != 'a' {
+			panic(errBoom)
+		}
+		_, err = l.Advance(1)
+		return lexBoom, err
+	})
+
+	l := NewLexer(runeio.NewReader(strings.NewReader("aab")), lexBoom, WithFilename("test.txt"))
+	for range l.Lex(context.Background()) {
+	}
+	<-l.Done()
+
+	err := l.Err()
+	if !errors.Is(err, ErrPanic) {
+		t.Fatalf("Err: want: %v, got: %v", ErrPanic, err)
+	}
+	if !errors.Is(err, errBoom) {
+		t.Errorf("Err: want: %v, got: %v", errBoom, err)
+	}
+	if got, want := err.Error(), "test.txt: panic in state lexBoom: boom: line 1, column 3"; got != want {
+		t.Errorf("Error: want: %q, got: %q", want, got)
+	}
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Err: want: *PanicError, got: %T", err)
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Errorf("Stack: want: stack trace, got: empty")
+	}
+	if panicErr.Value != errBoom {
+		t.Errorf("Value: want: %v, got: %v", errBoom, panicErr.Value)
+	}
+	panicErr.Value, panicErr.Stack = nil, nil
+	want := &PanicError{
+		State:  "lexBoom",
+		Column: 2,
+		File:   "test.txt",
+	}
+	if diff := cmp.Diff(want, panicErr); diff != "" {
+		t.Errorf("Err: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestLexer_WithErrorLexemes(t *testing.T) {
+	t.Parallel()
+
+	const errType LexemeType = -1
+	errDigit := errors.New("unexpected digit")
+
+	// letters lexes words of letters and returns an error for digits.
+	var letters State
+	letters = StateFn(func(_ context.Context, l *Lexer) (State, error) {
+		rn, err := l.Peek(1)
+		if err != nil {
+			if lexeme := l.Lexeme(wordType); lexeme.Value != "" {
+				l.Emit(lexeme)
+			}
+			return nil, err
+		}
+		switch {
+		case unicode.IsDigit(rn[0]):
+			return nil, errDigit
+		case unicode.IsSpace(rn[0]):
+			if lexeme := l.Lexeme(wordType); lexeme.Value != "" {
+				l.Emit(lexeme)
+			}
+			_, err = l.Discard(1)
+		default:
+			_, err = l.Advance(1)
+		}
+		return letters, err
+	})
+
+	l := NewLexer(runeio.NewReader(strings.NewReader("ab 1 c2d ef")), letters, WithErrorLexemes(errType, nil))
+
+	var got []*Lexeme
+	for lexeme := range l.Lex(context.Background()) {
+		got = append(got, lexeme)
+	}
+	<-l.Done()
+
+	if err := l.Err(); err != nil {
+		t.Errorf("Err: %v", err)
+	}
+
+	want := []*Lexeme{
+		{Type: wordType, Value: "ab", Pos: 0, Column: 0},
+		{Type: errType, Value: "unexpected digit", Pos: 3, Column: 3},
+		{Type: errType, Value: "unexpected digit", Pos: 5, Column: 5},
+		{Type: wordType, Value: "d", Pos: 7, Column: 7},
+		{Type: wordType, Value: "ef", Pos: 9, Column: 9},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+
+	if got, want := len(l.Errs()), 2; got != want {
+		t.Errorf("Errs: want: %v, got: %v", want, got)
+	}
+}
+
+func TestLexer_Reset(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(runeio.NewReader(strings.NewReader("Hello Lexemes!")), &wordState{}, WithMaxTokens(1))
+
+	for _, input := range []string{"A B", "C D"} {
+		l.Reset(runeio.NewReader(strings.NewReader(input)))
+
+		var got []*Lexeme
+		for lexeme := range l.Lex(context.Background()) {
+			got = append(got, lexeme)
+		}
+
+		want := []*Lexeme{
+			{
+				Type:  wordType,
+				Value: input[:1],
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected output (-want +got):\n%s", diff)
+		}
+		if err := l.Err(); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("Err: want: %v, got: %v", ErrLimitExceeded, err)
+		}
+	}
+}
+
+func TestLexer_Reset_source(t *testing.T) {
+	t.Parallel()
+
+	src := NewSource()
+	l := NewLexer(runeio.NewReader(strings.NewReader("Hello Lexemes!")), &wordState{}, WithSource(src))
+
+	for _, input := range []string{"A B", "CD E"} {
+		l.Reset(runeio.NewReader(strings.NewReader(input)))
+
+		for lexeme := range l.Lex(context.Background()) {
+			if got := src.LexemeText(lexeme); got != lexeme.Value {
+				t.Errorf("LexemeText: want: %q, got: %q", lexeme.Value, got)
+			}
+		}
+		if err := l.Err(); err != nil {
+			t.Fatalf("Err: %v", err)
+		}
+		if got := src.Text(0, 100); got != input {
+			t.Errorf("Text: want: %q, got: %q", input, got)
+		}
+	}
+}
@@ -19,24 +19,128 @@ package lexparse
 import (
 	"context"
 	"errors"
+	"io"
 )
 
+// Options configures LexParseWithOptions. The zero value uses the defaults of
+// the Lexer and Parser. New fields may be added in the future so Options
+// should be created using field names.
+type Options struct {
+	// Filename is the name of the input file. See WithFilename.
+	Filename string
+
+	// BufferSize is the size in runes of the Lexer's buffer. See
+	// WithBufferSize.
+	BufferSize int
+
+	// Trace is a writer to which the Lexer writes a line each time a State
+	// is run. See WithTrace.
+	Trace io.Writer
+
+	// MaxTokens is the maximum number of lexemes emitted by the Lexer. See
+	// WithMaxTokens.
+	MaxTokens int
+
+	// MaxInputBytes is the maximum number of bytes read by the Lexer. See
+	// WithMaxInputBytes.
+	MaxInputBytes int
+
+	// MaxDepth is the maximum depth of the parse tree. See
+	// Parser.SetMaxDepth.
+	MaxDepth int
+
+	// MaxNodes is the maximum number of nodes in the parse tree. See
+	// Parser.SetMaxNodes.
+	MaxNodes int
+
+	// RequireEOF requires that parsing consumes all of the input. See
+	// Parser.SetRequireEOF.
+	RequireEOF bool
+
+	// NoRecover disables the recovery of panics in States and parse
+	// functions. See WithRecoverPanics and Parser.SetRecoverPanics.
+	NoRecover bool
+
+	// Coverage records the States and named parse states that are run. See
+	// WithCoverage and Parser.SetCoverage.
+	Coverage *Coverage
+
+	// LexerOptions are additional options for the Lexer. They are applied
+	// after the options set by the other fields.
+	LexerOptions []LexerOption
+}
+
+// lexerOptions returns the Lexer options for the options.
+func (o *Options) lexerOptions() []LexerOption {
+	var opts []LexerOption
+	if o.Filename != "" {
+		opts = append(opts, WithFilename(o.Filename))
+	}
+	if o.BufferSize > 0 {
+		opts = append(opts, WithBufferSize(o.BufferSize))
+	}
+	if o.Trace != nil {
+		opts = append(opts, WithTrace(o.Trace))
+	}
+	if o.MaxTokens > 0 {
+		opts = append(opts, WithMaxTokens(o.MaxTokens))
+	}
+	if o.MaxInputBytes > 0 {
+		opts = append(opts, WithMaxInputBytes(o.MaxInputBytes))
+	}
+	if o.NoRecover {
+		opts = append(opts, WithRecoverPanics(false))
+	}
+	if o.Coverage != nil {
+		opts = append(opts, WithCoverage(o.Coverage))
+	}
+	return append(opts, o.LexerOptions...)
+}
+
 // LexParse lexes the content starting at initState and passes the results to a
 // parser starting at initFn. The resulting root node of the parse tree is returned.
+// The Lexer is configured with the given options. If parsing fails, the
+// context used by the lexer is cancelled with the parsing error as its cause.
 func LexParse[V comparable](
 	ctx context.Context,
 	r BufferedRuneReader,
 	initState State,
 	initFn ParseFn[V],
+	opts ...LexerOption,
+) (*Node[V], error) {
+	return LexParseWithOptions(ctx, r, initState, initFn, &Options{LexerOptions: opts})
+}
+
+// LexParseWithOptions is like LexParse but the Lexer and Parser are configured
+// by opts. If opts is nil the defaults are used.
+func LexParseWithOptions[V comparable](
+	ctx context.Context,
+	r BufferedRuneReader,
+	initState State,
+	initFn ParseFn[V],
+	opts *Options,
 ) (*Node[V], error) {
-	l := NewLexer(r, initState)
+	if opts == nil {
+		opts = &Options{}
+	}
+	l := NewLexer(r, initState, opts.lexerOptions()...)
 
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	p := NewParser[V](l.Lex(ctx))
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	lexemes := l.Lex(ctx)
+	p := NewParser[V](lexemes)
+	p.SetLexerErr(l.Err)
+	p.SetMaxDepth(opts.MaxDepth)
+	p.SetMaxNodes(opts.MaxNodes)
+	p.SetRequireEOF(opts.RequireEOF)
+	p.SetRecoverPanics(!opts.NoRecover)
+	p.SetCoverage(opts.Coverage)
 	n, pErr := p.Parse(ctx, initFn)
-	cancel()
+	cancel(pErr)
 
+	// Discard any lexemes emitted before the Lexer stopped so that it isn't
+	// left blocked if parsing stopped early.
+	Drain(lexemes)
 	<-l.Done()
 
 	// Check for lexing error.
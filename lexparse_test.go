@@ -113,3 +113,99 @@ func TestLexParse(t *testing.T) {
 		}
 	})
 }
+
+func TestLexParse_cancelCause(t *testing.T) {
+	t.Parallel()
+
+	r := runeio.NewReader(strings.NewReader("Hello World!"))
+
+	// The lexer is blocked emitting lexemes when the parser fails.
+	var lexerErr error
+	state := StateFn(func(ctx context.Context, l *Lexer) (State, error) {
+		for {
+			select {
+			case <-ctx.Done():
+				lexerErr = context.Cause(ctx)
+				return nil, lexerErr
+			default:
+			}
+			l.Emit(&Lexeme{Value: "x"})
+		}
+	})
+
+	_, err := LexParse(context.Background(), r, state, errParseFn)
+	if !errors.Is(err, errParse) {
+		t.Errorf("LexParse: want: %v, got: %v", errParse, err)
+	}
+	if !errors.Is(lexerErr, errParse) {
+		t.Errorf("context.Cause: want: %v, got: %v", errParse, lexerErr)
+	}
+}
+
+func TestLexParseWithOptions(t *testing.T) {
+	t.Parallel()
+
+	// parseOne parses a single word.
+	parseOne := func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		p.Node(p.Next().Value)
+		return nil, nil
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+
+		r := runeio.NewReader(strings.NewReader("Hello World!"))
+		got, err := LexParseWithOptions(context.Background(), r, &wordState{}, parseWord, nil)
+		if err != nil {
+			t.Fatalf("LexParseWithOptions: %v", err)
+		}
+		if got, want := len(got.Children), 2; got != want {
+			t.Errorf("Children: want: %v, got: %v", want, got)
+		}
+	})
+
+	t.Run("lexer", func(t *testing.T) {
+		t.Parallel()
+
+		var trace strings.Builder
+		r := runeio.NewReader(strings.NewReader("Hello World!"))
+		_, err := LexParseWithOptions(context.Background(), r, &wordState{}, parseWord, &Options{
+			Filename:   "test.txt",
+			BufferSize: 2,
+			Trace:      &trace,
+			MaxTokens:  1,
+		})
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("LexParseWithOptions: want: *LimitError, got: %v", err)
+		}
+		if !strings.HasPrefix(trace.String(), "test.txt: 1:1: ") {
+			t.Errorf("Trace: want: %q prefix, got: %q", "test.txt: 1:1: ", trace.String())
+		}
+	})
+
+	t.Run("max nodes", func(t *testing.T) {
+		t.Parallel()
+
+		r := runeio.NewReader(strings.NewReader("Hello World!"))
+		_, err := LexParseWithOptions(context.Background(), r, &wordState{}, parseWord, &Options{
+			MaxNodes: 1,
+		})
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("LexParseWithOptions: want: *LimitError, got: %v", err)
+		}
+	})
+
+	t.Run("require eof", func(t *testing.T) {
+		t.Parallel()
+
+		r := runeio.NewReader(strings.NewReader("Hello World!"))
+		_, err := LexParseWithOptions(context.Background(), r, &wordState{}, parseOne, &Options{
+			RequireEOF: true,
+		})
+		if !errors.Is(err, ErrTrailingInput) {
+			t.Errorf("LexParseWithOptions: want: %v, got: %v", ErrTrailingInput, err)
+		}
+	})
+}
@@ -0,0 +1,371 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lexparsetest provides a table-driven test harness for grammars
+// built with lexparse.
+//
+// A GrammarSuite lists inputs along with the lexemes, parse tree, or error
+// expected for each input. Run runs each case as a parallel subtest and
+// reports differences as line diffs.
+//
+//	func TestGrammar(t *testing.T) {
+//		t.Parallel()
+//
+//		s := &lexparsetest.GrammarSuite[string]{
+//			State:   lexparse.StateFn(lexWord),
+//			ParseFn: parseWords,
+//			Cases: []lexparsetest.Case{
+//				{
+//					Input:  "a b",
+//					Tokens: []lexparsetest.Token{{Type: wordType, Value: "a"}, {Type: wordType, Value: "b"}},
+//				},
+//				{Input: "a ;", ErrContains: "unexpected", ErrLine: 1, ErrColumn: 3},
+//				{Input: "if a { b }", Golden: "testdata/if.golden"},
+//			},
+//		}
+//		s.Run(t)
+//	}
+package lexparsetest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+// Token is an expected lexeme. Only the type and value are compared.
+type Token struct {
+	Type  lexparse.LexemeType
+	Value string
+}
+
+// Case is a test case for a grammar. Fields that are not set are not
+// checked. If none of Err, ErrContains, ErrLine, and ErrColumn are set, the
+// input is expected to be accepted without error.
+type Case struct {
+	// Name is the name of the subtest. If empty, the input is used.
+	Name string
+
+	// Input is the input to lex and parse.
+	Input string
+
+	// Tokens are the expected lexemes emitted by the lexer.
+	Tokens []Token
+
+	// Tree is the expected parse tree formatted by Node.Format using the
+	// suite's FormatOptions. Leading and trailing newlines are ignored.
+	Tree string
+
+	// Golden is the path of a file containing the expected parse tree. If
+	// the suite's Update field is true, the file is written with the actual
+	// tree instead.
+	Golden string
+
+	// Err is an error that the error returned by LexParse is expected to
+	// wrap.
+	Err error
+
+	// ErrContains is a substring that the error message is expected to
+	// contain.
+	ErrContains string
+
+	// ErrLine and ErrColumn are the expected one-indexed line and column of
+	// the error as reported by lexparse.NewDiagnostic. Zero values are not
+	// checked.
+	ErrLine   int
+	ErrColumn int
+}
+
+// wantErr returns true if the case expects an error.
+func (c *Case) wantErr() bool {
+	return c.Err != nil || c.ErrContains != "" || c.ErrLine != 0 || c.ErrColumn != 0
+}
+
+// GrammarSuite is a set of test cases for a grammar made up of a starting
+// lexer State and parse function.
+type GrammarSuite[V comparable] struct {
+	// State is the starting State of the lexer.
+	State lexparse.State
+
+	// ParseFn is the starting parse function. If nil, only the lexer is
+	// run and errors are those returned by the lexer.
+	ParseFn lexparse.ParseFn[V]
+
+	// LexerOptions are options for the lexer.
+	LexerOptions []lexparse.LexerOption
+
+	// RequireEOF requires that parsing consumes all of the input. See
+	// lexparse.Parser.SetRequireEOF.
+	RequireEOF bool
+
+	// FormatOptions are the options used to format trees. If nil, trees are
+	// formatted with the values formatted using the %v verb and positions.
+	FormatOptions *lexparse.FormatOptions[V]
+
+	// TypeName returns the name of a lexeme type used in diffs of tokens. If
+	// nil, the number of the type is used.
+	TypeName func(typ lexparse.LexemeType) string
+
+	// Coverage, if not nil, records the States and named parse states run
+	// by the cases. Cases run in parallel subtests so the coverage should be
+	// checked in a cleanup function of the test calling Run. Lexing done
+	// only to check Tokens is not recorded.
+	Coverage *lexparse.Coverage
+
+	// Update writes the actual trees to golden files rather than comparing
+	// them. It is typically set from a command line flag.
+	Update bool
+
+	// Cases are the test cases.
+	Cases []Case
+}
+
+// Run runs each of the suite's cases as a parallel subtest of t.
+func (s *GrammarSuite[V]) Run(t *testing.T) {
+	t.Helper()
+
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		name := c.Name
+		if name == "" {
+			name = c.Input
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s.run(t, c)
+		})
+	}
+}
+
+// run runs the test case c.
+func (s *GrammarSuite[V]) run(t *testing.T, c *Case) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if c.Tokens != nil || s.ParseFn == nil {
+		lexemes, err := s.lex(ctx, c.Input, s.ParseFn == nil)
+		if c.Tokens != nil {
+			if diff := Diff(s.tokenLines(c.Tokens), s.lexemeLines(lexemes)); diff != "" {
+				t.Errorf("lexemes: (-want, +got): \n%s", diff)
+			}
+		}
+		if s.ParseFn == nil {
+			checkErr(t, c, err)
+			return
+		}
+	}
+
+	root, err := lexparse.LexParseWithOptions(
+		ctx,
+		runeio.NewReader(bufio.NewReader(strings.NewReader(c.Input))),
+		s.State,
+		s.ParseFn,
+		&lexparse.Options{
+			RequireEOF:   s.RequireEOF,
+			Coverage:     s.Coverage,
+			LexerOptions: s.LexerOptions,
+		},
+	)
+	checkErr(t, c, err)
+	if err != nil || (c.Tree == "" && c.Golden == "") {
+		return
+	}
+
+	var b strings.Builder
+	if fErr := root.Format(&b, s.FormatOptions); fErr != nil {
+		t.Fatalf("formatting tree: %v", fErr)
+	}
+	got := b.String()
+
+	if c.Tree != "" {
+		if diff := Diff(strings.Trim(c.Tree, "\n"), strings.Trim(got, "\n")); diff != "" {
+			t.Errorf("tree: (-want, +got): \n%s", diff)
+		}
+	}
+	if c.Golden != "" {
+		checkGolden(t, c.Golden, got, s.Update)
+	}
+}
+
+// lex lexes input and returns the lexemes and the lexer's error. If coverage
+// is true the States run are recorded in the suite's Coverage.
+func (s *GrammarSuite[V]) lex(ctx context.Context, input string, coverage bool) ([]*lexparse.Lexeme, error) {
+	opts := s.LexerOptions
+	if coverage && s.Coverage != nil {
+		opts = append([]lexparse.LexerOption{lexparse.WithCoverage(s.Coverage)}, opts...)
+	}
+	l := lexparse.NewLexer(runeio.NewReader(bufio.NewReader(strings.NewReader(input))), s.State, opts...)
+	var lexemes []*lexparse.Lexeme
+	for lexeme := range l.Lex(ctx) {
+		lexemes = append(lexemes, lexeme)
+	}
+	<-l.Done()
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return lexemes, l.Err()
+}
+
+// typeName returns the name of typ.
+func (s *GrammarSuite[V]) typeName(typ lexparse.LexemeType) string {
+	if s.TypeName != nil {
+		return s.TypeName(typ)
+	}
+	return fmt.Sprint(int(typ))
+}
+
+// tokenLines returns the tokens with one token per line.
+func (s *GrammarSuite[V]) tokenLines(tokens []Token) string {
+	var b strings.Builder
+	for _, tok := range tokens {
+		fmt.Fprintf(&b, "%s %q\n", s.typeName(tok.Type), tok.Value)
+	}
+	return b.String()
+}
+
+// lexemeLines returns the type and value of the lexemes with one lexeme per
+// line.
+func (s *GrammarSuite[V]) lexemeLines(lexemes []*lexparse.Lexeme) string {
+	var b strings.Builder
+	for _, l := range lexemes {
+		fmt.Fprintf(&b, "%s %q\n", s.typeName(l.Type), l.Value)
+	}
+	return b.String()
+}
+
+// checkErr checks err against the expected error of c.
+func checkErr(t *testing.T, c *Case, err error) {
+	t.Helper()
+
+	if !c.wantErr() {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Errorf("error: want: error, got: <nil>")
+		return
+	}
+
+	if c.Err != nil && !errors.Is(err, c.Err) {
+		t.Errorf("error: want: %v, got: %v", c.Err, err)
+	}
+	if c.ErrContains != "" && !strings.Contains(err.Error(), c.ErrContains) {
+		t.Errorf("error: want: containing %q, got: %v", c.ErrContains, err)
+	}
+	if c.ErrLine != 0 || c.ErrColumn != 0 {
+		start := lexparse.NewDiagnostic(err).Range.Start
+		line, column := start.Line+1, start.Column+1
+		if (c.ErrLine != 0 && line != c.ErrLine) || (c.ErrColumn != 0 && column != c.ErrColumn) {
+			t.Errorf("error position: want: %d:%d, got: %d:%d (%v)", c.ErrLine, c.ErrColumn, line, column, err)
+		}
+	}
+}
+
+// checkGolden compares got to the contents of the golden file at path or
+// writes got to the file if update is true.
+func checkGolden(t *testing.T, path, got string, update bool) {
+	t.Helper()
+
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("updating golden file: %v", err)
+		}
+		//nolint:gosec // Golden files are not sensitive.
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("updating golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	if diff := Diff(string(want), got); diff != "" {
+		t.Errorf("tree: %s: (-want, +got): \n%s", path, diff)
+	}
+}
+
+// Diff returns a line diff of want and got or an empty string if they are
+// equal. Removed lines are prefixed with "-", added lines with "+", and
+// unchanged lines with a space.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a := splitLines(want)
+	b := splitLines(got)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var d strings.Builder
+	changed := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			d.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j >= len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			d.WriteString("- " + a[i] + "\n")
+			changed = true
+			i++
+		default:
+			d.WriteString("+ " + b[j] + "\n")
+			changed = true
+			j++
+		}
+	}
+	if !changed {
+		// The inputs differ only in a trailing newline.
+		return fmt.Sprintf("- %q\n+ %q\n", want, got)
+	}
+	return d.String()
+}
+
+// splitLines splits s into lines without the trailing newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
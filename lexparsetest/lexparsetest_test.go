@@ -0,0 +1,273 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparsetest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ianlewis/lexparse"
+)
+
+const (
+	wordType lexparse.LexemeType = iota
+	semiType
+)
+
+var (
+	spaceClass = lexparse.NewRuneClass(" \t\n")
+	wordClass  = lexparse.RuneClassFunc(func(rn rune) bool {
+		return !spaceClass.Contains(rn) && rn != ';'
+	})
+)
+
+// lexWord lexes words separated by whitespace and semicolons.
+func lexWord(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	if _, err := l.AdvanceWhile(spaceClass); err != nil {
+		return nil, ignoreEOF(err)
+	}
+	l.Ignore()
+
+	rn, ok := l.PeekRune()
+	if !ok {
+		return nil, nil
+	}
+	if rn == ';' {
+		if _, err := l.Advance(1); err != nil {
+			return nil, ignoreEOF(err)
+		}
+		l.Emit(l.Lexeme(semiType))
+		return lexparse.StateFn(lexWord), nil
+	}
+	if _, err := l.AdvanceWhile(wordClass); err != nil && !errors.Is(err, io.EOF) {
+		//nolint:wrapcheck // Error doesn't need to be wrapped.
+		return nil, err
+	}
+	l.Emit(l.Lexeme(wordType))
+	return lexparse.StateFn(lexWord), nil
+}
+
+func ignoreEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// parseWords adds each word as a child of the root. Semicolons are not
+// allowed.
+func parseWords(_ context.Context, p *lexparse.Parser[string]) (lexparse.ParseFn[string], error) {
+	for {
+		l := p.Next()
+		if l == nil {
+			return nil, nil
+		}
+		if l.Type == semiType {
+			return nil, &lexparse.LexemeError{Err: lexparse.ErrUnexpectedLexeme, Lexeme: l}
+		}
+		p.Node(l.Value)
+	}
+}
+
+func TestGrammarSuite(t *testing.T) {
+	t.Parallel()
+
+	s := &GrammarSuite[string]{
+		State:   lexparse.StateFn(lexWord),
+		ParseFn: parseWords,
+		FormatOptions: &lexparse.FormatOptions[string]{
+			Value: func(v string) string {
+				if v == "" {
+					return "root"
+				}
+				return v
+			},
+		},
+		TypeName: func(typ lexparse.LexemeType) string {
+			if typ == semiType {
+				return "semi"
+			}
+			return "word"
+		},
+		Cases: []Case{
+			{
+				Name:  "empty",
+				Input: "",
+				Tree: `
+root (1:1)
+`,
+			},
+			{
+				Input: "a ;",
+				Tokens: []Token{
+					{Type: wordType, Value: "a"},
+					{Type: semiType, Value: ";"},
+				},
+				Err:         lexparse.ErrUnexpectedLexeme,
+				ErrContains: `";"`,
+				ErrLine:     1,
+				ErrColumn:   3,
+			},
+			{
+				Input: "foo bar",
+				Tokens: []Token{
+					{Type: wordType, Value: "foo"},
+					{Type: wordType, Value: "bar"},
+				},
+				Tree: `
+root (1:1)
+├── foo (1:1)
+└── bar (1:5)
+`,
+			},
+			{
+				Name:   "golden",
+				Input:  "foo\n  bar baz",
+				Golden: "testdata/words.golden",
+			},
+			{
+				Name:      "error line",
+				Input:     "foo\nbar;",
+				ErrLine:   2,
+				ErrColumn: 4,
+			},
+		},
+	}
+	s.Run(t)
+}
+
+func TestGrammarSuite_lexerOnly(t *testing.T) {
+	t.Parallel()
+
+	s := &GrammarSuite[string]{
+		State: lexparse.StateFn(lexWord),
+		Cases: []Case{
+			{
+				Input: "a;b",
+				Tokens: []Token{
+					{Type: wordType, Value: "a"},
+					{Type: semiType, Value: ";"},
+					{Type: wordType, Value: "b"},
+				},
+			},
+		},
+	}
+	s.Run(t)
+}
+
+func TestGrammarSuite_update(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "testdata", "update.golden")
+	s := &GrammarSuite[string]{
+		State:         lexparse.StateFn(lexWord),
+		ParseFn:       parseWords,
+		FormatOptions: &lexparse.FormatOptions[string]{Compact: true, NoPos: true},
+		Update:        true,
+		Cases: []Case{
+			{Input: "a b", Golden: path},
+		},
+	}
+	t.Run("update", s.Run)
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if want := "(a b)"; string(got) != want {
+		t.Errorf("golden file: want: %q, got: %q", want, string(got))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		want string
+		got  string
+		diff string
+	}{
+		"equal": {
+			want: "a\nb\n",
+			got:  "a\nb\n",
+			diff: "",
+		},
+		"changed": {
+			want: "a\nb\nc\n",
+			got:  "a\nx\nc\n",
+			diff: "  a\n- b\n+ x\n  c\n",
+		},
+		"added": {
+			want: "a\n",
+			got:  "a\nb\n",
+			diff: "  a\n+ b\n",
+		},
+		"removed": {
+			want: "a\nb\n",
+			got:  "b\n",
+			diff: "- a\n  b\n",
+		},
+		"trailing newline": {
+			want: "a\n",
+			got:  "a",
+			diff: "- \"a\\n\"\n+ \"a\"\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tc.diff, Diff(tc.want, tc.got)); diff != "" {
+				t.Errorf("Diff: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGrammarSuite_coverage(t *testing.T) {
+	t.Parallel()
+
+	c := lexparse.NewCoverage()
+	c.AddStates(lexparse.NamedState("lexWord", lexWord))
+	c.AddParseStates("words", "unused")
+
+	s := &GrammarSuite[string]{
+		State:    lexparse.NamedState("lexWord", lexWord),
+		ParseFn:  lexparse.NamedParseState("words", parseWords),
+		Coverage: c,
+		Cases: []Case{
+			{Input: "a b"},
+			{Input: "c"},
+		},
+	}
+	t.Cleanup(func() {
+		missedLex, missedParse := c.Missed()
+		if len(missedLex) != 0 {
+			t.Errorf("Missed: want: no lex states, got: %v", missedLex)
+		}
+		if diff := cmp.Diff([]string{"unused"}, missedParse); diff != "" {
+			t.Errorf("Missed: (-want, +got): \n%s", diff)
+		}
+	})
+	s.Run(t)
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// ErrPosition indicates that an offset or line and column is not within the
+// input.
+var ErrPosition = errors.New("invalid position")
+
+// Position is a location in the input.
+type Position struct {
+	// Offset is the byte offset in the input. Note that the Pos of Lexemes
+	// and Nodes is counted in runes.
+	Offset int
+
+	// Line is the line number (zero indexed).
+	Line int
+
+	// Column is the column in the line in runes (zero indexed).
+	Column int
+}
+
+// LineIndex converts between offsets and line and column positions in the
+// input. Offsets are byte offsets, unlike the Pos of Lexemes and Nodes which
+// is counted in runes. Use PositionForPos and PosFor to convert rune offsets.
+// Lines and columns are zero indexed and columns are counted in runes, as
+// they are for Lexemes.
+type LineIndex struct {
+	src []byte
+
+	// lines holds the byte offset of the start of each line.
+	lines []int
+
+	// runeLines holds the rune offset of the start of each line.
+	runeLines []int
+
+	// runes is the number of runes in src.
+	runes int
+}
+
+// NewLineIndex returns a LineIndex for the given input.
+func NewLineIndex(src []byte) *LineIndex {
+	idx := &LineIndex{
+		src:       src,
+		lines:     []int{0},
+		runeLines: []int{0},
+	}
+	for i, rn := range string(src) {
+		idx.runes++
+		if rn == '\n' {
+			idx.lines = append(idx.lines, i+1)
+			idx.runeLines = append(idx.runeLines, idx.runes)
+		}
+	}
+	return idx
+}
+
+// LineIndex returns a LineIndex for the input read so far.
+func (s *Source) LineIndex() *LineIndex {
+	s.mu.Lock()
+	src := []byte(string(s.runes))
+	s.mu.Unlock()
+	return NewLineIndex(src)
+}
+
+// PositionFor returns the line and column for the byte offset.
+func (idx *LineIndex) PositionFor(offset int) (Position, error) {
+	if offset < 0 || offset > len(idx.src) {
+		return Position{}, fmt.Errorf("%w: offset %d", ErrPosition, offset)
+	}
+
+	// Find the last line starting at or before offset.
+	line := sort.Search(len(idx.lines), func(i int) bool {
+		return idx.lines[i] > offset
+	}) - 1
+
+	return Position{
+		Offset: offset,
+		Line:   line,
+		Column: utf8.RuneCount(idx.src[idx.lines[line]:offset]),
+	}, nil
+}
+
+// OffsetFor returns the byte offset for the line and column. The column may
+// refer to the position just past the end of the line.
+func (idx *LineIndex) OffsetFor(line, column int) (int, error) {
+	if line < 0 || line >= len(idx.lines) || column < 0 {
+		return 0, fmt.Errorf("%w: line %d, column %d", ErrPosition, line+1, column+1)
+	}
+
+	offset := idx.lines[line]
+	for i := 0; i < column; i++ {
+		if offset >= len(idx.src) || idx.src[offset] == '\n' {
+			return 0, fmt.Errorf("%w: line %d, column %d", ErrPosition, line+1, column+1)
+		}
+		_, size := utf8.DecodeRune(idx.src[offset:])
+		offset += size
+	}
+	return offset, nil
+}
+
+// PositionForPos returns the position for the rune offset pos, such as the
+// Pos of a Lexeme or Node. The Offset of the returned Position is the byte
+// offset.
+func (idx *LineIndex) PositionForPos(pos int) (Position, error) {
+	if pos < 0 || pos > idx.runes {
+		return Position{}, fmt.Errorf("%w: pos %d", ErrPosition, pos)
+	}
+
+	// Find the last line starting at or before pos.
+	line := sort.Search(len(idx.runeLines), func(i int) bool {
+		return idx.runeLines[i] > pos
+	}) - 1
+
+	column := pos - idx.runeLines[line]
+	offset, err := idx.OffsetFor(line, column)
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{
+		Offset: offset,
+		Line:   line,
+		Column: column,
+	}, nil
+}
+
+// PosFor returns the rune offset, as used by the Pos of Lexemes and Nodes,
+// for the line and column.
+func (idx *LineIndex) PosFor(line, column int) (int, error) {
+	if _, err := idx.OffsetFor(line, column); err != nil {
+		return 0, err
+	}
+	return idx.runeLines[line] + column, nil
+}
+
+// utf16Column returns the column in UTF-16 code units for the column in runes
+// on the line. The column is returned as-is if the position is invalid.
+func (idx *LineIndex) utf16Column(line, column int) int {
+	offset, err := idx.OffsetFor(line, column)
+	if err != nil {
+		return column
+	}
+
+	var n int
+	for b := idx.src[idx.lines[line]:offset]; len(b) > 0; {
+		rn, size := utf8.DecodeRune(b)
+		n++
+		if rn >= 0x10000 {
+			// Runes outside the Basic Multilingual Plane are encoded as a
+			// surrogate pair.
+			n++
+		}
+		b = b[size:]
+	}
+	return n
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLineIndex(t *testing.T) {
+	t.Parallel()
+
+	// "é" is two bytes.
+	idx := NewLineIndex([]byte("ab\ncé d\n\nx"))
+
+	testCases := map[string]struct {
+		pos Position
+		err error
+	}{
+		"start": {
+			pos: Position{Offset: 0, Line: 0, Column: 0},
+		},
+		"end of line": {
+			pos: Position{Offset: 2, Line: 0, Column: 2},
+		},
+		"second line": {
+			pos: Position{Offset: 3, Line: 1, Column: 0},
+		},
+		"after multi-byte rune": {
+			pos: Position{Offset: 6, Line: 1, Column: 2},
+		},
+		"empty line": {
+			pos: Position{Offset: 9, Line: 2, Column: 0},
+		},
+		"end of input": {
+			pos: Position{Offset: 11, Line: 3, Column: 1},
+		},
+		"negative offset": {
+			pos: Position{Offset: -1, Line: -1},
+			err: ErrPosition,
+		},
+		"past end": {
+			pos: Position{Offset: 12, Line: 3, Column: 2},
+			err: ErrPosition,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := idx.PositionFor(tc.pos.Offset)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("PositionFor: want: %v, got: %v", tc.err, err)
+			}
+			if tc.err == nil {
+				if diff := cmp.Diff(tc.pos, got); diff != "" {
+					t.Errorf("PositionFor: (-want, +got): \n%s", diff)
+				}
+			}
+
+			offset, err := idx.OffsetFor(tc.pos.Line, tc.pos.Column)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("OffsetFor: want: %v, got: %v", tc.err, err)
+			}
+			if tc.err == nil && offset != tc.pos.Offset {
+				t.Errorf("OffsetFor: want: %v, got: %v", tc.pos.Offset, offset)
+			}
+		})
+	}
+}
+
+func TestLineIndex_pos(t *testing.T) {
+	t.Parallel()
+
+	// "é" is two bytes but one rune.
+	idx := NewLineIndex([]byte("ab\ncé d\n\nx"))
+
+	testCases := map[string]struct {
+		pos  int
+		want Position
+		err  error
+	}{
+		"start": {
+			pos:  0,
+			want: Position{Offset: 0, Line: 0, Column: 0},
+		},
+		"second line": {
+			pos:  3,
+			want: Position{Offset: 3, Line: 1, Column: 0},
+		},
+		"after multi-byte rune": {
+			pos:  5,
+			want: Position{Offset: 6, Line: 1, Column: 2},
+		},
+		"empty line": {
+			pos:  8,
+			want: Position{Offset: 9, Line: 2, Column: 0},
+		},
+		"end of input": {
+			pos:  10,
+			want: Position{Offset: 11, Line: 3, Column: 1},
+		},
+		"negative": {
+			pos: -1,
+			err: ErrPosition,
+		},
+		"past end": {
+			pos: 11,
+			err: ErrPosition,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := idx.PositionForPos(tc.pos)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("PositionForPos: want: %v, got: %v", tc.err, err)
+			}
+			if tc.err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("PositionForPos: (-want, +got): \n%s", diff)
+			}
+
+			pos, err := idx.PosFor(tc.want.Line, tc.want.Column)
+			if err != nil {
+				t.Fatalf("PosFor: %v", err)
+			}
+			if pos != tc.pos {
+				t.Errorf("PosFor: want: %v, got: %v", tc.pos, pos)
+			}
+		})
+	}
+
+	if _, err := idx.PosFor(0, 3); !errors.Is(err, ErrPosition) {
+		t.Errorf("PosFor: want: %v, got: %v", ErrPosition, err)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+)
+
+// Message is the message of an error generated by lexparse identified by an
+// ID and its arguments rather than as a formatted string so that it can be
+// translated for end users.
+//
+// The ID matches the code of the Diagnostic returned by NewDiagnostic, e.g.
+// "unexpected-lexeme" or "limit-exceeded". Args holds the arguments that are
+// known for the error keyed by name:
+//
+//   - "file", "line", and "column": The position of the error. Lines and
+//     columns start at 1.
+//   - "lexeme" and "detail": The value of the lexeme and the description of
+//     a *LexemeError.
+//   - "limit" and "max": The name and value of the limit of a *LimitError.
+//   - "state": The name of the State or parse function of a *StuckError,
+//     *PanicError, *LexicalError, or *IOError.
+//   - "runs": The number of runs of a *StuckError.
+//   - "value": The value passed to panic for a *PanicError.
+//   - "err": The message of the underlying error, e.g. the error returned by
+//     a State or the reader.
+//
+// "error" is always set to the untranslated message returned by the error's
+// Error method.
+type Message struct {
+	// ID identifies the message. It is empty if the error was not generated
+	// by lexparse.
+	ID string
+
+	// Args are the arguments of the message.
+	Args map[string]any
+}
+
+// Catalog returns the translation of msg. It returns false if the message
+// has no translation.
+type Catalog func(msg Message) (string, bool)
+
+// ErrorMessage returns the Message for err.
+func ErrorMessage(err error) Message {
+	msg := Message{
+		ID: errorCode(err),
+		Args: map[string]any{
+			"error": err.Error(),
+		},
+	}
+
+	var (
+		panicErr   *PanicError
+		stuckErr   *StuckError
+		limitErr   *LimitError
+		ioErr      *IOError
+		scanErr    *ScanError
+		lexemeErr  *LexemeError
+		lexicalErr *LexicalError
+	)
+	switch {
+	case errors.As(err, &panicErr):
+		msg.setPos(panicErr.File, panicErr.Line, panicErr.Column)
+		msg.Args["state"] = panicErr.State
+		msg.Args["value"] = panicErr.Value
+	case errors.As(err, &stuckErr):
+		msg.setPos(stuckErr.File, stuckErr.Line, stuckErr.Column)
+		msg.Args["state"] = stuckErr.State
+		msg.Args["runs"] = stuckErr.Runs
+	case errors.As(err, &limitErr):
+		msg.setPos(limitErr.File, limitErr.Line, limitErr.Column)
+		msg.Args["limit"] = limitErr.Limit
+		msg.Args["max"] = limitErr.Max
+	case errors.As(err, &ioErr):
+		msg.setPos(ioErr.File, ioErr.Line, ioErr.Column)
+		msg.Args["state"] = ioErr.State
+		msg.setErr(ioErr.Err)
+	case errors.As(err, &scanErr):
+		msg.setPos("", scanErr.Line, scanErr.Column)
+		msg.Args["err"] = scanErr.Msg
+	case errors.As(err, &lexemeErr):
+		l := lexemeErr.Lexeme
+		msg.setPos(l.File, l.Line, l.Column)
+		msg.Args["lexeme"] = l.Value
+		msg.Args["detail"] = lexemeErr.Detail
+		msg.setErr(lexemeErr.Err)
+		if errors.As(err, &lexicalErr) {
+			msg.Args["state"] = lexicalErr.State
+		}
+	case errors.As(err, &lexicalErr):
+		msg.setPos(lexicalErr.File, lexicalErr.Line, lexicalErr.Column)
+		msg.Args["state"] = lexicalErr.State
+		msg.setErr(lexicalErr.Err)
+	}
+
+	return msg
+}
+
+// setPos sets the position arguments of the message.
+func (m *Message) setPos(file string, line, column int) {
+	if file != "" {
+		m.Args["file"] = file
+	}
+	m.Args["line"] = line + 1
+	m.Args["column"] = column + 1
+}
+
+// setErr sets the message of the underlying error if it is not nil.
+func (m *Message) setErr(err error) {
+	if err != nil {
+		m.Args["err"] = err.Error()
+	}
+}
+
+// Localize returns the message for err translated by c. If err was not
+// generated by lexparse or c has no translation for it, the message returned
+// by err's Error method is returned.
+func Localize(err error, c Catalog) string {
+	msg := ErrorMessage(err)
+	if msg.ID != "" && c != nil {
+		if s, ok := c(msg); ok {
+			return s
+		}
+	}
+	return err.Error()
+}
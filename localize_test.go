@@ -0,0 +1,163 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestErrorMessage(t *testing.T) {
+	t.Parallel()
+
+	errBad := errors.New("bad rune")
+
+	testCases := map[string]struct {
+		err  error
+		want Message
+	}{
+		"lexeme error": {
+			err: fmt.Errorf("parsing: %w", &LexemeError{
+				Err:    ErrUnexpectedLexeme,
+				Lexeme: &Lexeme{Value: "}", Line: 1, Column: 4, File: "a.txt"},
+			}),
+			want: Message{
+				ID: "unexpected-lexeme",
+				Args: map[string]any{
+					"error":  `parsing: a.txt: unexpected lexeme: "}": line 2, column 5`,
+					"file":   "a.txt",
+					"line":   2,
+					"column": 5,
+					"lexeme": "}",
+					"detail": "",
+					"err":    "unexpected lexeme",
+				},
+			},
+		},
+		"limit error": {
+			err: &LimitError{Limit: "depth", Max: 10, Line: 2, Column: 3},
+			want: Message{
+				ID: "limit-exceeded",
+				Args: map[string]any{
+					"error":  "limit exceeded: depth limit of 10: line 3, column 4",
+					"line":   3,
+					"column": 4,
+					"limit":  "depth",
+					"max":    10,
+				},
+			},
+		},
+		"lexical error": {
+			err: &LexicalError{Err: errBad, State: "lexWord", Column: 7},
+			want: Message{
+				ID: "lexical-error",
+				Args: map[string]any{
+					"error":  "bad rune",
+					"line":   1,
+					"column": 8,
+					"state":  "lexWord",
+					"err":    "bad rune",
+				},
+			},
+		},
+		"lexeme error without err": {
+			err: &LexemeError{Lexeme: &Lexeme{Value: "}"}},
+			want: Message{
+				Args: map[string]any{
+					"error":  `<nil>: "}": line 1, column 1`,
+					"line":   1,
+					"column": 1,
+					"lexeme": "}",
+					"detail": "",
+				},
+			},
+		},
+		"lexical error without err": {
+			err: &LexicalError{State: "lexWord"},
+			want: Message{
+				ID: "lexical-error",
+				Args: map[string]any{
+					"error":  "lexical error",
+					"line":   1,
+					"column": 1,
+					"state":  "lexWord",
+				},
+			},
+		},
+		"io error without err": {
+			err: &IOError{State: "lexWord"},
+			want: Message{
+				ID: "io-error",
+				Args: map[string]any{
+					"error":  "<nil>: line 1, column 1",
+					"line":   1,
+					"column": 1,
+					"state":  "lexWord",
+				},
+			},
+		},
+		"other": {
+			err: errBad,
+			want: Message{
+				Args: map[string]any{
+					"error": "bad rune",
+				},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tc.want, ErrorMessage(tc.err)); diff != "" {
+				t.Errorf("ErrorMessage: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	t.Parallel()
+
+	catalog := func(msg Message) (string, bool) {
+		if msg.ID != "unexpected-lexeme" {
+			return "", false
+		}
+		return fmt.Sprintf("lexème inattendu %q : ligne %d, colonne %d",
+			msg.Args["lexeme"], msg.Args["line"], msg.Args["column"]), true
+	}
+
+	err := &LexemeError{
+		Err:    ErrUnexpectedLexeme,
+		Lexeme: &Lexeme{Value: "}"},
+	}
+	if got, want := Localize(err, catalog), `lexème inattendu "}" : ligne 1, colonne 1`; got != want {
+		t.Errorf("Localize: want: %q, got: %q", want, got)
+	}
+
+	// Messages without a translation are not translated.
+	limitErr := &LimitError{Limit: "node", Max: 1}
+	if got, want := Localize(limitErr, catalog), limitErr.Error(); got != want {
+		t.Errorf("Localize: want: %q, got: %q", want, got)
+	}
+	if got, want := Localize(limitErr, nil), limitErr.Error(); got != want {
+		t.Errorf("Localize: want: %q, got: %q", want, got)
+	}
+}
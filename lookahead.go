@@ -0,0 +1,68 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+// Lookahead reads lexemes from a channel and provides lookahead of any
+// number of lexemes. It can be used to build hand-written parsers directly on
+// the output of a Lexer without a Parser.
+type Lookahead struct {
+	lexemes <-chan *Lexeme
+
+	// buf holds lexemes that have been peeked but not consumed.
+	buf []*Lexeme
+
+	// eof is true if the lexemes channel is closed.
+	eof bool
+}
+
+// NewLookahead returns a new Lookahead that reads from lexemes.
+func NewLookahead(lexemes <-chan *Lexeme) *Lookahead {
+	return &Lookahead{
+		lexemes: lexemes,
+	}
+}
+
+// Peek returns the next Lexeme without consuming it. Returns nil at the end
+// of input.
+func (l *Lookahead) Peek() *Lexeme {
+	return l.PeekN(0)
+}
+
+// PeekN returns the nth next Lexeme, where zero is the next Lexeme, without
+// consuming any lexemes. Returns nil if the end of input is reached first.
+func (l *Lookahead) PeekN(n int) *Lexeme {
+	for len(l.buf) <= n && !l.eof {
+		lexeme, ok := <-l.lexemes
+		if !ok {
+			l.eof = true
+			break
+		}
+		l.buf = append(l.buf, lexeme)
+	}
+	if n < 0 || n >= len(l.buf) {
+		return nil
+	}
+	return l.buf[n]
+}
+
+// Next consumes and returns the next Lexeme. Returns nil at the end of input.
+func (l *Lookahead) Next() *Lexeme {
+	lexeme := l.Peek()
+	if lexeme != nil {
+		l.buf[0] = nil
+		l.buf = l.buf[1:]
+	}
+	return lexeme
+}
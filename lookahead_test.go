@@ -0,0 +1,51 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLookahead(t *testing.T) {
+	t.Parallel()
+
+	lexemes, cancel := testLexer(t, "A B C")
+	defer cancel()
+
+	l := NewLookahead(lexemes)
+
+	if got, want := l.PeekN(2).Value, "C"; got != want {
+		t.Errorf("PeekN(2): want: %v, got: %v", want, got)
+	}
+	if got := l.PeekN(3); got != nil {
+		t.Errorf("PeekN(3): want: nil, got: %v", got)
+	}
+	if got, want := l.Peek().Value, "A"; got != want {
+		t.Errorf("Peek: want: %v, got: %v", want, got)
+	}
+
+	var got []string
+	for lexeme := l.Next(); lexeme != nil; lexeme = l.Next() {
+		got = append(got, lexeme.Value)
+	}
+	if diff := cmp.Diff([]string{"A", "B", "C"}, got); diff != "" {
+		t.Errorf("Next: (-want, +got): \n%s", diff)
+	}
+	if !l.Peek().IsEOF() {
+		t.Errorf("Peek: want EOF")
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"sync"
+)
+
+// Middleware transforms a stream of lexemes between a Lexer and a Parser. The
+// returned channel must be closed when in is closed or ctx is done.
+type Middleware func(ctx context.Context, in <-chan *Lexeme) <-chan *Lexeme
+
+// Chain returns a Middleware that applies each of the given middleware in
+// order.
+func Chain(m ...Middleware) Middleware {
+	return func(ctx context.Context, in <-chan *Lexeme) <-chan *Lexeme {
+		for _, f := range m {
+			in = f(ctx, in)
+		}
+		return in
+	}
+}
+
+// InsertTerminators returns a Middleware that implements automatic statement
+// terminator insertion similar to Go's automatic semicolon insertion. A
+// lexeme with the given type and value is inserted after a lexeme with one
+// of the types in after when it is followed by a newline or the end of the
+// input. Lexemes are followed by a newline if the next lexeme is on a later
+// line.
+func InsertTerminators(typ LexemeType, value string, after ...LexemeType) Middleware {
+	types := map[LexemeType]bool{}
+	for _, t := range after {
+		types[t] = true
+	}
+
+	return func(ctx context.Context, in <-chan *Lexeme) <-chan *Lexeme {
+		out := make(chan *Lexeme)
+		go func() {
+			defer close(out)
+
+			send := func(l *Lexeme) bool {
+				select {
+				case out <- l:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			}
+
+			var prev *Lexeme
+			for l := range in {
+				if prev != nil && types[prev.Type] && l.Line > prev.Line {
+					if !send(terminator(prev, typ, value)) {
+						return
+					}
+				}
+				if !send(l) {
+					return
+				}
+				prev = l
+			}
+			if prev != nil && types[prev.Type] {
+				_ = send(terminator(prev, typ, value))
+			}
+		}()
+		return out
+	}
+}
+
+// terminator returns a terminator lexeme positioned immediately after l.
+func terminator(l *Lexeme, typ LexemeType, value string) *Lexeme {
+	t := &Lexeme{
+		Type:   typ,
+		Value:  value,
+		Pos:    l.Pos,
+		Line:   l.Line,
+		Column: l.Column,
+		File:   l.File,
+	}
+	for _, rn := range l.Value {
+		t.Pos++
+		if rn == '\n' {
+			t.Line++
+			t.Column = 0
+		} else {
+			t.Column++
+		}
+	}
+	return t
+}
+
+// Tee returns n channels that each receive all of the lexemes read from in.
+// It allows multiple parsers, e.g. speculative parsers running in separate
+// goroutines, to consume the same lexeme stream independently. Lexemes are
+// buffered for each channel so a slow consumer doesn't block the others. The
+// returned channels are closed when in is closed or ctx is done.
+//
+// A single lexeme channel is safe for concurrent use but each lexeme is only
+// received by one of the receivers.
+func Tee(ctx context.Context, in <-chan *Lexeme, n int) []<-chan *Lexeme {
+	outs := make([]<-chan *Lexeme, n)
+	queues := make([]*lexemeQueue, n)
+	for i := range queues {
+		out := make(chan *Lexeme)
+		q := &lexemeQueue{notify: make(chan struct{}, 1)}
+		go q.forward(ctx, out)
+		outs[i] = out
+		queues[i] = q
+	}
+
+	go func() {
+		defer func() {
+			for _, q := range queues {
+				q.close()
+			}
+		}()
+		for {
+			select {
+			case l, ok := <-in:
+				if !ok {
+					return
+				}
+				for _, q := range queues {
+					q.push(l)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return outs
+}
+
+// Drain receives and discards lexemes from lexemes until it is closed and
+// returns the number of lexemes discarded. Lexers block when emitting a
+// lexeme until it is received or the context passed to Lex is done, so a
+// consumer that stops reading early, e.g. because of a parse error, should
+// cancel the context or drain the channel so the lexer's goroutines can
+// finish.
+func Drain(lexemes <-chan *Lexeme) int {
+	var n int
+	for range lexemes {
+		n++
+	}
+	return n
+}
+
+// lexemeQueue is an unbounded queue of lexemes.
+type lexemeQueue struct {
+	mu     sync.Mutex
+	queue  []*Lexeme
+	closed bool
+
+	// notify is signaled when the queue is modified.
+	notify chan struct{}
+}
+
+// push adds a lexeme to the queue.
+func (q *lexemeQueue) push(l *Lexeme) {
+	q.mu.Lock()
+	q.queue = append(q.queue, l)
+	q.mu.Unlock()
+	q.signal()
+}
+
+// close marks the queue as closed. Queued lexemes are still forwarded.
+func (q *lexemeQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.signal()
+}
+
+// signal notifies the forwarding goroutine that the queue was modified.
+func (q *lexemeQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// forward sends queued lexemes to out until the queue is closed and empty or
+// ctx is done. out is closed when forward returns.
+func (q *lexemeQueue) forward(ctx context.Context, out chan<- *Lexeme) {
+	defer close(out)
+	for {
+		q.mu.Lock()
+		if len(q.queue) == 0 {
+			closed := q.closed
+			q.mu.Unlock()
+			if closed {
+				return
+			}
+			select {
+			case <-q.notify:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		l := q.queue[0]
+		q.queue = q.queue[1:]
+		q.mu.Unlock()
+
+		select {
+		case out <- l:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ianlewis/runeio"
+)
+
+const (
+	identType LexemeType = iota + 200
+	semiType
+	braceType
+)
+
+// lexemeChan returns a closed channel containing the given lexemes.
+func lexemeChan(lexemes ...*Lexeme) <-chan *Lexeme {
+	ch := make(chan *Lexeme, len(lexemes))
+	for _, l := range lexemes {
+		ch <- l
+	}
+	close(ch)
+	return ch
+}
+
+func TestInsertTerminators(t *testing.T) {
+	t.Parallel()
+
+	// a {
+	// b
+	// }
+	in := lexemeChan(
+		&Lexeme{Type: identType, Value: "a", Pos: 0, Line: 0, Column: 0},
+		&Lexeme{Type: braceType, Value: "{", Pos: 2, Line: 0, Column: 2},
+		&Lexeme{Type: identType, Value: "b", Pos: 4, Line: 1, Column: 0},
+		&Lexeme{Type: braceType, Value: "}", Pos: 6, Line: 2, Column: 0},
+	)
+
+	m := InsertTerminators(semiType, ";", identType)
+	var got []*Lexeme
+	for l := range m(context.Background(), in) {
+		got = append(got, l)
+	}
+
+	want := []*Lexeme{
+		{Type: identType, Value: "a", Pos: 0, Line: 0, Column: 0},
+		{Type: braceType, Value: "{", Pos: 2, Line: 0, Column: 2},
+		{Type: identType, Value: "b", Pos: 4, Line: 1, Column: 0},
+		{Type: semiType, Value: ";", Pos: 5, Line: 1, Column: 1},
+		{Type: braceType, Value: "}", Pos: 6, Line: 2, Column: 0},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("InsertTerminators: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestInsertTerminators_eof(t *testing.T) {
+	t.Parallel()
+
+	in := lexemeChan(
+		&Lexeme{Type: identType, Value: "ab", Pos: 0, Line: 0, Column: 0, File: "a.txt"},
+	)
+
+	m := Chain(InsertTerminators(semiType, ";", identType))
+	var got []*Lexeme
+	for l := range m(context.Background(), in) {
+		got = append(got, l)
+	}
+
+	want := []*Lexeme{
+		{Type: identType, Value: "ab", Pos: 0, Line: 0, Column: 0, File: "a.txt"},
+		{Type: semiType, Value: ";", Pos: 2, Line: 0, Column: 2, File: "a.txt"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("InsertTerminators: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestInsertTerminators_multiline(t *testing.T) {
+	t.Parallel()
+
+	// A raw string spanning multiple lines.
+	in := lexemeChan(
+		&Lexeme{Type: identType, Value: "`a\nbc\nd`", Pos: 2, Line: 1, Column: 2},
+	)
+
+	m := InsertTerminators(semiType, ";", identType)
+	var got []*Lexeme
+	for l := range m(context.Background(), in) {
+		got = append(got, l)
+	}
+
+	want := []*Lexeme{
+		{Type: identType, Value: "`a\nbc\nd`", Pos: 2, Line: 1, Column: 2},
+		{Type: semiType, Value: ";", Pos: 10, Line: 3, Column: 2},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("InsertTerminators: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestTee(t *testing.T) {
+	t.Parallel()
+
+	want := []*Lexeme{
+		{Type: identType, Value: "a"},
+		{Type: identType, Value: "b", Pos: 2, Column: 2},
+		{Type: identType, Value: "c", Pos: 4, Column: 4},
+	}
+	outs := Tee(context.Background(), lexemeChan(want...), 3)
+
+	var wg sync.WaitGroup
+	got := make([][]*Lexeme, len(outs))
+	for i, out := range outs {
+		i, out := i, out
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for l := range out {
+				got[i] = append(got[i], l)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range outs {
+		if diff := cmp.Diff(want, got[i]); diff != "" {
+			t.Errorf("Tee %d: (-want, +got): \n%s", i, diff)
+		}
+	}
+}
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	t.Run("channel", func(t *testing.T) {
+		t.Parallel()
+
+		lexemes := lexemeChan(
+			&Lexeme{Type: identType, Value: "a"},
+			&Lexeme{Type: identType, Value: "b"},
+		)
+		if got, want := Drain(lexemes), 2; got != want {
+			t.Errorf("Drain: want: %v, got: %v", want, got)
+		}
+	})
+
+	t.Run("lexer", func(t *testing.T) {
+		t.Parallel()
+
+		// The lexer is blocked emitting the second word after the first is
+		// received and can only finish if the rest of the lexemes are
+		// drained.
+		l := NewLexer(runeio.NewReader(strings.NewReader("a b c d")), &wordState{})
+		lexemes := l.Lex(context.Background())
+		<-lexemes
+
+		if got, want := Drain(lexemes), 3; got != want {
+			t.Errorf("Drain: want: %v, got: %v", want, got)
+		}
+		select {
+		case <-l.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("Done: lexer did not finish")
+		}
+	})
+}
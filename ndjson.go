@@ -0,0 +1,118 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// WriteLexemes writes the lexemes received from lexemes to w as
+// newline-delimited JSON, one JSON object per line, until lexemes is closed or
+// ctx is done. The output can be read back with a LexemeDecoder, allowing
+// lexing and parsing to run in different processes.
+func WriteLexemes(ctx context.Context, w io.Writer, lexemes <-chan *Lexeme) error {
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case l, ok := <-lexemes:
+			if !ok {
+				return nil
+			}
+			if err := enc.Encode(l); err != nil {
+				return fmt.Errorf("encoding lexeme: %w", err)
+			}
+		case <-ctx.Done():
+			//nolint:wrapcheck // We don't need to wrap the context Error.
+			return ctx.Err()
+		}
+	}
+}
+
+// LexemeDecoder reads lexemes written as newline-delimited JSON by
+// WriteLexemes. It can be used in place of a Lexer as the input to a Parser.
+type LexemeDecoder struct {
+	r io.Reader
+
+	lexemes chan *Lexeme
+	done    chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewLexemeDecoder returns a new LexemeDecoder that reads from r.
+func NewLexemeDecoder(r io.Reader) *LexemeDecoder {
+	return &LexemeDecoder{
+		r:       r,
+		lexemes: make(chan *Lexeme),
+		done:    make(chan struct{}),
+	}
+}
+
+// Lex starts a new goroutine to decode the input and returns a channel of
+// lexemes. The channel is closed when the end of the input is reached, an
+// error occurs, or ctx is cancelled.
+func (d *LexemeDecoder) Lex(ctx context.Context) <-chan *Lexeme {
+	go func() {
+		defer close(d.done)
+		defer close(d.lexemes)
+
+		dec := json.NewDecoder(d.r)
+		for {
+			lexeme := &Lexeme{}
+			if err := dec.Decode(lexeme); err != nil {
+				if !errors.Is(err, io.EOF) {
+					d.setErr(fmt.Errorf("decoding lexeme: %w", err))
+				}
+				return
+			}
+
+			select {
+			case d.lexemes <- lexeme:
+			case <-ctx.Done():
+				d.setErr(ctx.Err())
+				return
+			}
+		}
+	}()
+	return d.lexemes
+}
+
+// setErr sets the decoder's error value.
+func (d *LexemeDecoder) setErr(err error) {
+	d.mu.Lock()
+	if d.err == nil {
+		d.err = err
+	}
+	d.mu.Unlock()
+}
+
+// Err returns the first error encountered.
+func (d *LexemeDecoder) Err() error {
+	d.mu.Lock()
+	err := d.err
+	d.mu.Unlock()
+	return err
+}
+
+// Done returns a channel that is closed when the decoder is finished running.
+func (d *LexemeDecoder) Done() <-chan struct{} {
+	return d.done
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteLexemes(t *testing.T) {
+	t.Parallel()
+
+	lexemes := []*Lexeme{
+		{Type: identType, Value: "a", Pos: 0, Line: 0, Column: 0},
+		{Type: semiType, Value: ";", Pos: 1, Line: 0, Column: 1, File: "a.txt"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLexemes(context.Background(), &buf, lexemeChan(lexemes...)); err != nil {
+		t.Fatalf("WriteLexemes: %v", err)
+	}
+
+	wantOut := `{"Type":200,"Value":"a","Pos":0,"Line":0,"Column":0}
+{"Type":201,"Value":";","Pos":1,"Line":0,"Column":1,"File":"a.txt"}
+`
+	if diff := cmp.Diff(wantOut, buf.String()); diff != "" {
+		t.Errorf("WriteLexemes: (-want, +got): \n%s", diff)
+	}
+
+	d := NewLexemeDecoder(&buf)
+	var got []*Lexeme
+	for l := range d.Lex(context.Background()) {
+		got = append(got, l)
+	}
+	<-d.Done()
+	if err := d.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if diff := cmp.Diff(lexemes, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestLexemeDecoder_error(t *testing.T) {
+	t.Parallel()
+
+	d := NewLexemeDecoder(strings.NewReader(`{"Type":200,"Value":"a"}` + "\n{bad\n"))
+	var got []*Lexeme
+	for l := range d.Lex(context.Background()) {
+		got = append(got, l)
+	}
+	<-d.Done()
+
+	if d.Err() == nil {
+		t.Errorf("Err: want error, got: %v", d.Err())
+	}
+	want := []*Lexeme{{Type: identType, Value: "a"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+}
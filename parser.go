@@ -17,12 +17,16 @@ package lexparse
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"runtime/debug"
 )
 
 // Node is the structure for a single node in the parse tree.
 type Node[V comparable] struct {
-	Parent   *Node[V]
+	// Parent is the parent node. It is excluded from JSON encoding so that
+	// trees can be encoded without cycles.
+	Parent   *Node[V] `json:"-"`
 	Children []*Node[V]
 	Value    V
 
@@ -34,6 +38,82 @@ type Node[V comparable] struct {
 
 	// Column is the column in the line of the input where the value was found.
 	Column int
+
+	// Meta holds arbitrary metadata about the node. It can be used by
+	// analysis passes (e.g. type checking) to annotate nodes in place. It is
+	// nil until SetMeta is called.
+	Meta map[string]any `json:",omitempty"`
+}
+
+// GetMeta returns the metadata value for key.
+func (p *Node[V]) GetMeta(key string) (any, bool) {
+	v, ok := p.Meta[key]
+	return v, ok
+}
+
+// SetMeta sets the metadata value for key.
+func (p *Node[V]) SetMeta(key string, v any) {
+	if p.Meta == nil {
+		p.Meta = map[string]any{}
+	}
+	p.Meta[key] = v
+}
+
+// copyMeta returns a shallow copy of the metadata map.
+func copyMeta(m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]any, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// Strip returns a copy of the tree rooted at n without Parent references.
+// Stripped trees can be compared or copied without following back-references.
+func (p *Node[V]) Strip() *Node[V] {
+	if p == nil {
+		return nil
+	}
+	n := &Node[V]{
+		Value:  p.Value,
+		Pos:    p.Pos,
+		Line:   p.Line,
+		Column: p.Column,
+		Meta:   copyMeta(p.Meta),
+	}
+	for _, c := range p.Children {
+		n.Children = append(n.Children, c.Strip())
+	}
+	return n
+}
+
+// Clone returns a deep copy of the tree rooted at n. The Parent references of
+// the copied nodes refer to their copied parents and the copy of n is detached
+// from the tree (i.e. its Parent is nil). Metadata maps are copied but their
+// values are not.
+func (p *Node[V]) Clone() *Node[V] {
+	return p.clone(nil)
+}
+
+func (p *Node[V]) clone(parent *Node[V]) *Node[V] {
+	if p == nil {
+		return nil
+	}
+	n := &Node[V]{
+		Parent: parent,
+		Value:  p.Value,
+		Pos:    p.Pos,
+		Line:   p.Line,
+		Column: p.Column,
+		Meta:   copyMeta(p.Meta),
+	}
+	for _, c := range p.Children {
+		n.Children = append(n.Children, c.clone(n))
+	}
+	return n
 }
 
 // Left returns the left child in the case of a binary tree.
@@ -90,6 +170,88 @@ func (p *Node[V]) SetRight(r *Node[V]) *Node[V] {
 // nil is returned.
 type ParseFn[V comparable] func(context.Context, *Parser[V]) (ParseFn[V], error)
 
+// NamedParseState returns a ParseFn that calls fn with name pushed onto the
+// parser's stack of parse states. Errors returned by fn, or set on the parser
+// while fn runs, are wrapped in a *ParseStateError that records the stack at
+// the time of the error so that grammar authors can see how the parser got
+// into a bad state. Parse functions that call other named parse functions
+// directly, e.g. a recursive descent parser, build up a stack of names.
+func NamedParseState[V comparable](name string, fn ParseFn[V]) ParseFn[V] {
+	return func(ctx context.Context, p *Parser[V]) (ParseFn[V], error) {
+		p.states = append(p.states, name)
+		p.recordState(EventStatePush, name)
+		if p.coverage != nil {
+			p.coverage.recordParseState(name)
+		}
+		returned := false
+		defer func() {
+			if !returned && p.panicState == "" {
+				// fn panicked. Record the innermost state for the PanicError.
+				p.panicState = name
+			}
+			p.states = p.states[:len(p.states)-1]
+			p.recordState(EventStatePop, name)
+		}()
+
+		next, err := fn(ctx, p)
+		returned = true
+		if err != nil && !errors.Is(err, io.EOF) {
+			err = p.stateError(err)
+		}
+		if p.err != nil {
+			p.err = p.stateError(p.err)
+		}
+		return next, err
+	}
+}
+
+// StateStack returns the names of the named parse states that are currently
+// running from outermost to innermost. See NamedParseState.
+func (p *Parser[V]) StateStack() []string {
+	return append([]string(nil), p.states...)
+}
+
+// stateError wraps err in a *ParseStateError with the current stack of parse
+// states if it isn't wrapped already.
+func (p *Parser[V]) stateError(err error) error {
+	var stateErr *ParseStateError
+	if errors.As(err, &stateErr) {
+		return err
+	}
+	return &ParseStateError{
+		Err:   err,
+		Stack: p.StateStack(),
+	}
+}
+
+// Hooks are optional callbacks that observe the construction of the parse
+// tree. They allow cross-cutting concerns such as validation, symbol
+// collection, or metrics to be implemented without modifying every ParseFn.
+type Hooks[V comparable] struct {
+	// OnNode is called when a node is added to the tree. If a subtree is
+	// added (e.g. by ExprParser) OnNode is called for each of its nodes.
+	OnNode func(n *Node[V])
+
+	// OnPush is called by Push after the new node becomes the current node.
+	OnPush func(n *Node[V])
+
+	// OnClimb is called by Climb with the node being climbed from after its
+	// parent becomes the current node.
+	OnClimb func(n *Node[V])
+
+	// Fold is called by Climb with the node being climbed from and its parent
+	// before the parent becomes the current node. The subtree rooted at child
+	// is complete and the value returned replaces the child's value. This
+	// allows values to be computed as subtrees are closed, e.g. to fold
+	// constant expressions or to interpret the input in a single pass. Fold
+	// may also modify the child's children, e.g. to discard them once they
+	// are folded. If Fold returns an error, Parse returns the error.
+	Fold func(parent, child *Node[V]) (V, error)
+}
+
+// historySize is the number of consumed lexemes remembered by the Parser.
+const historySize = 8
+
 // NewParser creates a new Parser that reads from the lexemes channel. The
 // parser is initialized with a root node with an empty value.
 func NewParser[V comparable](lexemes <-chan *Lexeme) *Parser[V] {
@@ -117,6 +279,80 @@ type Parser[V comparable] struct {
 
 	// next is the next lexeme in the stream.
 	next *Lexeme
+
+	// history is a ring buffer of the most recently consumed lexemes.
+	history [historySize]*Lexeme
+
+	// consumed is the number of lexemes consumed.
+	consumed int
+
+	// tape holds lexemes read while a checkpoint is active so that they can
+	// be replayed after a Restore.
+	tape []*Lexeme
+
+	// tapePos is the position of the next lexeme to read from tape.
+	tapePos int
+
+	// checkpoints is the number of active checkpoints.
+	checkpoints int
+
+	// boundaries is a stack of nodes above which Climb will not ascend.
+	boundaries []*Node[V]
+
+	// terminators are lexeme types that are treated as the end of input.
+	terminators map[LexemeType]bool
+
+	// requireEOF is true if Parse should verify that all lexemes were
+	// consumed.
+	requireEOF bool
+
+	// lexerErr returns the lexer's error when the lexemes channel is closed.
+	lexerErr func() error
+
+	// logEvents is true if events should be recorded.
+	logEvents bool
+
+	// events are the recorded events.
+	events []Event[V]
+
+	// hooks are callbacks called as the tree is built.
+	hooks Hooks[V]
+
+	// strictValues is true if Push and Node reject the zero value.
+	strictValues bool
+
+	// maxDepth is the maximum depth of the tree or zero for no limit.
+	maxDepth int
+
+	// maxNodes is the maximum number of nodes in the tree or zero for no
+	// limit.
+	maxNodes int
+
+	// nodes is the number of nodes added to the tree.
+	nodes int
+
+	// arena allocates new nodes if not nil.
+	arena *NodeArena[V]
+
+	// states is the stack of names of the named parse states that are
+	// running.
+	states []string
+
+	// noRecover is true if panics in parse functions are not recovered.
+	noRecover bool
+
+	// panicState is the name of the innermost named parse state that was
+	// running when a parse function panicked.
+	panicState string
+
+	// coverage records each named parse state run or is nil.
+	coverage *Coverage
+
+	// warnings are the warnings reported by Warn.
+	warnings []*Diagnostic
+
+	// err is the first error encountered while building the tree.
+	err error
 }
 
 // Parse builds a parse tree by repeatedly calling parseFn. parseFn
@@ -124,6 +360,26 @@ type Parser[V comparable] struct {
 // an error. The parse tree is built when parseFn returns nil for the
 // parseFn. Parsing can be cancelled by ctx.
 func (p *Parser[V]) Parse(ctx context.Context, parseFn ParseFn[V]) (*Node[V], error) {
+	if err := p.parse(ctx, parseFn); err != nil {
+		return p.root, err
+	}
+
+	if p.requireEOF {
+		if l := p.Peek(); l != nil {
+			return p.root, &LexemeError{
+				Err:    ErrTrailingInput,
+				Lexeme: l,
+			}
+		}
+		if p.err != nil {
+			return p.root, p.err
+		}
+	}
+	return p.root, nil
+}
+
+// parse repeatedly calls parseFn until it returns nil for the parseFn.
+func (p *Parser[V]) parse(ctx context.Context, parseFn ParseFn[V]) error {
 	for {
 		if parseFn == nil {
 			break
@@ -131,21 +387,318 @@ func (p *Parser[V]) Parse(ctx context.Context, parseFn ParseFn[V]) (*Node[V], er
 		select {
 		case <-ctx.Done():
 			//nolint:wrapcheck // We don't need to wrap the context Error.
-			return p.root, ctx.Err()
+			return ctx.Err()
 		default:
 		}
 
+		p.record(EventParseFn, nil)
+
 		var err error
-		parseFn, err = parseFn(ctx, p)
+		parseFn, err = p.runParseFn(ctx, parseFn)
+		if p.err != nil {
+			return p.err
+		}
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				break
 			}
 
-			return p.root, err
+			return err
 		}
 	}
-	return p.root, nil
+	return nil
+}
+
+// runParseFn calls parseFn. Unless disabled with SetRecoverPanics, a panic in
+// parseFn is returned as a *PanicError.
+func (p *Parser[V]) runParseFn(ctx context.Context, parseFn ParseFn[V]) (next ParseFn[V], err error) {
+	if !p.noRecover {
+		p.panicState = ""
+		defer func() {
+			if r := recover(); r != nil {
+				next, err = nil, p.panicError(r, parseFn)
+			}
+		}()
+	}
+	return parseFn(ctx, p)
+}
+
+// panicError returns a *PanicError for the value r recovered from a panic in
+// parseFn.
+func (p *Parser[V]) panicError(r any, parseFn ParseFn[V]) *PanicError {
+	pErr := &PanicError{
+		Value: r,
+		State: p.panicState,
+		Stack: debug.Stack(),
+	}
+	p.panicState = ""
+	if pErr.State == "" {
+		pErr.State = funcName(parseFn)
+	}
+	if p.lexeme != nil {
+		pErr.Line = p.lexeme.Line
+		pErr.Column = p.lexeme.Column
+		pErr.File = p.lexeme.File
+	}
+	return pErr
+}
+
+// SetRecoverPanics sets whether Parse recovers from panics in parse
+// functions. By default a panic in a parse function stops parsing and Parse
+// returns a *PanicError with the name of the innermost named parse state (see
+// NamedParseState) or parse function and the position of the current lexeme
+// rather than crashing the program. Recovery can be disabled while debugging
+// so that the panic propagates with its original stack trace.
+func (p *Parser[V]) SetRecoverPanics(enabled bool) {
+	p.noRecover = !enabled
+}
+
+// SetCoverage configures the parser to record each named parse state run in
+// c. Parse functions not created by NamedParseState are not recorded. A nil c
+// disables recording. See Coverage.
+func (p *Parser[V]) SetCoverage(c *Coverage) {
+	p.coverage = c
+}
+
+// ParseAll parses a sequence of independent top-level items, such as the
+// documents in a stream or the statements in a migration, until the end of
+// the input. Each item is parsed by calling parseFn as Parse does but with a
+// new root node, and the roots of the items are returned in order.
+// Terminators (see SetTerminators) between items are skipped so they can be
+// used to separate items. If parseFn consumes no lexemes for an item,
+// ParseAll returns an error wrapping ErrNoProgress. If an error occurs, the
+// roots of the items parsed so far, including the partial tree of the item
+// being parsed, are returned with the error. SetRequireEOF has no effect.
+func (p *Parser[V]) ParseAll(ctx context.Context, parseFn ParseFn[V]) ([]*Node[V], error) {
+	var roots []*Node[V]
+	for {
+		if p.SkipTerminator() != nil {
+			continue
+		}
+		if p.Peek() == nil {
+			return roots, p.err
+		}
+
+		root := &Node[V]{}
+		p.root = root
+		p.node = root
+		p.nodes = 0
+		p.boundaries = p.boundaries[:0]
+		roots = append(roots, root)
+
+		consumed := p.consumed
+		if err := p.parse(ctx, parseFn); err != nil {
+			return roots, err
+		}
+		if p.consumed == consumed {
+			err := ErrNoProgress
+			if l := p.Peek(); l != nil {
+				err = fmt.Errorf("%w: line %d, column %d", ErrNoProgress, l.Line+1, l.Column+1)
+			}
+			return roots, err
+		}
+	}
+}
+
+// SetRequireEOF sets whether Parse verifies that all lexemes have been
+// consumed when parsing completes. If set and lexemes remain, Parse returns a
+// *LexemeError wrapping ErrTrailingInput for the next lexeme.
+func (p *Parser[V]) SetRequireEOF(require bool) {
+	p.requireEOF = require
+}
+
+// SetTerminators sets lexeme types that are treated like the end of the
+// input, e.g. a ";;" statement terminator in a REPL. When the next lexeme is
+// a terminator, Peek and Next return nil and AtEOF returns true so that parse
+// functions stop at the terminator as if the input ended. The terminator can
+// then be consumed with SkipTerminator to continue parsing the rest of the
+// input.
+func (p *Parser[V]) SetTerminators(types ...LexemeType) {
+	p.terminators = map[LexemeType]bool{}
+	for _, typ := range types {
+		p.terminators[typ] = true
+	}
+}
+
+// Terminator returns the terminator lexeme at which the parser is stopped or
+// nil if the next lexeme is not a terminator.
+func (p *Parser[V]) Terminator() *Lexeme {
+	// NOTE: Peek only returns nil with a pending next lexeme if it is a
+	//       terminator.
+	if p.Peek() != nil || p.err != nil {
+		return nil
+	}
+	return p.next
+}
+
+// SkipTerminator consumes the terminator lexeme at which the parser is
+// stopped and returns it. Returns nil and consumes nothing if the next lexeme
+// is not a terminator.
+func (p *Parser[V]) SkipTerminator() *Lexeme {
+	l := p.Terminator()
+	if l == nil {
+		return nil
+	}
+	p.next = nil
+	p.consume(l)
+	return l
+}
+
+// SetHooks sets the callbacks called as the parse tree is built.
+func (p *Parser[V]) SetHooks(h Hooks[V]) {
+	p.hooks = h
+}
+
+// Reset resets the Parser to read from lexemes with a new root node so that
+// it can be reused. Settings such as hooks and limits are retained.
+func (p *Parser[V]) Reset(lexemes <-chan *Lexeme) {
+	root := &Node[V]{}
+	p.lexemes = lexemes
+	p.root = root
+	p.node = root
+	p.lexeme = nil
+	p.next = nil
+	p.history = [historySize]*Lexeme{}
+	p.consumed = 0
+	p.tape = p.tape[:0]
+	p.tapePos = 0
+	p.checkpoints = 0
+	p.boundaries = p.boundaries[:0]
+	p.nodes = 0
+	p.err = nil
+	p.events = nil
+	p.states = p.states[:0]
+	p.warnings = nil
+}
+
+// Warn reports a non-fatal problem at lexeme l, e.g. deprecated syntax or a
+// suspicious construct, without stopping the parse. If l is nil, the last
+// lexeme read is used. Warnings reported after a checkpoint is created are
+// discarded if the checkpoint is restored.
+func (p *Parser[V]) Warn(l *Lexeme, msg string) {
+	if l == nil {
+		l = p.lexeme
+	}
+	d := &Diagnostic{
+		Severity: SeverityWarning,
+		Message:  msg,
+	}
+	if l != nil {
+		d.File = l.File
+		d.Range = lexemeRange(l)
+	}
+	p.warnings = append(p.warnings, d)
+}
+
+// Warnings returns a copy of the warnings reported by Warn in the order they
+// were reported. Errors can be converted to diagnostics with NewDiagnostic.
+func (p *Parser[V]) Warnings() []*Diagnostic {
+	return append([]*Diagnostic(nil), p.warnings...)
+}
+
+// SetLexerErr sets a function that returns the lexer's error, e.g.
+// Lexer.Err. It is called when the lexemes channel is closed so that lexing
+// errors can be distinguished from the end of the input. If it returns a
+// non-nil error, Peek and Next return nil and Parse returns the error.
+func (p *Parser[V]) SetLexerErr(errFn func() error) {
+	p.lexerErr = errFn
+}
+
+// lexerErr returns the error returned by errFn when the lexemes channel is
+// closed. It returns nil if errFn is nil or the lexer was canceled.
+func lexerErr(errFn func() error) error {
+	if errFn == nil {
+		return nil
+	}
+	if err := errFn(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// SetMaxDepth sets the maximum depth of the parse tree. The root node has a
+// depth of zero. Nodes that would exceed the maximum depth are not added to
+// the tree, no further lexemes are returned by Peek and Next, and Parse
+// returns a *LimitError. A value of zero or less means no limit.
+func (p *Parser[V]) SetMaxDepth(n int) {
+	p.maxDepth = n
+}
+
+// SetMaxNodes sets the maximum number of nodes in the parse tree, not
+// including the root node. Nodes that would exceed the maximum are not added
+// to the tree, no further lexemes are returned by Peek and Next, and Parse
+// returns a *LimitError. A value of zero or less means no limit.
+func (p *Parser[V]) SetMaxNodes(n int) {
+	p.maxNodes = n
+}
+
+// SetArena sets the arena used to allocate new nodes. If a is nil, nodes are
+// allocated individually. The root node is not allocated from the arena.
+func (p *Parser[V]) SetArena(a *NodeArena[V]) {
+	p.arena = a
+}
+
+// setErr records the first error encountered while building the tree.
+func (p *Parser[V]) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// limitErr returns a *LimitError at the current lexeme position.
+func (p *Parser[V]) limitErr(limit string, n int) error {
+	err := &LimitError{
+		Limit: limit,
+		Max:   n,
+	}
+	if p.lexeme != nil {
+		err.Line = p.lexeme.Line
+		err.Column = p.lexeme.Column
+		err.File = p.lexeme.File
+	}
+	return err
+}
+
+// checkLimits returns an error if adding the subtree rooted at n as a child
+// of the current node would exceed the parser's limits.
+func (p *Parser[V]) checkLimits(n *Node[V]) error {
+	if p.maxDepth <= 0 && p.maxNodes <= 0 {
+		return nil
+	}
+
+	size, height := subtreeSize(n)
+	if p.maxNodes > 0 && p.nodes+size > p.maxNodes {
+		return p.limitErr("node", p.maxNodes)
+	}
+
+	if p.maxDepth > 0 {
+		// NOTE: The walk is bounded by the maximum depth.
+		depth := height
+		for c := p.node; c.Parent != nil && depth <= p.maxDepth; c = c.Parent {
+			depth++
+		}
+		if depth > p.maxDepth {
+			return p.limitErr("depth", p.maxDepth)
+		}
+	}
+	return nil
+}
+
+// subtreeSize returns the number of nodes in the subtree rooted at n and its
+// height.
+func subtreeSize[V comparable](n *Node[V]) (int, int) {
+	if n == nil {
+		return 0, 0
+	}
+	size, height := 1, 1
+	for _, c := range n.Children {
+		s, h := subtreeSize(c)
+		size += s
+		if h+1 > height {
+			height = h + 1
+		}
+	}
+	return size, height
 }
 
 // Root returns the root of the parse tree.
@@ -155,26 +708,90 @@ func (p *Parser[V]) Root() *Node[V] {
 
 // Peek returns the next Lexeme from the lexer without consuming it.
 func (p *Parser[V]) Peek() *Lexeme {
-	if p.next != nil {
-		return p.next
+	if p.err != nil {
+		return nil
+	}
+	if p.next == nil {
+		p.next = p.read()
+	}
+	if p.next != nil && p.terminators[p.next.Type] {
+		return nil
+	}
+	return p.next
+}
+
+// read reads the next lexeme from the tape or the lexemes channel.
+func (p *Parser[V]) read() *Lexeme {
+	if p.tapePos < len(p.tape) {
+		l := p.tape[p.tapePos]
+		p.tapePos++
+		return l
+	}
+
+	if p.checkpoints == 0 {
+		// Lexemes no longer need to be replayed.
+		p.tape = nil
+		p.tapePos = 0
 	}
+
 	l, ok := <-p.lexemes
 	if !ok {
+		if err := lexerErr(p.lexerErr); err != nil {
+			p.setErr(err)
+		}
 		return nil
 	}
-	p.next = l
-	return p.next
+	if p.checkpoints > 0 {
+		p.tape = append(p.tape, l)
+		p.tapePos++
+	}
+	return l
+}
+
+// AtEOF returns true if there are no more lexemes to consume or the next
+// lexeme is a terminator.
+func (p *Parser[V]) AtEOF() bool {
+	return p.Peek().IsEOF()
 }
 
 // Next returns the next Lexeme from the lexer. This is the new current lexeme
 // position.
 func (p *Parser[V]) Next() *Lexeme {
 	l := p.Peek()
-	p.next = nil
 	p.lexeme = l
+	if l != nil {
+		p.next = nil
+		p.consume(l)
+	}
 	return p.lexeme
 }
 
+// consume records l as the current, consumed lexeme.
+func (p *Parser[V]) consume(l *Lexeme) {
+	p.lexeme = l
+	p.history[p.consumed%historySize] = l
+	p.consumed++
+	p.record(EventNext, nil)
+}
+
+// Prev returns the most recently consumed Lexeme. Unlike the current lexeme,
+// it is not cleared when the end of the input is reached. It can be used to
+// produce errors such as "expected X after Y". Returns nil if no lexemes have
+// been consumed.
+func (p *Parser[V]) Prev() *Lexeme {
+	return p.PrevN(0)
+}
+
+// PrevN returns the nth most recently consumed Lexeme where zero is the most
+// recent. Only the last 8 lexemes are remembered. Returns nil if n is out of
+// range.
+func (p *Parser[V]) PrevN(n int) *Lexeme {
+	if n < 0 || n >= historySize || n >= p.consumed {
+		return nil
+	}
+	return p.history[(p.consumed-1-n)%historySize]
+}
+
 // Pos returns the current node position in the tree. May return nil if a root
 // node has not been created.
 func (p *Parser[V]) Pos() *Node[V] {
@@ -184,23 +801,103 @@ func (p *Parser[V]) Pos() *Node[V] {
 // Push creates a new node, adds it as a child to the current node, and sets it
 // as the current node. The new node is returned.
 func (p *Parser[V]) Push(v V) *Node[V] {
-	n := p.Node(v)
+	return p.push(p.Node(v))
+}
+
+// push makes n the current node if it was added to the tree.
+func (p *Parser[V]) push(n *Node[V]) *Node[V] {
+	if n.Parent == nil {
+		// The node was not added to the tree.
+		return n
+	}
 	p.node = n
+	p.record(EventPush, n)
+	if p.hooks.OnPush != nil {
+		p.hooks.OnPush(n)
+	}
 	return n
 }
 
 // Node creates a new node at the current lexeme position and adds it as a
 // child to the current node.
 func (p *Parser[V]) Node(v V) *Node[V] {
-	n := p.newNode(v)
+	var zero V
+	if p.strictValues && v == zero {
+		err := ErrZeroValue
+		if p.lexeme != nil {
+			err = fmt.Errorf("%w: line %d, column %d", ErrZeroValue, p.lexeme.Line+1, p.lexeme.Column+1)
+		}
+		p.setErr(err)
+		return p.NewNode(v)
+	}
+	return p.node0(v)
+}
+
+// NodeZero is like Node but adds a node with the zero value. It can be used to
+// add nodes with the zero value when SetStrictValues is enabled.
+func (p *Parser[V]) NodeZero() *Node[V] {
+	var zero V
+	return p.node0(zero)
+}
+
+// PushZero is like Push but pushes a node with the zero value. It can be used
+// to push nodes with the zero value when SetStrictValues is enabled.
+func (p *Parser[V]) PushZero() *Node[V] {
+	return p.push(p.NodeZero())
+}
+
+// node0 creates a new node and adds it as a child to the current node.
+func (p *Parser[V]) node0(v V) *Node[V] {
+	n := p.NewNode(v)
+	p.addChild(n)
+	return n
+}
+
+// SetStrictValues sets whether Push and Node reject the zero value of V, e.g.
+// nil for pointer values. Grammars that use the zero value to mean "no
+// value" can enable it to catch nodes added with a missing value where they
+// are added rather than when the tree is used. If enabled, Push and Node
+// don't add nodes with the zero value to the tree, no further lexemes are
+// returned by Peek and Next, and Parse returns an error wrapping
+// ErrZeroValue. Use PushZero and NodeZero to add nodes with the zero value
+// deliberately.
+func (p *Parser[V]) SetStrictValues(strict bool) {
+	p.strictValues = strict
+}
+
+// addChild adds n as the last child of the current node. The node is not
+// added if doing so would exceed the parser's limits.
+func (p *Parser[V]) addChild(n *Node[V]) {
+	if p.err != nil {
+		return
+	}
+	if err := p.checkLimits(n); err != nil {
+		p.setErr(err)
+		return
+	}
+	size, _ := subtreeSize(n)
+	p.nodes += size
+
 	n.Parent = p.node
 	p.node.Children = append(p.node.Children, n)
-	return n
+	p.record(EventNode, n)
+	p.onNode(n)
+}
+
+// onNode calls the OnNode hook for each node in the subtree rooted at n.
+func (p *Parser[V]) onNode(n *Node[V]) {
+	if p.hooks.OnNode == nil || n == nil {
+		return
+	}
+	p.hooks.OnNode(n)
+	for _, c := range n.Children {
+		p.onNode(c)
+	}
 }
 
-// newNode creates a new node at the current lexeme position and returns it
+// NewNode creates a new node at the current lexeme position and returns it
 // without adding it to the tree.
-func (p *Parser[V]) newNode(v V) *Node[V] {
+func (p *Parser[V]) NewNode(v V) *Node[V] {
 	var pos, line, col int
 	if p.lexeme != nil {
 		pos = p.lexeme.Pos
@@ -208,21 +905,132 @@ func (p *Parser[V]) newNode(v V) *Node[V] {
 		col = p.lexeme.Column
 	}
 
-	return &Node[V]{
-		Value:  v,
-		Pos:    pos,
-		Line:   line,
-		Column: col,
+	var n *Node[V]
+	if p.arena != nil {
+		n = p.arena.New()
+	} else {
+		n = &Node[V]{}
+	}
+	n.Value = v
+	n.Pos = pos
+	n.Line = line
+	n.Column = col
+	return n
+}
+
+var (
+	// ErrNoAncestor indicates that no ancestor of the current node matched.
+	ErrNoAncestor = errors.New("no matching ancestor")
+
+	// ErrBoundary indicates an attempt to climb above a boundary node.
+	ErrBoundary = errors.New("climb past boundary")
+
+	// ErrTrailingInput indicates that lexemes remained after parsing
+	// completed.
+	ErrTrailingInput = errors.New("unexpected trailing input")
+
+	// ErrZeroValue indicates an attempt to add a node with the zero value
+	// when strict values are enabled. See SetStrictValues.
+	ErrZeroValue = errors.New("zero node value")
+
+	// ErrNoProgress indicates that a parse function consumed no lexemes.
+	ErrNoProgress = errors.New("parser made no progress")
+)
+
+// PushBoundary makes the current node a boundary above which Climb will not
+// ascend. Boundaries can be used to ensure that a ParseFn for a nested
+// construct doesn't modify the tree outside of it, e.g. due to mismatched
+// closing lexemes. Boundaries are removed with PopBoundary.
+func (p *Parser[V]) PushBoundary() {
+	p.boundaries = append(p.boundaries, p.node)
+}
+
+// PopBoundary removes the most recently pushed boundary and returns the
+// boundary node. Returns nil if there are no boundaries.
+func (p *Parser[V]) PopBoundary() *Node[V] {
+	if len(p.boundaries) == 0 {
+		return nil
+	}
+	n := p.boundaries[len(p.boundaries)-1]
+	p.boundaries = p.boundaries[:len(p.boundaries)-1]
+	return n
+}
+
+// atBoundary returns true if the current node is the current boundary.
+func (p *Parser[V]) atBoundary() bool {
+	return len(p.boundaries) > 0 && p.node == p.boundaries[len(p.boundaries)-1]
+}
+
+// ClimbTo updates the current node position to the nearest node, starting
+// with the current node and then its ancestors, for which pred returns true.
+// The new current node is returned. If no node below the current boundary
+// matches, the current node position is unchanged and an error wrapping
+// ErrNoAncestor is returned.
+func (p *Parser[V]) ClimbTo(pred func(*Node[V]) bool) (*Node[V], error) {
+	var boundary *Node[V]
+	if len(p.boundaries) > 0 {
+		boundary = p.boundaries[len(p.boundaries)-1]
 	}
+
+	n := p.node
+	for n != nil && !pred(n) {
+		if n == boundary {
+			n = nil
+			break
+		}
+		n = n.Parent
+	}
+	if n == nil {
+		if p.lexeme != nil {
+			return nil, fmt.Errorf("%w: line %d, column %d", ErrNoAncestor, p.lexeme.Line+1, p.lexeme.Column+1)
+		}
+		return nil, ErrNoAncestor
+	}
+	for p.node != n {
+		_ = p.Climb()
+	}
+	return n, nil
+}
+
+// ClimbWhile updates the current node position to the current node's parent
+// while pred returns true for the current node. It stops at the root node or
+// the current boundary. The new current node is returned.
+func (p *Parser[V]) ClimbWhile(pred func(*Node[V]) bool) *Node[V] {
+	for p.node.Parent != nil && !p.atBoundary() && pred(p.node) {
+		_ = p.Climb()
+	}
+	return p.node
 }
 
 // Climb updates the current node position to the current node's parent
 // returning the previous current node. It is a no-op that returns the root
-// node if called on the root node.
+// node if called on the root node. If the current node is a boundary set by
+// PushBoundary, the position is unchanged and Parse returns an error wrapping
+// ErrBoundary.
 func (p *Parser[V]) Climb() *Node[V] {
 	n := p.node
+	if p.atBoundary() {
+		err := ErrBoundary
+		if p.lexeme != nil {
+			err = fmt.Errorf("%w: line %d, column %d", ErrBoundary, p.lexeme.Line+1, p.lexeme.Column+1)
+		}
+		p.setErr(err)
+		return n
+	}
 	if p.node.Parent != nil {
+		if p.hooks.Fold != nil {
+			v, err := p.hooks.Fold(n.Parent, n)
+			if err != nil {
+				p.setErr(err)
+			} else {
+				n.Value = v
+			}
+		}
 		p.node = p.node.Parent
+		p.record(EventClimb, n)
+		if p.hooks.OnClimb != nil {
+			p.hooks.OnClimb(n)
+		}
 	}
 	return n
 }
@@ -231,7 +1039,7 @@ func (p *Parser[V]) Climb() *Node[V] {
 // old node is removed from the tree and it's value is returned. Can be used to
 // replace the root node.
 func (p *Parser[V]) Replace(v V) V {
-	n := p.newNode(v)
+	n := p.NewNode(v)
 
 	// Replace the parent.
 	n.Parent = p.node.Parent
@@ -258,6 +1066,11 @@ func (p *Parser[V]) Replace(v V) V {
 	}
 	oldVal := p.node.Value
 	p.node = n
+	p.record(EventReplace, n)
+
+	if p.hooks.OnNode != nil {
+		p.hooks.OnNode(n)
+	}
 
 	return oldVal
 }
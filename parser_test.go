@@ -16,6 +16,9 @@ package lexparse
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -855,3 +858,1006 @@ func TestNode_SetRight_update_nil(t *testing.T) {
 		t.Errorf("root.Right(): want %v got %v", nil, root.Right())
 	}
 }
+
+func TestParser_SetHooks(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	p := NewParser[string](nil)
+	p.SetHooks(Hooks[string]{
+		OnNode: func(n *Node[string]) {
+			got = append(got, "node "+n.Value)
+		},
+		OnPush: func(n *Node[string]) {
+			got = append(got, "push "+n.Value)
+		},
+		OnClimb: func(n *Node[string]) {
+			got = append(got, "climb "+n.Value)
+		},
+	})
+
+	_ = p.Push("A")
+	_ = p.Node("B")
+	_ = p.Climb()
+	// Climbing from the root is a no-op.
+	_ = p.Climb()
+	_ = p.Replace("C")
+
+	want := []string{
+		"node A",
+		"push A",
+		"node B",
+		"climb A",
+		"node C",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("hooks (-want, +got): \n%s", diff)
+	}
+}
+
+func TestParser_SetHooks_fold(t *testing.T) {
+	t.Parallel()
+
+	errFold := errors.New("fold")
+
+	p := NewParser[string](nil)
+	p.SetHooks(Hooks[string]{
+		Fold: func(parent, child *Node[string]) (string, error) {
+			if parent != child.Parent {
+				t.Errorf("Fold: parent: want: %v, got: %v", child.Parent, parent)
+			}
+
+			// Evaluate the operator and discard its operands.
+			result := 0
+			if child.Value == "*" {
+				result = 1
+			}
+			for _, c := range child.Children {
+				n, err := strconv.Atoi(c.Value)
+				if err != nil {
+					return "", errFold
+				}
+				if child.Value == "*" {
+					result *= n
+				} else {
+					result += n
+				}
+			}
+			child.Children = nil
+			return strconv.Itoa(result), nil
+		},
+	})
+
+	// (+ 1 2 (* 3 4))
+	_ = p.Push("+")
+	_ = p.Node("1")
+	_ = p.Node("2")
+	_ = p.Push("*")
+	_ = p.Node("3")
+	_ = p.Node("4")
+	_ = p.Climb()
+	if got, want := p.Pos().Children[2].Value, "12"; got != want {
+		t.Errorf("Fold: want: %q, got: %q", want, got)
+	}
+	_ = p.Climb()
+
+	want := newTree(&Node[string]{Value: "15"})
+	if diff := cmp.Diff(want, p.Root()); diff != "" {
+		t.Errorf("Fold: (-want, +got): \n%s", diff)
+	}
+
+	// Errors returned by Fold stop parsing.
+	_, err := p.Parse(context.Background(), func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		_ = p.Push("+")
+		_ = p.Node("x")
+		_ = p.Climb()
+		return nil, nil
+	})
+	if !errors.Is(err, errFold) {
+		t.Errorf("Parse: want: %v, got: %v", errFold, err)
+	}
+}
+
+func TestParser_limits(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		maxDepth int
+		maxNodes int
+		limit    string
+	}{
+		"no limits": {},
+		"depth": {
+			maxDepth: 2,
+			limit:    "depth",
+		},
+		"nodes": {
+			maxNodes: 3,
+			limit:    "node",
+		},
+		"within limits": {
+			maxDepth: 4,
+			maxNodes: 4,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lexemes, cancel := testLexer(t, "A B C D")
+			defer cancel()
+
+			p := NewParser[string](lexemes)
+			p.SetMaxDepth(tc.maxDepth)
+			p.SetMaxNodes(tc.maxNodes)
+
+			// Each word is nested below the previous word.
+			pFn := func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+				for l := p.Next(); l != nil; l = p.Next() {
+					_ = p.Push(l.Value)
+				}
+				return nil, nil
+			}
+
+			_, err := p.Parse(context.Background(), pFn)
+			if tc.limit == "" {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("Parse: want: %v, got: %v", ErrLimitExceeded, err)
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("Parse: want: *LimitError, got: %T", err)
+			}
+			if got, want := limitErr.Limit, tc.limit; got != want {
+				t.Errorf("Limit: want: %v, got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestParser_panic(t *testing.T) {
+	t.Parallel()
+
+	parseItem := NamedParseState("item", func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		if l := p.Next(); l != nil && l.Value == "bad" {
+			panic("bad item")
+		}
+		return nil, nil
+	})
+	parseDoc := func(ctx context.Context, p *Parser[string]) (ParseFn[string], error) {
+		for p.Peek() != nil {
+			if _, err := parseItem(ctx, p); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	t.Run("recover", func(t *testing.T) {
+		t.Parallel()
+
+		lexemes, cancel := testLexer(t, "a b bad c")
+		defer cancel()
+
+		p := NewParser[string](lexemes)
+		_, err := p.Parse(context.Background(), parseDoc)
+		if !errors.Is(err, ErrPanic) {
+			t.Fatalf("Parse: want: %v, got: %v", ErrPanic, err)
+		}
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("Parse: want: *PanicError, got: %T", err)
+		}
+		panicErr.Stack = nil
+		want := &PanicError{
+			Value:  "bad item",
+			State:  "item",
+			Column: 4,
+		}
+		if diff := cmp.Diff(want, panicErr); diff != "" {
+			t.Errorf("Parse: (-want, +got): \n%s", diff)
+		}
+		if len(p.StateStack()) != 0 {
+			t.Errorf("StateStack: want: [], got: %v", p.StateStack())
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		lexemes, cancel := testLexer(t, "bad")
+		defer cancel()
+
+		p := NewParser[string](lexemes)
+		p.SetRecoverPanics(false)
+		defer func() {
+			if r := recover(); r != "bad item" {
+				t.Errorf("recover: want: %q, got: %v", "bad item", r)
+			}
+		}()
+		_, _ = p.Parse(context.Background(), parseDoc)
+		t.Errorf("Parse: want: panic, got: none")
+	})
+
+	t.Run("unnamed", func(t *testing.T) {
+		t.Parallel()
+
+		lexemes, cancel := testLexer(t, "bad")
+		defer cancel()
+
+		p := NewParser[string](lexemes)
+		_, err := p.Parse(context.Background(), parsePanic)
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("Parse: want: *PanicError, got: %T", err)
+		}
+		if got, want := panicErr.State, "github.com/ianlewis/lexparse.parsePanic"; got != want {
+			t.Errorf("State: want: %v, got: %v", want, got)
+		}
+	})
+}
+
+// parsePanic is a parse function that panics.
+func parsePanic(_ context.Context, _ *Parser[string]) (ParseFn[string], error) {
+	panic("bad")
+}
+
+func TestParser_Warn(t *testing.T) {
+	t.Parallel()
+
+	lexemes, cancel := testLexer(t, "a old\nb old c")
+	defer cancel()
+
+	p := NewParser[string](lexemes)
+	_, err := p.Parse(context.Background(), func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		for l := p.Next(); l != nil; l = p.Next() {
+			if l.Value == "old" {
+				p.Warn(l, "deprecated word")
+			}
+			if l.Value == "b" {
+				// Warnings after a restored checkpoint are discarded.
+				cp := p.Checkpoint()
+				p.Next()
+				p.Warn(nil, "speculative")
+				p.Restore(cp)
+			}
+			p.Node(l.Value)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := []*Diagnostic{
+		{
+			Range: Range{
+				Start: Position{Line: 0, Column: 2},
+				End:   Position{Line: 0, Column: 5},
+			},
+			Severity: SeverityWarning,
+			Message:  "deprecated word",
+		},
+		{
+			Range: Range{
+				Start: Position{Line: 1, Column: 2},
+				End:   Position{Line: 1, Column: 5},
+			},
+			Severity: SeverityWarning,
+			Message:  "deprecated word",
+		},
+	}
+	if diff := cmp.Diff(want, p.Warnings()); diff != "" {
+		t.Errorf("Warnings: (-want, +got): \n%s", diff)
+	}
+
+	// Modifying the returned slice doesn't modify the Parser's warnings.
+	p.Warnings()[0] = nil
+	if diff := cmp.Diff(want, p.Warnings()); diff != "" {
+		t.Errorf("Warnings: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestNamedParseState(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input    string
+		maxNodes int
+		err      error
+		stack    []string
+	}{
+		"ok": {
+			input: "list a b",
+		},
+		"unexpected lexeme": {
+			input: "list a bad",
+			err:   ErrUnexpectedLexeme,
+			stack: []string{"document", "list", "item"},
+		},
+		"limit": {
+			input:    "list a b",
+			maxNodes: 2,
+			err:      ErrLimitExceeded,
+			stack:    []string{"document", "list", "item"},
+		},
+		"document": {
+			input: "item",
+			err:   ErrUnexpectedLexeme,
+			stack: []string{"document"},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lexemes, cancel := testLexer(t, tc.input)
+			defer cancel()
+
+			p := NewParser[string](lexemes)
+			p.SetMaxNodes(tc.maxNodes)
+
+			parseItem := NamedParseState("item", func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+				l := p.Next()
+				if l.Value == "bad" {
+					return nil, &LexemeError{Err: ErrUnexpectedLexeme, Lexeme: l}
+				}
+				_ = p.Node(l.Value)
+				return nil, nil
+			})
+			parseList := NamedParseState("list", func(ctx context.Context, p *Parser[string]) (ParseFn[string], error) {
+				_ = p.Push(p.Next().Value)
+				for p.Peek() != nil {
+					if _, err := parseItem(ctx, p); err != nil {
+						return nil, err
+					}
+				}
+				return nil, nil
+			})
+			parseDocument := NamedParseState("document", func(ctx context.Context, p *Parser[string]) (ParseFn[string], error) {
+				if l := p.Peek(); l.Value != "list" {
+					return nil, &LexemeError{Err: ErrUnexpectedLexeme, Lexeme: l}
+				}
+				return parseList(ctx, p)
+			})
+
+			_, err := p.Parse(context.Background(), parseDocument)
+			if got, want := len(p.StateStack()), 0; got != want {
+				t.Errorf("StateStack: want: %v, got: %v", want, got)
+			}
+			if tc.err == nil {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Parse: want: %v, got: %v", tc.err, err)
+			}
+			var stateErr *ParseStateError
+			if !errors.As(err, &stateErr) {
+				t.Fatalf("Parse: want: *ParseStateError, got: %T", err)
+			}
+			if diff := cmp.Diff(tc.stack, stateErr.Stack); diff != "" {
+				t.Errorf("Stack: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParser_Prev(t *testing.T) {
+	t.Parallel()
+
+	lexemes, cancel := testLexer(t, "A B C D E F G H I J")
+	defer cancel()
+
+	p := NewParser[string](lexemes)
+	if got := p.Prev(); got != nil {
+		t.Errorf("Prev: want: %v, got: %v", nil, got)
+	}
+
+	var n int
+	for p.Next() != nil {
+		n++
+	}
+	if got, want := n, 10; got != want {
+		t.Fatalf("Next: want: %v lexemes, got: %v", want, got)
+	}
+
+	if got, want := p.Prev().Value, "J"; got != want {
+		t.Errorf("Prev: want: %v, got: %v", want, got)
+	}
+	if got, want := p.PrevN(2).Value, "H"; got != want {
+		t.Errorf("PrevN(2): want: %v, got: %v", want, got)
+	}
+	if got, want := p.PrevN(7).Value, "C"; got != want {
+		t.Errorf("PrevN(7): want: %v, got: %v", want, got)
+	}
+	if got := p.PrevN(8); got != nil {
+		t.Errorf("PrevN(8): want: %v, got: %v", nil, got)
+	}
+}
+
+func TestNode_Strip(t *testing.T) {
+	t.Parallel()
+
+	root := newTree(
+		&Node[string]{
+			Value:    "A",
+			Pos:      1,
+			Children: []*Node[string]{{Value: "B", Line: 1}},
+		},
+	)
+	// NOTE: addParent doesn't support nil children.
+	root.Children[0].Children = append(root.Children[0].Children, nil)
+
+	got := root.Strip()
+	want := &Node[string]{
+		Children: []*Node[string]{
+			{
+				Value:    "A",
+				Pos:      1,
+				Children: []*Node[string]{{Value: "B", Line: 1}, nil},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Strip: (-want, +got): \n%s", diff)
+	}
+	if got.Children[0] == root.Children[0] {
+		t.Errorf("Strip: node was not copied")
+	}
+}
+
+func TestNode_json(t *testing.T) {
+	t.Parallel()
+
+	root := newTree(&Node[string]{Value: "A", Pos: 1})
+
+	b, err := json.Marshal(root)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	want := `{"Children":[{"Children":null,"Value":"A","Pos":1,"Line":0,"Column":0}],` +
+		`"Value":"","Pos":0,"Line":0,"Column":0}`
+	if got := string(b); got != want {
+		t.Errorf("json.Marshal: want: %v, got: %v", want, got)
+	}
+}
+
+func TestNode_Clone(t *testing.T) {
+	t.Parallel()
+
+	root := newTree(
+		&Node[string]{
+			Value: "A",
+			Children: []*Node[string]{
+				{Value: "B", Pos: 2},
+				{Value: "C", Line: 1},
+			},
+		},
+	)
+	orig := root.Children[0]
+
+	got := orig.Clone()
+	if got.Parent != nil {
+		t.Errorf("Clone: want detached node, got parent %v", got.Parent)
+	}
+	// The expected tree is also detached.
+	want := addParent(&Node[string]{
+		Value: "A",
+		Children: []*Node[string]{
+			{Value: "B", Pos: 2},
+			{Value: "C", Line: 1},
+		},
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Clone: (-want, +got): \n%s", diff)
+	}
+
+	for i, c := range got.Children {
+		if c == orig.Children[i] {
+			t.Errorf("Clone: child %d was not copied", i)
+		}
+		if c.Parent != got {
+			t.Errorf("Clone: child %d has wrong parent", i)
+		}
+	}
+}
+
+func TestNode_Meta(t *testing.T) {
+	t.Parallel()
+
+	n := &Node[string]{Value: "A"}
+	if _, ok := n.GetMeta("type"); ok {
+		t.Errorf("GetMeta: want missing key")
+	}
+
+	n.SetMeta("type", "int")
+	v, ok := n.GetMeta("type")
+	if !ok || v != "int" {
+		t.Errorf("GetMeta: want: %v, got: %v", "int", v)
+	}
+
+	// Copies don't share metadata.
+	c := n.Clone()
+	c.SetMeta("type", "string")
+	if v, _ := n.GetMeta("type"); v != "int" {
+		t.Errorf("GetMeta: want: %v, got: %v", "int", v)
+	}
+}
+
+func TestParser_ClimbTo(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser[string](nil)
+	a := p.Push("(")
+	_ = p.Push("B")
+	_ = p.Push("C")
+
+	isParen := func(n *Node[string]) bool {
+		return n.Value == "("
+	}
+
+	got, err := p.ClimbTo(isParen)
+	if err != nil {
+		t.Fatalf("ClimbTo: %v", err)
+	}
+	if got != a || p.Pos() != a {
+		t.Errorf("ClimbTo: want: %v, got: %v", a, got)
+	}
+
+	_ = p.Climb()
+	if _, err := p.ClimbTo(isParen); !errors.Is(err, ErrNoAncestor) {
+		t.Errorf("ClimbTo: want: %v, got: %v", ErrNoAncestor, err)
+	}
+	if got, want := p.Pos(), p.Root(); got != want {
+		t.Errorf("Pos: want: %v, got: %v", want, got)
+	}
+}
+
+func TestParser_ClimbWhile(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser[string](nil)
+	a := p.Push("A")
+	_ = p.Push("x")
+	_ = p.Push("y")
+
+	got := p.ClimbWhile(func(n *Node[string]) bool {
+		return n.Value != "A"
+	})
+	if got != a || p.Pos() != a {
+		t.Errorf("ClimbWhile: want: %v, got: %v", a, got)
+	}
+
+	// Climbing stops at the root.
+	got = p.ClimbWhile(func(*Node[string]) bool {
+		return true
+	})
+	if got != p.Root() {
+		t.Errorf("ClimbWhile: want: %v, got: %v", p.Root(), got)
+	}
+}
+
+func TestParser_PushBoundary(t *testing.T) {
+	t.Parallel()
+
+	lexemes, cancel := testLexer(t, "A B ) ) C")
+	defer cancel()
+
+	p := NewParser[string](lexemes)
+	pFn := func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		_ = p.Push(p.Next().Value)
+		p.PushBoundary()
+		for l := p.Next(); l != nil; l = p.Next() {
+			if l.Value == ")" {
+				_ = p.Climb()
+				continue
+			}
+			_ = p.Push(l.Value)
+		}
+		return nil, nil
+	}
+
+	_, err := p.Parse(context.Background(), pFn)
+	if !errors.Is(err, ErrBoundary) {
+		t.Fatalf("Parse: want: %v, got: %v", ErrBoundary, err)
+	}
+	if got, want := p.Pos().Value, "A"; got != want {
+		t.Errorf("Pos: want: %v, got: %v", want, got)
+	}
+
+	if _, err := p.ClimbTo(func(n *Node[string]) bool {
+		return n == p.Root()
+	}); !errors.Is(err, ErrNoAncestor) {
+		t.Errorf("ClimbTo: want: %v, got: %v", ErrNoAncestor, err)
+	}
+
+	if got, want := p.PopBoundary().Value, "A"; got != want {
+		t.Errorf("PopBoundary: want: %v, got: %v", want, got)
+	}
+	if got := p.PopBoundary(); got != nil {
+		t.Errorf("PopBoundary: want: nil, got: %v", got)
+	}
+}
+
+func TestParser_AtEOF(t *testing.T) {
+	t.Parallel()
+
+	lexemes, cancel := testLexer(t, "A")
+	defer cancel()
+
+	p := NewParser[string](lexemes)
+	if p.AtEOF() {
+		t.Errorf("AtEOF: want: false, got: true")
+	}
+	if l := p.Next(); l.IsEOF() {
+		t.Errorf("IsEOF: want: false, got: true")
+	}
+	if !p.AtEOF() {
+		t.Errorf("AtEOF: want: true, got: false")
+	}
+	if l := p.Next(); !l.IsEOF() {
+		t.Errorf("IsEOF: want: true, got: false")
+	}
+}
+
+func TestParser_SetLexerErr(t *testing.T) {
+	t.Parallel()
+
+	errLex := errors.New("lexer error")
+
+	l := NewLexer(runeio.NewReader(strings.NewReader("A B")), StateFn(
+		func(_ context.Context, l *Lexer) (State, error) {
+			if _, err := l.Advance(1); err != nil {
+				return nil, err
+			}
+			l.Emit(l.Lexeme(wordType))
+			return nil, errLex
+		},
+	))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := NewParser[string](l.Lex(ctx))
+	p.SetLexerErr(l.Err)
+
+	pFn := func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		for l := p.Next(); l != nil; l = p.Next() {
+			_ = p.Node(l.Value)
+		}
+		return nil, nil
+	}
+	root, err := p.Parse(ctx, pFn)
+	if !errors.Is(err, errLex) {
+		t.Errorf("Parse: want: %v, got: %v", errLex, err)
+	}
+	if diff := cmp.Diff(newTree(&Node[string]{Value: "A"}), root); diff != "" {
+		t.Errorf("Parse: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestParser_SetRequireEOF(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		err   error
+	}{
+		"consumed": {
+			input: "A",
+		},
+		"trailing": {
+			input: "A B",
+			err:   ErrTrailingInput,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lexemes, cancel := testLexer(t, tc.input)
+			defer cancel()
+
+			p := NewParser[string](lexemes)
+			p.SetRequireEOF(true)
+
+			// Parse a single word.
+			pFn := func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+				_ = p.Node(p.Next().Value)
+				return nil, nil
+			}
+			_, err := p.Parse(context.Background(), pFn)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestParser_SetTerminators(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser[string](lexemeChan(
+		&Lexeme{Type: identType, Value: "a"},
+		&Lexeme{Type: identType, Value: "b"},
+		&Lexeme{Type: semiType, Value: ";;"},
+		&Lexeme{Type: identType, Value: "c"},
+	))
+	p.SetTerminators(semiType)
+	p.SetRequireEOF(true)
+
+	// Parse all words until the end of input.
+	pFn := func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		for l := p.Next(); l != nil; l = p.Next() {
+			_ = p.Node(l.Value)
+		}
+		return nil, nil
+	}
+
+	if _, err := p.Parse(context.Background(), pFn); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !p.AtEOF() {
+		t.Errorf("AtEOF: want: %v, got: %v", true, p.AtEOF())
+	}
+	if got, want := values(p.Root().Children), []string{"a", "b"}; !cmp.Equal(want, got) {
+		t.Errorf("Parse: want: %v, got: %v", want, got)
+	}
+
+	term := p.SkipTerminator()
+	if term == nil || term.Value != ";;" {
+		t.Fatalf("SkipTerminator: want: %q, got: %v", ";;", term)
+	}
+	if got := p.Terminator(); got != nil {
+		t.Errorf("Terminator: want: nil, got: %v", got)
+	}
+
+	if _, err := p.Parse(context.Background(), pFn); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, want := values(p.Root().Children), []string{"a", "b", "c"}; !cmp.Equal(want, got) {
+		t.Errorf("Parse: want: %v, got: %v", want, got)
+	}
+	if got := p.SkipTerminator(); got != nil {
+		t.Errorf("SkipTerminator: want: nil, got: %v", got)
+	}
+}
+
+func TestParser_ParseAll(t *testing.T) {
+	t.Parallel()
+
+	// Parse all words until the end of input.
+	parseWords := func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		for l := p.Next(); l != nil; l = p.Next() {
+			_ = p.Node(l.Value)
+		}
+		return nil, nil
+	}
+
+	// Parse a single word.
+	parseOne := func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		_ = p.Node(p.Next().Value)
+		return nil, nil
+	}
+
+	t.Run("terminators", func(t *testing.T) {
+		t.Parallel()
+
+		p := NewParser[string](lexemeChan(
+			&Lexeme{Type: semiType, Value: ";;"},
+			&Lexeme{Type: identType, Value: "a"},
+			&Lexeme{Type: identType, Value: "b"},
+			&Lexeme{Type: semiType, Value: ";;"},
+			&Lexeme{Type: semiType, Value: ";;"},
+			&Lexeme{Type: identType, Value: "c"},
+			&Lexeme{Type: semiType, Value: ";;"},
+		))
+		p.SetTerminators(semiType)
+
+		roots, err := p.ParseAll(context.Background(), parseWords)
+		if err != nil {
+			t.Fatalf("ParseAll: %v", err)
+		}
+		var got [][]string
+		for _, root := range roots {
+			got = append(got, values(root.Children))
+		}
+		if diff := cmp.Diff([][]string{{"a", "b"}, {"c"}}, got); diff != "" {
+			t.Errorf("ParseAll: (-want, +got): \n%s", diff)
+		}
+	})
+
+	t.Run("items", func(t *testing.T) {
+		t.Parallel()
+
+		p := NewParser[string](lexemeChan(
+			&Lexeme{Type: identType, Value: "a"},
+			&Lexeme{Type: identType, Value: "b"},
+		))
+		p.SetRequireEOF(true)
+
+		roots, err := p.ParseAll(context.Background(), parseOne)
+		if err != nil {
+			t.Fatalf("ParseAll: %v", err)
+		}
+		if got, want := len(roots), 2; got != want {
+			t.Fatalf("ParseAll: want: %v roots, got: %v", want, got)
+		}
+		if got, want := values(roots[1].Children), []string{"b"}; !cmp.Equal(want, got) {
+			t.Errorf("ParseAll: want: %v, got: %v", want, got)
+		}
+		if got, want := p.Root(), roots[1]; got != want {
+			t.Errorf("Root: want: %v, got: %v", want, got)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+
+		p := NewParser[string](lexemeChan())
+		roots, err := p.ParseAll(context.Background(), parseOne)
+		if err != nil {
+			t.Fatalf("ParseAll: %v", err)
+		}
+		if len(roots) != 0 {
+			t.Errorf("ParseAll: want: no roots, got: %v", roots)
+		}
+	})
+
+	t.Run("no progress", func(t *testing.T) {
+		t.Parallel()
+
+		p := NewParser[string](lexemeChan(
+			&Lexeme{Type: identType, Value: "a"},
+		))
+		roots, err := p.ParseAll(context.Background(), func(context.Context, *Parser[string]) (ParseFn[string], error) {
+			return nil, nil
+		})
+		if !errors.Is(err, ErrNoProgress) {
+			t.Errorf("ParseAll: want: %v, got: %v", ErrNoProgress, err)
+		}
+		if got, want := len(roots), 1; got != want {
+			t.Errorf("ParseAll: want: %v roots, got: %v", want, got)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		p := NewParser[string](lexemeChan(
+			&Lexeme{Type: identType, Value: "a"},
+			&Lexeme{Type: identType, Value: "b"},
+		))
+		errItem := errors.New("item error")
+		roots, err := p.ParseAll(context.Background(), func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+			l := p.Next()
+			_ = p.Node(l.Value)
+			if l.Value == "b" {
+				return nil, errItem
+			}
+			return nil, nil
+		})
+		if !errors.Is(err, errItem) {
+			t.Errorf("ParseAll: want: %v, got: %v", errItem, err)
+		}
+		if got, want := len(roots), 2; got != want {
+			t.Errorf("ParseAll: want: %v roots, got: %v", want, got)
+		}
+	})
+}
+
+func TestParser_SetStrictValues(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		strict bool
+		zero   bool
+		err    error
+	}{
+		"not strict": {
+			strict: false,
+		},
+		"strict": {
+			strict: true,
+			err:    ErrZeroValue,
+		},
+		"strict zero": {
+			strict: true,
+			zero:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewParser[*string](lexemeChan(
+				&Lexeme{Type: identType, Value: "a", Line: 1, Column: 2},
+				&Lexeme{Type: identType, Value: "b"},
+			))
+			p.SetStrictValues(tc.strict)
+
+			// Push the first lexeme's value and add an empty node.
+			pFn := func(_ context.Context, p *Parser[*string]) (ParseFn[*string], error) {
+				v := p.Next().Value
+				_ = p.Push(&v)
+				if tc.zero {
+					_ = p.NodeZero()
+					_ = p.PushZero()
+				} else {
+					_ = p.Node(nil)
+					_ = p.Push(nil)
+				}
+				return nil, nil
+			}
+
+			root, err := p.Parse(context.Background(), pFn)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Parse: want: %v, got: %v", tc.err, err)
+			}
+
+			a := root.Children[0]
+			if err != nil {
+				if got, want := err.Error(), "zero node value: line 2, column 3"; got != want {
+					t.Errorf("Parse: want: %q, got: %q", want, got)
+				}
+				if got := len(a.Children); got != 0 {
+					t.Errorf("Children: want: 0, got: %v", got)
+				}
+				if got := p.Next(); got != nil {
+					t.Errorf("Next: want: nil, got: %v", got)
+				}
+				return
+			}
+			if got, want := len(a.Children), 2; got != want {
+				t.Errorf("Children: want: %v, got: %v", want, got)
+			}
+			if got, want := p.Pos(), a.Children[1]; got != want {
+				t.Errorf("Pos: want: %v, got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestParser_Reset(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser[string](nil)
+	p.SetMaxNodes(2)
+
+	for _, input := range []string{"A B", "C D"} {
+		lexemes, cancel := testLexer(t, input)
+		p.Reset(lexemes)
+
+		root, err := p.Parse(context.Background(), wordsParseFn)
+		cancel()
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+
+		want := newTree(
+			&Node[string]{Value: input[:1]},
+			&Node[string]{Value: input[2:], Pos: 2, Column: 2},
+		)
+		if diff := cmp.Diff(want, root); diff != "" {
+			t.Errorf("Parse: (-want, +got): \n%s", diff)
+		}
+	}
+}
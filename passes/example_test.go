@@ -0,0 +1,85 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package passes_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/scanner"
+
+	"github.com/ianlewis/lexparse"
+	"github.com/ianlewis/lexparse/passes"
+)
+
+// prefix returns the expression rooted at n in prefix notation.
+func prefix(n *lexparse.Node[string]) string {
+	if len(n.Children) == 0 {
+		return n.Value
+	}
+	parts := []string{n.Value}
+	for _, c := range n.Children {
+		parts = append(parts, prefix(c))
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func Example() {
+	e := &lexparse.ExprParser[string]{
+		Operators: lexparse.NewOperatorTable().
+			Infix('+', 1, lexparse.AssocLeft).
+			Infix('-', 1, lexparse.AssocLeft).
+			Infix('*', 2, lexparse.AssocLeft).
+			Prefix('-', 3),
+		Value: func(l *lexparse.Lexeme) (string, error) {
+			return l.Value, nil
+		},
+	}
+	e.Operand = func(ctx context.Context, p *lexparse.Parser[string]) (*lexparse.Node[string], error) {
+		l := p.Next()
+		if l == nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if l.Type != '(' {
+			return p.NewNode(l.Value), nil
+		}
+		n, err := e.Expr(ctx, p, 0)
+		if r := p.Next(); r == nil || r.Type != ')' {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return n, err
+	}
+
+	ctx := context.Background()
+	l := lexparse.NewScanningLexer(strings.NewReader("x * (2 + 3) - -1"), scanner.ScanIdents|scanner.ScanInts)
+	p := lexparse.NewParser[string](l.Lex(ctx))
+	p.SetLexerErr(l.Err)
+	root, err := p.Parse(ctx, func(ctx context.Context, p *lexparse.Parser[string]) (lexparse.ParseFn[string], error) {
+		_, err := e.Parse(ctx, p)
+		return nil, err
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	root, err = passes.Run(root, passes.FoldConstants())
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(prefix(root.Children[0]))
+
+	// Output: (- (* x 5) -1)
+}
@@ -0,0 +1,214 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package passes runs a sequence of passes over a parse tree, as in a
+// multi-pass compiler built on lexparse.
+//
+// Each Pass takes the root of the tree and returns the root of the
+// transformed tree, which is passed to the next pass.
+//
+//	root, err := passes.Run(root,
+//		passes.Pass[string]{Name: "resolve", Run: resolve},
+//		passes.FoldConstants(),
+//	)
+//
+// Rewrite creates a pass that replaces nodes bottom-up. FoldConstants is a
+// reference pass built with Rewrite that folds arithmetic on constants in
+// trees built by lexparse.ExprParser.
+package passes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ianlewis/lexparse"
+)
+
+// Pass is a named transformation of a parse tree.
+type Pass[V comparable] struct {
+	// Name is the name of the pass used in errors.
+	Name string
+
+	// Run transforms the tree rooted at root and returns the root of the
+	// transformed tree. It may edit the tree in place and return root.
+	Run func(root *lexparse.Node[V]) (*lexparse.Node[V], error)
+}
+
+// Run runs the passes in order over the tree rooted at root and returns the
+// root of the resulting tree. If a pass returns an error, Run stops and
+// returns the error wrapped with the name of the pass and the root returned
+// by the previous pass.
+func Run[V comparable](root *lexparse.Node[V], passes ...Pass[V]) (*lexparse.Node[V], error) {
+	for _, p := range passes {
+		next, err := p.Run(root)
+		if err != nil {
+			return root, fmt.Errorf("pass %s: %w", p.Name, err)
+		}
+		root = next
+	}
+	return root, nil
+}
+
+// Rewrite returns a pass that calls fn for each node in the tree in
+// post-order, i.e. after the node's children have been rewritten, and
+// replaces the node with the node returned by fn. If fn returns nil the node
+// is removed from its parent. The tree is edited in place.
+func Rewrite[V comparable](name string, fn func(n *lexparse.Node[V]) (*lexparse.Node[V], error)) Pass[V] {
+	return Pass[V]{
+		Name: name,
+		Run: func(root *lexparse.Node[V]) (*lexparse.Node[V], error) {
+			return rewrite(root, fn)
+		},
+	}
+}
+
+// rewrite rewrites the tree rooted at n with fn.
+func rewrite[V comparable](
+	n *lexparse.Node[V],
+	fn func(n *lexparse.Node[V]) (*lexparse.Node[V], error),
+) (*lexparse.Node[V], error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	children := n.Children[:0]
+	for _, c := range n.Children {
+		rc, err := rewrite(c, fn)
+		if err != nil {
+			return nil, err
+		}
+		if rc == nil {
+			continue
+		}
+		rc.Parent = n
+		children = append(children, rc)
+	}
+	n.Children = children
+
+	parent := n.Parent
+	r, err := fn(n)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil {
+		r.Parent = parent
+	}
+	return r, nil
+}
+
+// FoldConstants returns a pass that folds arithmetic on constants in
+// expression trees built by lexparse.ExprParser with the source text of each
+// lexeme as the value. Operator nodes have the operator as their value and
+// their operands as children.
+//
+// The binary operators +, -, *, /, %, and ^ (exponentiation) and the unary
+// operators + and - are folded when all of their operands are numbers. Each
+// folded node is replaced by a number node at the position of the
+// expression. Division and remainder by zero and operations whose result is
+// not finite are not folded so that they are handled when the expression is
+// evaluated.
+func FoldConstants() Pass[string] {
+	return Rewrite("fold constants", foldConstant)
+}
+
+// foldConstant returns a number node for n if it is an operator node with
+// constant operands and n otherwise.
+func foldConstant(n *lexparse.Node[string]) (*lexparse.Node[string], error) {
+	var operands []float64
+	for _, c := range n.Children {
+		x, ok := number(c)
+		if !ok {
+			return n, nil
+		}
+		operands = append(operands, x)
+	}
+
+	var result float64
+	switch len(operands) {
+	case 1:
+		switch n.Value {
+		case "+":
+			result = operands[0]
+		case "-":
+			result = -operands[0]
+		default:
+			return n, nil
+		}
+	case 2:
+		x, y := operands[0], operands[1]
+		switch n.Value {
+		case "+":
+			result = x + y
+		case "-":
+			result = x - y
+		case "*":
+			result = x * y
+		case "/", "%":
+			if y == 0 {
+				return n, nil
+			}
+			if n.Value == "/" {
+				result = x / y
+			} else {
+				result = math.Mod(x, y)
+			}
+		case "^":
+			result = math.Pow(x, y)
+		default:
+			return n, nil
+		}
+	default:
+		return n, nil
+	}
+	if math.IsInf(result, 0) || math.IsNaN(result) {
+		return n, nil
+	}
+
+	// The folded expression starts at the operator of a unary expression or
+	// at the left operand of a binary expression.
+	start := n
+	if len(n.Children) == 2 {
+		start = n.Children[0]
+	}
+	return &lexparse.Node[string]{
+		Value:  strconv.FormatFloat(result, 'g', -1, 64),
+		Pos:    start.Pos,
+		Line:   start.Line,
+		Column: start.Column,
+	}, nil
+}
+
+// number returns the value of n if it is a number literal.
+func number(n *lexparse.Node[string]) (float64, bool) {
+	if len(n.Children) > 0 || n.Value == "" {
+		return 0, false
+	}
+	// NOTE: Only values starting with a digit or decimal point, after the
+	//       sign of a folded negative number, are numbers so that
+	//       identifiers such as "Inf" and "NaN" are not folded.
+	digits := strings.TrimPrefix(n.Value, "-")
+	if digits == "" {
+		return 0, false
+	}
+	if c := digits[0]; (c < '0' || c > '9') && c != '.' {
+		return 0, false
+	}
+	x, err := strconv.ParseFloat(n.Value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return x, true
+}
@@ -0,0 +1,230 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package passes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ianlewis/lexparse"
+)
+
+// tree returns a node with the value v and the given children.
+func tree(v string, children ...*lexparse.Node[string]) *lexparse.Node[string] {
+	n := &lexparse.Node[string]{Value: v}
+	for _, c := range children {
+		c.Parent = n
+		n.Children = append(n.Children, c)
+	}
+	return n
+}
+
+// prefix returns the tree rooted at n in prefix notation.
+func prefix(n *lexparse.Node[string]) string {
+	if len(n.Children) == 0 {
+		return n.Value
+	}
+	parts := []string{n.Value}
+	for _, c := range n.Children {
+		parts = append(parts, prefix(c))
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// checkParents returns false if the parent of a node below n is not set
+// correctly.
+func checkParents(n *lexparse.Node[string]) bool {
+	for _, c := range n.Children {
+		if c.Parent != n || !checkParents(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	pass := func(name string) Pass[string] {
+		return Pass[string]{
+			Name: name,
+			Run: func(root *lexparse.Node[string]) (*lexparse.Node[string], error) {
+				order = append(order, name)
+				return tree(name, root), nil
+			},
+		}
+	}
+
+	root, err := Run(tree("a"), pass("b"), pass("c"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got, want := prefix(root), "(c (b a))"; got != want {
+		t.Errorf("Run: want: %q, got: %q", want, got)
+	}
+	if got, want := strings.Join(order, ","), "b,c"; got != want {
+		t.Errorf("order: want: %q, got: %q", want, got)
+	}
+}
+
+func TestRun_error(t *testing.T) {
+	t.Parallel()
+
+	errFail := errors.New("fail")
+	ran := false
+	root := tree("a")
+	got, err := Run(root,
+		Pass[string]{
+			Name: "fail",
+			Run: func(*lexparse.Node[string]) (*lexparse.Node[string], error) {
+				return nil, errFail
+			},
+		},
+		Pass[string]{
+			Name: "next",
+			Run: func(root *lexparse.Node[string]) (*lexparse.Node[string], error) {
+				ran = true
+				return root, nil
+			},
+		},
+	)
+	if !errors.Is(err, errFail) {
+		t.Errorf("Run: want: %v, got: %v", errFail, err)
+	}
+	if want := "pass fail: fail"; err == nil || err.Error() != want {
+		t.Errorf("Run: want: %q, got: %v", want, err)
+	}
+	if got != root {
+		t.Errorf("Run: want: root, got: %v", got)
+	}
+	if ran {
+		t.Errorf("Run: pass after error was run")
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	t.Parallel()
+
+	// Remove "x" nodes and replace "y" nodes with "z".
+	pass := Rewrite("rewrite", func(n *lexparse.Node[string]) (*lexparse.Node[string], error) {
+		switch n.Value {
+		case "x":
+			return nil, nil
+		case "y":
+			return tree("z", n.Children...), nil
+		}
+		return n, nil
+	})
+
+	root, err := Run(tree("", tree("a", tree("x"), tree("y", tree("b"))), tree("x", tree("y"))), pass)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got, want := prefix(root), "( (a (z b)))"; got != want {
+		t.Errorf("Rewrite: want: %q, got: %q", want, got)
+	}
+	if !checkParents(root) {
+		t.Errorf("Rewrite: invalid parent")
+	}
+}
+
+func TestFoldConstants(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		tree *lexparse.Node[string]
+		want string
+	}{
+		"binary": {
+			tree: tree("+", tree("1"), tree("2")),
+			want: "3",
+		},
+		"nested": {
+			tree: tree("*", tree("+", tree("1"), tree("2")), tree("^", tree("2"), tree("3"))),
+			want: "24",
+		},
+		"partial": {
+			tree: tree("+", tree("x"), tree("*", tree("2"), tree("3.5"))),
+			want: "(+ x 7)",
+		},
+		"unary minus": {
+			tree: tree("-", tree("4"), tree("-", tree("2"))),
+			want: "6",
+		},
+		"remainder": {
+			tree: tree("%", tree("7"), tree("4")),
+			want: "3",
+		},
+		"division by zero": {
+			tree: tree("/", tree("1"), tree("-", tree("1"), tree("1"))),
+			want: "(/ 1 0)",
+		},
+		"not finite": {
+			tree: tree("^", tree("10"), tree("1000")),
+			want: "(^ 10 1000)",
+		},
+		"identifiers": {
+			tree: tree("+", tree("Inf"), tree("NaN")),
+			want: "(+ Inf NaN)",
+		},
+		"call": {
+			tree: tree("call", tree("f"), tree("1")),
+			want: "(call f 1)",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			root, err := Run(tree("", tc.tree), FoldConstants())
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := prefix(root.Children[0]); got != tc.want {
+				t.Errorf("FoldConstants: want: %q, got: %q", tc.want, got)
+			}
+			if !checkParents(root) {
+				t.Errorf("FoldConstants: invalid parent")
+			}
+		})
+	}
+}
+
+func TestFoldConstants_position(t *testing.T) {
+	t.Parallel()
+
+	left := &lexparse.Node[string]{Value: "1", Pos: 4, Line: 1, Column: 2}
+	op := &lexparse.Node[string]{Value: "+", Pos: 6, Line: 1, Column: 4}
+	right := &lexparse.Node[string]{Value: "2", Pos: 8, Line: 1, Column: 6}
+	op.Children = []*lexparse.Node[string]{left, right}
+	left.Parent, right.Parent = op, op
+
+	root, err := Run(tree("", op), FoldConstants())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	n := root.Children[0]
+	if n.Pos != left.Pos || n.Line != left.Line || n.Column != left.Column {
+		t.Errorf("FoldConstants: want: %d %d:%d, got: %d %d:%d",
+			left.Pos, left.Line, left.Column, n.Pos, n.Line, n.Column)
+	}
+	if n.Parent != root {
+		t.Errorf("Parent: want: root, got: %v", n.Parent)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+)
+
+// Rule is a parsing rule for PEG-style parsers. It returns true if the rule
+// matched. A rule that doesn't match may leave the parser in any state; the
+// combinators in this package restore the parser using checkpoints. A rule
+// that matches must leave the current node unchanged.
+type Rule[V comparable] func(ctx context.Context, p *Parser[V]) (bool, error)
+
+// Choice returns a Rule that implements PEG ordered choice. Each rule is tried
+// in order and the first rule to match is used. The parser is restored to its
+// prior state after each rule that doesn't match.
+func Choice[V comparable](rules ...Rule[V]) Rule[V] {
+	return func(ctx context.Context, p *Parser[V]) (bool, error) {
+		for _, r := range rules {
+			ok, err := attempt(ctx, p, r)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+}
+
+// attempt runs r restoring the parser if r doesn't match.
+func attempt[V comparable](ctx context.Context, p *Parser[V], r Rule[V]) (bool, error) {
+	select {
+	case <-ctx.Done():
+		//nolint:wrapcheck // We don't need to wrap the context Error.
+		return false, ctx.Err()
+	default:
+	}
+
+	cp := p.Checkpoint()
+	ok, err := r(ctx, p)
+	if err != nil || ok {
+		p.Release(cp)
+		return ok, err
+	}
+	p.Restore(cp)
+	return false, nil
+}
+
+// packratKey identifies the result of a rule at a position in the input.
+type packratKey struct {
+	// rule is the name of the rule.
+	rule string
+
+	// index is the index of the first lexeme processed by the rule.
+	index int
+}
+
+// packratResult is a memoized rule result.
+type packratResult[V comparable] struct {
+	// ok is true if the rule matched.
+	ok bool
+
+	// length is the number of lexemes consumed by the rule.
+	length int
+
+	// nodes are the nodes added to the current node by the rule.
+	nodes []*Node[V]
+}
+
+// Packrat memoizes the results of rules so that backtracking parsers run in
+// linear time. Results are keyed by rule name and the index of the lexeme in
+// the input so a Packrat must only be used with a single Parser.
+type Packrat[V comparable] struct {
+	memo map[packratKey]*packratResult[V]
+}
+
+// NewPackrat returns a new Packrat with no memoized results.
+func NewPackrat[V comparable]() *Packrat[V] {
+	return &Packrat[V]{
+		memo: map[packratKey]*packratResult[V]{},
+	}
+}
+
+// Memo returns a Rule that memoizes the result of r. If the rule has already
+// been applied at the current position in the input, the parser is advanced
+// past the lexemes matched and the nodes created by the rule are added to
+// the current node without running r.
+func (m *Packrat[V]) Memo(name string, r Rule[V]) Rule[V] {
+	return func(ctx context.Context, p *Parser[V]) (bool, error) {
+		key := packratKey{rule: name, index: p.consumed}
+		if res, ok := m.memo[key]; ok {
+			if !res.ok {
+				return false, nil
+			}
+			for i := 0; i < res.length; i++ {
+				_ = p.Next()
+			}
+			for _, n := range res.nodes {
+				p.addChild(n)
+			}
+			return true, nil
+		}
+
+		node := p.node
+		start := len(node.Children)
+		ok, err := attempt(ctx, p, r)
+		if err != nil {
+			return false, err
+		}
+
+		res := &packratResult[V]{ok: ok}
+		if ok {
+			res.length = p.consumed - key.index
+			res.nodes = append([]*Node[V](nil), node.Children[start:]...)
+		}
+		m.memo[key] = res
+		return ok, nil
+	}
+}
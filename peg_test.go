@@ -0,0 +1,118 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// word returns a Rule that matches a word with the given value.
+func word(v string) Rule[string] {
+	return func(_ context.Context, p *Parser[string]) (bool, error) {
+		l := p.Peek()
+		if l == nil || l.Value != v {
+			return false, nil
+		}
+		_ = p.Node(p.Next().Value)
+		return true, nil
+	}
+}
+
+// seq returns a Rule that matches each rule in order.
+func seq(rules ...Rule[string]) Rule[string] {
+	return func(ctx context.Context, p *Parser[string]) (bool, error) {
+		for _, r := range rules {
+			ok, err := r(ctx, p)
+			if err != nil || !ok {
+				return ok, err
+			}
+		}
+		return true, nil
+	}
+}
+
+func TestPackrat(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		input string
+		ok    bool
+		want  *Node[string]
+	}{
+		"first choice": {
+			input: "a a x",
+			ok:    true,
+			want: newTree(
+				&Node[string]{Value: "a"},
+				&Node[string]{Value: "a", Pos: 2, Column: 2},
+				&Node[string]{Value: "x", Pos: 4, Column: 4},
+			),
+		},
+		"second choice": {
+			input: "a a y",
+			ok:    true,
+			want: newTree(
+				&Node[string]{Value: "a"},
+				&Node[string]{Value: "a", Pos: 2, Column: 2},
+				&Node[string]{Value: "y", Pos: 4, Column: 4},
+			),
+		},
+		"no match": {
+			input: "a a z",
+			want:  newTree[string](),
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lexemes, cancel := testLexer(t, tc.input)
+			defer cancel()
+			p := NewParser[string](lexemes)
+
+			// S <- A "x" / A "y"
+			// A <- "a" A / "a"
+			var calls int
+			m := NewPackrat[string]()
+			var a Rule[string]
+			a = m.Memo("A", func(ctx context.Context, p *Parser[string]) (bool, error) {
+				calls++
+				return Choice(seq(word("a"), a), word("a"))(ctx, p)
+			})
+			s := Choice(seq(a, word("x")), seq(a, word("y")))
+
+			ok, err := s(context.Background(), p)
+			if err != nil {
+				t.Fatalf("s: %v", err)
+			}
+			if got, want := ok, tc.ok; got != want {
+				t.Errorf("s: want: %v, got: %v", want, got)
+			}
+			if diff := cmp.Diff(tc.want, p.Root()); diff != "" {
+				t.Errorf("Root: (-want, +got): \n%s", diff)
+			}
+
+			// A is run once at each of the three positions.
+			if got, want := calls, 3; got != want {
+				t.Errorf("calls: want: %v, got: %v", want, got)
+			}
+		})
+	}
+}
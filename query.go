@@ -0,0 +1,167 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrQuery indicates that a query path is invalid.
+var ErrQuery = errors.New("invalid query")
+
+// Predicate reports whether a node matches.
+type Predicate[V comparable] func(n *Node[V]) bool
+
+// ByValue returns a Predicate that matches nodes with the value v.
+func ByValue[V comparable](v V) Predicate[V] {
+	return func(n *Node[V]) bool {
+		return n.Value == v
+	}
+}
+
+// Any returns a Predicate that matches all nodes.
+func Any[V comparable]() Predicate[V] {
+	return func(*Node[V]) bool {
+		return true
+	}
+}
+
+// Step selects nodes relative to a set of nodes. Steps are combined using
+// Find.
+type Step[V comparable] func(nodes []*Node[V]) []*Node[V]
+
+// Child returns a Step that selects the children of each node that match p.
+func Child[V comparable](p Predicate[V]) Step[V] {
+	return func(nodes []*Node[V]) []*Node[V] {
+		var result []*Node[V]
+		for _, n := range nodes {
+			for _, c := range n.Children {
+				if c != nil && p(c) {
+					result = append(result, c)
+				}
+			}
+		}
+		return result
+	}
+}
+
+// Descendant returns a Step that selects the descendants of each node that
+// match p.
+func Descendant[V comparable](p Predicate[V]) Step[V] {
+	return func(nodes []*Node[V]) []*Node[V] {
+		var result []*Node[V]
+		var walk func(n *Node[V])
+		walk = func(n *Node[V]) {
+			for _, c := range n.Children {
+				if c == nil {
+					continue
+				}
+				if p(c) {
+					result = append(result, c)
+				}
+				walk(c)
+			}
+		}
+		for _, n := range nodes {
+			walk(n)
+		}
+		return result
+	}
+}
+
+// Where returns a Step that selects the nodes that match p.
+func Where[V comparable](p Predicate[V]) Step[V] {
+	return func(nodes []*Node[V]) []*Node[V] {
+		var result []*Node[V]
+		for _, n := range nodes {
+			if p(n) {
+				result = append(result, n)
+			}
+		}
+		return result
+	}
+}
+
+// Find applies each step in order starting with root and returns the
+// selected nodes. Each node is returned at most once.
+func Find[V comparable](root *Node[V], steps ...Step[V]) []*Node[V] {
+	if root == nil {
+		return nil
+	}
+	nodes := []*Node[V]{root}
+	for _, s := range steps {
+		nodes = unique(s(nodes))
+	}
+	return nodes
+}
+
+// unique removes duplicate nodes preserving order.
+func unique[V comparable](nodes []*Node[V]) []*Node[V] {
+	seen := make(map[*Node[V]]bool, len(nodes))
+	result := nodes[:0]
+	for _, n := range nodes {
+		if !seen[n] {
+			seen[n] = true
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// Query returns the nodes below root selected by path. A path is a list of
+// names separated by "/" which select children of the previously selected
+// nodes whose value, formatted using the %v verb, is equal to the name. A
+// name of "*" matches any node. Names separated by "//" select descendants
+// rather than children. For example, "a//b/*" selects all children of nodes
+// with the value "b" that are descendants of children of root with the value
+// "a". Paths may start with "/" and a path starting with "//" selects
+// descendants of root.
+func Query[V comparable](root *Node[V], path string) ([]*Node[V], error) {
+	var steps []Step[V]
+	descendant := false
+	for i, name := range strings.Split(path, "/") {
+		if name == "" {
+			switch {
+			case i == 0:
+				// Paths may start with "/".
+			case descendant:
+				return nil, fmt.Errorf("%w: %q", ErrQuery, path)
+			default:
+				descendant = true
+			}
+			continue
+		}
+
+		p := Any[V]()
+		if name != "*" {
+			name := name
+			p = func(n *Node[V]) bool {
+				return fmt.Sprintf("%v", n.Value) == name
+			}
+		}
+		if descendant {
+			steps = append(steps, Descendant(p))
+		} else {
+			steps = append(steps, Child(p))
+		}
+		descendant = false
+	}
+	if descendant || len(steps) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrQuery, path)
+	}
+	return Find(root, steps...), nil
+}
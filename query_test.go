@@ -0,0 +1,146 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// queryTestTree returns the tree:
+//
+//	(root)
+//	├── a
+//	│   ├── b
+//	│   │   └── x
+//	│   └── c
+//	│       └── b
+//	│           └── y
+//	└── b
+//	    └── z
+func queryTestTree() *Node[string] {
+	return newTree(
+		&Node[string]{
+			Value: "a",
+			Children: []*Node[string]{
+				{
+					Value:    "b",
+					Children: []*Node[string]{{Value: "x"}},
+				},
+				{
+					Value: "c",
+					Children: []*Node[string]{
+						{
+							Value:    "b",
+							Children: []*Node[string]{{Value: "y"}},
+						},
+					},
+				},
+			},
+		},
+		&Node[string]{
+			Value:    "b",
+			Children: []*Node[string]{{Value: "z"}},
+		},
+	)
+}
+
+// values returns the values of the nodes.
+func values(nodes []*Node[string]) []string {
+	var v []string
+	for _, n := range nodes {
+		v = append(v, n.Value)
+	}
+	return v
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		path string
+		want []string
+		err  error
+	}{
+		"child": {
+			path: "a/b/x",
+			want: []string{"x"},
+		},
+		"absolute": {
+			path: "/b/*",
+			want: []string{"z"},
+		},
+		"wildcard": {
+			path: "a/*",
+			want: []string{"b", "c"},
+		},
+		"descendant": {
+			path: "a//b/*",
+			want: []string{"x", "y"},
+		},
+		"root descendant": {
+			path: "//b",
+			want: []string{"b", "b", "b"},
+		},
+		"no match": {
+			path: "a/z",
+		},
+		"empty": {
+			path: "",
+			err:  ErrQuery,
+		},
+		"trailing slash": {
+			path: "a/",
+			err:  ErrQuery,
+		},
+		"triple slash": {
+			path: "a///b",
+			err:  ErrQuery,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Query(queryTestTree(), tc.path)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Query: want: %v, got: %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.want, values(got)); diff != "" {
+				t.Errorf("Query: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	root := queryTestTree()
+	got := Find(root,
+		Descendant(ByValue("b")),
+		Where(func(n *Node[string]) bool {
+			return n.Parent.Value != "a"
+		}),
+		Child(Any[string]()),
+	)
+	if diff := cmp.Diff([]string{"y", "z"}, values(got)); diff != "" {
+		t.Errorf("Find: (-want, +got): \n%s", diff)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"unicode/utf8"
+)
+
+// Layout of railroad diagrams in pixels.
+const (
+	rrMargin   = 20
+	rrCharW    = 8
+	rrBoxH     = 24
+	rrBoxPad   = 10
+	rrGap      = 20
+	rrRowGap   = 12
+	rrTitleH   = 24
+	rrDiagramH = 16
+)
+
+// rrAlt is an alternative of a nonterminal in a railroad diagram.
+type rrAlt struct {
+	// labels are the labels of the symbols.
+	labels []string
+
+	// terms records which symbols are terminals.
+	terms []bool
+}
+
+// width returns the width of the alternative.
+func (a *rrAlt) width() int {
+	var w int
+	for _, l := range a.labels {
+		w += rrLabelW(l) + rrGap
+	}
+	return w
+}
+
+// rrLabelW returns the width of the box for a label.
+func rrLabelW(label string) int {
+	return utf8.RuneCountInString(label)*rrCharW + 2*rrBoxPad
+}
+
+// Railroad writes railroad diagrams for the rules of g to w as an SVG
+// document. A diagram is drawn for each nonterminal, in the order they are
+// first defined, showing each of its alternatives. Terminals are drawn as
+// rounded boxes and nonterminals as square boxes. termName returns the label
+// for a terminal. If termName is nil, terminals are labelled as by
+// Symbol.String.
+func (g *Grammar) Railroad(w io.Writer, termName func(LexemeType) string) error {
+	var names []string
+	alts := map[string][]*rrAlt{}
+	for _, p := range g.Productions {
+		if _, ok := alts[p.Name]; !ok {
+			names = append(names, p.Name)
+		}
+		alt := &rrAlt{}
+		for _, s := range p.Symbols {
+			label := s.String()
+			if s.IsTerminal() && termName != nil {
+				label = termName(s.Type)
+			}
+			alt.labels = append(alt.labels, label)
+			alt.terms = append(alt.terms, s.IsTerminal())
+		}
+		alts[p.Name] = append(alts[p.Name], alt)
+	}
+
+	// Compute the size of the document.
+	width := 0
+	height := rrMargin
+	for _, name := range names {
+		for _, a := range alts[name] {
+			if aw := a.width(); aw > width {
+				width = aw
+			}
+		}
+		height += rrTitleH + len(alts[name])*(rrBoxH+rrRowGap) + rrDiagramH
+	}
+	width += 2*rrMargin + 2*rrGap
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" `+
+		`font-family="monospace" font-size="14">`+"\n", width, height)
+	_, _ = bw.WriteString(`<g fill="none" stroke="black">` + "\n")
+
+	y := rrMargin
+	for _, name := range names {
+		y = rrDiagram(bw, name, alts[name], width, y)
+	}
+
+	_, _ = bw.WriteString("</g>\n</svg>\n")
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return bw.Flush()
+}
+
+// rrDiagram writes the diagram for a nonterminal starting at y and returns
+// the y position of the next diagram.
+func rrDiagram(w *bufio.Writer, name string, alts []*rrAlt, width, y int) int {
+	fmt.Fprintf(w, `<text x="%d" y="%d" fill="black" stroke="none">%s</text>`+"\n",
+		rrMargin, y+rrTitleH/2, html.EscapeString(name))
+	y += rrTitleH
+
+	left := rrMargin
+	right := width - rrMargin
+	first := y + rrBoxH/2
+	last := first + (len(alts)-1)*(rrBoxH+rrRowGap)
+
+	// Rails joining the alternatives.
+	fmt.Fprintf(w, `<path d="M%d %d h%d M%d %d h%d"/>`+"\n", left-rrMargin/2, first, rrMargin/2, right, first, rrMargin/2)
+	if len(alts) > 1 {
+		fmt.Fprintf(w, `<path d="M%d %d V%d M%d %d V%d"/>`+"\n", left, first, last, right, first, last)
+	}
+
+	for _, a := range alts {
+		mid := y + rrBoxH/2
+		x := left + rrGap
+		fmt.Fprintf(w, `<path d="M%d %d H%d"/>`+"\n", left, mid, x)
+		for i, label := range a.labels {
+			lw := rrLabelW(label)
+			rx := 0
+			if a.terms[i] {
+				rx = rrBoxH / 2
+			}
+			fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" rx="%d"/>`+"\n", x, y, lw, rrBoxH, rx)
+			fmt.Fprintf(w, `<text x="%d" y="%d" fill="black" stroke="none" text-anchor="middle" `+
+				`dominant-baseline="central">%s</text>`+"\n", x+lw/2, mid, html.EscapeString(label))
+			x += lw
+			next := x + rrGap
+			if i == len(a.labels)-1 {
+				next = right
+			}
+			fmt.Fprintf(w, `<path d="M%d %d H%d"/>`+"\n", x, mid, next)
+			x = next
+		}
+		if len(a.labels) == 0 {
+			fmt.Fprintf(w, `<path d="M%d %d H%d"/>`+"\n", x, mid, right)
+		}
+		y += rrBoxH + rrRowGap
+	}
+
+	return y + rrDiagramH
+}
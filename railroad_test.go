@@ -0,0 +1,87 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGrammar_Railroad(t *testing.T) {
+	t.Parallel()
+
+	termNames := map[LexemeType]string{
+		plusType:   "+",
+		starType:   "*",
+		lparenType: "(",
+		rparenType: ")",
+		numType:    "<num>",
+	}
+
+	var b strings.Builder
+	if err := exprGrammar.Railroad(&b, func(typ LexemeType) string { return termNames[typ] }); err != nil {
+		t.Fatalf("Railroad: %v", err)
+	}
+
+	// Collect the text and count the boxes in the document.
+	var texts []string
+	var rects, rounded int
+	dec := xml.NewDecoder(strings.NewReader(b.String()))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %v", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if tok.Name.Local != "rect" {
+				continue
+			}
+			rects++
+			for _, a := range tok.Attr {
+				if a.Name.Local == "rx" && a.Value != "0" {
+					rounded++
+				}
+			}
+		case xml.CharData:
+			if s := strings.TrimSpace(string(tok)); s != "" {
+				texts = append(texts, s)
+			}
+		}
+	}
+
+	want := []string{
+		"E", "E", "+", "T", "T",
+		"T", "T", "*", "F", "F",
+		"F", "(", "E", ")", "<num>",
+	}
+	if diff := cmp.Diff(want, texts); diff != "" {
+		t.Errorf("text: (-want, +got): \n%s", diff)
+	}
+	if got, want := rects, 12; got != want {
+		t.Errorf("boxes: want: %v, got: %v", want, got)
+	}
+	if got, want := rounded, 5; got != want {
+		t.Errorf("terminal boxes: want: %v, got: %v", want, got)
+	}
+}
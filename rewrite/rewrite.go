@@ -0,0 +1,217 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rewrite replays a stream of lexemes through rules to produce
+// formatted or minified output.
+//
+// A Stream is built from the lexemes emitted by a lexparse.Lexer and the
+// lexparse.Source it retained. Each Token in the stream holds the source text
+// of its lexeme and the trivia, such as whitespace and comments, that precede
+// it. Rules edit tokens and the stream is written back out. Tokens and trivia
+// that are not edited are written exactly as they appeared in the source.
+//
+//	src := lexparse.NewSource()
+//	l := lexparse.NewLexer(r, state, lexparse.WithSource(src))
+//	s := rewrite.Collect(src, l.Lex(ctx))
+//	s.Apply(rewrite.Minify(needsSpace))
+//	fmt.Print(s)
+package rewrite
+
+import (
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ianlewis/lexparse"
+)
+
+// Token is a lexeme in a Stream together with its source text and the
+// trivia preceding it.
+type Token struct {
+	// Lexeme is the lexeme emitted by the lexer.
+	Lexeme *lexparse.Lexeme
+
+	// Text is the text written for the token. It is initially the source
+	// text of the lexeme.
+	Text string
+
+	// Leading is the text written before the token. It is initially the
+	// source text between the previous lexeme and this one.
+	Leading string
+
+	// Deleted is true if the token and its leading trivia are not written.
+	Deleted bool
+}
+
+// Stream is a sequence of tokens that can be edited by rules and written
+// back out.
+type Stream struct {
+	// Tokens are the tokens in the stream.
+	Tokens []*Token
+
+	// Trailing is the text written after the last token. It is initially the
+	// source text after the last lexeme.
+	Trailing string
+}
+
+// NewStream returns a stream of the lexemes with their text and trivia taken
+// from src. The lexemes must have been emitted by a lexer created with the
+// lexparse.WithSource option and must be in order.
+func NewStream(src *lexparse.Source, lexemes []*lexparse.Lexeme) *Stream {
+	s := &Stream{}
+	end := 0
+	for _, l := range lexemes {
+		text := src.LexemeText(l)
+		s.Tokens = append(s.Tokens, &Token{
+			Lexeme:  l,
+			Text:    text,
+			Leading: src.Text(end, l.Pos),
+		})
+		if e := l.Pos + utf8.RuneCountInString(text); e > end {
+			end = e
+		}
+	}
+	s.Trailing = src.Text(end, math.MaxInt)
+	return s
+}
+
+// Collect reads lexemes until the channel is closed and returns a stream of
+// them. See NewStream.
+func Collect(src *lexparse.Source, lexemes <-chan *lexparse.Lexeme) *Stream {
+	var ls []*lexparse.Lexeme
+	for l := range lexemes {
+		ls = append(ls, l)
+	}
+	return NewStream(src, ls)
+}
+
+// Rule edits the token at index i in the stream. Rules may edit any token in
+// the stream but typically only edit the token at i.
+type Rule func(s *Stream, i int)
+
+// Apply calls each rule for each token in the stream in order. Deleted tokens
+// are skipped.
+func (s *Stream) Apply(rules ...Rule) {
+	for _, rule := range rules {
+		for i, tok := range s.Tokens {
+			if !tok.Deleted {
+				rule(s, i)
+			}
+		}
+	}
+}
+
+// Prev returns the closest token before index i that is not deleted or nil
+// if there is none.
+func (s *Stream) Prev(i int) *Token {
+	for i--; i >= 0; i-- {
+		if !s.Tokens[i].Deleted {
+			return s.Tokens[i]
+		}
+	}
+	return nil
+}
+
+// Next returns the closest token after index i that is not deleted or nil if
+// there is none.
+func (s *Stream) Next(i int) *Token {
+	for i++; i < len(s.Tokens); i++ {
+		if !s.Tokens[i].Deleted {
+			return s.Tokens[i]
+		}
+	}
+	return nil
+}
+
+// WriteTo writes the stream to w.
+func (s *Stream) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, s.String())
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return int64(n), err
+}
+
+// String returns the text of the stream.
+func (s *Stream) String() string {
+	var b strings.Builder
+	for _, tok := range s.Tokens {
+		if tok.Deleted {
+			continue
+		}
+		b.WriteString(tok.Leading)
+		b.WriteString(tok.Text)
+	}
+	b.WriteString(s.Trailing)
+	return b.String()
+}
+
+// Minify returns a rule that removes trivia. The trivia before a token is
+// replaced with a single space if needsSpace returns true for the previous
+// token and the token, and removed otherwise. The trivia before the first
+// token and after the last token is removed. If needsSpace is nil no spaces
+// are written.
+func Minify(needsSpace func(prev, tok *Token) bool) Rule {
+	return func(s *Stream, i int) {
+		tok := s.Tokens[i]
+		prev := s.Prev(i)
+		tok.Leading = ""
+		if prev != nil && needsSpace != nil && needsSpace(prev, tok) {
+			tok.Leading = " "
+		}
+		if s.Next(i) == nil {
+			s.Trailing = ""
+		}
+	}
+}
+
+// Delete returns a rule that deletes tokens of the given types along with
+// their leading trivia.
+func Delete(types ...lexparse.LexemeType) Rule {
+	return func(s *Stream, i int) {
+		tok := s.Tokens[i]
+		for _, typ := range types {
+			if tok.Lexeme.Type == typ {
+				tok.Deleted = true
+				return
+			}
+		}
+	}
+}
+
+// Replace returns a rule that replaces the text of tokens of the given type
+// with the result of calling fn with the token.
+func Replace(typ lexparse.LexemeType, fn func(tok *Token) string) Rule {
+	return func(s *Stream, i int) {
+		tok := s.Tokens[i]
+		if tok.Lexeme.Type == typ {
+			tok.Text = fn(tok)
+		}
+	}
+}
+
+// Indent returns a rule that re-indents tokens that start a line. The
+// whitespace between the last newline in the trivia before such a token and
+// the token is replaced by indent repeated depth times, where depth is
+// returned by the depth function for the token at index i. Other trivia,
+// such as comments on preceding lines, is kept.
+func Indent(indent string, depth func(s *Stream, i int) int) Rule {
+	return func(s *Stream, i int) {
+		tok := s.Tokens[i]
+		nl := strings.LastIndexByte(tok.Leading, '\n')
+		if nl < 0 || strings.TrimSpace(tok.Leading[nl+1:]) != "" {
+			return
+		}
+		tok.Leading = tok.Leading[:nl+1] + strings.Repeat(indent, depth(s, i))
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rewrite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/ianlewis/runeio"
+
+	"github.com/ianlewis/lexparse"
+)
+
+const (
+	wordType lexparse.LexemeType = iota
+	punctType
+)
+
+var (
+	spaceClass = lexparse.RuneClassFunc(unicode.IsSpace)
+	wordClass  = lexparse.NewRuneClass("_", unicode.Letter, unicode.Digit)
+)
+
+// lexTest lexes words and punctuation. Whitespace and "#" comments are
+// ignored.
+func lexTest(_ context.Context, l *lexparse.Lexer) (lexparse.State, error) {
+	if _, err := l.AdvanceWhile(spaceClass); err != nil {
+		return nil, err
+	}
+	rns, err := l.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if rns[0] == '#' {
+		if _, err := l.SkipTo([]string{"\n"}); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		return lexparse.StateFn(lexTest), nil
+	}
+	l.Ignore()
+
+	typ := punctType
+	if wordClass.Contains(rns[0]) {
+		typ = wordType
+		if _, err := l.AdvanceWhile(wordClass); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	} else if _, err := l.Advance(1); err != nil {
+		return nil, err
+	}
+	l.Emit(l.Lexeme(typ))
+	return lexparse.StateFn(lexTest), nil
+}
+
+// collect lexes the input and returns a stream of its lexemes.
+func collect(t *testing.T, input string) *Stream {
+	t.Helper()
+
+	src := lexparse.NewSource()
+	r := runeio.NewReader(bufio.NewReader(strings.NewReader(input)))
+	l := lexparse.NewLexer(r, lexparse.StateFn(lexTest), lexparse.WithSource(src))
+	s := Collect(src, l.Lex(context.Background()))
+	<-l.Done()
+	if err := l.Err(); err != nil {
+		t.Fatalf("Lex: %v", err)
+	}
+	return s
+}
+
+// needsSpace returns true between two words.
+func needsSpace(prev, tok *Token) bool {
+	return prev.Lexeme.Type == wordType && tok.Lexeme.Type == wordType
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	input := "  # header\nfunc f ( a,b ) {\n    # body\n  return  a\n}\n\n"
+
+	testCases := map[string]struct {
+		rules []Rule
+		want  string
+	}{
+		"no rules": {
+			want: input,
+		},
+		"minify": {
+			rules: []Rule{Minify(needsSpace)},
+			want:  "func f(a,b){return a}",
+		},
+		"minify without spaces": {
+			rules: []Rule{Minify(nil)},
+			want:  "funcf(a,b){returna}",
+		},
+		"delete": {
+			rules: []Rule{Delete(punctType)},
+			want:  "  # header\nfunc f ab\n    # body\n  return  a\n\n",
+		},
+		"replace": {
+			rules: []Rule{Replace(wordType, func(tok *Token) string {
+				if tok.Text == "a" {
+					return "alpha"
+				}
+				return tok.Text
+			})},
+			want: "  # header\nfunc f ( alpha,b ) {\n    # body\n  return  alpha\n}\n\n",
+		},
+		"indent": {
+			rules: []Rule{Indent("\t", func(s *Stream, i int) int {
+				depth := 0
+				for _, tok := range s.Tokens[:i] {
+					switch tok.Text {
+					case "{":
+						depth++
+					case "}":
+						depth--
+					}
+				}
+				if s.Tokens[i].Text == "}" {
+					depth--
+				}
+				return depth
+			})},
+			want: "  # header\nfunc f ( a,b ) {\n    # body\n\treturn  a\n}\n\n",
+		},
+		"combined": {
+			rules: []Rule{
+				Delete(punctType),
+				Minify(needsSpace),
+			},
+			want: "func f a b return a",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s := collect(t, input)
+			s.Apply(tc.rules...)
+			if got := s.String(); got != tc.want {
+				t.Errorf("String: want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestStream_Prev_Next(t *testing.T) {
+	t.Parallel()
+
+	s := collect(t, "a b c")
+	s.Tokens[1].Deleted = true
+
+	if got, want := s.Next(0), s.Tokens[2]; got != want {
+		t.Errorf("Next(0): want: %v, got: %v", want.Text, got)
+	}
+	if got, want := s.Prev(2), s.Tokens[0]; got != want {
+		t.Errorf("Prev(2): want: %v, got: %v", want.Text, got)
+	}
+	if got := s.Prev(0); got != nil {
+		t.Errorf("Prev(0): want: nil, got: %v", got)
+	}
+	if got := s.Next(2); got != nil {
+		t.Errorf("Next(2): want: nil, got: %v", got)
+	}
+
+	var b strings.Builder
+	if _, err := s.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if got, want := b.String(), "a c"; got != want {
+		t.Errorf("WriteTo: want: %q, got: %q", want, got)
+	}
+}
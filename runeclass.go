@@ -0,0 +1,107 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// RuneClass is a set of runes such as the runes that may start an
+// identifier. A RuneClass is the union of literal runes, unicode range tables,
+// and predicate functions. Membership of ASCII runes is precomputed so lookups
+// for ASCII input are fast. RuneClasses are immutable and safe for concurrent
+// use.
+type RuneClass struct {
+	// ascii is a bitmap of the ASCII runes in the class.
+	ascii [2]uint64
+
+	runes  map[rune]bool
+	tables []*unicode.RangeTable
+	funcs  []func(rune) bool
+}
+
+// NewRuneClass returns a RuneClass containing the runes in runes and the
+// runes in the given range tables.
+func NewRuneClass(runes string, tables ...*unicode.RangeTable) *RuneClass {
+	c := &RuneClass{
+		runes:  map[rune]bool{},
+		tables: tables,
+	}
+	for _, rn := range runes {
+		c.runes[rn] = true
+	}
+	c.init()
+	return c
+}
+
+// RuneClassFunc returns a RuneClass containing the runes for which f returns
+// true. f must be a pure function of its argument.
+func RuneClassFunc(f func(rune) bool) *RuneClass {
+	c := &RuneClass{
+		funcs: []func(rune) bool{f},
+	}
+	c.init()
+	return c
+}
+
+// Union returns a new RuneClass containing the runes in c and in each of the
+// other classes.
+func (c *RuneClass) Union(others ...*RuneClass) *RuneClass {
+	u := &RuneClass{
+		runes: map[rune]bool{},
+	}
+	for _, o := range append([]*RuneClass{c}, others...) {
+		for rn := range o.runes {
+			u.runes[rn] = true
+		}
+		u.tables = append(u.tables, o.tables...)
+		u.funcs = append(u.funcs, o.funcs...)
+	}
+	u.init()
+	return u
+}
+
+// init precomputes the ASCII lookup table.
+func (c *RuneClass) init() {
+	for rn := rune(0); rn < utf8.RuneSelf; rn++ {
+		if c.contains(rn) {
+			c.ascii[rn/64] |= 1 << (rn % 64)
+		}
+	}
+}
+
+// Contains returns true if rn is in the class.
+func (c *RuneClass) Contains(rn rune) bool {
+	if rn >= 0 && rn < utf8.RuneSelf {
+		return c.ascii[rn/64]&(1<<(rn%64)) != 0
+	}
+	return c.contains(rn)
+}
+
+func (c *RuneClass) contains(rn rune) bool {
+	if c.runes[rn] {
+		return true
+	}
+	if len(c.tables) > 0 && unicode.In(rn, c.tables...) {
+		return true
+	}
+	for _, f := range c.funcs {
+		if f(rn) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"testing"
+	"unicode"
+)
+
+func TestRuneClass(t *testing.T) {
+	t.Parallel()
+
+	identStart := NewRuneClass("_", unicode.Letter)
+	identCont := identStart.Union(RuneClassFunc(unicode.IsDigit))
+
+	testCases := map[string]struct {
+		class *RuneClass
+		rn    rune
+		want  bool
+	}{
+		"literal": {
+			class: identStart,
+			rn:    '_',
+			want:  true,
+		},
+		"ascii table": {
+			class: identStart,
+			rn:    'a',
+			want:  true,
+		},
+		"non-ascii table": {
+			class: identStart,
+			rn:    'é',
+			want:  true,
+		},
+		"not in class": {
+			class: identStart,
+			rn:    '1',
+			want:  false,
+		},
+		"union func ascii": {
+			class: identCont,
+			rn:    '1',
+			want:  true,
+		},
+		"union func non-ascii": {
+			class: identCont,
+			rn:    '٣',
+			want:  true,
+		},
+		"union table": {
+			class: identCont,
+			rn:    'Z',
+			want:  true,
+		},
+		"union not in class": {
+			class: identCont,
+			rn:    '-',
+			want:  false,
+		},
+		"negative rune": {
+			class: identCont,
+			rn:    -1,
+			want:  false,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.class.Contains(tc.rn); got != tc.want {
+				t.Errorf("Contains(%q): want: %v, got: %v", tc.rn, tc.want, got)
+			}
+		})
+	}
+}
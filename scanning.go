@@ -0,0 +1,273 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"text/scanner"
+	"unicode/utf8"
+)
+
+// ErrScanner indicates an error reported by the text/scanner package.
+var ErrScanner = errors.New("scanner error")
+
+// ScanError is an error reported by the text/scanner package. It wraps
+// ErrScanner. Positions are zero based like the positions of lexemes.
+type ScanError struct {
+	// Msg is the error message reported by the scanner.
+	Msg string
+
+	// Pos is the position in the input where the error occurred.
+	Pos int
+
+	// Line is the line where the error occurred.
+	Line int
+
+	// Column is the column in the line where the error occurred.
+	Column int
+
+	// offset is the byte offset of the error.
+	offset int
+}
+
+// Error implements error.
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("%v: %s: line %d, column %d", ErrScanner, e.Msg, e.Line+1, e.Column+1)
+}
+
+// Unwrap returns ErrScanner.
+func (e *ScanError) Unwrap() error {
+	return ErrScanner
+}
+
+// ScanningLexer is a lexer that uses text/scanner to tokenize its input. It
+// can be used for languages with Go-like tokens without writing lexer states.
+// The type of each Lexeme is the token returned by scanner.Scanner.Scan, e.g.
+// scanner.Ident or '+', converted to a LexemeType. Types of other lexemes
+// mixed with the lexemes of a ScanningLexer should start at FirstUserType or
+// be allocated by NewLexemeType to avoid collisions.
+type ScanningLexer struct {
+	s scanner.Scanner
+
+	// r records the input so that byte offsets can be converted to rune
+	// offsets.
+	r *offsetReader
+
+	lexemes chan *Lexeme
+	done    chan struct{}
+
+	// errLexemes is true if scanner errors are emitted as lexemes of type
+	// errType rather than stopping the lexer.
+	errLexemes bool
+	errType    LexemeType
+
+	// pending holds the errors reported while scanning the current token.
+	pending []*ScanError
+
+	mu   sync.Mutex
+	err  error
+	errs []error
+}
+
+// NewScanningLexer returns a new ScanningLexer that reads from r. mode
+// controls which tokens are recognized (e.g. scanner.GoTokens).
+func NewScanningLexer(r io.Reader, mode uint) *ScanningLexer {
+	l := &ScanningLexer{}
+	l.s.Mode = mode
+	l.Reset(r)
+	return l
+}
+
+// Reset resets the ScanningLexer to read from r so that it can be reused.
+// Reset must only be called before Lex is called or after the channel
+// returned by Done is closed.
+func (l *ScanningLexer) Reset(r io.Reader) {
+	mode := l.s.Mode
+	l.r = &offsetReader{r: r}
+	l.lexemes = make(chan *Lexeme)
+	l.done = make(chan struct{})
+	l.err = nil
+	l.errs = nil
+	l.pending = nil
+
+	l.s.Init(l.r)
+	l.s.Mode = mode
+	l.s.Error = func(s *scanner.Scanner, msg string) {
+		pos := s.Pos()
+		l.pending = append(l.pending, &ScanError{
+			Msg:    msg,
+			Line:   pos.Line - 1,
+			Column: pos.Column - 1,
+			offset: pos.Offset,
+		})
+	}
+}
+
+// SetErrorLexemes configures the lexer to emit a lexeme of type typ for each
+// scanner error rather than stopping at the first error. The Value of the
+// lexeme is the error message. Error lexemes follow the lexeme for the
+// malformed token so that parsers can continue past malformed literals.
+func (l *ScanningLexer) SetErrorLexemes(typ LexemeType) {
+	l.errLexemes = true
+	l.errType = typ
+}
+
+// Lex starts a new goroutine to scan the input and returns a channel of
+// lexemes. The channel is closed when the end of the input is reached, an
+// error occurs, or ctx is cancelled.
+func (l *ScanningLexer) Lex(ctx context.Context) <-chan *Lexeme {
+	go func() {
+		defer close(l.done)
+		defer close(l.lexemes)
+		for {
+			tok := l.s.Scan()
+
+			pos := l.s.Position
+			lexeme := &Lexeme{
+				Type:   LexemeType(tok),
+				Value:  l.s.TokenText(),
+				Line:   pos.Line - 1,
+				Column: pos.Column - 1,
+			}
+			if tok != scanner.EOF {
+				lexeme.Pos = l.r.runeOffset(pos.Offset)
+			}
+
+			// NOTE: Errors are reported within or after the token so their
+			//       offsets are converted after the token's offset.
+			errLexemes := l.flushErrs()
+			if tok == scanner.EOF && len(errLexemes) == 0 {
+				return
+			}
+			if len(errLexemes) > 0 && !l.errLexemes {
+				return
+			}
+
+			var out []*Lexeme
+			if tok != scanner.EOF {
+				out = append(out, lexeme)
+			}
+			for _, lexeme := range append(out, errLexemes...) {
+				select {
+				case l.lexemes <- lexeme:
+				case <-ctx.Done():
+					l.setErr(ctx.Err())
+					return
+				}
+			}
+		}
+	}()
+	return l.lexemes
+}
+
+// flushErrs records the pending scanner errors and returns error lexemes for
+// them.
+func (l *ScanningLexer) flushErrs() []*Lexeme {
+	var lexemes []*Lexeme
+	for _, err := range l.pending {
+		err.Pos = l.r.runeOffset(err.offset)
+		l.mu.Lock()
+		if l.err == nil && !l.errLexemes {
+			l.err = err
+		}
+		l.errs = append(l.errs, err)
+		l.mu.Unlock()
+
+		lexemes = append(lexemes, &Lexeme{
+			Type:   l.errType,
+			Value:  err.Msg,
+			Pos:    err.Pos,
+			Line:   err.Line,
+			Column: err.Column,
+		})
+	}
+	l.pending = nil
+	return lexemes
+}
+
+// setErr sets the lexer's error value.
+func (l *ScanningLexer) setErr(err error) {
+	l.mu.Lock()
+	if l.err == nil {
+		l.err = err
+	}
+	l.mu.Unlock()
+}
+
+// Err returns the first error that stopped the lexer. If error lexemes are
+// enabled, scanner errors do not stop the lexer and are only returned by
+// Errs.
+func (l *ScanningLexer) Err() error {
+	l.mu.Lock()
+	err := l.err
+	l.mu.Unlock()
+	return err
+}
+
+// Errs returns the scanner errors encountered. Each error is a *ScanError.
+// Unless error lexemes are enabled, the lexer stops after the first token
+// with errors so only the errors for that token are returned. See
+// SetErrorLexemes.
+func (l *ScanningLexer) Errs() []error {
+	l.mu.Lock()
+	errs := append([]error(nil), l.errs...)
+	l.mu.Unlock()
+	return errs
+}
+
+// Done returns a channel that is closed when the lexer is finished running.
+func (l *ScanningLexer) Done() <-chan struct{} {
+	return l.done
+}
+
+// offsetReader records the input read from r so that byte offsets can be
+// converted to rune offsets.
+type offsetReader struct {
+	r io.Reader
+
+	// buf holds the input after offset.
+	buf []byte
+
+	// offset is the byte offset of the start of buf.
+	offset int
+
+	// runes is the rune offset of the start of buf.
+	runes int
+}
+
+// Read implements io.Reader.
+func (r *offsetReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return n, err
+}
+
+// runeOffset returns the rune offset for the byte offset. Offsets must be
+// non-decreasing.
+func (r *offsetReader) runeOffset(offset int) int {
+	n := offset - r.offset
+	if n > len(r.buf) {
+		n = len(r.buf)
+	}
+	r.runes += utf8.RuneCount(r.buf[:n])
+	r.buf = r.buf[n:]
+	r.offset += n
+	return r.runes
+}
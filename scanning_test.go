@@ -0,0 +1,138 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"text/scanner"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScanningLexer(t *testing.T) {
+	t.Parallel()
+
+	l := NewScanningLexer(strings.NewReader("é = 1 +\n\"two\""), scanner.GoTokens)
+
+	var got []*Lexeme
+	for lexeme := range l.Lex(context.Background()) {
+		got = append(got, lexeme)
+	}
+	if err := l.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+
+	want := []*Lexeme{
+		{Type: scanner.Ident, Value: "é", Pos: 0, Line: 0, Column: 0},
+		{Type: '=', Value: "=", Pos: 2, Line: 0, Column: 2},
+		{Type: scanner.Int, Value: "1", Pos: 4, Line: 0, Column: 4},
+		{Type: '+', Value: "+", Pos: 6, Line: 0, Column: 6},
+		{Type: scanner.String, Value: `"two"`, Pos: 8, Line: 1, Column: 0},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestScanningLexer_error(t *testing.T) {
+	t.Parallel()
+
+	l := NewScanningLexer(strings.NewReader("a '' \"unterminated"), scanner.GoTokens)
+	for range l.Lex(context.Background()) {
+		// Drain the lexemes.
+	}
+	<-l.Done()
+
+	err := l.Err()
+	if !errors.Is(err, ErrScanner) {
+		t.Errorf("Err: want: %v, got: %v", ErrScanner, err)
+	}
+
+	// The lexer stops after the first token with errors.
+	if errs := l.Errs(); len(errs) != 1 || errs[0] != err {
+		t.Errorf("Errs: want: %v, got: %v", []error{err}, errs)
+	}
+}
+
+func TestScanningLexer_SetErrorLexemes(t *testing.T) {
+	t.Parallel()
+
+	const errType LexemeType = -100
+
+	l := NewScanningLexer(strings.NewReader("a '' b\n\"c"), scanner.GoTokens)
+	l.SetErrorLexemes(errType)
+
+	var got []*Lexeme
+	for lexeme := range l.Lex(context.Background()) {
+		got = append(got, lexeme)
+	}
+	<-l.Done()
+
+	if err := l.Err(); err != nil {
+		t.Errorf("Err: %v", err)
+	}
+
+	want := []*Lexeme{
+		{Type: scanner.Ident, Value: "a", Pos: 0, Line: 0, Column: 0},
+		{Type: scanner.Char, Value: "''", Pos: 2, Line: 0, Column: 2},
+		{Type: errType, Value: "invalid char literal", Pos: 3, Line: 0, Column: 3},
+		{Type: scanner.Ident, Value: "b", Pos: 5, Line: 0, Column: 5},
+		{Type: scanner.String, Value: `"c`, Pos: 7, Line: 1, Column: 0},
+		{Type: errType, Value: "literal not terminated", Pos: 9, Line: 1, Column: 2},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Lex: (-want, +got): \n%s", diff)
+	}
+
+	errs := l.Errs()
+	if got, want := len(errs), 2; got != want {
+		t.Fatalf("Errs: want: %v, got: %v", want, got)
+	}
+	d := NewDiagnostic(errs[1])
+	wantDiag := &Diagnostic{
+		Range: Range{
+			Start: Position{Line: 1, Column: 2},
+			End:   Position{Line: 1, Column: 2},
+		},
+		Severity: SeverityError,
+		Message:  "scanner error: literal not terminated: line 2, column 3",
+		Code:     "scanner-error",
+	}
+	if diff := cmp.Diff(wantDiag, d); diff != "" {
+		t.Errorf("NewDiagnostic: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestScanningLexer_Reset(t *testing.T) {
+	t.Parallel()
+
+	l := NewScanningLexer(strings.NewReader("a"), scanner.ScanIdents)
+	for _, input := range []string{"x 1", "y 2"} {
+		l.Reset(strings.NewReader(input))
+
+		var got []string
+		for lexeme := range l.Lex(context.Background()) {
+			got = append(got, lexeme.Value)
+		}
+		// Numbers are not scanned in ScanIdents mode.
+		want := []string{input[:1], input[2:]}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Lex: (-want, +got): \n%s", diff)
+		}
+	}
+}
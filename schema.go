@@ -0,0 +1,262 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSchema indicates that a tree does not match its schema.
+var ErrSchema = errors.New("schema violation")
+
+// SchemaError is an error returned by Validate for a node that does not match
+// the schema. It wraps ErrSchema.
+type SchemaError struct {
+	// Msg describes the violation.
+	Msg string
+
+	// Pos is the position in the input of the node where the violation
+	// occurred.
+	Pos int
+
+	// Line is the line of the node where the violation occurred.
+	Line int
+
+	// Column is the column of the node where the violation occurred.
+	Column int
+}
+
+// Error implements error.
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%v: %s: line %d, column %d", ErrSchema, e.Msg, e.Line+1, e.Column+1)
+}
+
+// Unwrap returns ErrSchema.
+func (e *SchemaError) Unwrap() error {
+	return ErrSchema
+}
+
+// ChildSpec specifies the kinds and number of consecutive children of a node.
+// See One, Optional, and Many.
+type ChildSpec[K comparable] struct {
+	// Kinds are the allowed kinds of the children. If empty, children of any
+	// kind are allowed.
+	Kinds []K
+
+	// Min is the minimum number of children.
+	Min int
+
+	// Max is the maximum number of children. A negative value means no
+	// maximum.
+	Max int
+}
+
+// One returns a ChildSpec for exactly one child of one of the given kinds.
+func One[K comparable](kinds ...K) ChildSpec[K] {
+	return ChildSpec[K]{Kinds: kinds, Min: 1, Max: 1}
+}
+
+// Optional returns a ChildSpec for zero or one child of one of the given
+// kinds.
+func Optional[K comparable](kinds ...K) ChildSpec[K] {
+	return ChildSpec[K]{Kinds: kinds, Min: 0, Max: 1}
+}
+
+// Many returns a ChildSpec for any number of children of the given kinds.
+func Many[K comparable](kinds ...K) ChildSpec[K] {
+	return ChildSpec[K]{Kinds: kinds, Min: 0, Max: -1}
+}
+
+// allows returns true if the spec allows a child of kind k.
+func (c ChildSpec[K]) allows(k K) bool {
+	if len(c.Kinds) == 0 {
+		return true
+	}
+	for _, ck := range c.Kinds {
+		if ck == k {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a description of the allowed kinds.
+func (c ChildSpec[K]) String() string {
+	if len(c.Kinds) == 0 {
+		return "any kind"
+	}
+	kinds := make([]string, len(c.Kinds))
+	for i, k := range c.Kinds {
+		kinds[i] = fmt.Sprint(k)
+	}
+	return "kind " + strings.Join(kinds, " or ")
+}
+
+// Schema declares the children allowed for each kind of node in a parse tree.
+// The kind of a node is determined by a kind function, e.g. one that returns
+// a field of the node's value. For each kind, the children of a node are
+// described by a sequence of ChildSpecs which are matched against the
+// children in order. Each ChildSpec matches as many children as it can. Only
+// the first violation in the children of each node is reported.
+//
+//	s := lexparse.NewSchema(func(v *item) kind { return v.kind }).
+//		Root(lexparse.Many(textKind, ifKind)).
+//		Node(textKind).
+//		Node(ifKind, lexparse.One(exprKind), lexparse.One(blockKind), lexparse.Optional(blockKind))
+type Schema[V comparable, K comparable] struct {
+	kind  func(V) K
+	root  []ChildSpec[K]
+	rules map[K][]ChildSpec[K]
+}
+
+// NewSchema returns a new Schema that uses kind to determine the kind of a
+// node from its value. The kind function is not called for the root node.
+func NewSchema[V comparable, K comparable](kind func(V) K) *Schema[V, K] {
+	return &Schema[V, K]{
+		kind:  kind,
+		rules: map[K][]ChildSpec[K]{},
+	}
+}
+
+// Root declares the children allowed for the root node. If Root is not
+// called, the root node may have any children. The schema is returned.
+func (s *Schema[V, K]) Root(children ...ChildSpec[K]) *Schema[V, K] {
+	s.root = children
+	return s
+}
+
+// Node declares the children allowed for nodes of kind k. If no children are
+// given, nodes of kind k must not have children. Nodes of kinds that are not
+// declared are not checked. The schema is returned.
+func (s *Schema[V, K]) Node(k K, children ...ChildSpec[K]) *Schema[V, K] {
+	if children == nil {
+		children = []ChildSpec[K]{}
+	}
+	s.rules[k] = children
+	return s
+}
+
+// Kind returns the kind of n.
+func (s *Schema[V, K]) Kind(n *Node[V]) K {
+	return s.kind(n.Value)
+}
+
+// Child returns the first child of n of kind k or nil if there is none.
+func (s *Schema[V, K]) Child(n *Node[V], k K) *Node[V] {
+	for _, c := range n.Children {
+		if c != nil && s.kind(c.Value) == k {
+			return c
+		}
+	}
+	return nil
+}
+
+// ChildrenOf returns the children of n of kind k.
+func (s *Schema[V, K]) ChildrenOf(n *Node[V], k K) []*Node[V] {
+	var children []*Node[V]
+	for _, c := range n.Children {
+		if c != nil && s.kind(c.Value) == k {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+// Validate checks that the tree rooted at root matches the schema. The root
+// node is checked against the children declared by Root. Each violation is
+// returned as a *SchemaError and the errors are joined together in the order
+// the nodes are found in the tree. It returns nil if the tree matches the
+// schema.
+func Validate[V comparable, K comparable](root *Node[V], s *Schema[V, K]) error {
+	if root == nil {
+		return nil
+	}
+
+	var errs []error
+	if s.root != nil {
+		errs = s.check(root, "root", s.root, errs)
+	}
+	for _, c := range root.Children {
+		if c != nil {
+			errs = s.validate(c, errs)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate appends the violations in the tree rooted at n to errs.
+func (s *Schema[V, K]) validate(n *Node[V], errs []error) []error {
+	k := s.kind(n.Value)
+	if specs, ok := s.rules[k]; ok {
+		errs = s.check(n, fmt.Sprint(k), specs, errs)
+	}
+	for _, c := range n.Children {
+		if c != nil {
+			errs = s.validate(c, errs)
+		}
+	}
+	return errs
+}
+
+// check appends the violations in the children of n to errs. name is the name
+// of n's kind.
+func (s *Schema[V, K]) check(n *Node[V], name string, specs []ChildSpec[K], errs []error) []error {
+	var children []*Node[V]
+	for _, c := range n.Children {
+		if c != nil {
+			children = append(children, c)
+		}
+	}
+
+	i := 0
+	for _, spec := range specs {
+		count := 0
+		for i < len(children) && (spec.Max < 0 || count < spec.Max) && spec.allows(s.kind(children[i].Value)) {
+			i++
+			count++
+		}
+		if count >= spec.Min {
+			continue
+		}
+		// Report only the first violation as the remaining children can't be
+		// matched reliably.
+		if i < len(children) {
+			c := children[i]
+			return append(errs, &SchemaError{
+				Msg:    fmt.Sprintf("%s node: unexpected child of kind %v, want %v", name, s.kind(c.Value), spec),
+				Pos:    c.Pos,
+				Line:   c.Line,
+				Column: c.Column,
+			})
+		}
+		return append(errs, &SchemaError{
+			Msg:    fmt.Sprintf("%s node: missing child of %v", name, spec),
+			Pos:    n.Pos,
+			Line:   n.Line,
+			Column: n.Column,
+		})
+	}
+	for _, c := range children[i:] {
+		errs = append(errs, &SchemaError{
+			Msg:    fmt.Sprintf("%s node: unexpected child of kind %v", name, s.kind(c.Value)),
+			Pos:    c.Pos,
+			Line:   c.Line,
+			Column: c.Column,
+		})
+	}
+	return errs
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testSchema uses the value of a node as its kind.
+func testSchema() *Schema[string, string] {
+	return NewSchema(func(v string) string { return v }).
+		Root(Many("if", "text")).
+		Node("text").
+		Node("expr").
+		Node("block", Many("if", "text")).
+		Node("if", One("expr"), One("block"), Optional("block"))
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		root *Node[string]
+		want []error
+	}{
+		"valid": {
+			root: newTree(
+				&Node[string]{Value: "text"},
+				&Node[string]{
+					Value: "if",
+					Children: []*Node[string]{
+						{Value: "expr"},
+						{Value: "block", Children: []*Node[string]{{Value: "text"}}},
+						{Value: "block"},
+					},
+				},
+			),
+		},
+		"missing child": {
+			root: newTree(
+				&Node[string]{
+					Value:  "if",
+					Line:   1,
+					Column: 2,
+					Children: []*Node[string]{
+						{Value: "expr"},
+					},
+				},
+			),
+			want: []error{
+				&SchemaError{Msg: "if node: missing child of kind block", Line: 1, Column: 2},
+			},
+		},
+		"unexpected child": {
+			root: newTree(
+				&Node[string]{
+					Value: "if",
+					Children: []*Node[string]{
+						{Value: "expr"},
+						{Value: "block"},
+						{Value: "block"},
+						{Value: "block", Pos: 9, Line: 3, Column: 4},
+					},
+				},
+				&Node[string]{Value: "text", Children: []*Node[string]{{Value: "expr", Line: 5}}},
+			),
+			want: []error{
+				&SchemaError{Msg: "if node: unexpected child of kind block", Pos: 9, Line: 3, Column: 4},
+				&SchemaError{Msg: "text node: unexpected child of kind expr", Line: 5},
+			},
+		},
+		"root": {
+			root: newTree(&Node[string]{Value: "expr", Line: 2}),
+			want: []error{
+				&SchemaError{Msg: "root node: unexpected child of kind expr", Line: 2},
+			},
+		},
+		"undeclared kind": {
+			root: newTree(
+				&Node[string]{
+					Value: "text",
+				},
+				&Node[string]{
+					Value: "if",
+					Children: []*Node[string]{
+						{Value: "call", Children: []*Node[string]{{Value: "anything"}}},
+						{Value: "block"},
+					},
+				},
+			),
+			want: []error{
+				&SchemaError{Msg: "if node: unexpected child of kind call, want kind expr"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Validate(addParent(tc.root), testSchema())
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSchema) {
+				t.Fatalf("Validate: want: %v, got: %v", ErrSchema, err)
+			}
+			var joined interface{ Unwrap() []error }
+			if !errors.As(err, &joined) {
+				t.Fatalf("Validate: want: joined errors, got: %T", err)
+			}
+			if diff := cmp.Diff(tc.want, joined.Unwrap()); diff != "" {
+				t.Errorf("Validate: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSchema_Child(t *testing.T) {
+	t.Parallel()
+
+	s := testSchema()
+	n := &Node[string]{
+		Value: "if",
+		Children: []*Node[string]{
+			{Value: "expr"},
+			{Value: "block", Line: 1},
+			{Value: "block", Line: 2},
+		},
+	}
+
+	if got, want := s.Child(n, "block"), n.Children[1]; got != want {
+		t.Errorf("Child: want: %v, got: %v", want, got)
+	}
+	if got := s.Child(n, "text"); got != nil {
+		t.Errorf("Child: want: nil, got: %v", got)
+	}
+	if diff := cmp.Diff(n.Children[1:], s.ChildrenOf(n, "block")); diff != "" {
+		t.Errorf("ChildrenOf: (-want, +got): \n%s", diff)
+	}
+	if got, want := s.Kind(n), "if"; got != want {
+		t.Errorf("Kind: want: %q, got: %q", want, got)
+	}
+}
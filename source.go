@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import "sync"
+
+// Source retains the input read by a Lexer so that the original source text
+// covered by lexemes and nodes can be recovered. A Source is filled by a
+// Lexer created with the WithSource option.
+type Source struct {
+	mu sync.Mutex
+
+	// runes is the input read so far.
+	runes []rune
+
+	// ends maps the position of each emitted lexeme to the position of its
+	// end.
+	ends map[int]int
+}
+
+// NewSource returns a new empty Source.
+func NewSource() *Source {
+	return &Source{
+		ends: map[int]int{},
+	}
+}
+
+// WithSource configures the Lexer to retain its input in src.
+func WithSource(src *Source) LexerOption {
+	return func(l *Lexer) {
+		l.src = src
+	}
+}
+
+// write appends runes to the source.
+func (s *Source) write(rns ...rune) {
+	s.mu.Lock()
+	s.runes = append(s.runes, rns...)
+	s.mu.Unlock()
+}
+
+// reset discards the input retained so far.
+func (s *Source) reset() {
+	s.mu.Lock()
+	s.runes = nil
+	s.ends = map[int]int{}
+	s.mu.Unlock()
+}
+
+// emit records the end position of a lexeme starting at pos.
+func (s *Source) emit(pos, end int) {
+	s.mu.Lock()
+	s.ends[pos] = end
+	s.mu.Unlock()
+}
+
+// Text returns the source text between the start and end positions. The
+// positions are clamped to the input read so far.
+func (s *Source) Text(start, end int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if end > len(s.runes) {
+		end = len(s.runes)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start >= end {
+		return ""
+	}
+	return string(s.runes[start:end])
+}
+
+// LexemeText returns the source text for the lexeme.
+func (s *Source) LexemeText(l *Lexeme) string {
+	return s.Text(l.Pos, s.end(l.Pos))
+}
+
+// end returns the end position of the lexeme starting at pos or pos if
+// there is no such lexeme.
+func (s *Source) end(pos int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if end, ok := s.ends[pos]; ok {
+		return end
+	}
+	return pos
+}
+
+// NodeSpan returns the start and end positions of the source text covered by
+// the tree rooted at n. The span starts at the earliest position of the nodes
+// in the tree and ends at the end of the last lexeme at a node's position.
+func NodeSpan[V comparable](s *Source, n *Node[V]) (int, int) {
+	start, end := n.Pos, s.end(n.Pos)
+	for _, c := range n.Children {
+		if c == nil {
+			continue
+		}
+		cStart, cEnd := NodeSpan(s, c)
+		if cStart < start {
+			start = cStart
+		}
+		if cEnd > end {
+			end = cEnd
+		}
+	}
+	return start, end
+}
+
+// NodeText returns the source text covered by the tree rooted at n.
+func NodeText[V comparable](s *Source, n *Node[V]) string {
+	return s.Text(NodeSpan(s, n))
+}
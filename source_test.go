@@ -0,0 +1,60 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ianlewis/runeio"
+)
+
+func TestNodeText(t *testing.T) {
+	t.Parallel()
+
+	r := runeio.NewReader(strings.NewReader("Hello big\nWorld!"))
+	src := NewSource()
+
+	// The first word is the parent of the following words.
+	pFn := func(_ context.Context, p *Parser[string]) (ParseFn[string], error) {
+		_ = p.Push(p.Next().Value)
+		for l := p.Next(); l != nil; l = p.Next() {
+			_ = p.Node(l.Value)
+		}
+		return nil, nil
+	}
+
+	root, err := LexParse(context.Background(), r, &wordState{}, pFn, WithSource(src))
+	if err != nil {
+		t.Fatalf("LexParse: %v", err)
+	}
+
+	hello := root.Children[0]
+	if got, want := NodeText(src, hello), "Hello big\nWorld!"; got != want {
+		t.Errorf("NodeText: want: %q, got: %q", want, got)
+	}
+	if got, want := NodeText(src, hello.Children[0]), "big"; got != want {
+		t.Errorf("NodeText: want: %q, got: %q", want, got)
+	}
+
+	start, end := NodeSpan(src, hello.Children[1])
+	if start != 10 || end != 16 {
+		t.Errorf("NodeSpan: want: (10, 16), got: (%d, %d)", start, end)
+	}
+	if got, want := src.Text(-1, 100), "Hello big\nWorld!"; got != want {
+		t.Errorf("Text: want: %q, got: %q", want, got)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// sourceMapping maps a position in the generated output to a position in a
+// source file. Lines and columns are zero based.
+type sourceMapping struct {
+	genLine, genColumn int
+	source             int
+	line, column       int
+}
+
+// SourceMap records mappings from positions in generated output, such as
+// transpiled code, back to positions in the input. It can be encoded as a
+// version 3 source map by encoding it as JSON. Lines and columns are zero
+// based. Version 3 source maps count columns in UTF-16 code units. Columns in
+// the generated output are given in UTF-16 code units, as counted by
+// SourceMapWriter. Columns in sources are given in runes, like the positions
+// of lexemes, and are converted to UTF-16 code units if the source's
+// LineIndex has been set with SetLineIndex. Otherwise they are used as-is,
+// which is only correct if the source's lines contain no runes outside the
+// Basic Multilingual Plane before the mapped column.
+type SourceMap struct {
+	// File is the name of the generated file.
+	File string
+
+	sources  []string
+	index    map[string]int
+	lines    map[string]*LineIndex
+	mappings []sourceMapping
+}
+
+// NewSourceMap returns a new empty SourceMap for the generated file.
+func NewSourceMap(file string) *SourceMap {
+	return &SourceMap{
+		File:  file,
+		index: map[string]int{},
+		lines: map[string]*LineIndex{},
+	}
+}
+
+// SetLineIndex sets the LineIndex for the source file. It is used to convert
+// the columns of mappings added afterward for the source from runes to UTF-16
+// code units.
+func (m *SourceMap) SetLineIndex(source string, idx *LineIndex) {
+	m.lines[source] = idx
+}
+
+// Add adds a mapping from the line and column in the generated output to the
+// line and column in the source file.
+func (m *SourceMap) Add(genLine, genColumn int, source string, line, column int) {
+	if idx := m.lines[source]; idx != nil {
+		column = idx.utf16Column(line, column)
+	}
+	i, ok := m.index[source]
+	if !ok {
+		i = len(m.sources)
+		m.sources = append(m.sources, source)
+		m.index[source] = i
+	}
+	m.mappings = append(m.mappings, sourceMapping{
+		genLine:   genLine,
+		genColumn: genColumn,
+		source:    i,
+		line:      line,
+		column:    column,
+	})
+}
+
+// AddLexeme adds a mapping from the line and column in the generated output
+// to the position of the lexeme. The lexeme's File is used as the source.
+func (m *SourceMap) AddLexeme(genLine, genColumn int, l *Lexeme) {
+	m.Add(genLine, genColumn, l.File, l.Line, l.Column)
+}
+
+// Mappings returns the encoded mappings in the format used by version 3
+// source maps.
+func (m *SourceMap) Mappings() string {
+	mappings := append([]sourceMapping(nil), m.mappings...)
+	sort.SliceStable(mappings, func(i, j int) bool {
+		if mappings[i].genLine != mappings[j].genLine {
+			return mappings[i].genLine < mappings[j].genLine
+		}
+		return mappings[i].genColumn < mappings[j].genColumn
+	})
+
+	var b strings.Builder
+	var line, prevColumn, prevSource, prevLine, prevCol int
+	for i, s := range mappings {
+		if s.genLine > line {
+			b.WriteString(strings.Repeat(";", s.genLine-line))
+			line = s.genLine
+			prevColumn = 0
+		} else if i > 0 {
+			b.WriteByte(',')
+		}
+		writeVLQ(&b, s.genColumn-prevColumn)
+		writeVLQ(&b, s.source-prevSource)
+		writeVLQ(&b, s.line-prevLine)
+		writeVLQ(&b, s.column-prevCol)
+		prevColumn, prevSource, prevLine, prevCol = s.genColumn, s.source, s.line, s.column
+	}
+	return b.String()
+}
+
+// MarshalJSON implements json.Marshaler and returns a version 3 source map.
+func (m *SourceMap) MarshalJSON() ([]byte, error) {
+	sources := m.sources
+	if sources == nil {
+		sources = []string{}
+	}
+	b, err := json.Marshal(struct {
+		Version  int      `json:"version"`
+		File     string   `json:"file,omitempty"`
+		Sources  []string `json:"sources"`
+		Names    []string `json:"names"`
+		Mappings string   `json:"mappings"`
+	}{
+		Version:  3,
+		File:     m.File,
+		Sources:  sources,
+		Names:    []string{},
+		Mappings: m.Mappings(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding source map: %w", err)
+	}
+	return b, nil
+}
+
+// base64VLQ are the digits used in base 64 VLQ encoding.
+const base64VLQ = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// writeVLQ writes n to b using base 64 VLQ encoding.
+func writeVLQ(b *strings.Builder, n int) {
+	// The sign is stored in the least significant bit.
+	v := n << 1
+	if n < 0 {
+		v = (-n << 1) | 1
+	}
+	for {
+		digit := v & 0x1f
+		v >>= 5
+		if v > 0 {
+			// Set the continuation bit.
+			digit |= 0x20
+		}
+		b.WriteByte(base64VLQ[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
+
+// SourceMapWriter is an io.Writer that tracks the line and column of the
+// output written to it so that mappings can be added to a SourceMap at the
+// current output position. Columns are counted in UTF-16 code units.
+type SourceMapWriter struct {
+	w io.Writer
+	m *SourceMap
+
+	line, column int
+}
+
+// NewSourceMapWriter returns a new SourceMapWriter that writes to w and adds
+// mappings to m.
+func NewSourceMapWriter(w io.Writer, m *SourceMap) *SourceMapWriter {
+	return &SourceMapWriter{
+		w: w,
+		m: m,
+	}
+}
+
+// Write implements io.Writer.
+func (w *SourceMapWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	// NOTE: Runes are counted by their first byte so that runes split
+	//       between writes are counted once. Four byte UTF-8 sequences
+	//       encode runes outside the Basic Multilingual Plane, which are
+	//       encoded as a surrogate pair in UTF-16.
+	for _, c := range p[:n] {
+		switch {
+		case c == '\n':
+			w.line++
+			w.column = 0
+		case c >= 0xf0 && c <= 0xf4:
+			w.column += 2
+		case utf8.RuneStart(c):
+			w.column++
+		}
+	}
+	//nolint:wrapcheck // Error doesn't need to be wrapped.
+	return n, err
+}
+
+// Map adds a mapping from the current output position to the line and column
+// in the source file.
+func (w *SourceMapWriter) Map(source string, line, column int) {
+	w.m.Add(w.line, w.column, source, line, column)
+}
+
+// MapLexeme adds a mapping from the current output position to the position
+// of the lexeme.
+func (w *SourceMapWriter) MapLexeme(l *Lexeme) {
+	w.m.AddLexeme(w.line, w.column, l)
+}
+
+// MapNode adds a mapping from the current output position of w to the
+// position of the node in the source file.
+func MapNode[V comparable](w *SourceMapWriter, source string, n *Node[V]) {
+	w.Map(source, n.Line, n.Column)
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSourceMap(t *testing.T) {
+	t.Parallel()
+
+	m := NewSourceMap("out.js")
+	// Mappings are sorted by generated position.
+	m.Add(1, 2, "in.txt", 1, 0)
+	m.Add(0, 0, "in.txt", 0, 0)
+	m.Add(0, 4, "in.txt", 0, 4)
+	m.AddLexeme(3, 0, &Lexeme{File: "other.txt", Line: 20, Column: 1})
+
+	got, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	want := `{"version":3,"file":"out.js","sources":["in.txt","other.txt"],"names":[],"mappings":"AAAA,IAAI;EACJ;;ACmBC"}`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Marshal: (-want, +got): \n%s", diff)
+	}
+}
+
+func TestWriteVLQ(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[int]string{
+		0:    "A",
+		1:    "C",
+		-1:   "D",
+		15:   "e",
+		16:   "gB",
+		-16:  "hB",
+		1000: "w+B",
+	}
+
+	for n, want := range testCases {
+		n, want := n, want
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			t.Parallel()
+
+			var b strings.Builder
+			writeVLQ(&b, n)
+			if got := b.String(); got != want {
+				t.Errorf("writeVLQ(%d): want: %q, got: %q", n, want, got)
+			}
+		})
+	}
+}
+
+func TestSourceMapWriter(t *testing.T) {
+	t.Parallel()
+
+	m := NewSourceMap("")
+	var out strings.Builder
+	w := NewSourceMapWriter(&out, m)
+
+	MapNode(w, "in.txt", &Node[string]{Value: "a", Line: 0, Column: 0})
+	fmt.Fprint(w, "let a = ")
+	w.MapLexeme(&Lexeme{File: "in.txt", Line: 0, Column: 4})
+	fmt.Fprint(w, "\"é\";\n  ")
+	w.Map("in.txt", 1, 0)
+	fmt.Fprint(w, "b;")
+
+	if got, want := out.String(), "let a = \"é\";\n  b;"; got != want {
+		t.Errorf("output: want: %q, got: %q", want, got)
+	}
+	if got, want := m.Mappings(), "AAAA,QAAI;EACJ"; got != want {
+		t.Errorf("Mappings: want: %q, got: %q", want, got)
+	}
+}
+
+func TestSourceMap_utf16(t *testing.T) {
+	t.Parallel()
+
+	// "𝐱" is encoded as a surrogate pair in UTF-16.
+	m := NewSourceMap("")
+	m.SetLineIndex("in.txt", NewLineIndex([]byte("𝐱 = 1")))
+	var out strings.Builder
+	w := NewSourceMapWriter(&out, m)
+
+	// The rune is split between writes.
+	x := []byte("𝐱")
+	if _, err := w.Write(x[:2]); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := w.Write(x[2:]); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	w.MapLexeme(&Lexeme{File: "in.txt", Column: 2})
+
+	if got, want := m.Mappings(), "EAAG"; got != want {
+		t.Errorf("Mappings: want: %q, got: %q", want, got)
+	}
+}
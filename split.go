@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// NewSplitLexer returns a lexer that splits r into tokens using split, e.g.
+// bufio.ScanLines or bufio.ScanWords, so that line or word oriented formats
+// can reuse the standard library's split functions. The type of each lexeme
+// is returned by classify, which is passed the token. If classify is nil the
+// lexemes have type zero.
+//
+// Unlike FromScanner, lexemes are positioned. Input skipped by split, e.g.
+// the spaces between words, is counted so that the Pos, Line, and Column of
+// each lexeme are those of the start of its token in the input. This requires
+// that tokens returned by split are slices of the data passed to it, as they
+// are for the split functions in bufio. Other tokens are assumed to start at
+// the beginning of the data.
+func NewSplitLexer(r io.Reader, split bufio.SplitFunc, classify func([]byte) LexemeType) *FuncLexer {
+	var cur, start position
+
+	s := bufio.NewScanner(r)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := split(data, atEOF)
+		if advance < 0 || advance > len(data) {
+			// Let the scanner report the error.
+			return advance, token, err
+		}
+		if token != nil {
+			off := tokenOffset(data, token)
+			if off > advance {
+				off = 0
+			}
+			cur.update(data[:off])
+			start = cur
+			cur.update(data[off:advance])
+		} else {
+			cur.update(data[:advance])
+		}
+		return advance, token, err
+	})
+
+	return FromFunc(func(context.Context) (*Lexeme, error) {
+		if !s.Scan() {
+			if err := s.Err(); err != nil {
+				return nil, fmt.Errorf("scanning input: %w", err)
+			}
+			return nil, io.EOF
+		}
+
+		var typ LexemeType
+		if classify != nil {
+			typ = classify(s.Bytes())
+		}
+		return &Lexeme{
+			Type:   typ,
+			Value:  s.Text(),
+			Pos:    start.pos,
+			Line:   start.line,
+			Column: start.column,
+		}, nil
+	})
+}
+
+// position is a position in the input.
+type position struct {
+	pos    int
+	line   int
+	column int
+}
+
+// update advances the position past b.
+func (p *position) update(b []byte) {
+	for len(b) > 0 {
+		rn, size := utf8.DecodeRune(b)
+		b = b[size:]
+		p.pos++
+		if rn == '\n' {
+			p.line++
+			p.column = 0
+		} else {
+			p.column++
+		}
+	}
+}
+
+// tokenOffset returns the offset of token in data or zero if token isn't a
+// slice of data.
+func tokenOffset(data, token []byte) int {
+	off := cap(data) - cap(token)
+	if off < 0 || off+len(token) > len(data) {
+		return 0
+	}
+	if len(token) > 0 && &data[off] != &token[0] {
+		return 0
+	}
+	return off
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewSplitLexer(t *testing.T) {
+	t.Parallel()
+
+	const numType LexemeType = wordType + 1
+
+	// classify returns numType for tokens starting with a digit.
+	classify := func(b []byte) LexemeType {
+		if rn, _ := utf8.DecodeRune(b); unicode.IsDigit(rn) {
+			return numType
+		}
+		return wordType
+	}
+
+	testCases := map[string]struct {
+		input    string
+		split    bufio.SplitFunc
+		classify func([]byte) LexemeType
+		want     []*Lexeme
+	}{
+		"words": {
+			input:    "Hello  42\n  Foo",
+			split:    bufio.ScanWords,
+			classify: classify,
+			want: []*Lexeme{
+				{Type: wordType, Value: "Hello"},
+				{Type: numType, Value: "42", Pos: 7, Column: 7},
+				{Type: wordType, Value: "Foo", Pos: 12, Line: 1, Column: 2},
+			},
+		},
+		"lines": {
+			input: "héllo\r\n\nworld",
+			split: bufio.ScanLines,
+			want: []*Lexeme{
+				{Value: "héllo"},
+				{Value: "", Pos: 7, Line: 1},
+				{Value: "world", Pos: 8, Line: 2},
+			},
+		},
+		"runes": {
+			input:    "a世1",
+			split:    bufio.ScanRunes,
+			classify: classify,
+			want: []*Lexeme{
+				{Type: wordType, Value: "a"},
+				{Type: wordType, Value: "世", Pos: 1, Column: 1},
+				{Type: numType, Value: "1", Pos: 2, Column: 2},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := collect(NewSplitLexer(strings.NewReader(tc.input), tc.split, tc.classify))
+			if err != nil {
+				t.Fatalf("Err: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Lex: (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"fmt"
+	"sync"
+	"text/scanner"
+	"unicode"
+)
+
+// FirstUserType is the first LexemeType that doesn't collide with the types
+// of lexemes emitted by ScanningLexer, which are either runes, e.g. '+', or
+// negative text/scanner tokens, e.g. scanner.Ident. User-defined types that
+// may be mixed with ScanningLexer lexemes, e.g. by a Parser reading the
+// output of both a ScanningLexer and a Lexer, should start at FirstUserType.
+//
+//	const (
+//		keywordType = lexparse.FirstUserType + iota
+//		stringType
+//	)
+//
+// Types allocated by NewLexemeType also start at FirstUserType.
+const FirstUserType LexemeType = unicode.MaxRune + 1
+
+// lexemeTypes are the names of the types allocated by NewLexemeType.
+var lexemeTypes struct {
+	sync.Mutex
+	names []string
+}
+
+// allocBase is the first LexemeType allocated by NewLexemeType. It leaves
+// room for types declared relative to FirstUserType.
+const allocBase = FirstUserType + 1<<20
+
+// NewLexemeType allocates a new unique LexemeType with the given name. The
+// type is greater than FirstUserType so that it doesn't collide with the
+// types used by ScanningLexer, other allocated types, or types declared
+// relative to FirstUserType with fewer than 1<<20 values. TypeName returns the
+// name of the type. NewLexemeType is intended to be called when initializing
+// package variables and is safe for concurrent use.
+func NewLexemeType(name string) LexemeType {
+	lexemeTypes.Lock()
+	defer lexemeTypes.Unlock()
+	lexemeTypes.names = append(lexemeTypes.names, name)
+	return allocBase + LexemeType(len(lexemeTypes.names)-1)
+}
+
+// TypeName returns a name for typ. It returns the name of types allocated by
+// NewLexemeType, the name used by text/scanner for runes and text/scanner
+// tokens, e.g. "Ident" or `"+"`, and otherwise "LexemeType(n)".
+func TypeName(typ LexemeType) string {
+	if typ >= allocBase {
+		lexemeTypes.Lock()
+		defer lexemeTypes.Unlock()
+		if i := int(typ - allocBase); i < len(lexemeTypes.names) {
+			return lexemeTypes.names[i]
+		}
+	}
+	if typ >= scanner.Comment && typ < FirstUserType {
+		return scanner.TokenString(rune(typ))
+	}
+	return fmt.Sprintf("LexemeType(%d)", int(typ))
+}
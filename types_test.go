@@ -0,0 +1,79 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexparse
+
+import (
+	"testing"
+	"text/scanner"
+)
+
+var (
+	testKeywordType = NewLexemeType("Keyword")
+	testStringType  = NewLexemeType("String")
+)
+
+func TestNewLexemeType(t *testing.T) {
+	t.Parallel()
+
+	if testKeywordType == testStringType {
+		t.Errorf("NewLexemeType: types are not unique: %v", testKeywordType)
+	}
+	for _, typ := range []LexemeType{testKeywordType, testStringType} {
+		if typ < FirstUserType {
+			t.Errorf("NewLexemeType: want: >= %v, got: %v", FirstUserType, typ)
+		}
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		typ  LexemeType
+		want string
+	}{
+		"allocated": {
+			typ:  testKeywordType,
+			want: "Keyword",
+		},
+		"scanner token": {
+			typ:  scanner.Ident,
+			want: "Ident",
+		},
+		"rune": {
+			typ:  '+',
+			want: `"+"`,
+		},
+		"user type": {
+			typ:  FirstUserType + 1,
+			want: "LexemeType(1114113)",
+		},
+		"negative": {
+			typ:  -100,
+			want: "LexemeType(-100)",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := TypeName(tc.typ); got != tc.want {
+				t.Errorf("TypeName: want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}